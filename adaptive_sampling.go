@@ -0,0 +1,178 @@
+package logger
+
+import (
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultAdaptiveSamplingInterval is how often the sample rate is adjusted.
+	DefaultAdaptiveSamplingInterval = time.Second
+
+	// DefaultAdaptiveSamplingHighWater is the queue depth above which the sample rate is reduced.
+	DefaultAdaptiveSamplingHighWater = 1 << 14
+
+	// DefaultAdaptiveSamplingMinRate is the lowest sample rate for sampled events.
+	DefaultAdaptiveSamplingMinRate = 0.01
+)
+
+var (
+	// DefaultAdaptiveSamplingEvents are the (low severity) events sampled under load if none are given.
+	DefaultAdaptiveSamplingEvents = []EventFlag{EventDebug, EventInfo, EventWebRequestStart, EventWebRequestHeaders, EventWebRequestPostBody, EventWebResponse}
+)
+
+// NewAdaptiveSampling returns a load aware sampler for the given (or default) events.
+// The sample rate starts at 1 (every event is written), halves on each interval the queue is over
+// its high water mark or events were dropped, and doubles back once the queue is under half the high water mark.
+func NewAdaptiveSampling(events ...EventFlag) *AdaptiveSampling {
+	if len(events) == 0 {
+		events = DefaultAdaptiveSamplingEvents
+	}
+	sampled := make(map[EventFlag]bool, len(events))
+	for _, event := range events {
+		sampled[event] = true
+	}
+	return &AdaptiveSampling{
+		events:    sampled,
+		interval:  DefaultAdaptiveSamplingInterval,
+		highWater: DefaultAdaptiveSamplingHighWater,
+		minRate:   DefaultAdaptiveSamplingMinRate,
+		rate:      math.Float64bits(1),
+	}
+}
+
+// AdaptiveSampling reduces the sample rate of low severity events while the agent is under pressure.
+type AdaptiveSampling struct {
+	events    map[EventFlag]bool
+	interval  time.Duration
+	highWater int
+	minRate   float64
+
+	rate        uint64 // float64 bits
+	lastDropped int64
+	sampledOut  int64
+}
+
+// Interval returns how often the sample rate is adjusted.
+func (as *AdaptiveSampling) Interval() time.Duration { return as.interval }
+
+// SetInterval sets how often the sample rate is adjusted.
+func (as *AdaptiveSampling) SetInterval(interval time.Duration) { as.interval = interval }
+
+// HighWater returns the queue depth above which the sample rate is reduced.
+func (as *AdaptiveSampling) HighWater() int { return as.highWater }
+
+// SetHighWater sets the queue depth above which the sample rate is reduced.
+func (as *AdaptiveSampling) SetHighWater(highWater int) { as.highWater = highWater }
+
+// MinRate returns the lowest sample rate.
+func (as *AdaptiveSampling) MinRate() float64 { return as.minRate }
+
+// SetMinRate sets the lowest sample rate.
+func (as *AdaptiveSampling) SetMinRate(minRate float64) { as.minRate = minRate }
+
+// Rate returns the current sample rate, between the min rate and 1.
+func (as *AdaptiveSampling) Rate() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&as.rate))
+}
+
+// SampledOut returns the number of events skipped by the sampler.
+func (as *AdaptiveSampling) SampledOut() int64 {
+	return atomic.LoadInt64(&as.sampledOut)
+}
+
+// IsSampled returns if an event is subject to sampling.
+func (as *AdaptiveSampling) IsSampled(eventFlag EventFlag) bool {
+	return as.events[eventFlag]
+}
+
+// shouldWrite returns if an event should be written at the current sample rate.
+func (as *AdaptiveSampling) shouldWrite(eventFlag EventFlag) bool {
+	if !as.events[eventFlag] {
+		return true
+	}
+	rate := as.Rate()
+	if rate >= 1 || rand.Float64() < rate {
+		return true
+	}
+	atomic.AddInt64(&as.sampledOut, 1)
+	return false
+}
+
+// adjust updates the sample rate from the queue depth and the total number of dropped events.
+func (as *AdaptiveSampling) adjust(queueDepth int, dropped int64) {
+	rate := as.Rate()
+	if queueDepth >= as.highWater || dropped > as.lastDropped {
+		rate = math.Max(rate/2, as.minRate)
+	} else if queueDepth < as.highWater/2 {
+		rate = math.Min(rate*2, 1)
+	}
+	as.lastDropped = dropped
+	atomic.StoreUint64(&as.rate, math.Float64bits(rate))
+}
+
+// AdaptiveSampling returns the agent's adaptive sampler, if one is started.
+// It takes no locks, since it's checked for every event.
+func (da *Agent) AdaptiveSampling() *AdaptiveSampling {
+	if da == nil {
+		return nil
+	}
+	sampling, _ := da.root().adaptiveSampling.Load().(*AdaptiveSampling)
+	return sampling
+}
+
+// StartAdaptiveSampling starts sampling low severity events under load (see `NewAdaptiveSampling`).
+// The sampler is shared by the root agent and the agents derived from it; it replaces any sampler already started.
+func (da *Agent) StartAdaptiveSampling(sampling *AdaptiveSampling) {
+	root := da.root()
+	root.StopAdaptiveSampling()
+
+	stop := make(chan struct{})
+	root.eventsLock.Lock()
+	root.adaptiveSampling.Store(sampling)
+	root.samplingStop = stop
+	root.eventsLock.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(sampling.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				sampling.adjust(root.QueueDepth(), root.totalDropped())
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopAdaptiveSampling stops sampling; every enabled event is written again.
+func (da *Agent) StopAdaptiveSampling() {
+	root := da.root()
+	root.eventsLock.Lock()
+	defer root.eventsLock.Unlock()
+	if root.samplingStop != nil {
+		close(root.samplingStop)
+		root.samplingStop = nil
+	}
+	root.adaptiveSampling.Store((*AdaptiveSampling)(nil))
+}
+
+// shouldWrite returns if an enabled event survives the root agent's adaptive sampler.
+func (da *Agent) shouldWrite(eventFlag EventFlag) bool {
+	if sampling := da.AdaptiveSampling(); sampling != nil {
+		return sampling.shouldWrite(eventFlag)
+	}
+	return true
+}
+
+// totalDropped returns the total number of events dropped.
+func (da *Agent) totalDropped() (total int64) {
+	for _, count := range da.DroppedEvents() {
+		total += count
+	}
+	return
+}
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestAdaptiveSamplingAdjust(t *testing.T) {
+	assert := assert.New(t)
+
+	sampling := NewAdaptiveSampling()
+	sampling.SetHighWater(100)
+	sampling.SetMinRate(0.25)
+	assert.Equal(1.0, sampling.Rate())
+
+	sampling.adjust(100, 0)
+	assert.Equal(0.5, sampling.Rate())
+	sampling.adjust(10, 5)
+	assert.Equal(0.25, sampling.Rate())
+	sampling.adjust(100, 5)
+	assert.Equal(0.25, sampling.Rate())
+
+	sampling.adjust(75, 5)
+	assert.Equal(0.25, sampling.Rate(), "between the high water mark and half of it the rate holds")
+	sampling.adjust(10, 5)
+	assert.Equal(0.5, sampling.Rate())
+	sampling.adjust(10, 5)
+	sampling.adjust(10, 5)
+	assert.Equal(1.0, sampling.Rate())
+}
+
+func TestAgentAdaptiveSampling(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventDebug, EventError), NewWriter(buffer))
+	defer da.Close()
+
+	sampling := NewAdaptiveSampling(EventDebug)
+	sampling.SetInterval(time.Hour)
+	sampling.SetMinRate(0)
+	da.Clone().StartAdaptiveSampling(sampling)
+	assert.Equal(sampling, da.AdaptiveSampling())
+
+	for x := 0; x < 64; x++ {
+		sampling.adjust(sampling.HighWater(), 0)
+	}
+	for x := 0; x < 10; x++ {
+		da.Debugf("debug %d", x)
+	}
+	da.Errorf("error")
+	da.Flush()
+
+	assert.Equal(int64(10), sampling.SampledOut())
+	assert.False(strings.Contains(buffer.String(), "debug"), buffer.String())
+	assert.True(strings.Contains(buffer.String(), "error"), buffer.String())
+
+	da.StopAdaptiveSampling()
+	assert.Nil(da.AdaptiveSampling())
+	da.Debugf("debug after")
+	da.Flush()
+	assert.True(strings.Contains(buffer.String(), "debug after"), buffer.String())
+}
@@ -1,19 +1,26 @@
 package logger
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/blendlabs/go-workqueue"
 )
 
 var (
-	// DefaultAgentQueueWorkers is the number of consumers (goroutines) for the agent work queue.
+	// DefaultAgentQueueWorkers is the minimum number of consumers (goroutines) for the agent work queue.
 	DefaultAgentQueueWorkers = 4
 
+	// DefaultAgentQueueMaxWorkers is the maximum number of consumers the agent work queue scales up to under load.
+	DefaultAgentQueueMaxWorkers = 32
+
+	// DefaultAgentQueueShards is the number of single worker shards used when the agent orders events.
+	DefaultAgentQueueShards = 4
+
 	// DefaultAgentQueueLength is the maximum number of items to buffer in the event queue.
 	DefaultAgentQueueLength = 1 << 20 // 1mm items
 )
@@ -26,6 +33,9 @@ var (
 var (
 	// DefaultAgentVerbosity is the default verbosity for a diagnostics agent inited from the environment.
 	DefaultAgentVerbosity = NewEventFlagSet(EventFatalError, EventError, EventWebRequest, EventInfo)
+
+	// DefaultAgentPriorityEvents are the events written ahead of other queued events.
+	DefaultAgentPriorityEvents = []EventFlag{EventFatalError, EventError}
 )
 
 // Default returnes a default Agent singleton.
@@ -42,29 +52,44 @@ func SetDefault(agent *Agent) {
 
 // New returns a new diagnostics with a given bitflag verbosity.
 func New(events *EventFlagSet) *Agent {
-	return &Agent{
-		events:         events,
-		eventQueue:     newEventQueue(),
-		eventListeners: map[EventFlag][]EventListener{},
-		debugListeners: []EventListener{},
-		writer:         NewWriterWithError(os.Stdout, os.Stderr),
+	agent := &Agent{
+		eventQueue: newEventQueue(),
+		metaOutput: NewSyncOutput(os.Stderr),
+		started:    time.Now(),
 	}
+	agent.eventQueue.SetPanicHandler(agent.queuePanicked)
+	agent.priorityEvents.Store(newPriorityEvents())
+	agent.events.Store(events.clone())
+	agent.eventListeners.Store(newListenerRegistry())
+	agent.writer.Store(NewWriterWithError(os.Stdout, os.Stderr))
+	agent.categories.Store(newCategoryRouting())
+	return agent
 }
 
 // NewWithWriter returns a new diagnostics with a given bitflag verbosity and writer.
 func NewWithWriter(events *EventFlagSet, writer *Writer) *Agent {
-	return &Agent{
-		events:         events,
-		eventQueue:     newEventQueue(),
-		eventListeners: map[EventFlag][]EventListener{},
-		debugListeners: []EventListener{},
-		writer:         writer,
+	agent := &Agent{
+		eventQueue: newEventQueue(),
+		metaOutput: NewSyncOutput(os.Stderr),
+		started:    time.Now(),
 	}
+	agent.eventQueue.SetPanicHandler(agent.queuePanicked)
+	agent.priorityEvents.Store(newPriorityEvents())
+	agent.events.Store(events.clone())
+	agent.eventListeners.Store(newListenerRegistry())
+	agent.writer.Store(writer)
+	writer.SetMetaReporter(agent.Metaf)
+	agent.categories.Store(newCategoryRouting())
+	return agent
 }
 
 // NewFromEnvironment returns a new diagnostics with a given bitflag verbosity.
 func NewFromEnvironment() *Agent {
-	return NewWithWriter(NewEventFlagSetFromEnvironment(), NewWriterFromEnvironment())
+	agent := NewWithWriter(NewEventFlagSetFromEnvironment(), NewWriterFromEnvironment())
+	if categoryCSV := os.Getenv(EnvironmentVariableLogCategories); len(categoryCSV) > 0 {
+		agent.SetCategoriesFromCSV(categoryCSV)
+	}
+	return agent
 }
 
 // All returns a valid agent that fires all events.
@@ -85,97 +110,286 @@ func None(writer ...*Writer) *Agent {
 
 // Agent is a handler for various logging events with descendent handlers.
 type Agent struct {
-	writer             *Writer
+	writer             atomic.Value // *Writer, see `SetWriter`
+	categories         atomic.Value // *categoryRouting, replaced (never mutated) on change
 	eventsLock         sync.Mutex
-	events             *EventFlagSet
+	events             atomic.Value // *EventFlagSet, replaced (never mutated) on change
+	priorityEvents     atomic.Value // map[EventFlag]bool, replaced (never mutated) on change
+	syncFatal          bool
+	eagerFormatting    int32
+	sequenceNumbers    int32
+	profilerLabels     int32
+	suppressions       []*SuppressionRule
+	adaptiveSampling   atomic.Value // *AdaptiveSampling, see `StartAdaptiveSampling`
+	samplingStop       chan struct{}
+	governor           *MemoryGovernor
+	governorStop       chan struct{}
+	errorClassifier    *ErrorClassifier
+	component          string
+	category           Category
+	categoryAgents     sync.Map     // Category -> *Agent, see `WithCategory`
+	componentEvents    atomic.Value // map[string]*EventFlagSet, replaced (never mutated) on change
+	ordering           atomic.Value // *eventOrdering, replaced (never mutated) on change
 	eventListenersLock sync.Mutex
-	eventListeners     map[EventFlag][]EventListener
-	debugListeners     []EventListener
-	eventQueue         *workqueue.Queue
+	eventListeners     atomic.Value // *listenerRegistry, replaced (never mutated) on change
+	eventQueue         *EventQueue
+	parent             *Agent
+	listenerParent     *Agent       // the agent a derived agent inherits listeners (and default fields) from
+	defaultFields      atomic.Value // Fields, replaced (never mutated) on change
+	namespace          atomic.Value // string, see `SetNamespace`
+
+	metaOutput           io.Writer
+	started              time.Time
+	lastSaturationReport int64
+	sequence             uint64
+	dropped              droppedEvents
+	counters             eventCounters
+	recent               recentEvents
+	fatal                fatalHooks
 }
 
 // Writer returns the inner Logger for the diagnostics agent.
 func (da *Agent) Writer() *Writer {
-	return da.writer
+	writer, _ := da.writer.Load().(*Writer)
+	return writer
+}
+
+// SetWriter swaps the agent's writer at runtime, e.g. to switch from stdout to a file without restarting.
+// Events queued before the call are flushed to the old writer first; the old writer is not closed.
+func (da *Agent) SetWriter(writer *Writer) {
+	da.Flush()
+	da.writer.Store(writer)
+	writer.SetMetaReporter(da.Metaf)
 }
 
 // EventQueue returns the inner event queue for the agent.
-func (da *Agent) EventQueue() *workqueue.Queue {
+// It returned a `*workqueue.Queue` before the queue scaled its workers; see the README for upgrading.
+func (da *Agent) EventQueue() *EventQueue {
 	return da.eventQueue
 }
 
-// Events returns the EventFlagSet
+// Namespace returns the namespace (or tenant) stamped on the agent's output: its own, the one it inherits from
+// the agent it was derived from, or else its writer's (see `Writer.SetNamespace`).
+func (da *Agent) Namespace() string {
+	if da == nil {
+		return ""
+	}
+	if namespace := da.agentNamespace(); len(namespace) > 0 {
+		return namespace
+	}
+	if writer := da.Writer(); writer != nil {
+		return writer.Namespace()
+	}
+	return ""
+}
+
+// SetNamespace sets the namespace (or tenant) stamped on the agent's output, and inherited by agents derived
+// from it that don't set their own. It travels with each event rather than being set on the (shared) writer,
+// so it doesn't change the namespace of the agent a clone was derived from, or of its siblings.
+// Listeners can read it with `NamespaceFromTimeSource` to route or redact per tenant.
+func (da *Agent) SetNamespace(namespace string) {
+	da.namespace.Store(namespace)
+}
+
+// agentNamespace returns the namespace set on the agent (or the agent it was derived from), if any.
+func (da *Agent) agentNamespace() string {
+	if namespace, _ := da.namespace.Load().(string); len(namespace) > 0 {
+		return namespace
+	}
+	if da.listenerParent != nil {
+		return da.listenerParent.agentNamespace()
+	}
+	return ""
+}
+
+// Events returns a copy of the EventFlagSet; changing it doesn't change the agent's verbosity
+// (use `SetVerbosity`, `EnableEvent` or `DisableEvent`).
 func (da *Agent) Events() *EventFlagSet {
 	if da == nil {
 		return nil
 	}
-	return da.events
+	if len(da.component) > 0 {
+		return da.parent.componentEventsFor(da.component).clone()
+	}
+	return da.loadEvents().clone()
 }
 
 // SetVerbosity sets the agent verbosity synchronously.
+// The agent keeps a copy of the flag set, so it's safe to change it afterwards.
 func (da *Agent) SetVerbosity(events *EventFlagSet) {
+	if len(da.component) > 0 {
+		da.parent.SetComponentVerbosity(da.component, events)
+		return
+	}
 	da.eventsLock.Lock()
-	da.events = events
+	da.events.Store(events.clone())
 	da.eventsLock.Unlock()
 }
 
 // EnableEvent flips the bit flag for a given event.
 func (da *Agent) EnableEvent(eventFlag EventFlag) {
+	if len(da.component) > 0 {
+		da.parent.updateComponentEvents(da.component, func(events *EventFlagSet) *EventFlagSet {
+			events.Enable(eventFlag)
+			return events
+		})
+		return
+	}
 	da.eventsLock.Lock()
-	da.events.Enable(eventFlag)
+	events := da.loadEvents().clone()
+	events.Enable(eventFlag)
+	da.events.Store(events)
 	da.eventsLock.Unlock()
 }
 
 // DisableEvent flips the bit flag for a given event.
 func (da *Agent) DisableEvent(eventFlag EventFlag) {
+	if len(da.component) > 0 {
+		da.parent.updateComponentEvents(da.component, func(events *EventFlagSet) *EventFlagSet {
+			events.Disable(eventFlag)
+			return events
+		})
+		return
+	}
 	da.eventsLock.Lock()
-	da.events.Disable(eventFlag)
+	events := da.loadEvents().clone()
+	events.Disable(eventFlag)
+	da.events.Store(events)
 	da.eventsLock.Unlock()
 }
 
 // IsEnabled asserts if a flag value is set or not.
+// It takes no locks, so it's safe (and cheap) to call concurrently with verbosity changes.
 func (da *Agent) IsEnabled(flagValue EventFlag) bool {
 	if da == nil {
 		return false
 	}
-	da.eventsLock.Lock()
-	enabled := da.events.IsEnabled(flagValue)
-	da.eventsLock.Unlock()
-	return enabled
+	if !da.IsCategoryEnabled(da.EventCategory(flagValue)) {
+		return false
+	}
+	if len(da.component) > 0 {
+		return da.parent.componentEventsFor(da.component).IsEnabled(flagValue)
+	}
+	return da.loadEvents().IsEnabled(flagValue)
 }
 
-// HasListener returns if there are registered listener for an event.
-func (da *Agent) HasListener(event EventFlag) bool {
+// loadEvents returns the current flag set, which must not be mutated.
+func (da *Agent) loadEvents() *EventFlagSet {
+	events, _ := da.events.Load().(*EventFlagSet)
+	return events
+}
+
+// IsPriorityEvent returns if an event is written ahead of other queued events.
+// Like `IsEnabled` it takes no locks.
+func (da *Agent) IsPriorityEvent(flagValue EventFlag) bool {
 	if da == nil {
 		return false
 	}
-	if da.eventListeners == nil {
+	priorityEvents, _ := da.priorityEvents.Load().(map[EventFlag]bool)
+	return priorityEvents[flagValue]
+}
+
+// SetPriorityEvents sets the events written ahead of other queued events.
+func (da *Agent) SetPriorityEvents(eventFlags ...EventFlag) {
+	priorityEvents := map[EventFlag]bool{}
+	for _, eventFlag := range eventFlags {
+		priorityEvents[eventFlag] = true
+	}
+	da.priorityEvents.Store(priorityEvents)
+}
+
+// SyncFatal returns if fatal errors are written synchronously.
+func (da *Agent) SyncFatal() bool {
+	if da == nil {
 		return false
 	}
-	listeners, hasHandler := da.eventListeners[event]
-	if !hasHandler {
+	da.eventsLock.Lock()
+	defer da.eventsLock.Unlock()
+	return da.syncFatal
+}
+
+// SetSyncFatal sets if fatal errors are written directly to the error stream, bypassing the queue,
+// so a crash immediately after logging never loses the line. Listeners are still triggered asynchronously.
+func (da *Agent) SetSyncFatal(syncFatal bool) {
+	da.eventsLock.Lock()
+	da.syncFatal = syncFatal
+	da.eventsLock.Unlock()
+}
+
+// HasListener returns if there are registered listener for an event, including debug listeners.
+func (da *Agent) HasListener(event EventFlag) bool {
+	if da == nil {
 		return false
 	}
-	return len(listeners) > 0
+	for agent := da; agent != nil; agent = agent.listenerParentFor(event) {
+		if agent.loadListeners().hasListeners(event) {
+			return true
+		}
+	}
+	return false
 }
 
 // AddEventListener adds a listener for errors.
 func (da *Agent) AddEventListener(eventFlag EventFlag, listener EventListener) {
 	da.eventListenersLock.Lock()
-	da.eventListeners[eventFlag] = append(da.eventListeners[eventFlag], listener)
+	da.eventListeners.Store(da.loadListeners().withListener(eventFlag, listener))
 	da.eventListenersLock.Unlock()
 }
 
 // AddDebugListener adds a listener that will fire on *all* events.
 func (da *Agent) AddDebugListener(listener EventListener) {
 	da.eventListenersLock.Lock()
-	da.debugListeners = append(da.debugListeners, listener)
+	da.eventListeners.Store(da.loadListeners().withDebugListener(listener))
 	da.eventListenersLock.Unlock()
 }
 
-// RemoveListeners clears *all* listeners for an EventFlag.
+// RemoveListeners clears *all* listeners for an EventFlag, masking any it inherits (see `MaskListeners`).
 func (da *Agent) RemoveListeners(eventFlag EventFlag) {
-	delete(da.eventListeners, eventFlag)
+	da.eventListenersLock.Lock()
+	da.eventListeners.Store(da.loadListeners().withoutListeners(eventFlag))
+	da.eventListenersLock.Unlock()
+}
+
+// InheritsListeners returns if the agent fires the listeners of the agent it was derived from (see `Clone`).
+func (da *Agent) InheritsListeners() bool {
+	return da.listenerParent != nil && da.loadListeners().inherit
+}
+
+// SetInheritListeners sets if a derived agent fires the listeners of the agent it was derived from,
+// including listeners added to it later; derived agents inherit them by default.
+func (da *Agent) SetInheritListeners(inherit bool) {
+	da.eventListenersLock.Lock()
+	da.eventListeners.Store(da.loadListeners().withInherit(inherit))
+	da.eventListenersLock.Unlock()
+}
+
+// MaskListeners stops a derived agent firing the listeners it inherits for events, e.g. to keep a noisy
+// component's errors out of the root agent's alerting hooks. Its own listeners still fire.
+func (da *Agent) MaskListeners(eventFlags ...EventFlag) {
+	da.eventListenersLock.Lock()
+	da.eventListeners.Store(da.loadListeners().withMasked(true, eventFlags...))
+	da.eventListenersLock.Unlock()
+}
+
+// UnmaskListeners lets a derived agent fire the listeners it inherits for events again.
+func (da *Agent) UnmaskListeners(eventFlags ...EventFlag) {
+	da.eventListenersLock.Lock()
+	da.eventListeners.Store(da.loadListeners().withMasked(false, eventFlags...))
+	da.eventListenersLock.Unlock()
+}
+
+// listenerParentFor returns the agent whose listeners the agent inherits for an event, if any.
+func (da *Agent) listenerParentFor(eventFlag EventFlag) *Agent {
+	if da.listenerParent != nil && da.loadListeners().inherits(eventFlag) {
+		return da.listenerParent
+	}
+	return nil
+}
+
+// loadListeners returns the current listener registry, which must not be mutated.
+func (da *Agent) loadListeners() *listenerRegistry {
+	listeners, _ := da.eventListeners.Load().(*listenerRegistry)
+	return listeners
 }
 
 // OnEvent fires the currently configured event listeners.
@@ -183,8 +397,15 @@ func (da *Agent) OnEvent(eventFlag EventFlag, state ...interface{}) {
 	if da == nil {
 		return
 	}
-	if da.IsEnabled(eventFlag) && da.HasListener(eventFlag) {
-		da.eventQueue.Enqueue(da.triggerListeners, append([]interface{}{TimeNow(), eventFlag}, state...)...)
+	da.onEvent(TimeNow(), eventFlag, state...)
+}
+
+// onEvent fires the listeners for an event that happened at a given time.
+func (da *Agent) onEvent(ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+	if da.IsEnabled(eventFlag) && da.HasListener(eventFlag) && da.shouldWrite(eventFlag) {
+		da.countEvent(eventFlag)
+		da.recordRecent(eventFlag, "", state...)
+		da.enqueue(da.triggerListeners, acquireState(state, da.numbered(ts), eventFlag)...)
 	}
 }
 
@@ -285,11 +506,19 @@ func (da *Agent) WriteEventf(event EventFlag, color AnsiColorCode, format string
 	if da == nil {
 		return
 	}
-	if da.IsEnabled(event) {
-		da.queueWrite(event, ColorLightYellow, format, args...)
+	da.writeEventf(TimeNow(), event, color, format, args...)
+}
+
+// writeEventf writes an event that happened at a given time to the standard output and triggers events.
+func (da *Agent) writeEventf(ts TimeSource, event EventFlag, color AnsiColorCode, format string, args ...interface{}) {
+	if da.IsEnabled(event) && da.shouldWrite(event) {
+		ts = da.numbered(ts)
+		da.countEvent(event)
+		da.recordRecent(event, format, args...)
+		da.queueWriteWithTimeSource(ts, event, ColorLightYellow, format, args...)
 
 		if da.HasListener(event) {
-			da.eventQueue.Enqueue(da.triggerListeners, append([]interface{}{TimeNow(), event, format}, args...)...)
+			da.enqueue(da.triggerListeners, acquireState(args, ts, event, format)...)
 		}
 	}
 }
@@ -299,11 +528,14 @@ func (da *Agent) WriteErrorEventf(event EventFlag, color AnsiColorCode, format s
 	if da == nil {
 		return
 	}
-	if da.IsEnabled(event) {
-		da.queueWriteError(event, ColorLightYellow, format, args...)
+	if da.IsEnabled(event) && da.shouldWrite(event) {
+		ts := da.numbered(TimeNow())
+		da.countEvent(event)
+		da.recordRecent(event, format, args...)
+		da.queueWriteErrorWithTimeSource(ts, event, ColorLightYellow, format, args...)
 
 		if da.HasListener(event) {
-			da.eventQueue.Enqueue(da.triggerListeners, append([]interface{}{TimeNow(), event, format}, args...)...)
+			da.enqueue(da.triggerListeners, acquireState(args, ts, event, format)...)
 		}
 	}
 }
@@ -314,16 +546,33 @@ func (da *Agent) ErrorEventWithState(event EventFlag, color AnsiColorCode, err e
 		return err
 	}
 	if err != nil {
-		if da.IsEnabled(event) {
-			da.queueWriteError(event, color, "%+v", err)
-			if da.HasListener(event) {
-				da.eventQueue.Enqueue(da.triggerListeners, append([]interface{}{TimeNow(), event, err}, state...)...)
-			}
+		event, color = da.classifyError(event, color, err)
+		if da.IsEnabled(event) && da.shouldWrite(event) {
+			da.errorEvent(TimeNow(), event, color, "%+v", err, state...)
+		}
+		if event == EventFatalError {
+			da.onFatal(err)
 		}
 	}
 	return err
 }
 
+// errorEvent writes a classified error event that happened at a given time with a format for its stack,
+// and triggers its listeners with the error and a given state.
+func (da *Agent) errorEvent(ts TimeSource, event EventFlag, color AnsiColorCode, format string, err error, state ...interface{}) {
+	ts = da.numbered(ts)
+	da.countEvent(event)
+	da.recordRecent(event, format, stackFormatter{err})
+	if IsSeverityAtLeast(event, EventWarning) {
+		da.queueWriteErrorWithTimeSource(ts, event, color, format, stackFormatter{err})
+	} else {
+		da.queueWriteWithTimeSource(ts, event, color, format, stackFormatter{err})
+	}
+	if da.HasListener(event) {
+		da.enqueue(da.triggerListeners, acquireState(state, ts, event, err)...)
+	}
+}
+
 // --------------------------------------------------------------------------------
 // synchronous methods
 // --------------------------------------------------------------------------------
@@ -333,34 +582,105 @@ func (da *Agent) Sync() *SyncAgent {
 	return &SyncAgent{a: da}
 }
 
+// Clone returns a new agent that shares the agent's event queue (and writer),
+// with its own copy of the agent's verbosity, e.g. to give a component its own verbosity.
+// It fires the agent's listeners as well as its own (see `SetInheritListeners` and `MaskListeners`).
+func (da *Agent) Clone() *Agent {
+	root := da
+	if da.parent != nil {
+		root = da.parent
+	}
+
+	da.eventsLock.Lock()
+	clone := &Agent{
+		syncFatal:       da.syncFatal,
+		eagerFormatting: atomic.LoadInt32(&da.eagerFormatting),
+		errorClassifier: da.errorClassifier,
+		profilerLabels:  atomic.LoadInt32(&da.profilerLabels),
+		suppressions:    da.suppressions,
+		component:       da.component,
+		eventQueue:      da.eventQueue,
+		parent:          root,
+		metaOutput:      da.metaOutput,
+		started:         da.started,
+	}
+	clone.events.Store(da.loadEvents().clone())
+	clone.priorityEvents.Store(da.priorityEvents.Load())
+	clone.ordering.Store(da.loadOrdering())
+	clone.writer.Store(da.Writer())
+	clone.category = da.category
+	da.eventsLock.Unlock()
+
+	clone.listenerParent = da
+	clone.eventListeners.Store(newListenerRegistry().withInherit(true))
+	return clone
+}
+
+// WithWriter returns a clone of the agent (see `Clone`) that writes to a different writer,
+// e.g. to give a component its own output target without running another queue.
+func (da *Agent) WithWriter(writer *Writer) *Agent {
+	clone := da.Clone()
+	clone.writer.Store(writer)
+	return clone
+}
+
 // --------------------------------------------------------------------------------
 // finalizers
 // --------------------------------------------------------------------------------
 
-// Close releases shared resources for the agent.
+// Close releases shared resources for the agent, writing the queued events before it closes the outputs.
+// Agents derived with `Clone` or `WithWriter` only close their own writer; the queue is left to the root agent.
 func (da *Agent) Close() (err error) {
-	if da.eventQueue != nil {
-		err = da.eventQueue.Close()
-		if err != nil {
-			return
+	return da.closeContext(context.Background())
+}
+
+// closeContext closes the agent, waiting for the queue's workers to write the remaining events until the context
+// ends; the outputs are closed either way.
+func (da *Agent) closeContext(ctx context.Context) (err error) {
+	da.StopDropReport()
+	if da.parent != nil {
+		if writer := da.Writer(); writer != nil && writer != da.parent.Writer() {
+			err = writer.Close()
 		}
+		return
+	}
+	da.StopAdaptiveSampling()
+	da.StopMemoryGovernor()
+	if orderedQueue, _ := da.orderedQueueAndOrdering(); orderedQueue != nil {
+		orderedQueue.CloseContext(ctx)
+	}
+	if da.eventQueue != nil {
+		da.eventQueue.CloseContext(ctx)
+	}
+	if err = da.closeCategoryWriters(); err != nil {
+		return
 	}
-	if da.writer != nil {
-		err = da.writer.Close()
+	if writer := da.Writer(); writer != nil {
+		err = writer.Close()
 	}
 	return
 }
 
+// Flush blocks until the currently queued events have been processed.
+// Unlike `Drain` it leaves the agent's verbosity alone and the agent open.
+// It must not be called from a listener, which would wait on itself.
+func (da *Agent) Flush() {
+	if da == nil || da.eventQueue == nil {
+		return
+	}
+	da.eventQueue.Flush()
+	if orderedQueue, _ := da.orderedQueueAndOrdering(); orderedQueue != nil {
+		orderedQueue.Flush()
+	}
+}
+
 // Drain waits for the agent to finish it's queue of events before closing.
 func (da *Agent) Drain() error {
 	if da == nil {
 		return nil
 	}
 	da.SetVerbosity(NewEventFlagSetNone())
-
-	for da.eventQueue.Len() > 0 {
-		time.Sleep(time.Millisecond)
-	}
+	da.Flush()
 	return da.Close()
 }
 
@@ -370,6 +690,7 @@ func (da *Agent) Drain() error {
 
 // triggerListeners triggers the currently configured event listeners.
 func (da *Agent) triggerListeners(actionState ...interface{}) error {
+	defer releaseState(actionState)
 	if len(actionState) < 2 {
 		return nil
 	}
@@ -383,20 +704,26 @@ func (da *Agent) triggerListeners(actionState ...interface{}) error {
 	if err != nil {
 		return err
 	}
+	timeSource = withNamespace(withFields(timeSource, da.DefaultFields()), da.agentNamespace())
+	if da.writerFor(eventFlag).isStructured() {
+		timeSource = withEvent(timeSource, eventFlag)
+	}
 
-	da.eventListenersLock.Lock()
-	listeners := da.eventListeners[eventFlag]
-	da.eventListenersLock.Unlock()
+	for agent := da; agent != nil; agent = agent.listenerParentFor(eventFlag) {
+		registry := agent.loadListeners()
+		if registry == nil {
+			continue
+		}
 
-	for x := 0; x < len(listeners); x++ {
-		listener := listeners[x]
-		listener(da.writer, timeSource, eventFlag, actionState[2:]...)
-	}
+		listeners := registry.events[eventFlag]
+		for x := 0; x < len(listeners); x++ {
+			listener := listeners[x]
+			da.invokeListener(listener, timeSource, eventFlag, actionState[2:]...)
+		}
 
-	if len(da.debugListeners) > 0 {
-		for x := 0; x < len(da.debugListeners); x++ {
-			listener := da.debugListeners[x]
-			listener(da.writer, timeSource, eventFlag, actionState[2:]...)
+		for x := 0; x < len(registry.debug); x++ {
+			listener := registry.debug[x]
+			da.invokeListener(listener, timeSource, eventFlag, actionState[2:]...)
 		}
 	}
 
@@ -405,30 +732,86 @@ func (da *Agent) triggerListeners(actionState ...interface{}) error {
 
 // printf checks an event flag and writes a message with a given color.
 func (da *Agent) queueWrite(eventFlag EventFlag, color AnsiColorCode, format string, args ...interface{}) {
+	da.queueWriteWithTimeSource(TimeNow(), eventFlag, color, format, args...)
+}
+
+// queueWriteWithTimeSource queues a message write for an event that happened at a given time.
+func (da *Agent) queueWriteWithTimeSource(ts TimeSource, eventFlag EventFlag, color AnsiColorCode, format string, args ...interface{}) {
 	if len(format) > 0 {
-		da.eventQueue.Enqueue(da.write, append([]interface{}{TimeNow(), eventFlag, color, format}, args...)...)
+		if da.EagerFormatting() {
+			if buf := da.formatEager(ts, eventFlag, color, format, args...); buf != nil {
+				da.enqueue(da.writeFormatted, acquireState(nil, ts, eventFlag, buf)...)
+			}
+			return
+		}
+		da.enqueue(da.write, acquireState(args, ts, eventFlag, color, format)...)
 	}
 }
 
 // errorf checks an event flag and writes a message to the error stream (if one is configured) with a given color.
 func (da *Agent) queueWriteError(eventFlag EventFlag, color AnsiColorCode, format string, args ...interface{}) {
+	da.queueWriteErrorWithTimeSource(TimeNow(), eventFlag, color, format, args...)
+}
+
+// queueWriteErrorWithTimeSource queues a message write to the error stream for an event that happened at a given time.
+func (da *Agent) queueWriteErrorWithTimeSource(ts TimeSource, eventFlag EventFlag, color AnsiColorCode, format string, args ...interface{}) {
 	if len(format) > 0 {
-		da.eventQueue.Enqueue(da.writeError, append([]interface{}{TimeNow(), eventFlag, color, format}, args...)...)
+		if eventFlag == EventFatalError && da.SyncFatal() {
+			da.writeError(acquireState(args, ts, eventFlag, color, format)...)
+			return
+		}
+		if da.EagerFormatting() {
+			if buf := da.formatEager(ts, eventFlag, color, format, args...); buf != nil {
+				da.enqueue(da.writeFormattedError, acquireState(nil, ts, eventFlag, buf)...)
+			}
+			return
+		}
+		da.enqueue(da.writeError, acquireState(args, ts, eventFlag, color, format)...)
 	}
 }
 
 func (da *Agent) write(actionState ...interface{}) error {
-	return da.writeWithOutput(da.writer.PrintfWithTimeSource, actionState...)
+	defer releaseState(actionState)
+	return da.onWriteError(da.writeToStream(false, actionState...), actionState...)
 }
 
 func (da *Agent) writeError(actionState ...interface{}) error {
-	return da.writeWithOutput(da.writer.ErrorfWithTimeSource, actionState...)
+	defer releaseState(actionState)
+	return da.onWriteError(da.writeToStream(true, actionState...), actionState...)
+}
+
+// writeToStream writes an event message to the output or error stream, as mapped by the writer (see `SetErrorStreamEvents`),
+// flushing buffered outputs for severe events (see `SetFlushSeverity`).
+func (da *Agent) writeToStream(toErrorOutput bool, actionState ...interface{}) error {
+	var eventFlag EventFlag
+	if len(actionState) > 1 {
+		eventFlag, _ = actionState[1].(EventFlag)
+	}
+	writer := da.writerFor(eventFlag)
+	output := writer.PrintfWithTimeSource
+	if writer.UsesErrorOutput(eventFlag, toErrorOutput) {
+		output = writer.ErrorfWithTimeSource
+	}
+	if err := da.writeWithOutput(writer, output, actionState...); err != nil {
+		return err
+	}
+	return writer.flushForSeverity(eventFlag)
 }
 
 type loggerOutputWithTimeSource func(ts TimeSource, format string, args ...interface{}) (int64, error)
 
-// writeEventMessage writes an event message.
-func (da *Agent) writeWithOutput(output loggerOutputWithTimeSource, actionState ...interface{}) error {
+// writeWithOutput writes an event message, with profiler labels if enabled.
+func (da *Agent) writeWithOutput(writer *Writer, output loggerOutputWithTimeSource, actionState ...interface{}) error {
+	if eventFlag, isLabeled := da.profilerLabelFor(actionState...); isLabeled {
+		var err error
+		withProfilerLabels(eventFlag, nil, func(context.Context) { err = da.writeWithOutputUnlabeled(writer, output, actionState...) })
+		return err
+	}
+	return da.writeWithOutputUnlabeled(writer, output, actionState...)
+}
+
+// writeWithOutputUnlabeled writes an event message.
+func (da *Agent) writeWithOutputUnlabeled(writer *Writer, output loggerOutputWithTimeSource, actionState ...interface{}) error {
 	if len(actionState) < 4 {
 		return nil
 	}
@@ -453,12 +836,29 @@ func (da *Agent) writeWithOutput(output loggerOutputWithTimeSource, actionState
 		return err
 	}
 
-	_, err = output(timeSource, "%s %s", da.writer.FormatEvent(eventFlag, labelColor), fmt.Sprintf(format, actionState[4:]...))
+	message := fmt.Sprintf(format, writer.SanitizeArgs(format, actionState[4:]...)...)
+	if da.isSuppressed(eventFlag, []byte(message)) {
+		return nil
+	}
+	_, err = output(da.recordTimeSource(writer, timeSource, eventFlag), "%s %s%s", writer.FormatEvent(eventFlag, labelColor), da.fieldsPrefix(writer, timeSource), message)
 	return err
 }
 
-func newEventQueue() *workqueue.Queue {
-	eq := workqueue.NewWithWorkers(DefaultAgentQueueWorkers)
+func newPriorityEvents() map[EventFlag]bool {
+	priorityEvents := map[EventFlag]bool{}
+	for _, eventFlag := range DefaultAgentPriorityEvents {
+		priorityEvents[eventFlag] = true
+	}
+	return priorityEvents
+}
+
+// queuePanicked reports a queued action (e.g. a write) that panicked.
+func (da *Agent) queuePanicked(recovered interface{}) {
+	da.Metaf("queued action panicked: %v", recovered)
+}
+
+func newEventQueue() *EventQueue {
+	eq := NewEventQueue(DefaultAgentQueueWorkers, DefaultAgentQueueMaxWorkers)
 	eq.SetMaxWorkItems(DefaultAgentQueueLength) //more than this and queuing will block
 	eq.Start()
 	return eq
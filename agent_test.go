@@ -19,6 +19,7 @@ func TestNewEventQueue(t *testing.T) {
 	defer eq.Close()
 	assert.Zero(eq.Len())
 	assert.Equal(DefaultAgentQueueWorkers, eq.NumWorkers())
+	assert.Equal(DefaultAgentQueueMaxWorkers, eq.MaxWorkers())
 	assert.Equal(DefaultAgentQueueLength, eq.MaxWorkItems())
 }
 
@@ -32,7 +33,7 @@ func TestNewAgent(t *testing.T) {
 	assert.NotNil(da)
 	assert.NotNil(da.Events())
 	assert.True(da.Events().IsAllEnabled())
-	assert.NotNil(da.eventListeners)
+	assert.NotNil(da.loadListeners())
 	assert.NotNil(da.eventQueue)
 }
 
@@ -113,12 +114,45 @@ func TestAgentVerbosity(t *testing.T) {
 	assert.False(da.IsEnabled(EventWebRequest))
 }
 
+func TestAgentVerbosityConcurrent(t *testing.T) {
+	assert := assert.New(t)
+
+	da := New(NewEventFlagSet(EventInfo))
+	wg := sync.WaitGroup{}
+	for x := 0; x < 4; x++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for y := 0; y < 100; y++ {
+				da.EnableEvent(EventDebug)
+				da.DisableEvent(EventDebug)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for y := 0; y < 100; y++ {
+				da.IsEnabled(EventDebug)
+				da.Events().IsEnabled(EventInfo)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.True(da.IsEnabled(EventInfo))
+	assert.False(da.IsEnabled(EventDebug))
+
+	events := NewEventFlagSet(EventInfo)
+	da.SetVerbosity(events)
+	events.Enable(EventDebug)
+	assert.False(da.IsEnabled(EventDebug))
+}
+
 func TestAgentAddEventListener(t *testing.T) {
 	assert := assert.New(t)
 
 	da := New(NewEventFlagSetAll())
 
-	assert.NotNil(da.eventListeners)
+	assert.NotNil(da.loadListeners())
 	da.AddEventListener(EventError, func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {})
 	assert.True(da.IsEnabled(EventError))
 	assert.True(da.HasListener(EventError))
@@ -134,7 +168,7 @@ func TestAgentOnEvent(t *testing.T) {
 	wg := sync.WaitGroup{}
 	wg.Add(1)
 
-	assert.NotNil(da.eventListeners)
+	assert.NotNil(da.loadListeners())
 	da.AddEventListener(EventError, func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
 		defer wg.Done()
 		assert.Equal(EventError, eventFlag)
@@ -160,7 +194,7 @@ func TestAgentOnEventMultipleListeners(t *testing.T) {
 	wg := sync.WaitGroup{}
 	wg.Add(2)
 
-	assert.NotNil(da.eventListeners)
+	assert.NotNil(da.loadListeners())
 	da.AddEventListener(EventError, func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
 		defer wg.Done()
 		assert.Equal(EventError, eventFlag)
@@ -191,7 +225,7 @@ func TestAgentOnEventUnhandled(t *testing.T) {
 	da := All(NewWriter(buffer))
 	defer da.Close()
 
-	assert.NotNil(da.eventListeners)
+	assert.NotNil(da.loadListeners())
 	da.AddEventListener(EventError, func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
 		assert.FailNow("The Error Handler shouldn't have fired")
 	})
@@ -210,7 +244,7 @@ func TestAgentOnEventUnflagged(t *testing.T) {
 	da := NewWithWriter(NewEventFlagSet(EventInfo, EventWebRequest), NewWriter(buffer))
 	defer da.Close()
 
-	assert.NotNil(da.eventListeners)
+	assert.NotNil(da.loadListeners())
 	da.AddEventListener(EventError, func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
 		assert.FailNow("The Error Handler shouldn't have fired")
 	})
@@ -226,7 +260,7 @@ func TestAgentTriggerListeners(t *testing.T) {
 	buffer := bytes.NewBuffer([]byte{})
 	da := NewWithWriter(NewEventFlagSetAll(), NewWriter(buffer))
 	defer da.Close()
-	da.writer.SetUseAnsiColors(false)
+	da.Writer().SetUseAnsiColors(false)
 
 	ts := TimeInstance(time.Date(2016, 01, 02, 03, 04, 05, 06, time.UTC))
 	da.AddEventListener(EventInfo, func(wr *Writer, ts TimeSource, e EventFlag, state ...interface{}) {
@@ -247,10 +281,10 @@ func TestAgentWriteEventMessageWithOutput(t *testing.T) {
 	da := NewWithWriter(NewEventFlagSetAll(), NewWriter(buffer))
 	defer da.Close()
 
-	da.writer.SetUseAnsiColors(false)
+	da.Writer().SetUseAnsiColors(false)
 
 	ts := TimeInstance(time.Date(2016, 01, 02, 03, 04, 05, 06, time.UTC))
-	err := da.writeWithOutput(da.writer.PrintfWithTimeSource, ts, EventFlag("test"), ColorWhite, "%s World", "Hello")
+	err := da.writeWithOutput(da.Writer(), da.Writer().PrintfWithTimeSource, ts, EventFlag("test"), ColorWhite, "%s World", "Hello")
 	assert.Nil(err)
 	assert.True(strings.HasPrefix(buffer.String(), time.Time(ts).Format(DefaultTimeFormat)))
 	assert.True(strings.HasSuffix(buffer.String(), "Hello World\n"))
@@ -265,7 +299,35 @@ func TestAgentRemoveListeners(t *testing.T) {
 	da.RemoveListeners(EventError)
 
 	assert.False(da.HasListener(EventError))
+	assert.True(da.HasListener(EventInfo))
+}
+
+func TestAgentListenersConcurrent(t *testing.T) {
+	assert := assert.New(t)
 
+	da := New(NewEventFlagSetAll())
+	wg := sync.WaitGroup{}
+	for x := 0; x < 4; x++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for y := 0; y < 100; y++ {
+				da.AddEventListener(EventDebug, func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {})
+				da.AddDebugListener(func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for y := 0; y < 100; y++ {
+				da.HasListener(EventDebug)
+				da.triggerListeners(TimeNow(), EventDebug)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(da.loadListeners().listeners(EventDebug), 400)
+	assert.Len(da.loadListeners().debug, 400)
 }
 
 func BenchmarkAgentIsEnabled(b *testing.B) {
@@ -280,3 +342,261 @@ func BenchmarkAgentIsEnabled(b *testing.B) {
 		}
 	}
 }
+
+func TestAgentNamespaceListener(t *testing.T) {
+	assert := assert.New(t)
+
+	da := New(NewEventFlagSetAll())
+	da.SetNamespace("tenant-a")
+	assert.Equal("tenant-a", da.Namespace())
+
+	var routed, defaulted bool
+	da.AddEventListener(EventInfo, NewNamespaceListener(map[string]EventListener{
+		"tenant-a": func(wr *Writer, ts TimeSource, e EventFlag, state ...interface{}) {
+			routed = true
+		},
+	}, func(wr *Writer, ts TimeSource, e EventFlag, state ...interface{}) {
+		defaulted = true
+	}))
+
+	da.Sync().OnEvent(EventInfo)
+	assert.True(routed)
+	assert.False(defaulted)
+}
+
+func TestAgentCloneNamespaceDoesNotLeak(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventInfo), NewWriter(buffer))
+	defer da.Close()
+	da.Writer().SetShowTimestamp(false)
+	da.Writer().SetUseAnsiColors(false)
+	da.SetNamespace("parent")
+
+	clone := da.Clone()
+	assert.Equal("parent", clone.Namespace(), "clones inherit the namespace")
+	clone.SetNamespace("tenant-a")
+	sibling := da.Clone()
+
+	assert.Equal("parent", da.Namespace())
+	assert.Equal("tenant-a", clone.Namespace())
+	assert.Equal("parent", sibling.Namespace())
+	assert.Empty(da.Writer().Namespace())
+
+	namespaces := make(chan string, 3)
+	da.AddEventListener(EventInfo, func(wr *Writer, ts TimeSource, e EventFlag, state ...interface{}) {
+		namespaces <- NamespaceFromTimeSource(ts)
+	})
+	da.Infof("from parent")
+	da.Flush()
+	clone.Infof("from clone")
+	da.Flush()
+	sibling.Infof("from sibling")
+	da.Flush()
+
+	assert.Equal("parent", <-namespaces)
+	assert.Equal("tenant-a", <-namespaces)
+	assert.Equal("parent", <-namespaces)
+	output := buffer.String()
+	assert.True(strings.Contains(output, "{parent} [info] from parent"), output)
+	assert.True(strings.Contains(output, "{tenant-a} [info] from clone"), output)
+	assert.True(strings.Contains(output, "{parent} [info] from sibling"), output)
+}
+
+// signalOutput is a test output that signals each write on a channel, so tests can wait
+// for queued writes before reading what was written.
+type signalOutput struct {
+	bytes.Buffer
+	written chan struct{}
+}
+
+func newSignalOutput() *signalOutput {
+	return &signalOutput{written: make(chan struct{}, 1024)}
+}
+
+func (so *signalOutput) Write(b []byte) (int, error) {
+	written, err := so.Buffer.Write(b)
+	so.written <- struct{}{}
+	return written, err
+}
+
+func TestAgentPriorityEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	da := New(NewEventFlagSetAll())
+	defer da.Close()
+	assert.True(da.IsPriorityEvent(EventError))
+	assert.True(da.IsPriorityEvent(EventFatalError))
+	assert.False(da.IsPriorityEvent(EventInfo))
+
+	da.SetPriorityEvents(EventWarning)
+	assert.True(da.IsPriorityEvent(EventWarning))
+	assert.False(da.IsPriorityEvent(EventError))
+	assert.True(da.Clone().IsPriorityEvent(EventWarning))
+	assert.Equal(DefaultEventQueuePriorityLength, cap(da.eventQueue.priority))
+}
+
+func TestAgentSyncFatal(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := All(NewWriter(buffer))
+	defer da.Close()
+	assert.False(da.SyncFatal())
+	da.SetSyncFatal(true)
+	assert.True(da.SyncFatal())
+
+	da.Fatalf("a fatal error")
+	assert.True(strings.Contains(buffer.String(), "a fatal error"), buffer.String())
+}
+
+func TestAgentFlush(t *testing.T) {
+	assert := assert.New(t)
+
+	output := bytes.NewBuffer(nil)
+	da := All(NewWriter(NewSyncOutput(output)))
+	defer da.Close()
+
+	for x := 0; x < 10; x++ {
+		da.Infof("line %d", x)
+	}
+	da.Flush()
+	assert.Equal(10, strings.Count(output.String(), "line"))
+	assert.True(da.Events().IsAllEnabled())
+	assert.True(da.EventQueue().Running())
+}
+
+func TestAgentClone(t *testing.T) {
+	assert := assert.New(t)
+
+	da := New(NewEventFlagSet(EventInfo))
+	defer da.Close()
+	da.AddEventListener(EventInfo, func(wr *Writer, ts TimeSource, e EventFlag, state ...interface{}) {})
+
+	clone := da.Clone()
+	assert.True(clone.EventQueue() == da.EventQueue())
+	assert.True(clone.Writer() == da.Writer())
+	assert.True(clone.HasListener(EventInfo))
+
+	clone.EnableEvent(EventDebug)
+	assert.True(clone.IsEnabled(EventDebug))
+	assert.False(da.IsEnabled(EventDebug))
+
+	clone.RemoveListeners(EventInfo)
+	assert.True(da.HasListener(EventInfo))
+
+	assert.Nil(clone.Close())
+	assert.True(da.EventQueue().Running())
+}
+
+func TestAgentCloneInheritsListeners(t *testing.T) {
+	assert := assert.New(t)
+
+	da := NewWithWriter(NewEventFlagSet(EventInfo, EventError), NewWriter(bytes.NewBuffer(nil)))
+	defer da.Close()
+
+	var lock sync.Mutex
+	fired := map[string]int{}
+	listener := func(name string) EventListener {
+		return func(wr *Writer, ts TimeSource, e EventFlag, state ...interface{}) {
+			lock.Lock()
+			fired[name+":"+string(e)]++
+			lock.Unlock()
+		}
+	}
+
+	clone := da.Clone()
+	assert.True(clone.InheritsListeners())
+	assert.False(da.InheritsListeners())
+	grandchild := clone.Clone()
+
+	da.AddEventListener(EventError, listener("root"))
+	clone.AddEventListener(EventInfo, listener("clone"))
+	assert.True(grandchild.HasListener(EventError), "listeners added to the parent after cloning are inherited")
+
+	grandchild.Errorf("error")
+	grandchild.Infof("info")
+	da.Infof("info")
+	da.Flush()
+	lock.Lock()
+	assert.Equal(1, fired["root:error"])
+	assert.Equal(1, fired["clone:info"], "listeners added to a clone don't fire for its parent")
+	lock.Unlock()
+
+	grandchild.MaskListeners(EventError)
+	assert.False(grandchild.HasListener(EventError))
+	grandchild.Errorf("masked")
+	grandchild.UnmaskListeners(EventError)
+	clone.SetInheritListeners(false)
+	grandchild.Errorf("detached")
+	assert.False(grandchild.HasListener(EventError))
+	grandchild.SetInheritListeners(true)
+	clone.SetInheritListeners(true)
+	grandchild.Errorf("inherited")
+	da.Flush()
+	lock.Lock()
+	assert.Equal(2, fired["root:error"])
+	lock.Unlock()
+}
+
+func TestAgentWithWriter(t *testing.T) {
+	assert := assert.New(t)
+
+	rootOutput := bytes.NewBuffer(nil)
+	da := All(NewWriter(NewSyncOutput(rootOutput)))
+	defer da.Close()
+
+	componentOutput := bytes.NewBuffer(nil)
+	component := da.WithWriter(NewWriter(NewSyncOutput(componentOutput)))
+	assert.True(component.EventQueue() == da.EventQueue())
+
+	component.Infof("component line")
+	da.Infof("root line")
+	da.Flush()
+	assert.True(strings.Contains(componentOutput.String(), "component line"))
+	assert.False(strings.Contains(componentOutput.String(), "root line"))
+	assert.True(strings.Contains(rootOutput.String(), "root line"))
+}
+
+func TestAgentSetWriter(t *testing.T) {
+	assert := assert.New(t)
+
+	oldBuffer := bytes.NewBuffer(nil)
+	da := All(NewWriter(oldBuffer))
+	defer da.Close()
+
+	da.Infof("before swap")
+	newBuffer := bytes.NewBuffer(nil)
+	newWriter := NewWriter(newBuffer)
+	da.SetWriter(newWriter)
+	assert.True(da.Writer() == newWriter)
+	assert.True(strings.Contains(oldBuffer.String(), "before swap"))
+
+	da.Infof("after swap")
+	da.Flush()
+	assert.True(strings.Contains(newBuffer.String(), "after swap"))
+	assert.False(strings.Contains(oldBuffer.String(), "after swap"))
+}
+
+func TestAgentSetWriterConcurrent(t *testing.T) {
+	assert := assert.New(t)
+
+	da := All(NewWriter(NewSyncOutput(bytes.NewBuffer(nil))))
+	defer da.Close()
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for x := 0; x < 100; x++ {
+			da.Infof("line %d", x)
+		}
+	}()
+	for x := 0; x < 5; x++ {
+		da.SetWriter(NewWriter(NewSyncOutput(bytes.NewBuffer(nil))))
+	}
+	wg.Wait()
+	da.Flush()
+	assert.NotNil(da.Writer())
+}
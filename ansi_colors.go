@@ -1,5 +1,12 @@
 package logger
 
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
 // AnsiColorCode represents an ansi color code fragment.
 type AnsiColorCode string
 
@@ -76,3 +83,133 @@ const (
 	// ColorReset is the posix escape code fragment to reset all formatting.
 	ColorReset AnsiColorCode = "0m"
 )
+
+// ColorProfile is the range of colors a terminal supports.
+type ColorProfile int
+
+const (
+	// ColorProfileTrueColor supports 24-bit colors; colors are written as is.
+	ColorProfileTrueColor ColorProfile = iota
+	// ColorProfile256 supports the 256 color palette; 24-bit colors are degraded to their nearest palette color.
+	ColorProfile256
+	// ColorProfileBasic supports the 16 basic colors; 256 and 24-bit colors are degraded to their nearest basic color.
+	ColorProfileBasic
+)
+
+// DetectColorProfile returns the color profile of the terminal from the `COLORTERM` and `TERM` environment variables.
+func DetectColorProfile() ColorProfile {
+	colorTerm := strings.ToLower(os.Getenv("COLORTERM"))
+	if colorTerm == "truecolor" || colorTerm == "24bit" {
+		return ColorProfileTrueColor
+	}
+	if strings.Contains(strings.ToLower(os.Getenv("TERM")), "256color") {
+		return ColorProfile256
+	}
+	return ColorProfileBasic
+}
+
+// Color256 returns the color code for a color in the 256 color palette.
+func Color256(color uint8) AnsiColorCode {
+	return AnsiColorCode(fmt.Sprintf("38;5;%dm", color))
+}
+
+// ColorRGB returns the color code for a 24-bit color.
+func ColorRGB(r, g, b uint8) AnsiColorCode {
+	return AnsiColorCode(fmt.Sprintf("38;2;%d;%d;%dm", r, g, b))
+}
+
+// Degrade returns the nearest color code supported by a color profile.
+func (acc AnsiColorCode) Degrade(profile ColorProfile) AnsiColorCode {
+	if profile == ColorProfileTrueColor {
+		return acc
+	}
+	var r, g, b, color int
+	if _, err := fmt.Sscanf(string(acc), "38;2;%d;%d;%dm", &r, &g, &b); err == nil {
+		if profile == ColorProfile256 {
+			return Color256(rgbTo256(r, g, b))
+		}
+		return rgbToBasic(r, g, b)
+	}
+	if profile == ColorProfileBasic {
+		if _, err := fmt.Sscanf(string(acc), "38;5;%dm", &color); err == nil {
+			if color < 8 {
+				return AnsiColorCode(strconv.Itoa(30+color) + "m")
+			}
+			if color < 16 {
+				return AnsiColorCode(strconv.Itoa(90+color-8) + "m")
+			}
+			r, g, b = color256ToRGB(color)
+			return rgbToBasic(r, g, b)
+		}
+	}
+	return acc
+}
+
+// rgbTo256 returns the nearest color in the 6x6x6 cube or the grayscale ramp of the 256 color palette.
+func rgbTo256(r, g, b int) uint8 {
+	if r == g && g == b {
+		if r < 8 {
+			return 16
+		}
+		if r > 248 {
+			return 231
+		}
+		return uint8(232 + (r-8)*24/247)
+	}
+	cube := func(value int) int { return (value*5 + 127) / 255 }
+	return uint8(16 + 36*cube(r) + 6*cube(g) + cube(b))
+}
+
+func color256ToRGB(color int) (r, g, b int) {
+	if color >= 232 {
+		level := 8 + (color-232)*10
+		return level, level, level
+	}
+	color -= 16
+	level := func(value int) int {
+		if value == 0 {
+			return 0
+		}
+		return 55 + value*40
+	}
+	return level(color / 36), level((color / 6) % 6), level(color % 6)
+}
+
+// rgbToBasic returns the nearest of the 16 basic colors.
+func rgbToBasic(r, g, b int) AnsiColorCode {
+	var code int
+	if r >= 128 {
+		code |= 1
+	}
+	if g >= 128 {
+		code |= 2
+	}
+	if b >= 128 {
+		code |= 4
+	}
+	if r >= 192 || g >= 192 || b >= 192 {
+		return AnsiColorCode(strconv.Itoa(90+code) + "m")
+	}
+	return AnsiColorCode(strconv.Itoa(30+code) + "m")
+}
+
+// StripAnsi returns a string without ansi escape sequences.
+func StripAnsi(value string) string {
+	return string(stripAnsi([]byte(value)))
+}
+
+// stripAnsi removes control sequences of the form `ESC [ params final-byte`.
+func stripAnsi(buffer []byte) []byte {
+	output := make([]byte, 0, len(buffer))
+	for x := 0; x < len(buffer); x++ {
+		if buffer[x] == '\033' && x+1 < len(buffer) && buffer[x+1] == '[' {
+			x += 2
+			for x < len(buffer) && (buffer[x] < 0x40 || buffer[x] > 0x7e) {
+				x++
+			}
+			continue
+		}
+		output = append(output, buffer[x])
+	}
+	return output
+}
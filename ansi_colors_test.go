@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"os"
 	"testing"
 
 	"github.com/blendlabs/go-assert"
@@ -15,3 +16,37 @@ func TestAnsiColorApply(t *testing.T) {
 	appliedBlack := ColorBlack.Apply("test")
 	assert.Equal(ColorBlack.escaped()+"test"+ColorReset.escaped(), appliedBlack)
 }
+
+func TestAnsiColorDegrade(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("38;5;196m", string(Color256(196)))
+	assert.Equal("38;2;255;0;0m", string(ColorRGB(255, 0, 0)))
+
+	assert.Equal(ColorRGB(255, 0, 0), ColorRGB(255, 0, 0).Degrade(ColorProfileTrueColor))
+	assert.Equal(Color256(196), ColorRGB(255, 0, 0).Degrade(ColorProfile256))
+	assert.Equal(ColorLightRed, ColorRGB(255, 0, 0).Degrade(ColorProfileBasic))
+	assert.Equal(ColorRed, ColorRGB(160, 0, 0).Degrade(ColorProfileBasic))
+	assert.Equal(ColorRed, Color256(1).Degrade(ColorProfileBasic))
+	assert.Equal(ColorLightBlue, Color256(12).Degrade(ColorProfileBasic))
+	assert.Equal(ColorLightRed, Color256(196).Degrade(ColorProfileBasic))
+	assert.Equal(ColorBlue, ColorBlue.Degrade(ColorProfileBasic))
+}
+
+func TestDetectColorProfile(t *testing.T) {
+	assert := assert.New(t)
+
+	oldColorTerm, oldTerm := os.Getenv("COLORTERM"), os.Getenv("TERM")
+	defer func() {
+		os.Setenv("COLORTERM", oldColorTerm)
+		os.Setenv("TERM", oldTerm)
+	}()
+
+	os.Setenv("COLORTERM", "truecolor")
+	assert.Equal(ColorProfileTrueColor, DetectColorProfile())
+	os.Setenv("COLORTERM", "")
+	os.Setenv("TERM", "xterm-256color")
+	assert.Equal(ColorProfile256, DetectColorProfile())
+	os.Setenv("TERM", "xterm")
+	assert.Equal(ColorProfileBasic, DetectColorProfile())
+}
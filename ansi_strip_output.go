@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"io"
+	"os"
+)
+
+// IsTerminal returns if an output is a terminal (character device).
+func IsTerminal(output io.Writer) bool {
+	file, isFile := output.(*os.File)
+	if !isFile {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// NewTerminalAwareOutput returns the output as is if it's a terminal, and otherwise wraps it
+// so ansi escape sequences are stripped before they're written.
+func NewTerminalAwareOutput(output io.Writer) io.Writer {
+	if IsTerminal(output) {
+		return output
+	}
+	return NewAnsiStripOutput(output)
+}
+
+// NewAnsiStripOutput returns a new output that strips ansi escape sequences before writing to the inner output.
+func NewAnsiStripOutput(output io.Writer) *AnsiStripOutput {
+	return &AnsiStripOutput{output: output}
+}
+
+// AnsiStripOutput strips ansi escape sequences (colors) from writes, for files, pipes and network sinks
+// that share a writer with a colorized terminal output.
+type AnsiStripOutput struct {
+	output io.Writer
+}
+
+// Write writes the buffer without escape sequences to the inner output.
+func (aso *AnsiStripOutput) Write(buffer []byte) (int, error) {
+	if _, err := aso.output.Write(stripAnsi(buffer)); err != nil {
+		return 0, err
+	}
+	return len(buffer), nil
+}
+
+// Flush flushes the inner output (if it buffers writes).
+func (aso *AnsiStripOutput) Flush() error {
+	return FlushOutput(aso.output)
+}
+
+// Reopen reopens the inner output (if it can reopen its files).
+func (aso *AnsiStripOutput) Reopen() error {
+	return ReopenOutput(aso.output)
+}
+
+// SetMetaReporter sets how the inner output reports problems that don't fail writes, see `OutputMetaReporter`.
+func (aso *AnsiStripOutput) SetMetaReporter(reporter MetaReporter) {
+	SetOutputMetaReporter(aso.output, reporter)
+}
+
+// Close closes the inner output (if it is an io.Closer).
+func (aso *AnsiStripOutput) Close() error {
+	if closer, isCloser := aso.output.(io.Closer); isCloser {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestAnsiStripOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	output := NewAnsiStripOutput(buffer)
+	colorized := ColorRed.Apply("error") + " " + ColorRGB(1, 2, 3).Apply("message")
+	written, err := output.Write([]byte(colorized))
+	assert.Nil(err)
+	assert.Equal(len(colorized), written)
+	assert.Equal("error message", buffer.String())
+	assert.Equal("error message", StripAnsi(colorized))
+}
+
+func TestNewTerminalAwareOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	assert.False(IsTerminal(buffer))
+	_, isStripped := NewTerminalAwareOutput(buffer).(*AnsiStripOutput)
+	assert.True(isStripped)
+}
@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBatchListenerMaxSize is the default number of events that triggers a batch flush.
+	DefaultBatchListenerMaxSize = 512
+	// DefaultBatchListenerInterval is the default interval batches are flushed on.
+	DefaultBatchListenerInterval = 5 * time.Second
+)
+
+// BatchedEvent is an event collected by a batch listener.
+type BatchedEvent struct {
+	Writer     *Writer
+	TimeSource TimeSource
+	EventFlag  EventFlag
+	State      []interface{}
+}
+
+// BatchEventListener is a listener for batches of events.
+type BatchEventListener func(events []BatchedEvent)
+
+// NewBatchListener returns a listener that collects events and hands them to a batch listener
+// once `maxSize` events are collected or `interval` elapses, whichever is first.
+// It's meant for exporters to bulk apis; register it with `AddEventListener(flag, batch.Listen)`.
+func NewBatchListener(listener BatchEventListener, maxSize int, interval time.Duration) *BatchListener {
+	bl := &BatchListener{
+		listener: listener,
+		maxSize:  maxSize,
+		stop:     make(chan struct{}),
+	}
+	if interval > 0 {
+		go bl.runInterval(interval)
+	}
+	return bl
+}
+
+// BatchListener collects events into batches.
+type BatchListener struct {
+	listener BatchEventListener
+	maxSize  int
+
+	syncRoot sync.Mutex
+	events   []BatchedEvent
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// Listen is the EventListener.
+func (bl *BatchListener) Listen(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+	bl.syncRoot.Lock()
+	bl.events = append(bl.events, BatchedEvent{Writer: writer, TimeSource: ts, EventFlag: eventFlag, State: append([]interface{}{}, state...)})
+	var batch []BatchedEvent
+	if bl.maxSize > 0 && len(bl.events) >= bl.maxSize {
+		batch, bl.events = bl.events, nil
+	}
+	bl.syncRoot.Unlock()
+
+	if len(batch) > 0 {
+		bl.listener(batch)
+	}
+}
+
+// Len returns the number of collected events waiting to be flushed.
+func (bl *BatchListener) Len() int {
+	bl.syncRoot.Lock()
+	defer bl.syncRoot.Unlock()
+	return len(bl.events)
+}
+
+// Flush hands any collected events to the batch listener.
+func (bl *BatchListener) Flush() {
+	bl.syncRoot.Lock()
+	batch := bl.events
+	bl.events = nil
+	bl.syncRoot.Unlock()
+
+	if len(batch) > 0 {
+		bl.listener(batch)
+	}
+}
+
+// Close stops the flush interval and flushes any collected events.
+func (bl *BatchListener) Close() error {
+	bl.stopOnce.Do(func() { close(bl.stop) })
+	bl.Flush()
+	return nil
+}
+
+func (bl *BatchListener) runInterval(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			bl.Flush()
+		case <-bl.stop:
+			return
+		}
+	}
+}
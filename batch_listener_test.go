@@ -0,0 +1,44 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestBatchListenerMaxSize(t *testing.T) {
+	assert := assert.New(t)
+
+	batches := make(chan []BatchedEvent, 2)
+	bl := NewBatchListener(func(events []BatchedEvent) {
+		batches <- events
+	}, 2, 0)
+
+	bl.Listen(nil, TimeNow(), EventInfo, "one")
+	assert.Equal(1, bl.Len())
+	bl.Listen(nil, TimeNow(), EventInfo, "two")
+	assert.Zero(bl.Len())
+
+	batch := <-batches
+	assert.Len(batch, 2)
+	assert.Equal(EventInfo, batch[0].EventFlag)
+	assert.Equal("two", batch[1].State[0])
+
+	bl.Listen(nil, TimeNow(), EventDebug, "three")
+	bl.Close()
+	assert.Len(<-batches, 1)
+}
+
+func TestBatchListenerInterval(t *testing.T) {
+	assert := assert.New(t)
+
+	batches := make(chan []BatchedEvent, 1)
+	bl := NewBatchListener(func(events []BatchedEvent) {
+		batches <- events
+	}, 100, time.Millisecond)
+	defer bl.Close()
+
+	bl.Listen(nil, TimeNow(), EventInfo, "one")
+	assert.Len(<-batches, 1)
+}
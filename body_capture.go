@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultBodyCaptureMaxBytes is the number of body bytes captured by middleware body capture.
+	DefaultBodyCaptureMaxBytes = 64 << 10
+)
+
+// cappedBuffer is a buffer that keeps at most `maxBytes`, discarding (but accepting) the rest.
+type cappedBuffer struct {
+	buffer    bytes.Buffer
+	maxBytes  int
+	truncated bool
+}
+
+// Write buffers what fits under the cap; it never fails, so it can't break the reader it's teed from.
+func (cb *cappedBuffer) Write(contents []byte) (int, error) {
+	remaining := cb.maxBytes - cb.buffer.Len()
+	if len(contents) > remaining {
+		cb.truncated = true
+		if remaining > 0 {
+			cb.buffer.Write(contents[:remaining])
+		}
+		return len(contents), nil
+	}
+	cb.buffer.Write(contents)
+	return len(contents), nil
+}
+
+// teeBody wraps a body so that what's read from it is captured (up to `maxBytes`) as it streams through,
+// returning the wrapped body and the capture.
+func teeBody(body io.ReadCloser, maxBytes int) (io.ReadCloser, *cappedBuffer) {
+	captured := &cappedBuffer{maxBytes: maxBytes}
+	if body == nil || body == http.NoBody {
+		return body, captured
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(body, captured), body}, captured
+}
+
+var (
+	// DefaultBodyCaptureExcludedContentTypes are binary content types worth excluding from body capture,
+	// see `Middleware.SetBodyCaptureExcludedContentTypes`.
+	DefaultBodyCaptureExcludedContentTypes = []string{"multipart/form-data", "application/octet-stream", "image/*", "audio/*", "video/*"}
+)
+
+// capturesContentType returns if a body of a content type is captured: it must match one of the allowed
+// media types (if any are given) and none of the excluded ones. Media types can end in a `/*` wildcard,
+// e.g. `image/*`; parameters (e.g. `; charset=utf-8`) are ignored.
+func capturesContentType(contentType string, allowed, excluded []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	if matchesMediaType(mediaType, excluded) {
+		return false
+	}
+	return len(allowed) == 0 || matchesMediaType(mediaType, allowed)
+}
+
+func matchesMediaType(mediaType string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if pattern == mediaType {
+			return true
+		}
+		if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestTeeBody(t *testing.T) {
+	assert := assert.New(t)
+
+	body, captured := teeBody(ioutil.NopCloser(strings.NewReader("hello world")), 5)
+	read, err := ioutil.ReadAll(body)
+	assert.Nil(err)
+	assert.Equal("hello world", string(read))
+	assert.Equal("hello", captured.buffer.String())
+	assert.True(captured.truncated)
+
+	body, captured = teeBody(http.NoBody, 5)
+	assert.Equal(http.NoBody, body)
+	assert.Zero(captured.buffer.Len())
+}
+
+func TestMiddlewareRequestBodyCapture(t *testing.T) {
+	assert := assert.New(t)
+
+	da := NewWithWriter(NewEventFlagSet(EventWebRequestPostBody, EventWebRequestCurl), NewWriter(bytes.NewBuffer(nil)))
+	defer da.Close()
+
+	var lock sync.Mutex
+	var bodies []string
+	da.AddEventListener(EventWebRequestPostBody, NewRequestBodyListener(func(writer *Writer, ts TimeSource, body []byte) {
+		lock.Lock()
+		defer lock.Unlock()
+		bodies = append(bodies, string(body))
+	}))
+	var repro CurlRepro
+	da.AddEventListener(EventWebRequestCurl, NewCurlReproListener(func(writer *Writer, ts TimeSource, r CurlRepro) {
+		repro = r
+	}))
+
+	middleware := NewMiddleware(da)
+	middleware.SetRequestBodyCapture(true)
+	middleware.SetBodyCaptureMaxBytes(8)
+	middleware.SetCurlRepro(true)
+	var handled string
+	handler := middleware.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.Method == "POST" {
+			read, _ := ioutil.ReadAll(req.Body)
+			handled = string(read)
+			res.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+
+	handler(httptest.NewRecorder(), httptest.NewRequest("POST", "/upload", strings.NewReader("a large upload")))
+	handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	da.Flush()
+
+	assert.Equal("a large upload", handled)
+	assert.Equal([]string{"a large "}, bodies)
+	assert.Equal("a large ", string(repro.Body))
+}
+
+func TestCapturesContentType(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(capturesContentType("", nil, nil))
+	assert.True(capturesContentType("application/json; charset=utf-8", []string{"application/json"}, nil))
+	assert.False(capturesContentType("text/html", []string{"application/json"}, nil))
+	assert.False(capturesContentType("", []string{"application/json"}, nil))
+	assert.True(capturesContentType("text/plain", []string{"text/*"}, nil))
+	assert.False(capturesContentType("image/png", nil, DefaultBodyCaptureExcludedContentTypes))
+	assert.False(capturesContentType("Multipart/Form-Data; boundary=x", nil, DefaultBodyCaptureExcludedContentTypes))
+	assert.False(capturesContentType("text/csv", []string{"text/*"}, []string{"text/csv"}))
+}
+
+func TestMiddlewareBodyCaptureContentTypes(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventWebRequestPostBody, EventWebDump), NewWriter(buffer))
+	defer da.Close()
+	da.Writer().SetUseAnsiColors(false)
+	da.AddEventListener(EventWebRequestPostBody, NewRequestBodyListener(WriteRequestBody))
+	da.AddEventListener(EventWebDump, NewHTTPDumpListener(WriteHTTPDump))
+
+	middleware := NewMiddleware(da)
+	middleware.SetRequestBodyCapture(true)
+	middleware.SetHTTPDump(true)
+	middleware.SetBodyCaptureContentTypes("application/json")
+	middleware.SetBodyCaptureExcludedContentTypes(DefaultBodyCaptureExcludedContentTypes...)
+	handler := middleware.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		ioutil.ReadAll(req.Body)
+		res.Header().Set("Content-Type", req.Header.Get("Content-Type"))
+		res.Write([]byte("response-" + req.URL.Path[1:]))
+	})
+
+	req := httptest.NewRequest("POST", "/json", strings.NewReader(`{"id":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	handler(httptest.NewRecorder(), req)
+	req = httptest.NewRequest("POST", "/image", strings.NewReader("\x89PNG"))
+	req.Header.Set("Content-Type", "image/png")
+	handler(httptest.NewRecorder(), req)
+	da.Flush()
+
+	output := buffer.String()
+	assert.True(strings.Contains(output, `{"id":1}`), output)
+	assert.True(strings.Contains(output, "response-json"), output)
+	assert.False(strings.Contains(output, "PNG"), output)
+	assert.False(strings.Contains(output, "response-image"), output)
+	assert.True(strings.Contains(output, "Content-Type: image/png"), output)
+}
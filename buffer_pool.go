@@ -3,31 +3,99 @@ package logger
 import (
 	"bytes"
 	"sync"
+	"sync/atomic"
 )
 
-// NewBufferPool returns a new BufferPool.
+const (
+	// DefaultBufferPoolMaxSize is the largest buffer a pool keeps by default; larger buffers are left to the gc.
+	DefaultBufferPoolMaxSize = 1 << 16 // 64kb
+
+	// bufferPoolSampleWindow is the number of buffers returned to a pool between resizes.
+	bufferPoolSampleWindow = 256
+	// bufferPoolShrinkFactor is how many times larger than the pool's buffer size a returned buffer can be before
+	// it's dropped rather than kept, releasing the memory held after a spike of large payloads.
+	bufferPoolShrinkFactor = 4
+)
+
+// NewBufferPool returns a new BufferPool, whose buffers start with (at least) `bufferSize` bytes of capacity.
 func NewBufferPool(bufferSize int) *BufferPool {
-	return &BufferPool{
-		Pool: sync.Pool{New: func() interface{} {
-			b := bytes.NewBuffer(make([]byte, bufferSize))
-			b.Reset()
-			return b
-		}},
+	bp := &BufferPool{
+		minSize: int64(bufferSize),
+		maxSize: DefaultBufferPoolMaxSize,
+		size:    int64(bufferSize),
 	}
+	bp.Pool.New = func() interface{} {
+		return bytes.NewBuffer(make([]byte, 0, bp.BufferSize()))
+	}
+	return bp
 }
 
-// BufferPool is a sync.Pool of bytes.Buffer.
+// BufferPool is a sync.Pool of bytes.Buffer that sizes new buffers to fit the payloads it has seen recently:
+// every `bufferPoolSampleWindow` buffers returned it resizes to the largest of them (rounded up to a power of two,
+// between the initial and the max buffer size), and drops returned buffers much larger than that, so it grows
+// with the payloads written and releases memory once a spike of large payloads passes.
 type BufferPool struct {
+	minSize    int64
+	maxSize    int64
+	size       int64
+	windowMax  int64
+	windowPuts int64
 	sync.Pool
 }
 
+// BufferSize returns the capacity new buffers are created with.
+func (bp *BufferPool) BufferSize() int {
+	return int(atomic.LoadInt64(&bp.size))
+}
+
+// MaxBufferSize returns the largest buffer the pool keeps.
+func (bp *BufferPool) MaxBufferSize() int {
+	return int(atomic.LoadInt64(&bp.maxSize))
+}
+
+// SetMaxBufferSize sets the largest buffer the pool keeps; larger buffers are dropped when they're returned.
+func (bp *BufferPool) SetMaxBufferSize(maxSize int) {
+	atomic.StoreInt64(&bp.maxSize, int64(maxSize))
+}
+
 // Get returns a pooled bytes.Buffer instance.
 func (bp *BufferPool) Get() *bytes.Buffer {
 	return bp.Pool.Get().(*bytes.Buffer)
 }
 
-// Put returns the pooled instance.
+// Put returns the pooled instance, noting the size of its payload.
 func (bp *BufferPool) Put(b *bytes.Buffer) {
+	bp.observe(int64(b.Len()))
+	capacity := int64(b.Cap())
+	if capacity > atomic.LoadInt64(&bp.maxSize) || capacity > bufferPoolShrinkFactor*atomic.LoadInt64(&bp.size) {
+		return
+	}
 	b.Reset()
 	bp.Pool.Put(b)
 }
+
+// observe records a payload size, resizing the pool at the end of each sample window.
+func (bp *BufferPool) observe(payloadSize int64) {
+	for {
+		windowMax := atomic.LoadInt64(&bp.windowMax)
+		if payloadSize <= windowMax || atomic.CompareAndSwapInt64(&bp.windowMax, windowMax, payloadSize) {
+			break
+		}
+	}
+	if atomic.AddInt64(&bp.windowPuts, 1)%bufferPoolSampleWindow != 0 {
+		return
+	}
+
+	windowMax := atomic.SwapInt64(&bp.windowMax, 0)
+	size := bp.minSize
+	if size < 1 {
+		size = 1
+	}
+	for size < windowMax {
+		size <<= 1
+	}
+	if maxSize := atomic.LoadInt64(&bp.maxSize); size > maxSize && maxSize >= bp.minSize {
+		size = maxSize
+	}
+	atomic.StoreInt64(&bp.size, size)
+}
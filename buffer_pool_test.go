@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"bytes"
 	"testing"
 
 	assert "github.com/blendlabs/go-assert"
@@ -14,3 +15,49 @@ func TestBufferPool(t *testing.T) {
 	assert.NotNil(buf)
 	pool.Put(buf)
 }
+
+func TestBufferPoolGrowsAndShrinks(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := NewBufferPool(256)
+	assert.Equal(256, pool.BufferSize())
+	assert.Equal(DefaultBufferPoolMaxSize, pool.MaxBufferSize())
+
+	large := bytes.Repeat([]byte("a"), 3000)
+	for x := 0; x < bufferPoolSampleWindow; x++ {
+		buf := pool.Get()
+		buf.Write(large)
+		pool.Put(buf)
+	}
+	assert.Equal(4096, pool.BufferSize())
+	assert.True(pool.Get().Cap() >= 3000)
+
+	for x := 0; x < bufferPoolSampleWindow; x++ {
+		buf := pool.Get()
+		buf.WriteString("small")
+		pool.Put(buf)
+	}
+	assert.Equal(256, pool.BufferSize())
+
+	spike := bytes.NewBuffer(make([]byte, 0, 8192))
+	pool.Put(spike)
+	for x := 0; x < bufferPoolSampleWindow; x++ {
+		assert.False(pool.Get() == spike, "buffers much larger than the pool's size are dropped")
+	}
+}
+
+func TestBufferPoolMaxBufferSize(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := NewBufferPool(256)
+	pool.SetMaxBufferSize(1024)
+	assert.Equal(1024, pool.MaxBufferSize())
+
+	large := bytes.Repeat([]byte("a"), 4000)
+	for x := 0; x < bufferPoolSampleWindow; x++ {
+		buf := pool.Get()
+		buf.Write(large)
+		pool.Put(buf)
+	}
+	assert.Equal(1024, pool.BufferSize())
+}
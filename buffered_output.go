@@ -0,0 +1,149 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBufferedOutputSize is the number of bytes buffered before a buffered output flushes.
+	DefaultBufferedOutputSize = 64 << 10
+
+	// DefaultBufferedOutputInterval is the longest a line waits in a buffered output before it's flushed.
+	DefaultBufferedOutputInterval = time.Second
+)
+
+// OutputFlusher is implemented by outputs that buffer writes, see `Writer.Flush`.
+type OutputFlusher interface {
+	Flush() error
+}
+
+// FlushOutput flushes an output if it buffers writes (is an `OutputFlusher`).
+func FlushOutput(output io.Writer) error {
+	if flusher, isFlusher := output.(OutputFlusher); isFlusher {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// NewBufferedOutput returns an output that coalesces writes, flushing to the inner output once `size` bytes
+// are buffered and at least every `interval` (if positive). Use `Writer.SetFlushSeverity` to flush severe
+// events (e.g. errors) immediately.
+func NewBufferedOutput(output io.Writer, size int, interval time.Duration) *BufferedOutput {
+	bo := &BufferedOutput{
+		output: output,
+		buffer: bytes.NewBuffer(make([]byte, 0, size)),
+		size:   size,
+		stop:   make(chan struct{}),
+	}
+	if interval > 0 {
+		go bo.flushEvery(interval)
+	}
+	return bo
+}
+
+// BufferedOutput coalesces writes to an inner output, see `NewBufferedOutput`.
+type BufferedOutput struct {
+	sync.Mutex
+	output   io.Writer
+	buffer   *bytes.Buffer
+	size     int
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// Write buffers the bytes, flushing if the buffer is full.
+func (bo *BufferedOutput) Write(buffer []byte) (int, error) {
+	bo.Lock()
+	defer bo.Unlock()
+	written, _ := bo.buffer.Write(buffer)
+	if bo.buffer.Len() >= bo.size {
+		if err := bo.flushUnlocked(); err != nil {
+			return 0, err
+		}
+	}
+	return written, nil
+}
+
+// Buffered returns the number of bytes waiting to be flushed.
+func (bo *BufferedOutput) Buffered() int {
+	bo.Lock()
+	defer bo.Unlock()
+	return bo.buffer.Len()
+}
+
+// Flush writes the buffered bytes to the inner output.
+func (bo *BufferedOutput) Flush() error {
+	bo.Lock()
+	defer bo.Unlock()
+	return bo.flushUnlocked()
+}
+
+// Reopen flushes the buffer and reopens the inner output (if it can reopen its files).
+func (bo *BufferedOutput) Reopen() error {
+	bo.Lock()
+	defer bo.Unlock()
+	if err := bo.flushUnlocked(); err != nil {
+		return err
+	}
+	return ReopenOutput(bo.output)
+}
+
+// SetMetaReporter sets how the inner output reports problems that don't fail writes, see `OutputMetaReporter`.
+func (bo *BufferedOutput) SetMetaReporter(reporter MetaReporter) {
+	SetOutputMetaReporter(bo.output, reporter)
+}
+
+// Close flushes the buffer and closes the inner output (if it is an io.Closer).
+func (bo *BufferedOutput) Close() error {
+	bo.stopOnce.Do(func() { close(bo.stop) })
+	if err := bo.Flush(); err != nil {
+		return err
+	}
+	if closer, isCloser := bo.output.(io.Closer); isCloser {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (bo *BufferedOutput) flushUnlocked() error {
+	if bo.buffer.Len() == 0 {
+		return nil
+	}
+	_, err := bo.buffer.WriteTo(bo.output)
+	bo.buffer.Reset()
+	return err
+}
+
+func (bo *BufferedOutput) flushEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			bo.Flush()
+		case <-bo.stop:
+			return
+		}
+	}
+}
+
+// severityRanks orders the severity events, from least to most severe.
+var severityRanks = map[EventFlag]int{
+	EventSilly:      1,
+	EventDebug:      2,
+	EventInfo:       3,
+	EventWarning:    4,
+	EventError:      5,
+	EventFatalError: 6,
+}
+
+// IsSeverityAtLeast returns if an event is a severity event at least as severe as the threshold,
+// e.g. `IsSeverityAtLeast(EventFatalError, EventError)`. Other events (e.g. `EventWebRequest`) aren't.
+func IsSeverityAtLeast(event, threshold EventFlag) bool {
+	rank, isSeverity := severityRanks[event]
+	thresholdRank, isThresholdSeverity := severityRanks[threshold]
+	return isSeverity && isThresholdSeverity && rank >= thresholdRank
+}
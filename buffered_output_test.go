@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestBufferedOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	inner := bytes.NewBuffer(nil)
+	output := NewBufferedOutput(inner, 16, 0)
+
+	written, err := output.Write([]byte("hello "))
+	assert.Nil(err)
+	assert.Equal(6, written)
+	assert.Zero(inner.Len())
+	assert.Equal(6, output.Buffered())
+
+	output.Write([]byte("buffered world"))
+	assert.Equal("hello buffered world", inner.String(), "a full buffer flushes")
+	assert.Zero(output.Buffered())
+
+	output.Write([]byte("!"))
+	assert.Nil(output.Close())
+	assert.Equal("hello buffered world!", inner.String())
+}
+
+func TestBufferedOutputInterval(t *testing.T) {
+	assert := assert.New(t)
+
+	inner := NewSyncOutput(bytes.NewBuffer(nil)).(*SyncOutput)
+	output := NewBufferedOutput(inner, DefaultBufferedOutputSize, time.Millisecond)
+	defer output.Close()
+
+	output.Write([]byte("hello"))
+	deadline := time.Now().Add(time.Second)
+	for output.Buffered() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Zero(output.Buffered())
+}
+
+func TestIsSeverityAtLeast(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(IsSeverityAtLeast(EventError, EventError))
+	assert.True(IsSeverityAtLeast(EventFatalError, EventWarning))
+	assert.False(IsSeverityAtLeast(EventInfo, EventError))
+	assert.False(IsSeverityAtLeast(EventWebRequest, EventDebug))
+	assert.False(IsSeverityAtLeast(EventError, EventWebRequest))
+}
+
+func TestWriterFlushSeverity(t *testing.T) {
+	assert := assert.New(t)
+
+	inner, errorInner := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	output := NewBufferedOutput(inner, DefaultBufferedOutputSize, 0)
+	errorOutput := NewBufferedOutput(errorInner, DefaultBufferedOutputSize, 0)
+	writer := NewWriterWithError(NewMultiOutput(output), NewAnsiStripOutput(errorOutput))
+	writer.SetFlushSeverity(EventError)
+	assert.Equal(EventError, writer.FlushSeverity())
+
+	da := NewWithWriter(NewEventFlagSet(EventInfo, EventError), writer)
+	defer da.Close()
+
+	da.Infof("info message")
+	da.Flush()
+	assert.Zero(inner.Len())
+	assert.True(output.Buffered() > 0)
+
+	da.Errorf("error message")
+	da.Flush()
+	assert.True(strings.Contains(errorInner.String(), "error message"), errorInner.String())
+	assert.True(strings.Contains(inner.String(), "info message"), "severe events flush every output")
+}
@@ -0,0 +1,238 @@
+package logger
+
+import (
+	"strings"
+)
+
+// Category is the channel an event is routed to (e.g. the access log), independent of its severity.
+type Category string
+
+const (
+	// CategoryApplication is the category of application messages, and of events not mapped to another category.
+	CategoryApplication Category = "app"
+	// CategoryAccess is the category of the access log, see `DefaultAccessLogEvents`.
+	CategoryAccess Category = "access"
+)
+
+var (
+	// DefaultAccessLogEvents are the events in `CategoryAccess` by default.
+	DefaultAccessLogEvents = []EventFlag{EventWebRequestStart, EventWebRequest}
+)
+
+// categoryRouting maps events to categories and categories to writers, and filters categories.
+type categoryRouting struct {
+	events   map[EventFlag]Category
+	writers  map[Category]*Writer
+	enabled  map[Category]bool // nil enables every category not disabled
+	disabled map[Category]bool
+}
+
+func newCategoryRouting() *categoryRouting {
+	routing := &categoryRouting{events: map[EventFlag]Category{}, writers: map[Category]*Writer{}, disabled: map[Category]bool{}}
+	for _, eventFlag := range DefaultAccessLogEvents {
+		routing.events[eventFlag] = CategoryAccess
+	}
+	return routing
+}
+
+// clone returns a copy of the routing that can be changed.
+func (cr *categoryRouting) clone() *categoryRouting {
+	cloned := &categoryRouting{events: map[EventFlag]Category{}, writers: map[Category]*Writer{}, disabled: map[Category]bool{}}
+	for eventFlag, category := range cr.events {
+		cloned.events[eventFlag] = category
+	}
+	for category, writer := range cr.writers {
+		cloned.writers[category] = writer
+	}
+	if cr.enabled != nil {
+		cloned.enabled = map[Category]bool{}
+		for category := range cr.enabled {
+			cloned.enabled[category] = true
+		}
+	}
+	for category := range cr.disabled {
+		cloned.disabled[category] = true
+	}
+	return cloned
+}
+
+func (cr *categoryRouting) isEnabled(category Category) bool {
+	if cr.disabled[category] {
+		return false
+	}
+	return cr.enabled == nil || cr.enabled[category]
+}
+
+func (cr *categoryRouting) category(eventFlag EventFlag) Category {
+	if category, hasCategory := cr.events[eventFlag]; hasCategory {
+		return category
+	}
+	return CategoryApplication
+}
+
+// loadCategories returns the current category routing (shared by derived agents), which must not be mutated.
+func (da *Agent) loadCategories() *categoryRouting {
+	if routing, _ := da.root().categories.Load().(*categoryRouting); routing != nil {
+		return routing
+	}
+	return newCategoryRouting()
+}
+
+// updateCategories replaces the category routing with a changed copy.
+func (da *Agent) updateCategories(update func(*categoryRouting)) {
+	root := da.root()
+	root.eventsLock.Lock()
+	defer root.eventsLock.Unlock()
+	routing := root.loadCategories().clone()
+	update(routing)
+	root.categories.Store(routing)
+}
+
+// WithCategory returns an agent whose events are all in a category (e.g. `security` or `billing`), whatever
+// their severity, so they can be filtered (see `DisableCategory`) and routed (see `SetCategoryWriter`) on their own.
+// Agents are cached per category, so it's cheap per call, e.g. `agent.WithCategory("billing").Infof(...)`.
+// Otherwise it behaves like a clone of the agent (see `Clone`).
+func (da *Agent) WithCategory(category Category) *Agent {
+	if da == nil {
+		return nil
+	}
+	if cached, isCached := da.categoryAgents.Load(category); isCached {
+		return cached.(*Agent)
+	}
+	agent := da.Clone()
+	agent.category = category
+	cached, _ := da.categoryAgents.LoadOrStore(category, agent)
+	return cached.(*Agent)
+}
+
+// Category returns the category the agent's events are in, if it was created with `WithCategory`.
+func (da *Agent) Category() Category {
+	if da == nil {
+		return ""
+	}
+	return da.category
+}
+
+// EventCategory returns the category an event is routed to.
+func (da *Agent) EventCategory(eventFlag EventFlag) Category {
+	if da == nil {
+		return CategoryApplication
+	}
+	if len(da.category) > 0 {
+		return da.category
+	}
+	return da.loadCategories().category(eventFlag)
+}
+
+// IsCategoryEnabled returns if events in a category are written and fire listeners.
+func (da *Agent) IsCategoryEnabled(category Category) bool {
+	if da == nil {
+		return false
+	}
+	return da.loadCategories().isEnabled(category)
+}
+
+// EnableCategory enables a category's events (subject to the agent's verbosity).
+func (da *Agent) EnableCategory(category Category) {
+	da.updateCategories(func(routing *categoryRouting) {
+		delete(routing.disabled, category)
+		if routing.enabled != nil {
+			routing.enabled[category] = true
+		}
+	})
+}
+
+// DisableCategory disables a category's events, whatever their severity, e.g. `DisableCategory(CategoryAccess)`.
+func (da *Agent) DisableCategory(category Category) {
+	da.updateCategories(func(routing *categoryRouting) {
+		routing.disabled[category] = true
+	})
+}
+
+// SetEnabledCategories enables only the given categories; with none, every category not disabled is enabled.
+func (da *Agent) SetEnabledCategories(categories ...Category) {
+	da.updateCategories(func(routing *categoryRouting) {
+		routing.enabled = nil
+		routing.disabled = map[Category]bool{}
+		if len(categories) == 0 {
+			return
+		}
+		routing.enabled = map[Category]bool{}
+		for _, category := range categories {
+			routing.enabled[category] = true
+		}
+	})
+}
+
+// SetCategoriesFromCSV enables and disables categories from a csv, e.g. `-access` (everything but the access log)
+// or `app,security` (only those), as read from `LOG_CATEGORIES` by `NewFromEnvironment`.
+func (da *Agent) SetCategoriesFromCSV(categoryCSV string) {
+	var enabled []Category
+	var disabled []Category
+	for _, category := range strings.Split(categoryCSV, ",") {
+		category = strings.ToLower(strings.TrimSpace(category))
+		if len(category) == 0 {
+			continue
+		}
+		if strings.HasPrefix(category, "-") {
+			disabled = append(disabled, Category(strings.TrimPrefix(category, "-")))
+			continue
+		}
+		enabled = append(enabled, Category(category))
+	}
+	da.SetEnabledCategories(enabled...)
+	for _, category := range disabled {
+		da.DisableCategory(category)
+	}
+}
+
+// SetEventCategory sets the category an event is routed to, e.g. `SetEventCategory(EventWebRequestSlow, CategoryAccess)`.
+func (da *Agent) SetEventCategory(eventFlag EventFlag, category Category) {
+	da.updateCategories(func(routing *categoryRouting) {
+		routing.events[eventFlag] = category
+	})
+}
+
+// CategoryWriter returns the writer a category is routed to, which is the agent's writer unless set.
+func (da *Agent) CategoryWriter(category Category) *Writer {
+	if writer := da.loadCategories().writers[category]; writer != nil {
+		return writer
+	}
+	return da.Writer()
+}
+
+// SetCategoryWriter routes the events of a category to their own writer (with its own output, rotation and format),
+// e.g. `SetCategoryWriter(CategoryAccess, accessLog)` to split the access log from application messages.
+// Listeners for the category's events are handed that writer. A nil writer routes the category back to the agent's writer.
+func (da *Agent) SetCategoryWriter(category Category, writer *Writer) {
+	da.Flush()
+	da.updateCategories(func(routing *categoryRouting) {
+		if writer == nil {
+			delete(routing.writers, category)
+			return
+		}
+		routing.writers[category] = writer
+	})
+}
+
+// writerFor returns the writer an event is written to, see `SetCategoryWriter`.
+func (da *Agent) writerFor(eventFlag EventFlag) *Writer {
+	if writer := da.loadCategories().writers[da.EventCategory(eventFlag)]; writer != nil {
+		return writer
+	}
+	return da.Writer()
+}
+
+// closeCategoryWriters closes the writers categories are routed to (other than the agent's writer).
+func (da *Agent) closeCategoryWriters() error {
+	var err error
+	for _, writer := range da.loadCategories().writers {
+		if writer == da.Writer() {
+			continue
+		}
+		if closeErr := writer.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestAgentCategoryWriter(t *testing.T) {
+	assert := assert.New(t)
+
+	app := bytes.NewBuffer(nil)
+	access := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventInfo, EventWebRequest), NewWriter(app))
+	defer da.Close()
+	da.AddEventListener(EventWebRequest, NewRequestListener(WriteRequest))
+
+	assert.Equal(CategoryAccess, da.EventCategory(EventWebRequest))
+	assert.Equal(CategoryApplication, da.EventCategory(EventInfo))
+	assert.Equal(da.Writer(), da.CategoryWriter(CategoryAccess))
+
+	accessWriter := NewWriter(access)
+	accessWriter.SetShowTimestamp(false)
+	da.SetCategoryWriter(CategoryAccess, accessWriter)
+	assert.Equal(accessWriter, da.CategoryWriter(CategoryAccess))
+
+	da.Infof("application message")
+	da.OnEvent(EventWebRequest, httptest.NewRequest("GET", "/users", nil), 200, 10, time.Millisecond)
+	da.Flush()
+
+	assert.True(strings.Contains(app.String(), "application message"), app.String())
+	assert.False(strings.Contains(app.String(), "/users"), app.String())
+	assert.True(strings.Contains(access.String(), "/users"), access.String())
+	assert.False(strings.Contains(access.String(), "application message"), access.String())
+
+	da.SetEventCategory(EventInfo, CategoryAccess)
+	da.Infof("now in the access log")
+	da.Flush()
+	assert.True(strings.Contains(access.String(), "now in the access log"), access.String())
+
+	da.SetCategoryWriter(CategoryAccess, nil)
+	assert.Equal(da.Writer(), da.CategoryWriter(CategoryAccess))
+}
+
+func TestAgentWithCategory(t *testing.T) {
+	assert := assert.New(t)
+
+	app := bytes.NewBuffer(nil)
+	security := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventInfo, EventWarning), NewWriter(app))
+	defer da.Close()
+	da.SetCategoryWriter("security", NewWriter(security))
+
+	audit := da.WithCategory("security")
+	assert.True(audit == da.WithCategory("security"))
+	assert.Equal(Category("security"), audit.Category())
+	assert.Equal(Category("security"), audit.EventCategory(EventInfo))
+
+	audit.Infof("login succeeded")
+	da.WithCategory("security").Warningf("login failed")
+	da.WithCategory("billing").Infof("invoice sent")
+	da.Infof("cache warmed")
+	audit.Flush()
+
+	assert.True(strings.Contains(security.String(), "login succeeded"), security.String())
+	assert.True(strings.Contains(security.String(), "login failed"), security.String())
+	assert.True(strings.Contains(app.String(), "invoice sent"), app.String())
+	assert.False(strings.Contains(app.String(), "login"), app.String())
+
+	da.DisableCategory("billing")
+	assert.False(da.WithCategory("billing").IsEnabled(EventInfo))
+	assert.True(da.IsEnabled(EventInfo))
+	da.EnableCategory("billing")
+	assert.True(da.WithCategory("billing").IsEnabled(EventInfo))
+
+	da.SetCategoriesFromCSV("app, security")
+	assert.True(da.IsCategoryEnabled(CategoryApplication))
+	assert.True(audit.IsEnabled(EventWarning))
+	assert.False(da.IsCategoryEnabled("billing"))
+	assert.False(da.IsEnabled(EventWebRequest))
+
+	da.SetCategoriesFromCSV("-access")
+	assert.True(da.IsCategoryEnabled("billing"))
+	assert.False(da.IsCategoryEnabled(CategoryAccess))
+}
@@ -0,0 +1,66 @@
+// logdecrypt decrypts log files written with an encrypted output, e.g. `logdecrypt app.log.1 app.log | logfmt`.
+// The base64 encoded key is read from `LOG_ENCRYPTION_KEY` (or `-key`); envelope encrypted files are decrypted with
+// `-unwrap-command` instead. Files are read from stdin if none are given.
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	logger "github.com/blendlabs/go-logger"
+)
+
+func main() {
+	encodedKey := flag.String("key", os.Getenv(logger.EnvironmentVariableLogEncryptionKey), "the base64 encoded key")
+	unwrapCommand := flag.String("unwrap-command", "", "for envelope encrypted files, a shell command that reads a wrapped data key on stdin and writes the unwrapped key to stdout, e.g. a kms decrypt")
+	flag.Parse()
+
+	key, err := base64.StdEncoding.DecodeString(*encodedKey)
+	if err != nil || (len(key) == 0 && len(*unwrapCommand) == 0) {
+		fmt.Fprintf(os.Stderr, "logdecrypt: a base64 encoded key (`-key` or `%s`) or an `-unwrap-command` is required\n", logger.EnvironmentVariableLogEncryptionKey)
+		os.Exit(1)
+	}
+	var unwrapKey logger.KeyUnwrapper
+	if len(*unwrapCommand) > 0 {
+		unwrapKey = commandUnwrapper(*unwrapCommand)
+	}
+
+	if err := decrypt(flag.Args(), os.Stdin, os.Stdout, key, unwrapKey); err != nil {
+		fmt.Fprintf(os.Stderr, "logdecrypt: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// decrypt decrypts the given files (or stdin if there are none) to the output as one input, in order, so records
+// of envelope encrypted files can use the key header of the file rotated before them.
+func decrypt(paths []string, stdin io.Reader, output io.Writer, key []byte, unwrapKey logger.KeyUnwrapper) error {
+	if len(paths) == 0 {
+		return logger.DecryptRecords(stdin, output, key, unwrapKey)
+	}
+	inputs := make([]io.Reader, 0, len(paths))
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		inputs = append(inputs, file)
+	}
+	return logger.DecryptRecords(io.MultiReader(inputs...), output, key, unwrapKey)
+}
+
+// commandUnwrapper returns a key unwrapper that runs a shell command with the wrapped key on stdin,
+// and reads the unwrapped key from its stdout.
+func commandUnwrapper(command string) logger.KeyUnwrapper {
+	return func(wrappedKey []byte) ([]byte, error) {
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Stdin = bytes.NewReader(wrappedKey)
+		cmd.Stderr = os.Stderr
+		return cmd.Output()
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+	logger "github.com/blendlabs/go-logger"
+)
+
+func TestDecrypt(t *testing.T) {
+	assert := assert.New(t)
+
+	key := bytes.Repeat([]byte{7}, 32)
+	encrypted := bytes.NewBuffer(nil)
+	output, err := logger.NewEncryptedOutput(encrypted, key)
+	assert.Nil(err)
+	output.Write([]byte("first line\n"))
+	output.Write([]byte("second line\n"))
+
+	dir, err := ioutil.TempDir("", "logdecrypt")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	assert.Nil(ioutil.WriteFile(path, encrypted.Bytes(), 0600))
+
+	decrypted := bytes.NewBuffer(nil)
+	assert.Nil(decrypt([]string{path}, nil, decrypted, key, nil))
+	assert.Equal("first line\nsecond line\n", decrypted.String())
+
+	decrypted.Reset()
+	assert.Nil(decrypt(nil, bytes.NewReader(encrypted.Bytes()), decrypted, key, nil))
+	assert.Equal("first line\nsecond line\n", decrypted.String())
+
+	assert.NotNil(decrypt([]string{path}, nil, decrypted, bytes.Repeat([]byte{8}, 32), nil))
+}
+
+func TestDecryptEnvelope(t *testing.T) {
+	assert := assert.New(t)
+
+	// a stand in for a kms; the wrapped key is the data key base64 encoded, which the unwrap command decodes.
+	wrapKey := func(dataKey []byte) ([]byte, error) {
+		return []byte(base64.StdEncoding.EncodeToString(dataKey)), nil
+	}
+	dir, err := ioutil.TempDir("", "logdecrypt")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	encrypted := bytes.NewBuffer(nil)
+	output, err := logger.NewEnvelopeEncryptedOutput(encrypted, wrapKey)
+	assert.Nil(err)
+	output.Write([]byte("first line\n"))
+	rotated := filepath.Join(dir, "app.log.1")
+	assert.Nil(ioutil.WriteFile(rotated, encrypted.Bytes(), 0600))
+
+	encrypted.Reset()
+	output.Write([]byte("second line\n"))
+	path := filepath.Join(dir, "app.log")
+	assert.Nil(ioutil.WriteFile(path, encrypted.Bytes(), 0600))
+	assert.False(strings.Contains(encrypted.String(), logger.EncryptedKeyHeaderPrefix), "the key header is in the rotated file")
+
+	decrypted := bytes.NewBuffer(nil)
+	assert.Nil(decrypt([]string{rotated, path}, nil, decrypted, nil, commandUnwrapper("base64 -d")))
+	assert.Equal("first line\nsecond line\n", decrypted.String())
+
+	assert.NotNil(decrypt([]string{path}, nil, bytes.NewBuffer(nil), nil, commandUnwrapper("base64 -d")))
+	assert.NotNil(decrypt([]string{rotated, path}, nil, bytes.NewBuffer(nil), nil, commandUnwrapper("exit 1")))
+}
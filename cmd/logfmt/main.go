@@ -0,0 +1,139 @@
+// logfmt re-renders json log lines read from stdin as human readable lines, e.g. `kubectl logs pod | logfmt`.
+// Lines that aren't json objects are passed through unchanged.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	logger "github.com/blendlabs/go-logger"
+)
+
+var (
+	timeKeys    = []string{"time", "timestamp", "ts"}
+	flagKeys    = []string{"flag", "event", "level"}
+	messageKeys = []string{"message", "msg"}
+	labelKeys   = []string{"label"}
+	nsKeys      = []string{"namespace", "ns"}
+)
+
+func main() {
+	noColor := flag.Bool("no-color", false, "disable ansi colors")
+	timeFormat := flag.String("time-format", logger.DefaultTimeFormat, "the timestamp format")
+	flag.Parse()
+
+	writer := logger.NewWriter(os.Stdout)
+	writer.SetUseAnsiColors(!*noColor)
+	writer.SetTimeFormat(*timeFormat)
+	writer.SetContinuationPrefix(logger.DefaultContinuationPrefix)
+	writer.SetSanitizeControlChars(true)
+
+	if err := render(writer, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "logfmt: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// render reads lines from `r`, writing json lines with the writer and anything else to `passthrough`.
+func render(writer *logger.Writer, r io.Reader, passthrough io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var fields map[string]interface{}
+		if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &fields) != nil {
+			passthrough.Write(append(line, '\n'))
+			continue
+		}
+		renderFields(writer, fields)
+	}
+	return scanner.Err()
+}
+
+// renderFields writes a parsed json line; well known keys are rendered as the writer would,
+// the remaining ones as sorted `key=value` pairs.
+func renderFields(writer *logger.Writer, fields map[string]interface{}) {
+	flattenFields(fields)
+	ts, hasTime := parseTime(takeString(fields, timeKeys...))
+	writer.SetShowTimestamp(hasTime)
+	writer.SetLabel(takeString(fields, labelKeys...))
+	writer.SetShowLabel(len(writer.Label()) > 0)
+	writer.SetNamespace(takeString(fields, nsKeys...))
+
+	buffer := writer.GetBuffer()
+	defer writer.PutBuffer(buffer)
+
+	if eventFlag := logger.EventFlag(takeString(fields, flagKeys...)); len(eventFlag) > 0 {
+		buffer.WriteString(writer.FormatEvent(eventFlag, colorFor(eventFlag)))
+		buffer.WriteRune(logger.RuneSpace)
+	}
+	buffer.WriteString(fmt.Sprintf("%s", writer.SanitizeArgs("%s", takeString(fields, messageKeys...))...))
+	if len(fields) > 0 {
+		buffer.WriteRune(logger.RuneSpace)
+		buffer.WriteString(writer.FormatFields(fields))
+	}
+	writer.WriteWithTimeSource(ts, []byte(strings.TrimSpace(buffer.String())))
+}
+
+// flattenFields moves the nested fields of versioned lines (see `logger.Record`) to the top level,
+// and drops the schema version.
+func flattenFields(fields map[string]interface{}) {
+	if _, isVersioned := fields["schema_version"]; !isVersioned {
+		return
+	}
+	delete(fields, "schema_version")
+	nested, hasNested := fields["fields"].(map[string]interface{})
+	if !hasNested {
+		return
+	}
+	delete(fields, "fields")
+	for key, value := range nested {
+		if _, isReserved := fields[key]; !isReserved {
+			fields[key] = value
+		}
+	}
+}
+
+// takeString removes the first of the given keys present in the fields and returns its value.
+func takeString(fields map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if value, hasValue := fields[key]; hasValue {
+			delete(fields, key)
+			if typed, isString := value.(string); isString {
+				return typed
+			}
+			return fmt.Sprint(value)
+		}
+	}
+	return ""
+}
+
+// parseTime parses an rfc3339 timestamp.
+func parseTime(value string) (logger.TimeSource, bool) {
+	if len(value) == 0 {
+		return logger.SystemClock, false
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return logger.SystemClock, false
+	}
+	return logger.TimeInstance(parsed), true
+}
+
+// colorFor returns the label color the agent uses for an event.
+func colorFor(eventFlag logger.EventFlag) logger.AnsiColorCode {
+	switch strings.ToLower(string(eventFlag)) {
+	case string(logger.EventError), string(logger.EventFatalError):
+		return logger.ColorRed
+	case string(logger.EventWarning), "warn", string(logger.EventDebug):
+		return logger.ColorLightYellow
+	default:
+		return logger.ColorLightWhite
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+	logger "github.com/blendlabs/go-logger"
+)
+
+func TestRender(t *testing.T) {
+	assert := assert.New(t)
+
+	output := bytes.NewBuffer(nil)
+	writer := logger.NewWriter(output)
+	writer.SetUseAnsiColors(false)
+	writer.SetContinuationPrefix(logger.DefaultContinuationPrefix)
+	writer.SetSanitizeControlChars(true)
+
+	input := strings.Join([]string{
+		`{"time":"2017-01-02T03:04:05Z","level":"error","msg":"it broke\nat main.go:12","user":"bailey","attempt":2}`,
+		`{"flag":"info","message":"hello"}`,
+		`{"schema_version":1,"flag":"info","message":"versioned","fields":{"service":"api"}}`,
+		`not json`,
+	}, "\n")
+	assert.Nil(render(writer, strings.NewReader(input), output))
+
+	assert.Equal(
+		"2017-01-02T03:04:05Z [error] it broke\n  | at main.go:12 attempt=2 user=bailey\n"+
+			"[info] hello\n"+
+			"[info] versioned service=api\n"+
+			"not json\n",
+		output.String())
+}
+
+func TestColorFor(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(logger.ColorRed, colorFor("ERROR"))
+	assert.Equal(logger.ColorLightYellow, colorFor("warn"))
+	assert.Equal(logger.ColorLightWhite, colorFor("web.request"))
+}
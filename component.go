@@ -0,0 +1,75 @@
+package logger
+
+// Component returns an agent for a component (or source) of the application, e.g. `db`,
+// whose verbosity can be overridden independently (see `SetComponentVerbosity`) at runtime.
+// Without an override a component agent follows the root agent's verbosity; changing a component agent's
+// verbosity (`SetVerbosity`, `EnableEvent` or `DisableEvent`) sets the override for its component.
+// Otherwise it behaves like a clone of the agent (see `Clone`).
+func (da *Agent) Component(name string) *Agent {
+	component := da.Clone()
+	component.component = name
+	return component
+}
+
+// ComponentName returns the agent's component, if it was created with `Component`.
+func (da *Agent) ComponentName() string {
+	if da == nil {
+		return ""
+	}
+	return da.component
+}
+
+// ComponentVerbosity returns a copy of the verbosity override for a component, or nil if it isn't overridden.
+func (da *Agent) ComponentVerbosity(name string) *EventFlagSet {
+	return da.root().loadComponentEvents()[name].clone()
+}
+
+// SetComponentVerbosity overrides the verbosity of a component's agents, e.g. `debug` for `db` only.
+func (da *Agent) SetComponentVerbosity(name string, events *EventFlagSet) {
+	da.root().updateComponentEvents(name, func(*EventFlagSet) *EventFlagSet { return events.clone() })
+}
+
+// ClearComponentVerbosity removes a component's verbosity override; its agents follow the root agent's verbosity again.
+func (da *Agent) ClearComponentVerbosity(name string) {
+	da.root().updateComponentEvents(name, func(*EventFlagSet) *EventFlagSet { return nil })
+}
+
+// root returns the agent derived agents were created from.
+func (da *Agent) root() *Agent {
+	if da.parent != nil {
+		return da.parent
+	}
+	return da
+}
+
+// componentEventsFor returns the flag set that applies to a component (which must not be mutated).
+func (da *Agent) componentEventsFor(name string) *EventFlagSet {
+	if events, hasOverride := da.loadComponentEvents()[name]; hasOverride {
+		return events
+	}
+	return da.loadEvents()
+}
+
+func (da *Agent) loadComponentEvents() map[string]*EventFlagSet {
+	componentEvents, _ := da.componentEvents.Load().(map[string]*EventFlagSet)
+	return componentEvents
+}
+
+// updateComponentEvents replaces a component's override with the result of `update`, which is handed
+// a copy of the current flag set for the component; a nil result removes the override.
+func (da *Agent) updateComponentEvents(name string, update func(*EventFlagSet) *EventFlagSet) {
+	da.eventsLock.Lock()
+	defer da.eventsLock.Unlock()
+
+	current := da.loadComponentEvents()
+	updated := make(map[string]*EventFlagSet, len(current)+1)
+	for component, events := range current {
+		updated[component] = events
+	}
+	if events := update(da.componentEventsFor(name).clone()); events != nil {
+		updated[name] = events
+	} else {
+		delete(updated, name)
+	}
+	da.componentEvents.Store(updated)
+}
@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestAgentComponentVerbosity(t *testing.T) {
+	assert := assert.New(t)
+
+	da := New(NewEventFlagSet(EventInfo, EventError))
+	defer da.Close()
+	db := da.Component("db")
+	http := da.Component("http")
+	assert.Equal("db", db.ComponentName())
+	assert.Empty(da.ComponentName())
+
+	// components follow the root agent without an override.
+	assert.False(db.IsEnabled(EventDebug))
+	da.EnableEvent(EventWarning)
+	assert.True(db.IsEnabled(EventWarning))
+	assert.Nil(da.ComponentVerbosity("db"))
+
+	da.SetComponentVerbosity("db", NewEventFlagSet(EventDebug, EventInfo))
+	assert.True(db.IsEnabled(EventDebug))
+	assert.False(db.IsEnabled(EventWarning))
+	assert.False(http.IsEnabled(EventDebug))
+	assert.False(da.IsEnabled(EventDebug))
+	assert.True(da.ComponentVerbosity("db").IsEnabled(EventDebug))
+
+	da.ClearComponentVerbosity("db")
+	assert.False(db.IsEnabled(EventDebug))
+	assert.True(db.IsEnabled(EventWarning))
+}
+
+func TestAgentComponentSetVerbosity(t *testing.T) {
+	assert := assert.New(t)
+
+	da := New(NewEventFlagSet(EventInfo))
+	defer da.Close()
+	db := da.Component("db")
+
+	db.EnableEvent(EventDebug)
+	assert.True(db.IsEnabled(EventDebug))
+	assert.True(db.IsEnabled(EventInfo))
+	assert.False(da.IsEnabled(EventDebug))
+	assert.True(db.Events().IsEnabled(EventDebug))
+
+	db.DisableEvent(EventInfo)
+	assert.False(db.IsEnabled(EventInfo))
+	assert.True(da.IsEnabled(EventInfo))
+
+	db.SetVerbosity(NewEventFlagSet(EventError))
+	assert.True(da.ComponentVerbosity("db").IsEnabled(EventError))
+	assert.False(db.IsEnabled(EventDebug))
+
+	// components of a component share its root's overrides.
+	assert.True(db.Component("db").IsEnabled(EventError))
+}
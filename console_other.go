@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package logger
+
+import "os"
+
+// EnableVirtualTerminal enables ansi escape sequence processing for a console.
+// Terminals outside of Windows process them already, so it does nothing.
+func EnableVirtualTerminal(file *os.File) error {
+	return nil
+}
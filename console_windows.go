@@ -0,0 +1,35 @@
+//go:build windows
+// +build windows
+
+package logger
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const enableVirtualTerminalProcessing = 0x0004
+
+var (
+	kernel32           = syscall.NewLazyDLL("kernel32.dll")
+	procGetConsoleMode = kernel32.NewProc("GetConsoleMode")
+	procSetConsoleMode = kernel32.NewProc("SetConsoleMode")
+)
+
+// EnableVirtualTerminal enables ansi escape sequence processing for a console.
+// It returns an error if the file isn't a console or the console doesn't support it (pre Windows 10).
+func EnableVirtualTerminal(file *os.File) error {
+	handle := syscall.Handle(file.Fd())
+	var mode uint32
+	if result, _, err := procGetConsoleMode.Call(uintptr(handle), uintptr(unsafe.Pointer(&mode))); result == 0 {
+		return err
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return nil
+	}
+	if result, _, err := procSetConsoleMode.Call(uintptr(handle), uintptr(mode|enableVirtualTerminalProcessing)); result == 0 {
+		return err
+	}
+	return nil
+}
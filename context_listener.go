@@ -0,0 +1,44 @@
+package logger
+
+import (
+	"context"
+)
+
+// ContextFromTimeSource returns the context a listener's event was fired with (see `OnEventContext`),
+// or `context.Background()` if it was fired without one. It has the context's values, but is never cancelled
+// and has no deadline: the request it came from has usually finished by the time a listener runs.
+func ContextFromTimeSource(ts TimeSource) context.Context {
+	if ctx := MetadataFromTimeSource(ts).Context; ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+// ContextEventListener is a listener that's handed the values of the context its event was fired with,
+// e.g. to read trace or tenant data set upstream. The context isn't cancelled when the original is (see
+// `ContextFromTimeSource`), so listeners that do i/o should set their own timeouts.
+type ContextEventListener func(ctx context.Context, writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{})
+
+// NewContextListener returns an event listener that hands the event's context to a context listener.
+func NewContextListener(listener ContextEventListener) EventListener {
+	return func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		listener(ContextFromTimeSource(ts), writer, ts, eventFlag, state...)
+	}
+}
+
+// OnEventContext fires the currently configured event listeners, handing them a context
+// (see `NewContextListener`). Request agents fire events with their request's context.
+func (da *Agent) OnEventContext(ctx context.Context, eventFlag EventFlag, state ...interface{}) {
+	if da == nil {
+		return
+	}
+	da.onEvent(withContext(TimeNow(), ctx), eventFlag, state...)
+}
+
+// OnEventContext fires the currently configured event listeners synchronously, handing them a context.
+func (sa *SyncAgent) OnEventContext(ctx context.Context, eventFlag EventFlag, state ...interface{}) {
+	if sa == nil || sa.a == nil {
+		return
+	}
+	sa.onEvent(withContext(TimeNow(), ctx), eventFlag, state...)
+}
@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+type tenantKey struct{}
+
+func TestContextFromTimeSource(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(context.Background(), ContextFromTimeSource(TimeNow()))
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	ts := withContext(TimeNow(), ctx)
+	assert.Equal("acme", ContextFromTimeSource(ts).Value(tenantKey{}))
+	assert.False(ts.UTCNow().IsZero())
+	assert.Equal("acme", MetadataFromTimeSource(ts).Context.Value(tenantKey{}))
+	assert.Equal(ts, withContext(ts, nil).(eventTimeSource), "a nil context leaves the time source as is")
+}
+
+func TestAgentOnEventContext(t *testing.T) {
+	assert := assert.New(t)
+
+	da := NewWithWriter(NewEventFlagSet(EventInfo), NewWriter(bytes.NewBuffer(nil)))
+	defer da.Close()
+
+	tenants := make(chan interface{}, 3)
+	da.AddEventListener(EventInfo, NewContextListener(func(ctx context.Context, writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		tenants <- ctx.Value(tenantKey{})
+	}))
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	da.OnEventContext(ctx, EventInfo, "with context")
+	assert.Equal("acme", <-tenants)
+
+	da.OnEvent(EventInfo, "without context")
+	assert.Nil(<-tenants)
+
+	da.Sync().OnEventContext(ctx, EventInfo, "sync")
+	assert.Equal("acme", <-tenants)
+}
+
+func TestRequestAgentOnEventContext(t *testing.T) {
+	assert := assert.New(t)
+
+	da := NewWithWriter(NewEventFlagSet(EventInfo), NewWriter(bytes.NewBuffer(nil)))
+	defer da.Close()
+
+	tenants := make(chan interface{}, 1)
+	da.AddEventListener(EventInfo, NewContextListener(func(ctx context.Context, writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		tenants <- ctx.Value(tenantKey{})
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), tenantKey{}, "acme"))
+	NewRequestAgent(da, req, "req-1").OnEvent(EventInfo, "request event")
+	assert.Equal("acme", <-tenants)
+}
+
+func TestListenerContextOutlivesRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	da := NewWithWriter(NewEventFlagSet(EventInfo), NewWriter(bytes.NewBuffer(nil)))
+	defer da.Close()
+
+	errs := make(chan error, 1)
+	tenants := make(chan interface{}, 1)
+	release := make(chan struct{})
+	da.AddEventListener(EventInfo, NewContextListener(func(ctx context.Context, writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		<-release
+		errs <- ctx.Err()
+		tenants <- ctx.Value(tenantKey{})
+	}))
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), tenantKey{}, "acme"))
+	da.OnEventContext(ctx, EventInfo, "request event")
+	cancel()
+	close(release)
+	assert.Nil(<-errs, "the listener runs after the request, so it isn't handed its cancellation")
+	assert.Equal("acme", <-tenants)
+}
+
+func TestRequestAgentErrorEventWithState(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventError), NewWriter(buffer))
+	defer da.Close()
+	da.Writer().SetUseAnsiColors(false)
+
+	states := make(chan []interface{}, 1)
+	da.AddEventListener(EventError, NewContextListener(func(ctx context.Context, writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		states <- append([]interface{}{ctx.Value(tenantKey{})}, state...)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), tenantKey{}, "acme"))
+	ra := NewRequestAgent(da, req, "req-1")
+	ra.ErrorEventWithState(EventError, ColorRed, fmt.Errorf("failed"), "extra")
+	state := <-states
+	da.Flush()
+
+	assert.Len(state, 4)
+	assert.Equal("acme", state[0])
+	assert.Equal("failed", state[1].(error).Error())
+	assert.Equal(req, state[2])
+	assert.Equal("extra", state[3])
+	assert.True(ra.Errored())
+	assert.True(strings.Contains(buffer.String(), "request_id=req-1"), buffer.String())
+	assert.True(strings.Contains(buffer.String(), "failed"), buffer.String())
+}
@@ -0,0 +1,111 @@
+package logger
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// EventWebRequestCurl fires with a curl command reproducing a failed request (a 5xx response, or one whose
+	// request agent fired an error), see `Middleware.SetCurlRepro`.
+	EventWebRequestCurl EventFlag = "web.request.curl"
+)
+
+var (
+	// DefaultCurlHeaders are the request headers included in curl commands.
+	DefaultCurlHeaders = []string{"Accept", "Accept-Language", "Content-Type", "User-Agent"}
+)
+
+// CurlRepro is the state of an `EventWebRequestCurl` event.
+type CurlRepro struct {
+	Request    *http.Request
+	StatusCode int
+	// Headers are the headers included in the command.
+	Headers []string
+	// Body is the captured request body (if any, see `Middleware.SetHTTPDump` and `Middleware.SetRequestBodyCapture`).
+	Body []byte
+}
+
+// FormatCurl returns a curl command reproducing a request, with only the given headers (masked, see `MaskHeaders`),
+// the values of scrubbed query parameters redacted, and the body (if given). The command is sanitized, so
+// control characters in the body are escaped.
+func (wr *Writer) FormatCurl(req *http.Request, body []byte, headers ...string) string {
+	command := bytes.NewBufferString("curl")
+	if req.Method != "GET" || len(body) > 0 {
+		command.WriteString(" -X " + req.Method)
+	}
+	command.WriteString(" " + shellQuote(requestURL(req, wr.ScrubbedQueryParams()...)))
+
+	masked := MaskHeaders(req.Header, wr.maskedHeaders...)
+	keys := make([]string, 0, len(headers))
+	for _, header := range headers {
+		keys = append(keys, http.CanonicalHeaderKey(header))
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range masked[key] {
+			command.WriteString(" -H " + shellQuote(key+": "+value))
+		}
+	}
+	if len(body) > 0 {
+		command.WriteString(" --data-binary " + shellQuote(string(body)))
+	}
+	return wr.Sanitize(command.String())
+}
+
+// requestURL returns the absolute url of a request, with the values of scrubbed query parameters redacted.
+func requestURL(req *http.Request, scrubbedQueryParams ...string) string {
+	scheme := req.URL.Scheme
+	if len(scheme) == 0 {
+		scheme = "http"
+		if req.TLS != nil {
+			scheme = "https"
+		}
+	}
+	host := req.URL.Host
+	if len(host) == 0 {
+		host = req.Host
+	}
+	url := scheme + "://" + host + req.URL.EscapedPath()
+	if len(req.URL.RawQuery) > 0 {
+		url += "?" + ScrubQuery(req.URL.RawQuery, scrubbedQueryParams...)
+	}
+	return url
+}
+
+// shellQuote single quotes a value for posix shells.
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+// CurlReproListener is a listener for curl repro events.
+type CurlReproListener func(writer *Writer, ts TimeSource, repro CurlRepro)
+
+// NewCurlReproListener returns a new handler for curl repro events, e.g. `NewCurlReproListener(WriteCurlRepro)`.
+func NewCurlReproListener(listener CurlReproListener) EventListener {
+	return func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		if len(state) < 1 {
+			return
+		}
+		if repro, isRepro := state[0].(CurlRepro); isRepro {
+			listener(writer, ts, repro)
+		}
+	}
+}
+
+// WriteCurlRepro is a helper method to write curl repro events to a writer,
+// e.g. `[web.request.curl] 500 curl -X POST 'http://localhost/orders' -H 'Content-Type: application/json' ...`.
+func WriteCurlRepro(writer *Writer, ts TimeSource, repro CurlRepro) {
+	buffer := writer.GetBuffer()
+	defer writer.PutBuffer(buffer)
+
+	buffer.WriteString(writer.FormatEvent(EventWebRequestCurl, ColorRed))
+	buffer.WriteRune(RuneSpace)
+	buffer.WriteString(writer.ColorizeByStatusCode(repro.StatusCode, strconv.Itoa(repro.StatusCode)))
+	buffer.WriteRune(RuneSpace)
+	buffer.WriteString(writer.FormatCurl(repro.Request, repro.Body, repro.Headers...))
+	writer.WriteWithTimeSource(ts, buffer.Bytes())
+}
@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestWriterFormatCurl(t *testing.T) {
+	assert := assert.New(t)
+
+	req := httptest.NewRequest("POST", "https://shop.example.com/orders?id=1&token=abc", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Internal", "skipped")
+
+	writer := NewWriter(bytes.NewBuffer(nil))
+	writer.SetScrubbedQueryParams("token")
+	command := writer.FormatCurl(req, []byte(`{"note":"it's"}`), "content-type", "Authorization")
+	assert.Equal(`curl -X POST 'https://shop.example.com/orders?id=1&token=`+RedactedValue+`'`+
+		` -H 'Authorization: Bearer `+RedactedValue+`' -H 'Content-Type: application/json'`+
+		` --data-binary '{"note":"it'\''s"}'`, command)
+
+	assert.Equal("curl 'http://example.com/'", writer.FormatCurl(httptest.NewRequest("GET", "/", nil), nil))
+}
+
+func TestMiddlewareCurlRepro(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventWebRequestCurl, EventWebDump), NewWriter(buffer))
+	defer da.Close()
+	da.AddEventListener(EventWebRequestCurl, NewCurlReproListener(WriteCurlRepro))
+
+	middleware := NewMiddleware(da)
+	middleware.SetCurlRepro(true)
+	middleware.SetHTTPDump(true)
+	handler := middleware.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/fails":
+			res.WriteHeader(http.StatusBadGateway)
+		case "/errors":
+			ForRequest(req.Context()).Error(errors.New("handled"))
+		}
+	})
+
+	handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+	handler(httptest.NewRecorder(), httptest.NewRequest("PUT", "/fails", strings.NewReader("payload")))
+	handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/errors", nil))
+	da.Flush()
+
+	output := buffer.String()
+	assert.False(strings.Contains(output, "/ok"), output)
+	assert.True(strings.Contains(output, "curl -X PUT 'http://example.com/fails' --data-binary 'payload'"), output)
+	assert.True(strings.Contains(output, "curl 'http://example.com/errors'"), output)
+}
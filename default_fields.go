@@ -0,0 +1,58 @@
+package logger
+
+// DefaultFields returns the fields merged into every event the agent fires: those set on the agents it was
+// derived from (see `Clone`), overridden by its own.
+func (da *Agent) DefaultFields() Fields {
+	if da == nil {
+		return nil
+	}
+	var inherited Fields
+	if da.listenerParent != nil {
+		inherited = da.listenerParent.DefaultFields()
+	}
+	own, _ := da.defaultFields.Load().(Fields)
+	return mergeFields(inherited, own)
+}
+
+// SetDefaultFields sets fields (e.g. service, version and region) merged into every event the agent fires,
+// and those of agents derived from it, instead of repeating them per call. Messages are written with the fields
+// before them, e.g. `[info] [region=us-east-1 service=api] started`, and listeners can read them with
+// `FieldsFromTimeSource`. The agent keeps a copy of the fields.
+func (da *Agent) SetDefaultFields(fields Fields) {
+	copied := Fields{}
+	for key, value := range fields {
+		copied[key] = value
+	}
+	da.defaultFields.Store(copied)
+}
+
+// fieldsPrefix returns the default fields, and those an event's time source carries (see `withFields`),
+// formatted to lead messages, or "" if there are none.
+func (da *Agent) fieldsPrefix(writer *Writer, ts TimeSource) string {
+	if writer.isStructured() {
+		return ""
+	}
+	fields := mergeFields(da.DefaultFields(), FieldsFromTimeSource(ts))
+	if len(fields) == 0 {
+		return ""
+	}
+	return "[" + writer.FormatFields(fields) + "] "
+}
+
+// mergeFields returns fields overridden by others, without changing either.
+func mergeFields(fields, overrides Fields) Fields {
+	if len(overrides) == 0 {
+		return fields
+	}
+	if len(fields) == 0 {
+		return overrides
+	}
+	merged := Fields{}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestAgentDefaultFields(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventInfo, EventError), NewWriter(buffer))
+	defer da.Close()
+	da.Writer().SetUseAnsiColors(false)
+	da.Writer().SetShowTimestamp(false)
+
+	fields := Fields{"service": "api", "version": "1.2"}
+	da.SetDefaultFields(fields)
+	fields["version"] = "changed"
+	assert.Equal(Fields{"service": "api", "version": "1.2"}, da.DefaultFields())
+
+	var lock sync.Mutex
+	var listened Fields
+	da.AddEventListener(EventError, func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		lock.Lock()
+		defer lock.Unlock()
+		listened = FieldsFromTimeSource(ts)
+	})
+
+	db := da.Component("db")
+	db.SetDefaultFields(Fields{"region": "us-east-1", "service": "db"})
+	da.Infof("started")
+	db.Infof("connected")
+	da.Errorf("failed")
+	da.Flush()
+
+	output := buffer.String()
+	assert.True(strings.Contains(output, "[info] [service=api version=1.2] started\n"), output)
+	assert.True(strings.Contains(output, "[info] [region=us-east-1 service=db version=1.2] connected\n"), output)
+	lock.Lock()
+	assert.Equal("api", listened["service"])
+	lock.Unlock()
+
+	da.SetEagerFormatting(true)
+	da.SetDefaultFields(nil)
+	db.Infof("eager")
+	da.Flush()
+	assert.True(strings.Contains(buffer.String(), "[info] [region=us-east-1 service=db] eager\n"), buffer.String())
+	assert.Nil(FieldsFromTimeSource(TimeNow()))
+}
@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// droppedEvents counts events dropped per flag.
+type droppedEvents struct {
+	sync.Mutex
+	counts       map[EventFlag]int64
+	reported     map[EventFlag]int64
+	stopReport   chan struct{}
+	dropWhenFull bool
+}
+
+// DropWhenSaturated returns if events are dropped (rather than blocking the caller) when the event queue is full.
+func (da *Agent) DropWhenSaturated() bool {
+	da.dropped.Lock()
+	defer da.dropped.Unlock()
+	return da.dropped.dropWhenFull
+}
+
+// SetDropWhenSaturated sets if events are dropped (rather than blocking the caller) when the event queue is full.
+func (da *Agent) SetDropWhenSaturated(dropWhenSaturated bool) {
+	da.dropped.Lock()
+	da.dropped.dropWhenFull = dropWhenSaturated
+	da.dropped.Unlock()
+}
+
+// DroppedEvents returns the number of events dropped per flag, due to a full queue or failed writes.
+func (da *Agent) DroppedEvents() map[EventFlag]int64 {
+	da.dropped.Lock()
+	defer da.dropped.Unlock()
+	counts := make(map[EventFlag]int64, len(da.dropped.counts))
+	for flag, count := range da.dropped.counts {
+		counts[flag] = count
+	}
+	return counts
+}
+
+// StartDropReport writes a meta event summarizing the events dropped since the last report on an interval,
+// e.g. "dropped 1,204 debug events in the last 1m0s". Nothing is written for intervals without drops.
+func (da *Agent) StartDropReport(interval time.Duration) {
+	da.dropped.Lock()
+	if da.dropped.stopReport != nil {
+		da.dropped.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	da.dropped.stopReport = stop
+	da.dropped.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				da.reportDropped(interval)
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopDropReport stops the periodic drop report.
+func (da *Agent) StopDropReport() {
+	da.dropped.Lock()
+	defer da.dropped.Unlock()
+	if da.dropped.stopReport != nil {
+		close(da.dropped.stopReport)
+		da.dropped.stopReport = nil
+	}
+}
+
+func (da *Agent) recordDropped(eventFlag EventFlag) {
+	da.dropped.Lock()
+	if da.dropped.counts == nil {
+		da.dropped.counts = map[EventFlag]int64{}
+	}
+	da.dropped.counts[eventFlag]++
+	da.dropped.Unlock()
+}
+
+func (da *Agent) reportDropped(interval time.Duration) {
+	da.dropped.Lock()
+	if da.dropped.reported == nil {
+		da.dropped.reported = map[EventFlag]int64{}
+	}
+	deltas := map[EventFlag]int64{}
+	var flags []string
+	for flag, count := range da.dropped.counts {
+		if delta := count - da.dropped.reported[flag]; delta > 0 {
+			deltas[flag] = delta
+			flags = append(flags, string(flag))
+		}
+		da.dropped.reported[flag] = count
+	}
+	da.dropped.Unlock()
+
+	sort.Strings(flags)
+	for _, flag := range flags {
+		da.Metaf("dropped %s %s events in the last %v", FormatCount(deltas[EventFlag(flag)]), flag, interval)
+	}
+}
+
+// FormatCount returns a count with thousands separators, e.g. `1,204`.
+func FormatCount(count int64) string {
+	value := strconv.FormatInt(count, 10)
+	sign := ""
+	if count < 0 {
+		sign, value = "-", value[1:]
+	}
+	for x := len(value) - 3; x > 0; x -= 3 {
+		value = value[:x] + "," + value[x:]
+	}
+	return sign + value
+}
@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestFormatCount(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("0", FormatCount(0))
+	assert.Equal("999", FormatCount(999))
+	assert.Equal("1,204", FormatCount(1204))
+	assert.Equal("1,234,567", FormatCount(1234567))
+	assert.Equal("-1,000", FormatCount(-1000))
+}
+
+func TestAgentDroppedEventsWriteFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	da := NewWithWriter(NewEventFlagSetAll(), NewWriter(errorOutput{}))
+	defer da.Close()
+	da.SetMetaOutput(bytes.NewBuffer(nil))
+
+	da.Sync().Infof("one")
+	da.Sync().Infof("two")
+	da.Sync().Debugf("three")
+	dropped := da.DroppedEvents()
+	assert.Equal(2, dropped[EventInfo])
+	assert.Equal(1, dropped[EventDebug])
+}
+
+func TestAgentDroppedEventsWhenSaturated(t *testing.T) {
+	assert := assert.New(t)
+
+	da := New(NewEventFlagSetAll())
+	defer da.Close()
+	da.SetMetaOutput(bytes.NewBuffer(nil))
+	da.SetDropWhenSaturated(true)
+	assert.True(da.DropWhenSaturated())
+
+	release := make(chan struct{})
+	block := func(...interface{}) error { <-release; return nil }
+	da.EventQueue().SetMaxWorkItems(1)
+	for x := 0; x <= da.EventQueue().NumWorkers(); x++ {
+		da.EventQueue().Enqueue(block)
+	}
+	for da.EventQueue().Len() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	da.Infof("dropped")
+	close(release)
+	assert.Equal(1, da.DroppedEvents()[EventInfo])
+}
+
+func TestAgentDropReport(t *testing.T) {
+	assert := assert.New(t)
+
+	meta := newSignalOutput()
+	da := New(NewEventFlagSetAll())
+	defer da.Close()
+	da.SetMetaOutput(meta)
+
+	for x := 0; x < 1204; x++ {
+		da.recordDropped(EventDebug)
+	}
+	da.StartDropReport(time.Millisecond)
+	<-meta.written
+	da.StopDropReport()
+	assert.True(strings.Contains(meta.String(), "dropped 1,204 debug events in the last 1ms"), meta.String())
+}
@@ -0,0 +1,205 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultDumpMaxDepth is the depth nested values are dumped to by `Agent.Dump`.
+	DefaultDumpMaxDepth = 8
+
+	// dumpIndent indents each level of a dumped value.
+	dumpIndent = "  "
+)
+
+// Dump writes a pretty printed (reflection based, depth limited and cycle safe) dump of a value
+// at `EventDebug`, e.g. `da.Dump("cart", cart)`. Nothing is computed unless debug events are enabled.
+func (da *Agent) Dump(label string, v interface{}) {
+	if da == nil || !da.IsEnabled(EventDebug) {
+		return
+	}
+	// the dump quotes strings, so it's written as the format (rather than as a sanitized argument) to keep its newlines.
+	da.Debugf(strings.Replace(SanitizeControlChars(label)+": "+FormatDump(v, DefaultDumpMaxDepth), "%", "%%", -1))
+}
+
+// FormatDump pretty prints a value, following pointers, maps, slices and structs (including their unexported fields)
+// up to `maxDepth` levels deep. Values already being dumped (cycles) are written as `<cycle>`.
+func FormatDump(v interface{}, maxDepth int) string {
+	dumper := &dumper{buffer: bytes.NewBuffer(nil), maxDepth: maxDepth, visiting: map[uintptr]bool{}}
+	dumper.dump(reflect.ValueOf(v), 0)
+	return dumper.buffer.String()
+}
+
+type dumper struct {
+	buffer   *bytes.Buffer
+	maxDepth int
+	visiting map[uintptr]bool
+}
+
+func (d *dumper) dump(v reflect.Value, depth int) {
+	if !v.IsValid() {
+		d.buffer.WriteString("nil")
+		return
+	}
+	if v.CanInterface() && v.Kind() != reflect.Interface && !(v.Kind() == reflect.Ptr && v.IsNil()) {
+		switch typed := v.Interface().(type) {
+		case error:
+			d.buffer.WriteString(strconv.Quote(typed.Error()))
+			return
+		case fmt.Stringer:
+			d.buffer.WriteString(strconv.Quote(typed.String()))
+			return
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		d.buffer.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		d.buffer.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		d.buffer.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		d.buffer.WriteString(strconv.FormatFloat(v.Float(), 'g', -1, 64))
+	case reflect.Complex64, reflect.Complex128:
+		d.buffer.WriteString(strconv.FormatComplex(v.Complex(), 'g', -1, 128))
+	case reflect.String:
+		d.buffer.WriteString(strconv.Quote(v.String()))
+	case reflect.Ptr:
+		if v.IsNil() {
+			d.buffer.WriteString("nil")
+			return
+		}
+		if d.visiting[v.Pointer()] {
+			d.buffer.WriteString("<cycle>")
+			return
+		}
+		d.visiting[v.Pointer()] = true
+		defer delete(d.visiting, v.Pointer())
+		d.buffer.WriteRune('&')
+		d.dump(v.Elem(), depth)
+	case reflect.Interface:
+		if v.IsNil() {
+			d.buffer.WriteString("nil")
+			return
+		}
+		d.dump(v.Elem(), depth)
+	case reflect.Struct:
+		d.dumpStruct(v, depth)
+	case reflect.Map:
+		d.dumpMap(v, depth)
+	case reflect.Slice, reflect.Array:
+		d.dumpList(v, depth)
+	default:
+		// chans, funcs and unsafe pointers.
+		d.buffer.WriteString(v.Type().String())
+	}
+}
+
+func (d *dumper) dumpStruct(v reflect.Value, depth int) {
+	d.buffer.WriteString(v.Type().String())
+	if v.NumField() == 0 {
+		d.buffer.WriteString("{}")
+		return
+	}
+	if depth >= d.maxDepth {
+		d.buffer.WriteString("{...}")
+		return
+	}
+	d.buffer.WriteString("{\n")
+	for x := 0; x < v.NumField(); x++ {
+		d.writeIndent(depth + 1)
+		d.buffer.WriteString(v.Type().Field(x).Name + ": ")
+		d.dump(v.Field(x), depth+1)
+		d.buffer.WriteString(",\n")
+	}
+	d.writeIndent(depth)
+	d.buffer.WriteRune('}')
+}
+
+func (d *dumper) dumpMap(v reflect.Value, depth int) {
+	d.buffer.WriteString(v.Type().String())
+	if v.IsNil() {
+		d.buffer.WriteString("(nil)")
+		return
+	}
+	if v.Len() == 0 {
+		d.buffer.WriteString("{}")
+		return
+	}
+	if depth >= d.maxDepth {
+		d.buffer.WriteString("{...}")
+		return
+	}
+	if d.visiting[v.Pointer()] {
+		d.buffer.WriteString("<cycle>")
+		return
+	}
+	d.visiting[v.Pointer()] = true
+	defer delete(d.visiting, v.Pointer())
+
+	keys := v.MapKeys()
+	renderedKeys := make([]string, len(keys))
+	for x, key := range keys {
+		keyDumper := &dumper{buffer: bytes.NewBuffer(nil), visiting: map[uintptr]bool{}}
+		keyDumper.dump(key, 0)
+		renderedKeys[x] = keyDumper.buffer.String()
+	}
+	order := make([]int, len(keys))
+	for x := range order {
+		order[x] = x
+	}
+	sort.Slice(order, func(i, j int) bool { return renderedKeys[order[i]] < renderedKeys[order[j]] })
+
+	d.buffer.WriteString("{\n")
+	for _, x := range order {
+		d.writeIndent(depth + 1)
+		d.buffer.WriteString(renderedKeys[x] + ": ")
+		d.dump(v.MapIndex(keys[x]), depth+1)
+		d.buffer.WriteString(",\n")
+	}
+	d.writeIndent(depth)
+	d.buffer.WriteRune('}')
+}
+
+func (d *dumper) dumpList(v reflect.Value, depth int) {
+	d.buffer.WriteString(v.Type().String())
+	if v.Kind() == reflect.Slice && v.IsNil() {
+		d.buffer.WriteString("(nil)")
+		return
+	}
+	if v.Len() == 0 {
+		d.buffer.WriteString("{}")
+		return
+	}
+	if depth >= d.maxDepth {
+		d.buffer.WriteString("{...}")
+		return
+	}
+	if v.Kind() == reflect.Slice {
+		if d.visiting[v.Pointer()] {
+			d.buffer.WriteString("<cycle>")
+			return
+		}
+		d.visiting[v.Pointer()] = true
+		defer delete(d.visiting, v.Pointer())
+	}
+
+	d.buffer.WriteString("{\n")
+	for x := 0; x < v.Len(); x++ {
+		d.writeIndent(depth + 1)
+		d.dump(v.Index(x), depth+1)
+		d.buffer.WriteString(",\n")
+	}
+	d.writeIndent(depth)
+	d.buffer.WriteRune('}')
+}
+
+func (d *dumper) writeIndent(depth int) {
+	d.buffer.WriteString(strings.Repeat(dumpIndent, depth))
+}
@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+type dumpNode struct {
+	Name     string
+	Children []*dumpNode
+	Parent   *dumpNode
+	tags     map[string]int
+}
+
+func TestFormatDump(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("nil", FormatDump(nil, 4))
+	assert.Equal(`"value"`, FormatDump("value", 4))
+	assert.Equal(`"boom"`, FormatDump(errors.New("boom"), 4))
+	assert.Equal(`"1s"`, FormatDump(time.Second, 4))
+	assert.Equal("[]int(nil)", FormatDump([]int(nil), 4))
+
+	root := &dumpNode{Name: "root", tags: map[string]int{"b": 2, "a": 1}}
+	root.Children = []*dumpNode{{Name: "child", Parent: root}}
+	expected := `&logger.dumpNode{
+  Name: "root",
+  Children: []*logger.dumpNode{
+    &logger.dumpNode{
+      Name: "child",
+      Children: []*logger.dumpNode(nil),
+      Parent: <cycle>,
+      tags: map[string]int(nil),
+    },
+  },
+  Parent: nil,
+  tags: map[string]int{
+    "a": 1,
+    "b": 2,
+  },
+}`
+	assert.Equal(expected, FormatDump(root, 8))
+	assert.Equal("&logger.dumpNode{...}", FormatDump(root, 0))
+}
+
+func TestAgentDump(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventInfo), NewWriter(buffer))
+	defer da.Close()
+	da.Writer().SetUseAnsiColors(false)
+	da.Writer().SetShowTimestamp(false)
+
+	da.Dump("ignored", map[string]string{"a": "b"})
+	da.EnableEvent(EventDebug)
+	da.Dump("rate", map[string]string{"rate": "100%"})
+	da.Flush()
+	assert.Equal("[debug] rate: map[string]string{\n  \"rate\": \"100%\",\n}\n", buffer.String())
+	assert.False(strings.Contains(buffer.String(), "ignored"))
+}
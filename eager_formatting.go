@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// EagerFormatting returns if messages are formatted when they're logged, rather than by the queue's workers.
+// Like `IsEnabled` it takes no locks.
+func (da *Agent) EagerFormatting() bool {
+	if da == nil {
+		return false
+	}
+	return atomic.LoadInt32(&da.eagerFormatting) == 1
+}
+
+// SetEagerFormatting sets if messages are formatted when they're logged (in the calling goroutine).
+// Only the rendered message (in a pooled buffer) travels through the queue rather than the format and args,
+// which bounds queue memory and means args changed after the call can't change (or break) the message.
+// Listeners are still triggered with the args.
+func (da *Agent) SetEagerFormatting(eagerFormatting bool) {
+	atomic.StoreInt32(&da.eagerFormatting, boolAsInt32(eagerFormatting))
+}
+
+// formatEager renders an event message to a pooled buffer; the write action returns it to the pool.
+// It returns nil if the message is suppressed (see `AddSuppression`).
+func (da *Agent) formatEager(ts TimeSource, eventFlag EventFlag, color AnsiColorCode, format string, args ...interface{}) *bytes.Buffer {
+	writer := da.writerFor(eventFlag)
+	buf := writer.GetBuffer()
+	buf.WriteString(writer.FormatEvent(eventFlag, color))
+	buf.WriteRune(RuneSpace)
+	buf.WriteString(da.fieldsPrefix(writer, ts))
+	messageStart := buf.Len()
+	fmt.Fprintf(buf, format, writer.SanitizeArgs(format, args...)...)
+	if da.isSuppressed(eventFlag, buf.Bytes()[messageStart:]) {
+		writer.PutBuffer(buf)
+		return nil
+	}
+	return buf
+}
+
+func (da *Agent) writeFormatted(actionState ...interface{}) error {
+	defer releaseState(actionState)
+	return da.onWriteError(da.writeFormattedWithOutput(false, actionState...), actionState...)
+}
+
+func (da *Agent) writeFormattedError(actionState ...interface{}) error {
+	defer releaseState(actionState)
+	return da.onWriteError(da.writeFormattedWithOutput(true, actionState...), actionState...)
+}
+
+// writeFormattedWithOutput writes an eagerly formatted message, with profiler labels if enabled.
+func (da *Agent) writeFormattedWithOutput(toErrorOutput bool, actionState ...interface{}) error {
+	if eventFlag, isLabeled := da.profilerLabelFor(actionState...); isLabeled {
+		var err error
+		withProfilerLabels(eventFlag, nil, func(context.Context) { err = da.writeFormattedWithOutputUnlabeled(toErrorOutput, actionState...) })
+		return err
+	}
+	return da.writeFormattedWithOutputUnlabeled(toErrorOutput, actionState...)
+}
+
+// writeFormattedWithOutputUnlabeled writes an eagerly formatted message.
+func (da *Agent) writeFormattedWithOutputUnlabeled(toErrorOutput bool, actionState ...interface{}) error {
+	if len(actionState) < 3 {
+		return nil
+	}
+
+	timeSource, err := stateAsTimeSource(actionState[0])
+	if err != nil {
+		return err
+	}
+
+	buf, isBuffer := actionState[2].(*bytes.Buffer)
+	if !isBuffer {
+		return errTypeConversion
+	}
+	eventFlag, _ := actionState[1].(EventFlag)
+	writer := da.writerFor(eventFlag)
+	defer writer.PutBuffer(buf)
+
+	output := writer.Output
+	if writer.UsesErrorOutput(eventFlag, toErrorOutput) {
+		output = writer.GetErrorOutput()
+	}
+	if output == nil {
+		return nil
+	}
+	if _, err = writer.fwriteWithTimeSource(da.recordTimeSource(writer, timeSource, eventFlag), output, buf.Bytes()); err != nil {
+		return err
+	}
+	return writer.flushForSeverity(eventFlag)
+}
@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+)
+
+type changingStringer struct {
+	value atomic.Value
+}
+
+func (cs *changingStringer) String() string {
+	return cs.value.Load().(string)
+}
+
+func TestAgentEagerFormatting(t *testing.T) {
+	assert := assert.New(t)
+
+	output := newSignalOutput()
+	errorOutput := newSignalOutput()
+	writer := NewWriterWithError(output, errorOutput)
+	writer.SetUseAnsiColors(false)
+	writer.SetShowTimestamp(false)
+
+	da := NewWithWriter(NewEventFlagSetAll(), writer)
+	defer da.Close()
+	assert.False(da.EagerFormatting())
+	da.SetEagerFormatting(true)
+	assert.True(da.EagerFormatting())
+
+	arg := &changingStringer{}
+	arg.value.Store("before")
+	da.Infof("value is %v", arg)
+	da.Warningf("value is %v", arg)
+	arg.value.Store("after")
+	da.Flush()
+
+	assert.Equal("[info] value is before\n", output.String())
+	assert.Equal("[warning] value is before\n", errorOutput.String())
+	assert.False(strings.Contains(output.String(), "after"))
+}
+
+func TestAgentEagerFormattingListeners(t *testing.T) {
+	assert := assert.New(t)
+
+	writer := NewWriter(newSignalOutput())
+	da := NewWithWriter(NewEventFlagSetAll(), writer)
+	defer da.Close()
+	da.SetEagerFormatting(true)
+
+	states := make(chan []interface{}, 1)
+	da.AddEventListener(EventInfo, func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		states <- append([]interface{}{}, state...)
+	})
+	da.Infof("hello %s", "world")
+	assert.Equal([]interface{}{"hello %s", "world"}, <-states)
+	da.Flush()
+}
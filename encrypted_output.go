@@ -0,0 +1,298 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"sync"
+)
+
+const (
+	// EncryptedOutputDataKeySize is the size of the data keys generated for envelope encryption (AES-256).
+	EncryptedOutputDataKeySize = 32
+	// EncryptedOutputMaxRecordsPerKey is the most records encrypted with a key, as AES-GCM with random nonces is only
+	// safe for 2^32 messages per key (NIST SP 800-38D).
+	EncryptedOutputMaxRecordsPerKey = 1 << 32
+	// EncryptedOutputKeyHeaderInterval is how often (in records) an envelope encrypted output repeats the header line
+	// with its wrapped data key, so files rotated by the inner output soon carry the key themselves.
+	EncryptedOutputKeyHeaderInterval = 1 << 10
+	// EncryptedKeyHeaderPrefix starts the header lines of envelope encrypted outputs, e.g. `key:<base64 wrapped key>`.
+	EncryptedKeyHeaderPrefix = "key:"
+)
+
+var (
+	// ErrEncryptedRecordInvalid is returned when decrypting a malformed or tampered with record.
+	ErrEncryptedRecordInvalid = errors.New("Invalid encrypted record")
+	// ErrEncryptedRecordNoKey is returned when decrypting an envelope encrypted record without an unwrapper.
+	ErrEncryptedRecordNoKey = errors.New("Envelope encrypted record requires a key unwrapper")
+	// ErrEncryptedRecordKeyMissing is returned when decrypting an envelope encrypted record before the header line with its key.
+	ErrEncryptedRecordKeyMissing = errors.New("Envelope encrypted record precedes its key header; decrypt rotated files in order")
+	// ErrEncryptedOutputKeyExhausted is returned when writing more than the record limit (see `SetMaxRecordsPerKey`)
+	// to an output encrypting with a given key, which (unlike envelope encryption) can't be rotated.
+	ErrEncryptedOutputKeyExhausted = errors.New("Encrypted output key reached its record limit")
+)
+
+// KeyWrapper encrypts (wraps) a data key with a master key, e.g. with a KMS.
+type KeyWrapper func(dataKey []byte) ([]byte, error)
+
+// KeyUnwrapper decrypts (unwraps) a data key wrapped by a `KeyWrapper`.
+type KeyUnwrapper func(wrappedKey []byte) ([]byte, error)
+
+// NewEncryptedOutput returns an output that encrypts each record (write) with AES-GCM with the given key (16, 24 or 32 bytes).
+// Records are written as a line of base64 `nonce|ciphertext`; use `DecryptRecords` to read them back.
+// A key encrypts at most `EncryptedOutputMaxRecordsPerKey` records, after which writes fail with
+// `ErrEncryptedOutputKeyExhausted`; use `NewEnvelopeEncryptedOutput` for long lived outputs, which rotates its data keys.
+func NewEncryptedOutput(output io.Writer, key []byte) (*EncryptedOutput, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedOutput{output: output, aead: aead, maxRecords: EncryptedOutputMaxRecordsPerKey}, nil
+}
+
+// NewEnvelopeEncryptedOutput returns an output that encrypts records with a generated data key, written wrapped
+// (by `wrapKey`) on a header line (see `EncryptedKeyHeaderPrefix`) before the first record, after reopening and every
+// `EncryptedOutputKeyHeaderInterval` records, so rotated files can be decrypted by whoever can unwrap the key, and the
+// master key never has to leave the KMS. Records are written as `<key id>.<base64 nonce|ciphertext>`.
+// A new data key is generated after each `EncryptedOutputMaxRecordsPerKey` records (see `SetMaxRecordsPerKey`).
+func NewEnvelopeEncryptedOutput(output io.Writer, wrapKey KeyWrapper) (*EncryptedOutput, error) {
+	encrypted := &EncryptedOutput{output: output, wrapKey: wrapKey, maxRecords: EncryptedOutputMaxRecordsPerKey}
+	if err := encrypted.rotateKey(); err != nil {
+		return nil, err
+	}
+	return encrypted, nil
+}
+
+// NewEncryptedFileOutput returns a new rotating file output (without archive compression, which doesn't
+// shrink encrypted records) whose records are encrypted with the given key.
+func NewEncryptedFileOutput(filePath string, key []byte) (*EncryptedOutput, error) {
+	fileOutput, err := NewFileOutput(filePath, false, FileOutputDefaultFileSize, FileOutputDefaultMaxArchiveFiles)
+	if err != nil {
+		return nil, err
+	}
+	return NewEncryptedOutput(fileOutput, key)
+}
+
+// EncryptedOutput encrypts records before writing them to the inner output, for logs containing regulated data
+// that must be stored encrypted at rest. Each write is a record; the writer writes one (possibly multi-line) message per write.
+type EncryptedOutput struct {
+	output     io.Writer
+	aead       cipher.AEAD
+	wrapKey    KeyWrapper
+	wrappedKey []byte
+	keyID      []byte
+	records    uint64
+	maxRecords uint64
+	headerDue  bool
+	syncRoot   sync.Mutex
+}
+
+// MaxRecordsPerKey returns the most records encrypted with a key, see `SetMaxRecordsPerKey`.
+func (eo *EncryptedOutput) MaxRecordsPerKey() uint64 {
+	eo.syncRoot.Lock()
+	defer eo.syncRoot.Unlock()
+	return eo.maxRecords
+}
+
+// SetMaxRecordsPerKey sets the most records encrypted with a key before an envelope encrypted output generates
+// a new data key (or an output encrypting with a given key fails), capped at `EncryptedOutputMaxRecordsPerKey`.
+func (eo *EncryptedOutput) SetMaxRecordsPerKey(maxRecords uint64) {
+	if maxRecords == 0 || maxRecords > EncryptedOutputMaxRecordsPerKey {
+		maxRecords = EncryptedOutputMaxRecordsPerKey
+	}
+	eo.syncRoot.Lock()
+	eo.maxRecords = maxRecords
+	eo.syncRoot.Unlock()
+}
+
+// Write encrypts a record and writes it to the inner output as a single line, preceded by the key header line
+// if one is due (see `NewEnvelopeEncryptedOutput`).
+func (eo *EncryptedOutput) Write(record []byte) (int, error) {
+	eo.syncRoot.Lock()
+	defer eo.syncRoot.Unlock()
+	if eo.records >= eo.maxRecords {
+		if eo.wrapKey == nil {
+			return 0, ErrEncryptedOutputKeyExhausted
+		}
+		if err := eo.rotateKey(); err != nil {
+			return 0, err
+		}
+	}
+
+	nonce := make([]byte, eo.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return 0, err
+	}
+	var line []byte
+	if eo.wrapKey != nil && (eo.headerDue || eo.records%EncryptedOutputKeyHeaderInterval == 0) {
+		line = append(line, EncryptedKeyHeaderPrefix...)
+		line = append(line, eo.wrappedKey...)
+		line = append(line, '\n')
+	}
+	sealed := eo.aead.Seal(nonce, nonce, bytes.TrimSuffix(record, []byte{'\n'}), eo.keyID)
+	eo.records++
+	if len(eo.keyID) > 0 {
+		line = append(line, eo.keyID...)
+		line = append(line, '.')
+	}
+	line = append(line, base64.StdEncoding.EncodeToString(sealed)...)
+	line = append(line, '\n')
+
+	if _, err := eo.output.Write(line); err != nil {
+		return 0, err
+	}
+	eo.headerDue = false
+	return len(record), nil
+}
+
+// rotateKey generates and wraps a new data key; the next record is preceded by its header line.
+func (eo *EncryptedOutput) rotateKey() error {
+	dataKey := make([]byte, EncryptedOutputDataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return err
+	}
+	wrapped, err := eo.wrapKey(dataKey)
+	if err != nil {
+		return err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return err
+	}
+	eo.aead = aead
+	eo.wrappedKey = []byte(base64.StdEncoding.EncodeToString(wrapped))
+	eo.keyID = dataKeyID(wrapped)
+	eo.records = 0
+	return nil
+}
+
+// Flush flushes the inner output (if it buffers writes).
+func (eo *EncryptedOutput) Flush() error {
+	return FlushOutput(eo.output)
+}
+
+// Reopen reopens the inner output (if it can reopen its files); the next record is preceded by the key header line.
+func (eo *EncryptedOutput) Reopen() error {
+	eo.syncRoot.Lock()
+	defer eo.syncRoot.Unlock()
+	eo.headerDue = true
+	return ReopenOutput(eo.output)
+}
+
+// SetMetaReporter sets how the inner output reports problems that don't fail writes, see `OutputMetaReporter`.
+func (eo *EncryptedOutput) SetMetaReporter(reporter MetaReporter) {
+	SetOutputMetaReporter(eo.output, reporter)
+}
+
+// Close closes the inner output (if it is an io.Closer).
+func (eo *EncryptedOutput) Close() error {
+	if closer, isCloser := eo.output.(io.Closer); isCloser {
+		return closer.Close()
+	}
+	return nil
+}
+
+// DecryptRecords reads records written by an `EncryptedOutput` and writes the decrypted records to `output`, one per line.
+// Use `key` for records encrypted with a key, and `unwrapKey` for envelope encrypted records, whose data keys are
+// unwrapped once each, from their header lines. Records a file starts with before its first header line use the key of
+// the file rotated before it, so decrypt rotated files as one input, in order (e.g. with an `io.MultiReader`).
+// Decryption fails (with `ErrEncryptedRecordInvalid`) on the first record that was altered.
+func DecryptRecords(input io.Reader, output io.Writer, key []byte, unwrapKey KeyUnwrapper) error {
+	var aead cipher.AEAD
+	var err error
+	if len(key) > 0 {
+		if aead, err = newAEAD(key); err != nil {
+			return err
+		}
+	}
+	dataKeys := map[string]cipher.AEAD{}
+
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if bytes.HasPrefix(line, []byte(EncryptedKeyHeaderPrefix)) {
+			if err = unwrapDataKey(dataKeys, line[len(EncryptedKeyHeaderPrefix):], unwrapKey); err != nil {
+				return err
+			}
+			continue
+		}
+		var keyID []byte
+		recordAEAD := aead
+		if separator := bytes.IndexByte(line, '.'); separator >= 0 {
+			keyID, line = line[:separator], line[separator+1:]
+			if unwrapKey == nil {
+				return ErrEncryptedRecordNoKey
+			}
+			var hasKey bool
+			if recordAEAD, hasKey = dataKeys[string(keyID)]; !hasKey {
+				return ErrEncryptedRecordKeyMissing
+			}
+		}
+		if recordAEAD == nil {
+			return ErrEncryptedRecordNoKey
+		}
+
+		sealed, err := base64.StdEncoding.DecodeString(string(line))
+		if err != nil || len(sealed) < recordAEAD.NonceSize() {
+			return ErrEncryptedRecordInvalid
+		}
+		nonce, ciphertext := sealed[:recordAEAD.NonceSize()], sealed[recordAEAD.NonceSize():]
+		record, err := recordAEAD.Open(nil, nonce, ciphertext, keyID)
+		if err != nil {
+			return ErrEncryptedRecordInvalid
+		}
+		if _, err = output.Write(append(record, '\n')); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// unwrapDataKey adds the cipher for the (base64 encoded) wrapped data key of a header line, unwrapping it once per key.
+func unwrapDataKey(dataKeys map[string]cipher.AEAD, wrappedKey []byte, unwrapKey KeyUnwrapper) error {
+	if unwrapKey == nil {
+		return ErrEncryptedRecordNoKey
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(string(wrappedKey))
+	if err != nil {
+		return ErrEncryptedRecordInvalid
+	}
+	keyID := string(dataKeyID(wrapped))
+	if _, hasKey := dataKeys[keyID]; hasKey {
+		return nil
+	}
+	dataKey, err := unwrapKey(wrapped)
+	if err != nil {
+		return err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return err
+	}
+	dataKeys[keyID] = aead
+	return nil
+}
+
+// dataKeyID returns the id records reference their (wrapped) data key by, which is also their additional data.
+func dataKeyID(wrappedKey []byte) []byte {
+	sum := sha256.Sum256(wrappedKey)
+	return []byte(base64.RawStdEncoding.EncodeToString(sum[:8]))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
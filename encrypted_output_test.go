@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestEncryptedOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	key := bytes.Repeat([]byte{1}, 32)
+	encrypted := bytes.NewBuffer(nil)
+	output, err := NewEncryptedOutput(encrypted, key)
+	assert.Nil(err)
+
+	writer := NewWriter(output)
+	writer.SetUseAnsiColors(false)
+	writer.SetShowTimestamp(false)
+	writer.Printf("ssn is %s", "123-45-6789")
+	writer.Printf("second")
+
+	assert.False(strings.Contains(encrypted.String(), "123-45-6789"))
+	assert.Equal(2, strings.Count(encrypted.String(), "\n"))
+
+	decrypted := bytes.NewBuffer(nil)
+	assert.Nil(DecryptRecords(bytes.NewReader(encrypted.Bytes()), decrypted, key, nil))
+	assert.Equal("ssn is 123-45-6789\nsecond\n", decrypted.String())
+
+	_, err = NewEncryptedOutput(encrypted, []byte("short"))
+	assert.NotNil(err)
+}
+
+func TestEncryptedOutputTampered(t *testing.T) {
+	assert := assert.New(t)
+
+	key := bytes.Repeat([]byte{1}, 16)
+	encrypted := bytes.NewBuffer(nil)
+	output, _ := NewEncryptedOutput(encrypted, key)
+	output.Write([]byte("amount=100\n"))
+
+	tampered := append([]byte{}, encrypted.Bytes()...)
+	if tampered[20] == 'A' {
+		tampered[20] = 'B'
+	} else {
+		tampered[20] = 'A'
+	}
+	err := DecryptRecords(bytes.NewReader(tampered), bytes.NewBuffer(nil), key, nil)
+	assert.Equal(ErrEncryptedRecordInvalid, err)
+
+	err = DecryptRecords(bytes.NewReader(encrypted.Bytes()), bytes.NewBuffer(nil), bytes.Repeat([]byte{2}, 16), nil)
+	assert.Equal(ErrEncryptedRecordInvalid, err)
+}
+
+func TestEnvelopeEncryptedOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	// a stand in for a kms; the "master key" just reverses the data key.
+	reverse := func(key []byte) ([]byte, error) {
+		reversed := make([]byte, len(key))
+		for x := range key {
+			reversed[len(key)-1-x] = key[x]
+		}
+		return reversed, nil
+	}
+
+	encrypted := bytes.NewBuffer(nil)
+	output, err := NewEnvelopeEncryptedOutput(encrypted, reverse)
+	assert.Nil(err)
+	output.Write([]byte("first\n"))
+	output.Write([]byte("second\n"))
+
+	unwraps := 0
+	unwrap := func(wrapped []byte) ([]byte, error) {
+		unwraps++
+		return reverse(wrapped)
+	}
+	decrypted := bytes.NewBuffer(nil)
+	assert.Nil(DecryptRecords(bytes.NewReader(encrypted.Bytes()), decrypted, nil, unwrap))
+	assert.Equal("first\nsecond\n", decrypted.String())
+	assert.Equal(1, unwraps)
+	assert.Equal(1, strings.Count(encrypted.String(), EncryptedKeyHeaderPrefix), "the wrapped key is written once")
+
+	assert.Equal(ErrEncryptedRecordNoKey, DecryptRecords(bytes.NewReader(encrypted.Bytes()), decrypted, nil, nil))
+
+	lines := strings.SplitAfter(encrypted.String(), "\n")
+	err = DecryptRecords(strings.NewReader(lines[2]), decrypted, nil, unwrap)
+	assert.Equal(ErrEncryptedRecordKeyMissing, err)
+}
+
+func TestEnvelopeEncryptedOutputRotatesKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	wraps := 0
+	identity := func(key []byte) ([]byte, error) {
+		wraps++
+		return key, nil
+	}
+	encrypted := bytes.NewBuffer(nil)
+	output, err := NewEnvelopeEncryptedOutput(encrypted, identity)
+	assert.Nil(err)
+	output.SetMaxRecordsPerKey(2)
+	for _, record := range []string{"one", "two", "three", "four", "five"} {
+		_, err = output.Write([]byte(record + "\n"))
+		assert.Nil(err)
+	}
+	assert.Equal(3, wraps)
+	assert.Equal(3, strings.Count(encrypted.String(), EncryptedKeyHeaderPrefix))
+
+	assert.Nil(output.Reopen())
+	output.Write([]byte("six\n"))
+	assert.Equal(4, strings.Count(encrypted.String(), EncryptedKeyHeaderPrefix), "reopening repeats the key header")
+
+	decrypted := bytes.NewBuffer(nil)
+	assert.Nil(DecryptRecords(bytes.NewReader(encrypted.Bytes()), decrypted, nil, identity))
+	assert.Equal("one\ntwo\nthree\nfour\nfive\nsix\n", decrypted.String())
+}
+
+func TestEncryptedOutputRecordLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	output, err := NewEncryptedOutput(bytes.NewBuffer(nil), bytes.Repeat([]byte{1}, 32))
+	assert.Nil(err)
+	assert.Equal(uint64(EncryptedOutputMaxRecordsPerKey), output.MaxRecordsPerKey())
+	output.SetMaxRecordsPerKey(1)
+	_, err = output.Write([]byte("one\n"))
+	assert.Nil(err)
+	_, err = output.Write([]byte("two\n"))
+	assert.Equal(ErrEncryptedOutputKeyExhausted, err)
+}
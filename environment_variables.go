@@ -5,7 +5,13 @@ const (
 
 	// EnvironmentVariableLogEvents is the log verbosity environment variable.
 	EnvironmentVariableLogEvents = "LOG_EVENTS"
+	// EnvironmentVariableLogErrorEvents is the env var holding the csv of events written to the error stream.
+	EnvironmentVariableLogErrorEvents = "LOG_ERROR_EVENTS"
+	// EnvironmentVariableLogCategories is the env var holding the csv of enabled (or `-` disabled) event categories.
+	EnvironmentVariableLogCategories = "LOG_CATEGORIES"
 
+	// EnvironmentVariableLogFormat is the env var that sets the output format, `text` (the default) or `json`.
+	EnvironmentVariableLogFormat = "LOG_FORMAT"
 	// EnvironmentVariableUseAnsiColors is the env var that controls if we use ansi colors in output.
 	EnvironmentVariableUseAnsiColors = "LOG_USE_COLOR"
 	// EnvironmentVariableShowTimestamp is the env var that controls if we show timestamps in output.
@@ -14,6 +20,24 @@ const (
 	EnvironmentVariableShowLabel = "LOG_SHOW_LABEL"
 	// EnvironmentVariableLogLabel is the env var that sets the descriptive label in output.
 	EnvironmentVariableLogLabel = "LOG_LABEL"
+	// EnvironmentVariableLogNamespace is the env var that sets the namespace (or tenant) stamped on output.
+	EnvironmentVariableLogNamespace = "LOG_NAMESPACE"
+	// EnvironmentVariableScanSecrets is the env var that controls if output is scanned for secrets before it is written.
+	EnvironmentVariableScanSecrets = "LOG_SCAN_SECRETS"
+	// EnvironmentVariableLogEncryptionKey is the env var holding the base64 encoded key for encrypted log files.
+	EnvironmentVariableLogEncryptionKey = "LOG_ENCRYPTION_KEY"
+	// EnvironmentVariableLogSigningKey is the env var holding the base64 encoded key log records are signed with.
+	EnvironmentVariableLogSigningKey = "LOG_SIGNING_KEY"
+	// EnvironmentVariableLogKubernetesMetadata is the env var that controls if the pod's metadata is stamped on output.
+	EnvironmentVariableLogKubernetesMetadata = "LOG_K8S_METADATA"
+	// EnvironmentVariableKubernetesPodInfoPath is the env var that sets where the downward API volume is mounted.
+	EnvironmentVariableKubernetesPodInfoPath = "LOG_K8S_PODINFO_PATH"
+	// EnvironmentVariableKubernetesPodName is the (downward API) env var holding the pod name.
+	EnvironmentVariableKubernetesPodName = "POD_NAME"
+	// EnvironmentVariableKubernetesNamespace is the (downward API) env var holding the pod namespace.
+	EnvironmentVariableKubernetesNamespace = "POD_NAMESPACE"
+	// EnvironmentVariableKubernetesNodeName is the (downward API) env var holding the node name.
+	EnvironmentVariableKubernetesNodeName = "NODE_NAME"
 
 	// EnvironmentVariableLogOutFile is the variable for what file to write to.
 	EnvironmentVariableLogOutFile = "LOG_OUT_FILE"
@@ -34,4 +58,12 @@ const (
 	EnvironmentVariableLogOutMaxArchive = "LOG_OUT_MAX_ARCHIVE"
 	// EnvironmentVariableLogErrMaxSizeBytes
 	EnvironmentVariableLogErrMaxArchive = "LOG_ERR_MAX_ARCHIVE"
+
+	// EnvironmentVariableLogOutFsync is the sync policy of the output file, see `ParseFileSyncPolicy`.
+	EnvironmentVariableLogOutFsync = "LOG_OUT_FSYNC"
+	// EnvironmentVariableLogErrFsync is the sync policy of the error output file, see `ParseFileSyncPolicy`.
+	EnvironmentVariableLogErrFsync = "LOG_ERR_FSYNC"
+
+	// EnvironmentVariableLogFileDetectRotation enables reopening the output files when they're moved, see `FileOutput.SetDetectRotation`.
+	EnvironmentVariableLogFileDetectRotation = "LOG_FILE_DETECT_ROTATION"
 )
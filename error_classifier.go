@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"reflect"
+)
+
+// ErrorRule returns the event an error is logged as, and if the rule applies to it.
+type ErrorRule func(err error) (EventFlag, bool)
+
+// NewErrorClassifier returns a new, empty error classifier.
+func NewErrorClassifier() *ErrorClassifier {
+	return &ErrorClassifier{}
+}
+
+// DefaultErrorClassifier returns an error classifier that logs canceled contexts (`context.Canceled`) as debug messages.
+func DefaultErrorClassifier() *ErrorClassifier {
+	classifier := NewErrorClassifier()
+	classifier.AddSentinel(context.Canceled, EventDebug)
+	return classifier
+}
+
+// ErrorClassifier maps expected errors (by sentinel or type, anywhere in their wrap chain) to the events they're
+// logged as, e.g. `sql.ErrNoRows` as `EventInfo`, so they stop polluting the error stream; see `Agent.SetErrorClassifier`.
+// Rules are checked in the order they were added, and must be added before the classifier is in use.
+type ErrorClassifier struct {
+	rules []ErrorRule
+}
+
+// Rules returns the classifier's rules.
+func (ec *ErrorClassifier) Rules() []ErrorRule { return ec.rules }
+
+// AddRule adds a rule.
+func (ec *ErrorClassifier) AddRule(rule ErrorRule) {
+	ec.rules = append(ec.rules, rule)
+}
+
+// AddSentinel logs errors matching a sentinel (see `errors.Is`) as an event, e.g. `AddSentinel(sql.ErrNoRows, EventInfo)`.
+func (ec *ErrorClassifier) AddSentinel(sentinel error, event EventFlag) {
+	ec.AddRule(func(err error) (EventFlag, bool) {
+		return event, errors.Is(err, sentinel)
+	})
+}
+
+// AddType logs errors of the type of a sample (see `errors.As`) as an event, e.g. `AddType(&net.OpError{}, EventWarning)`.
+func (ec *ErrorClassifier) AddType(sample error, event EventFlag) {
+	sampleType := reflect.TypeOf(sample)
+	ec.AddRule(func(err error) (EventFlag, bool) {
+		return event, errors.As(err, reflect.New(sampleType).Interface())
+	})
+}
+
+// Classify returns the event an error is logged as, or the given event if no rule applies.
+func (ec *ErrorClassifier) Classify(err error, event EventFlag) EventFlag {
+	if ec == nil {
+		return event
+	}
+	for _, rule := range ec.rules {
+		if classified, applies := rule(err); applies {
+			return classified
+		}
+	}
+	return event
+}
+
+// severityColors are the label colors of the severity events.
+var severityColors = map[EventFlag]AnsiColorCode{
+	EventSilly:      ColorLightWhite,
+	EventDebug:      ColorLightYellow,
+	EventInfo:       ColorLightWhite,
+	EventWarning:    ColorLightYellow,
+	EventError:      ColorRed,
+	EventFatalError: ColorRed,
+}
+
+// ErrorClassifier returns the classifier for errors logged with `Warning` and `Error`, if any.
+func (da *Agent) ErrorClassifier() *ErrorClassifier {
+	da.eventsLock.Lock()
+	defer da.eventsLock.Unlock()
+	return da.errorClassifier
+}
+
+// SetErrorClassifier sets a classifier that picks the event errors logged with `Warning` and `Error` (and their
+// request and sync agent counterparts) are logged as, e.g. `DefaultErrorClassifier()`. Errors classified as
+// less severe than warnings are written to the output stream; listeners are handed the error as usual.
+func (da *Agent) SetErrorClassifier(classifier *ErrorClassifier) {
+	da.eventsLock.Lock()
+	da.errorClassifier = classifier
+	da.eventsLock.Unlock()
+}
+
+// classifyError returns the event (and color) a warning or error is logged as, see `SetErrorClassifier`.
+func (da *Agent) classifyError(event EventFlag, color AnsiColorCode, err error) (EventFlag, AnsiColorCode) {
+	if event != EventWarning && event != EventError {
+		return event, color
+	}
+	classified := da.ErrorClassifier().Classify(err, event)
+	if classified == event {
+		return event, color
+	}
+	if classifiedColor, hasColor := severityColors[classified]; hasColor {
+		return classified, classifiedColor
+	}
+	return classified, color
+}
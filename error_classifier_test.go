@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+var errNotFound = errors.New("not found")
+
+func TestErrorClassifier(t *testing.T) {
+	assert := assert.New(t)
+
+	classifier := DefaultErrorClassifier()
+	classifier.AddSentinel(errNotFound, EventInfo)
+	classifier.AddType(&net.OpError{}, EventWarning)
+
+	assert.Equal(EventDebug, classifier.Classify(fmt.Errorf("query: %w", context.Canceled), EventError))
+	assert.Equal(EventInfo, classifier.Classify(fmt.Errorf("user 1: %w", errNotFound), EventError))
+	assert.Equal(EventWarning, classifier.Classify(&net.OpError{Op: "dial", Err: errors.New("refused")}, EventError))
+	assert.Equal(EventError, classifier.Classify(errors.New("boom"), EventError))
+
+	var nilClassifier *ErrorClassifier
+	assert.Equal(EventError, nilClassifier.Classify(context.Canceled, EventError))
+}
+
+func TestAgentErrorClassifier(t *testing.T) {
+	assert := assert.New(t)
+
+	output := bytes.NewBuffer(nil)
+	errorOutput := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventDebug, EventError), NewWriterWithError(output, errorOutput))
+	defer da.Close()
+	da.Writer().SetUseAnsiColors(false)
+
+	classifier := DefaultErrorClassifier()
+	classifier.AddSentinel(errNotFound, EventInfo)
+	da.SetErrorClassifier(classifier)
+	assert.Equal(classifier, da.ErrorClassifier())
+
+	da.Error(fmt.Errorf("request: %w", context.Canceled))
+	da.Error(errNotFound)
+	da.Error(errors.New("boom"))
+	da.Sync().Error(context.Canceled)
+	da.Fatal(context.Canceled)
+	da.Flush()
+
+	assert.True(strings.Contains(output.String(), "[debug] request: context canceled\n"), output.String())
+	assert.True(strings.Contains(output.String(), "[debug] context canceled\n"), output.String())
+	assert.False(strings.Contains(output.String()+errorOutput.String(), "not found"), errorOutput.String())
+	assert.True(strings.Contains(errorOutput.String(), "[error] boom\n"), errorOutput.String())
+	assert.False(strings.Contains(errorOutput.String(), "canceled"), errorOutput.String())
+}
@@ -25,10 +25,14 @@ const (
 	EventWebRequestStart EventFlag = "web.request.start"
 	// EventWebRequest fires when an app completes handling a request.
 	EventWebRequest EventFlag = "web.request"
+	// EventWebRequestHeaders fires to provide the headers of a request.
+	EventWebRequestHeaders EventFlag = "web.request.headers"
 	// EventWebRequestPostBody fires when a request has a post body.
 	EventWebRequestPostBody EventFlag = "web.request.postbody"
 	// EventWebResponse fires to provide the raw response to a request.
 	EventWebResponse EventFlag = "web.response"
+	// EventWebClientRequest fires when an outbound request made through a `RoundTripper` completes.
+	EventWebClientRequest EventFlag = "web.client.request"
 )
 
 // EventFlag is a flag to enable or disable triggering handlers for an event.
@@ -0,0 +1,44 @@
+package logger
+
+import "sync"
+
+const (
+	// EventGroupWeb is a group of the inbound web request event flags.
+	EventGroupWeb EventFlag = "web"
+	// EventGroupErrors is a group of the warning, error and fatal event flags.
+	EventGroupErrors EventFlag = "errors"
+)
+
+var (
+	eventFlagGroupsLock sync.RWMutex
+	eventFlagGroups     = map[EventFlag][]EventFlag{
+		EventGroupWeb:    {EventWebRequestStart, EventWebRequest, EventWebRequestHeaders, EventWebRequestPostBody, EventWebResponse},
+		EventGroupErrors: {EventWarning, EventError, EventFatalError},
+		"warn":           {EventWarning},
+		"err":            {EventError},
+	}
+)
+
+// RegisterEventFlagGroup registers a named group (or with a single flag, an alias) of event flags.
+// Group names can be used anywhere a flag is enabled or disabled, including `LOG_EVENTS`.
+func RegisterEventFlagGroup(name EventFlag, flags ...EventFlag) {
+	eventFlagGroupsLock.Lock()
+	defer eventFlagGroupsLock.Unlock()
+	eventFlagGroups[name] = flags
+}
+
+// EventFlagGroup returns the flags for a named group, and if the group exists.
+func EventFlagGroup(name EventFlag) ([]EventFlag, bool) {
+	eventFlagGroupsLock.RLock()
+	defer eventFlagGroupsLock.RUnlock()
+	flags, hasGroup := eventFlagGroups[name]
+	return flags, hasGroup
+}
+
+// expandEventFlag returns the flags for a group name, or the flag itself if it isn't a group.
+func expandEventFlag(flag EventFlag) []EventFlag {
+	if flags, hasGroup := EventFlagGroup(flag); hasGroup {
+		return flags
+	}
+	return []EventFlag{flag}
+}
@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestEventFlagGroupFromCSV(t *testing.T) {
+	assert := assert.New(t)
+
+	set := NewEventFlagSetFromCSV("errors,web,-web.request.postbody")
+	assert.True(set.IsEnabled(EventWarning))
+	assert.True(set.IsEnabled(EventError))
+	assert.True(set.IsEnabled(EventFatalError))
+	assert.True(set.IsEnabled(EventWebRequest))
+	assert.True(set.IsEnabled(EventWebRequestStart))
+	assert.False(set.IsEnabled(EventWebRequestPostBody))
+	assert.False(set.IsEnabled(EventInfo))
+
+	set = NewEventFlagSetFromCSV("all,-web")
+	assert.True(set.IsEnabled(EventInfo))
+	assert.False(set.IsEnabled(EventWebRequest))
+}
+
+func TestEventFlagGroupProgrammatic(t *testing.T) {
+	assert := assert.New(t)
+
+	RegisterEventFlagGroup("test.group", "foo", "bar")
+	flags, ok := EventFlagGroup("test.group")
+	assert.True(ok)
+	assert.Len(flags, 2)
+
+	set := NewEventFlagSet("test.group", "warn")
+	assert.True(set.IsEnabled("foo"))
+	assert.True(set.IsEnabled("bar"))
+	assert.True(set.IsEnabled(EventWarning))
+	set.Disable("test.group")
+	assert.False(set.IsEnabled("foo"))
+	assert.False(set.IsEnabled("bar"))
+}
@@ -1,7 +1,9 @@
 package logger
 
 import (
+	"fmt"
 	"os"
+	"sort"
 	"strings"
 )
 
@@ -43,15 +45,21 @@ func NewEventFlagSetFromEnvironment() *EventFlagSet {
 
 // NewEventFlagSetFromCSV returns a new event flag set from a csv of event flags.
 // These flags are case insensitive.
+// Flags (or groups) prefixed with `-` are disabled, e.g. `all,-debug,-web.request.postbody`;
+// a csv of only disabled flags implies `all`. Later flags take precedence over earlier ones.
 func NewEventFlagSetFromCSV(flagCSV string) *EventFlagSet {
 	flagSet := &EventFlagSet{
 		flags: map[EventFlag]bool{},
 	}
 
 	flags := strings.Split(flagCSV, ",")
+	var hasEnabled, hasDisabled bool
 
 	for _, flag := range flags {
 		parsedFlag := EventFlag(strings.Trim(strings.ToLower(flag), " \t\n"))
+		if len(parsedFlag) == 0 {
+			continue
+		}
 		if string(parsedFlag) == string(EventAll) {
 			flagSet.all = true
 		}
@@ -61,16 +69,39 @@ func NewEventFlagSetFromCSV(flagCSV string) *EventFlagSet {
 		}
 
 		if strings.HasPrefix(string(parsedFlag), "-") {
-			flag := EventFlag(strings.TrimPrefix(string(parsedFlag), "-"))
-			flagSet.flags[flag] = false
+			hasDisabled = true
+			for _, flag := range expandEventFlag(EventFlag(strings.TrimPrefix(string(parsedFlag), "-"))) {
+				flagSet.flags[flag] = false
+			}
 		} else {
-			flagSet.flags[parsedFlag] = true
+			hasEnabled = true
+			if parsedFlag != EventNone {
+				flagSet.none = false
+			}
+			for _, flag := range expandEventFlag(parsedFlag) {
+				flagSet.flags[flag] = true
+			}
 		}
 	}
 
+	if hasDisabled && !hasEnabled {
+		flagSet.all = true
+	}
 	return flagSet
 }
 
+// ParseEventFlagSet parses an event flag set from the csv syntax `String()` produces
+// (and `NewEventFlagSetFromEnvironment` accepts), e.g. `all, -debug`.
+func ParseEventFlagSet(value string) (*EventFlagSet, error) {
+	for _, flag := range strings.Split(value, ",") {
+		flag = strings.Trim(flag, " \t\n")
+		if flag == "-" || strings.ContainsAny(flag, " \t\n") {
+			return nil, fmt.Errorf("Invalid event flag `%s`", flag)
+		}
+	}
+	return NewEventFlagSetFromCSV(value), nil
+}
+
 // EventFlagSet is a set of event flags.
 type EventFlagSet struct {
 	flags map[EventFlag]bool
@@ -78,15 +109,31 @@ type EventFlagSet struct {
 	none  bool
 }
 
-// Enable enables an event flag.
+// clone returns a copy of the flag set.
+func (efs *EventFlagSet) clone() *EventFlagSet {
+	if efs == nil {
+		return nil
+	}
+	flags := make(map[EventFlag]bool, len(efs.flags))
+	for flag, enabled := range efs.flags {
+		flags[flag] = enabled
+	}
+	return &EventFlagSet{flags: flags, all: efs.all, none: efs.none}
+}
+
+// Enable enables an event flag (or each flag in a group).
 func (efs *EventFlagSet) Enable(flagValue EventFlag) {
 	efs.none = false
-	efs.flags[flagValue] = true
+	for _, flag := range expandEventFlag(flagValue) {
+		efs.flags[flag] = true
+	}
 }
 
-// Disable disabled an event flag.
+// Disable disabled an event flag (or each flag in a group).
 func (efs *EventFlagSet) Disable(flagValue EventFlag) {
-	efs.flags[flagValue] = false
+	for _, flag := range expandEventFlag(flagValue) {
+		efs.flags[flag] = false
+	}
 }
 
 // EnableAll flips the `all` bit on the flag set.
@@ -131,17 +178,16 @@ func (efs EventFlagSet) IsEnabled(flagValue EventFlag) bool {
 	return false
 }
 
+// String returns the flag set as a csv of flags, with disabled flags prefixed by `-`.
+// The result can be parsed back with `ParseEventFlagSet`.
 func (efs EventFlagSet) String() string {
 	if efs.none {
 		return string(EventNone)
 	}
 
 	var flags []string
-	if efs.all {
-		flags = []string{string(EventAll)}
-	}
 	for key, enabled := range efs.flags {
-		if key != EventAll {
+		if key != EventAll && key != EventNone {
 			if enabled {
 				flags = append(flags, string(key))
 			} else {
@@ -149,5 +195,9 @@ func (efs EventFlagSet) String() string {
 			}
 		}
 	}
+	sort.Strings(flags)
+	if efs.all {
+		flags = append([]string{string(EventAll)}, flags...)
+	}
 	return strings.Join(flags, ", ")
 }
@@ -108,3 +108,51 @@ func TestEventFlagNoneEnableEvents(t *testing.T) {
 	flags.Enable("test_flag")
 	assert.True(flags.IsEnabled("test_flag"))
 }
+
+func TestEventFlagSetStringRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, value := range []string{"all", "none", "all, -debug, -web.request", "error, info, web.request", ""} {
+		set, err := ParseEventFlagSet(value)
+		assert.Nil(err)
+		assert.Equal(value, set.String())
+	}
+
+	set := NewEventFlagSet(EventInfo, EventError)
+	set.Disable(EventDebug)
+	parsed, err := ParseEventFlagSet(set.String())
+	assert.Nil(err)
+	assert.True(parsed.IsEnabled(EventInfo))
+	assert.True(parsed.IsEnabled(EventError))
+	assert.False(parsed.IsEnabled(EventDebug))
+
+	_, err = ParseEventFlagSet("info, -")
+	assert.NotNil(err)
+	_, err = ParseEventFlagSet("in fo")
+	assert.NotNil(err)
+}
+
+func TestEventFlagSetFromCSVNegation(t *testing.T) {
+	assert := assert.New(t)
+
+	set := NewEventFlagSetFromCSV("all,-debug,-web.request.postbody")
+	assert.True(set.IsEnabled(EventInfo))
+	assert.True(set.IsEnabled(EventWebRequest))
+	assert.False(set.IsEnabled(EventDebug))
+	assert.False(set.IsEnabled(EventWebRequestPostBody))
+
+	set = NewEventFlagSetFromCSV("-debug, -silly")
+	assert.True(set.IsAllEnabled())
+	assert.True(set.IsEnabled(EventInfo))
+	assert.False(set.IsEnabled(EventDebug))
+	assert.False(set.IsEnabled(EventSilly))
+
+	set = NewEventFlagSetFromCSV("info,-info")
+	assert.False(set.IsAllEnabled())
+	assert.False(set.IsEnabled(EventInfo))
+
+	set = NewEventFlagSetFromCSV("none,info")
+	assert.False(set.IsNoneEnabled())
+	assert.True(set.IsEnabled(EventInfo))
+	assert.False(set.IsEnabled(EventError))
+}
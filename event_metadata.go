@@ -0,0 +1,101 @@
+package logger
+
+import "context"
+
+// EventMetadata is what an agent knows about an event besides its time and state: the context it was fired with
+// (see `OnEventContext`), the fields and namespace of the agent that fired it (see `SetDefaultFields`, `RequestAgent`
+// and `SetNamespace`), its flag (for json output, see `OutputFormatJSON`) and its sequence number (see `SetSequenceNumbers`).
+// It travels with the event's time source, so listener signatures are unchanged; read it with `MetadataFromTimeSource`.
+type EventMetadata struct {
+	// Context carries the values of the context the event was fired with, but not its cancellation or deadline,
+	// since listeners usually run after the call that fired the event returned (see `withContext`).
+	Context   context.Context
+	Fields    Fields
+	Namespace string
+	Event     EventFlag
+	Sequence  uint64
+}
+
+// eventTimeSource is the time source of an event that carries metadata.
+type eventTimeSource struct {
+	TimeSource
+	metadata EventMetadata
+}
+
+// MetadataFromTimeSource returns the metadata carried by an event's time source, if any.
+func MetadataFromTimeSource(ts TimeSource) EventMetadata {
+	if typed, isTyped := ts.(eventTimeSource); isTyped {
+		return typed.metadata
+	}
+	return EventMetadata{}
+}
+
+// withMetadata returns a time source that carries its metadata as changed by `update`.
+func withMetadata(ts TimeSource, update func(*EventMetadata)) TimeSource {
+	typed, isTyped := ts.(eventTimeSource)
+	if !isTyped {
+		typed = eventTimeSource{TimeSource: ts}
+	}
+	update(&typed.metadata)
+	return typed
+}
+
+// withContext returns a time source that also carries a context's values. The context is detached from its
+// cancellation (and deadline), so listeners running on the queue after a request finishes can still use it.
+func withContext(ts TimeSource, ctx context.Context) TimeSource {
+	if ctx == nil {
+		return ts
+	}
+	return withMetadata(ts, func(metadata *EventMetadata) { metadata.Context = context.WithoutCancel(ctx) })
+}
+
+// withFields returns a time source that also carries fields. Fields it already carries (e.g. a request agent's)
+// take precedence over the given ones (e.g. the agent's default fields).
+func withFields(ts TimeSource, fields Fields) TimeSource {
+	if len(fields) == 0 {
+		return ts
+	}
+	return withMetadata(ts, func(metadata *EventMetadata) { metadata.Fields = mergeFields(fields, metadata.Fields) })
+}
+
+// withNamespace returns a time source that also carries the namespace of the agent that fired its event.
+func withNamespace(ts TimeSource, namespace string) TimeSource {
+	if len(namespace) == 0 {
+		return ts
+	}
+	return withMetadata(ts, func(metadata *EventMetadata) { metadata.Namespace = namespace })
+}
+
+// withEvent returns a time source that also carries the event it's the time of.
+func withEvent(ts TimeSource, eventFlag EventFlag) TimeSource {
+	return withMetadata(ts, func(metadata *EventMetadata) { metadata.Event = eventFlag })
+}
+
+// withSequence returns a time source that also carries the sequence number of its event.
+func withSequence(ts TimeSource, sequence uint64) TimeSource {
+	return withMetadata(ts, func(metadata *EventMetadata) { metadata.Sequence = sequence })
+}
+
+// FieldsFromTimeSource returns the fields of a listener's event: the default fields of the agent it was fired by
+// (see `SetDefaultFields`) and those of the request agent that fired it (see `RequestAgent`), or nil if it has none.
+func FieldsFromTimeSource(ts TimeSource) Fields {
+	return MetadataFromTimeSource(ts).Fields
+}
+
+// NamespaceFromTimeSource returns the namespace of the agent a listener's event was fired by (see `Agent.SetNamespace`),
+// or "" if it has none, in which case the writer's namespace applies.
+func NamespaceFromTimeSource(ts TimeSource) string {
+	return MetadataFromTimeSource(ts).Namespace
+}
+
+// EventFromTimeSource returns the event a line is written for, if it's carried by its time source
+// (which it is for json output, see `OutputFormatJSON`).
+func EventFromTimeSource(ts TimeSource) EventFlag {
+	return MetadataFromTimeSource(ts).Event
+}
+
+// SequenceFromTimeSource returns the sequence number of a listener's event (see `Agent.SetSequenceNumbers`),
+// or 0 if it wasn't numbered.
+func SequenceFromTimeSource(ts TimeSource) uint64 {
+	return MetadataFromTimeSource(ts).Sequence
+}
@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// EventOrdering is how the agent orders the events it processes.
+type EventOrdering int
+
+const (
+	// EventOrderingNone processes events on a pool of workers; events can be written out of order.
+	EventOrderingNone EventOrdering = iota
+	// EventOrderingPerEvent processes events with the same flag in the order they were fired.
+	EventOrderingPerEvent
+	// EventOrderingPerRequest processes events for the same request in the order they were fired,
+	// e.g. `web.request.start` is always written before `web.request`. Events without a request are ordered per flag.
+	EventOrderingPerRequest
+)
+
+// Ordering returns the agent's event ordering.
+func (da *Agent) Ordering() EventOrdering {
+	_, ordering := da.orderedQueueAndOrdering()
+	return ordering
+}
+
+// eventOrdering is an agent's ordering and the queue ordered events are processed on, replaced (never mutated) on change.
+type eventOrdering struct {
+	ordering EventOrdering
+	queue    *ShardedQueue
+}
+
+// SetOrdering sets the agent's event ordering.
+// Ordered events are processed on sharded single worker queues, which trades throughput
+// (and the priority lane, see `SetPriorityEvents`) for FIFO ordering within a flag or request.
+// The shards split the capacity of the agent's queue (see `EventQueue.MaxWorkItems`) between them.
+func (da *Agent) SetOrdering(ordering EventOrdering) {
+	da.eventsLock.Lock()
+	current := da.loadOrdering()
+	next := &eventOrdering{ordering: ordering, queue: current.queue}
+	var retired *ShardedQueue
+	if ordering == EventOrderingNone {
+		retired, next.queue = current.queue, nil
+	} else if next.queue == nil {
+		next.queue = NewShardedQueue(DefaultAgentQueueShards)
+		next.queue.SetMaxWorkItems(da.eventQueue.MaxWorkItems())
+		next.queue.SetPanicHandler(da.queuePanicked)
+		next.queue.Start()
+	}
+	da.ordering.Store(next)
+	da.eventsLock.Unlock()
+
+	// the retired shards finish the events already queued on them; shards shared with a parent agent are left open.
+	if retired != nil && da.parent == nil {
+		retired.Close()
+	}
+}
+
+// orderedQueueAndOrdering returns the queue ordered events are processed on (if any) and the ordering.
+// It takes no locks, since it's called for every event.
+func (da *Agent) orderedQueueAndOrdering() (*ShardedQueue, EventOrdering) {
+	current := da.loadOrdering()
+	return current.queue, current.ordering
+}
+
+// loadOrdering returns the agent's current ordering, which must not be mutated.
+func (da *Agent) loadOrdering() *eventOrdering {
+	if current, _ := da.ordering.Load().(*eventOrdering); current != nil {
+		return current
+	}
+	return &eventOrdering{}
+}
+
+// orderingKey returns the shard key for an action's state (time source, event flag, state...).
+func orderingKey(ordering EventOrdering, args ...interface{}) string {
+	if ordering == EventOrderingPerRequest && len(args) > 2 {
+		for _, state := range args[2:] {
+			if req, isRequest := state.(*http.Request); isRequest && req != nil {
+				if requestID := GetRequestID(req); len(requestID) > 0 {
+					return requestID
+				}
+				return fmt.Sprintf("%p", req)
+			}
+		}
+	}
+	if len(args) > 1 {
+		if eventFlag, err := stateAsEventFlag(args[1]); err == nil {
+			return string(eventFlag)
+		}
+	}
+	return ""
+}
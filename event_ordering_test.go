@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestOrderingKey(t *testing.T) {
+	assert := assert.New(t)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderRequestID, "abc")
+
+	assert.Equal("web.request", orderingKey(EventOrderingPerEvent, TimeNow(), EventWebRequest, req))
+	assert.Equal("abc", orderingKey(EventOrderingPerRequest, TimeNow(), EventWebRequest, req))
+	assert.Equal("info", orderingKey(EventOrderingPerRequest, TimeNow(), EventInfo, "message"))
+}
+
+func TestAgentOrderingPerRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	da := New(NewEventFlagSetAll())
+	defer da.Close()
+	da.SetOrdering(EventOrderingPerRequest)
+	assert.Equal(EventOrderingPerRequest, da.Ordering())
+	orderedQueue, _ := da.orderedQueueAndOrdering()
+	assert.Equal(da.EventQueue().MaxWorkItems(), orderedQueue.MaxWorkItems(), "the shards split the agent's capacity")
+	assert.Equal(EventOrderingPerRequest, da.Clone().Ordering())
+
+	var order []EventFlag
+	record := func(wr *Writer, ts TimeSource, e EventFlag, state ...interface{}) {
+		order = append(order, e)
+	}
+	da.AddEventListener(EventWebRequestStart, record)
+	da.AddEventListener(EventWebRequest, record)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderRequestID, "abc")
+	for x := 0; x < 50; x++ {
+		da.OnEvent(EventWebRequestStart, req)
+		da.OnEvent(EventWebRequest, req)
+	}
+	da.Flush()
+	assert.Len(order, 100)
+	for x := 0; x < len(order); x += 2 {
+		assert.Equal(EventWebRequestStart, order[x])
+		assert.Equal(EventWebRequest, order[x+1])
+	}
+
+	da.SetOrdering(EventOrderingNone)
+	assert.Equal(EventOrderingNone, da.Ordering())
+}
@@ -0,0 +1,427 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultEventQueueScaleInterval is the default interval the event queue re-evaluates its worker count on.
+	DefaultEventQueueScaleInterval = 100 * time.Millisecond
+
+	// DefaultEventQueueIdleIntervals is the default number of consecutive idle intervals before a worker is retired.
+	DefaultEventQueueIdleIntervals = 10
+
+	// DefaultEventQueuePriorityLength is the number of items the priority lane buffers before enqueuing priority items blocks.
+	// Priority events are rare (errors by default) and taken first, so the lane stays small whatever the queue length.
+	DefaultEventQueuePriorityLength = 1 << 10
+)
+
+// QueueAction is an action processed by the event queue.
+type QueueAction func(...interface{}) error
+
+// QueuePanicHandler is called with the value recovered from an action that panicked.
+type QueuePanicHandler func(recovered interface{})
+
+type queueItem struct {
+	action QueueAction
+	args   []interface{}
+	epoch  *queueEpoch
+}
+
+// queueEpoch tracks the items enqueued between flushes.
+type queueEpoch struct {
+	pending  sync.WaitGroup
+	previous *queueEpoch
+}
+
+// NewEventQueue returns a new event queue that scales between a min and max number of workers.
+func NewEventQueue(minWorkers, maxWorkers int) *EventQueue {
+	if maxWorkers < minWorkers {
+		maxWorkers = minWorkers
+	}
+	return &EventQueue{
+		minWorkers:    int32(minWorkers),
+		maxWorkers:    int32(maxWorkers),
+		maxWorkItems:  DefaultAgentQueueLength,
+		scaleInterval: DefaultEventQueueScaleInterval,
+		idleIntervals: DefaultEventQueueIdleIntervals,
+	}
+}
+
+// EventQueue is a work queue that scales its workers (consumers) with load.
+// It has a priority lane; workers always take priority items before normal ones.
+// On each scale interval a worker is added if the queue did not drain in the last interval,
+// and a worker is retired once the queue has been empty for a number of consecutive intervals.
+type EventQueue struct {
+	syncRoot sync.RWMutex
+	running  bool
+	items    chan queueItem
+	priority chan queueItem
+	retire   chan struct{}
+	stop     chan struct{}
+	epoch    *queueEpoch
+	senders  sync.WaitGroup
+	workers  sync.WaitGroup
+
+	numWorkers   int32
+	minWorkers   int32
+	maxWorkers   int32
+	maxWorkItems int
+	processed    int64
+	highWater    int64
+	panicHandler atomic.Value // QueuePanicHandler, see `SetPanicHandler`
+
+	scaleInterval time.Duration
+	idleIntervals int
+	lastProcessed int64
+	idleFor       int
+}
+
+// Start starts the workers and the scaler.
+func (eq *EventQueue) Start() {
+	eq.syncRoot.Lock()
+	defer eq.syncRoot.Unlock()
+	if eq.running {
+		return
+	}
+	eq.items = make(chan queueItem, eq.maxWorkItems)
+	eq.priority = make(chan queueItem, DefaultEventQueuePriorityLength)
+	eq.retire = make(chan struct{})
+	eq.stop = make(chan struct{})
+	eq.epoch = &queueEpoch{}
+	eq.running = true
+	for x := 0; x < eq.MinWorkers(); x++ {
+		eq.addWorker()
+	}
+	if eq.scaleInterval > 0 {
+		go eq.runScaler(eq.stop, eq.scaleInterval)
+	}
+}
+
+// Enqueue adds an action to the queue; it blocks if the queue is full.
+// If the queue is not running the action is processed synchronously.
+func (eq *EventQueue) Enqueue(action QueueAction, args ...interface{}) {
+	eq.send(false, action, args...)
+}
+
+// EnqueuePriority adds an action to the priority lane, ahead of any normal items;
+// it blocks if the lane is full (see `DefaultEventQueuePriorityLength`).
+// If the queue is not running the action is processed synchronously.
+func (eq *EventQueue) EnqueuePriority(action QueueAction, args ...interface{}) {
+	eq.send(true, action, args...)
+}
+
+// send queues an item on a lane. The lock is only held to register the item (and its sender), not while
+// waiting for room, so a full queue doesn't hold up `Flush` or `Close`, and actions (e.g. listeners) that
+// enqueue while the queue is full just wait for the other workers to make room.
+// If the queue is closed while the sender waits, the action is processed synchronously.
+func (eq *EventQueue) send(priority bool, action QueueAction, args ...interface{}) {
+	eq.syncRoot.RLock()
+	if !eq.running {
+		eq.syncRoot.RUnlock()
+		action(args...)
+		return
+	}
+	item := queueItem{action: action, args: args, epoch: eq.epoch}
+	items, stop := eq.items, eq.stop
+	if priority {
+		items = eq.priority
+	}
+	item.epoch.pending.Add(1)
+	eq.recordHighWater()
+	eq.senders.Add(1)
+	eq.syncRoot.RUnlock()
+	defer eq.senders.Done()
+
+	select {
+	case items <- item:
+		return
+	default:
+	}
+	select {
+	case items <- item:
+	case <-stop:
+		eq.process(item)
+	}
+}
+
+// HighWater returns the largest number of items that have been queued at once.
+func (eq *EventQueue) HighWater() int {
+	return int(atomic.LoadInt64(&eq.highWater))
+}
+
+// recordHighWater raises the high water mark to the current length, counting an item about to be queued
+// (measuring after queueing would miss it if a worker took it first); the caller holds the read lock.
+func (eq *EventQueue) recordHighWater() {
+	length := int64(len(eq.items)+len(eq.priority)) + 1
+	for {
+		highWater := atomic.LoadInt64(&eq.highWater)
+		if length <= highWater || atomic.CompareAndSwapInt64(&eq.highWater, highWater, length) {
+			return
+		}
+	}
+}
+
+// Flush blocks until the items queued before the call have been processed.
+// Items enqueued while flushing are not waited on.
+func (eq *EventQueue) Flush() {
+	eq.syncRoot.Lock()
+	if !eq.running {
+		eq.syncRoot.Unlock()
+		return
+	}
+	flushed := eq.epoch
+	eq.epoch = &queueEpoch{previous: flushed}
+	eq.syncRoot.Unlock()
+
+	for epoch := flushed; epoch != nil; {
+		epoch.pending.Wait()
+		eq.syncRoot.Lock()
+		previous := epoch.previous
+		eq.syncRoot.Unlock()
+		epoch = previous
+	}
+
+	// everything before the flushed epoch is done, so later flushes can stop here.
+	eq.syncRoot.Lock()
+	flushed.previous = nil
+	eq.syncRoot.Unlock()
+}
+
+// Len returns the number of queued items, including priority items.
+func (eq *EventQueue) Len() int {
+	eq.syncRoot.RLock()
+	defer eq.syncRoot.RUnlock()
+	return len(eq.items) + len(eq.priority)
+}
+
+// PriorityLen returns the number of queued priority items.
+func (eq *EventQueue) PriorityLen() int {
+	eq.syncRoot.RLock()
+	defer eq.syncRoot.RUnlock()
+	return len(eq.priority)
+}
+
+// Running returns if the queue has been started and not closed.
+func (eq *EventQueue) Running() bool {
+	eq.syncRoot.RLock()
+	defer eq.syncRoot.RUnlock()
+	return eq.running
+}
+
+// NumWorkers returns the current number of workers.
+func (eq *EventQueue) NumWorkers() int {
+	return int(atomic.LoadInt32(&eq.numWorkers))
+}
+
+// MinWorkers returns the minimum number of workers.
+func (eq *EventQueue) MinWorkers() int {
+	return int(atomic.LoadInt32(&eq.minWorkers))
+}
+
+// SetMinWorkers sets the minimum number of workers; it takes effect on the next scale interval.
+func (eq *EventQueue) SetMinWorkers(minWorkers int) {
+	atomic.StoreInt32(&eq.minWorkers, int32(minWorkers))
+}
+
+// MaxWorkers returns the maximum number of workers.
+func (eq *EventQueue) MaxWorkers() int {
+	return int(atomic.LoadInt32(&eq.maxWorkers))
+}
+
+// SetMaxWorkers sets the maximum number of workers; it takes effect on the next scale interval.
+func (eq *EventQueue) SetMaxWorkers(maxWorkers int) {
+	atomic.StoreInt32(&eq.maxWorkers, int32(maxWorkers))
+}
+
+// MaxWorkItems returns the maximum number of queued items before enqueuing blocks.
+func (eq *EventQueue) MaxWorkItems() int {
+	return eq.maxWorkItems
+}
+
+// SetMaxWorkItems sets the maximum number of queued items before enqueuing blocks.
+// The queue's capacity is fixed when it starts, later changes only affect saturation checks.
+func (eq *EventQueue) SetMaxWorkItems(maxWorkItems int) {
+	eq.maxWorkItems = maxWorkItems
+}
+
+// ScaleInterval returns the interval the worker count is re-evaluated on.
+func (eq *EventQueue) ScaleInterval() time.Duration {
+	return eq.scaleInterval
+}
+
+// SetScaleInterval sets the interval the worker count is re-evaluated on; it must be set before `Start`.
+// A zero interval disables scaling.
+func (eq *EventQueue) SetScaleInterval(interval time.Duration) {
+	eq.scaleInterval = interval
+}
+
+// SetPanicHandler sets the handler for actions that panic; the worker recovers and moves on to the next item.
+// Without one, panics are written to stderr (agents report them as `EventLoggerMeta` messages, see `Agent.Metaf`).
+func (eq *EventQueue) SetPanicHandler(handler QueuePanicHandler) {
+	eq.panicHandler.Store(handler)
+}
+
+// Close stops the queue and waits for the workers to process the remaining items and exit,
+// so outputs the items write to can be closed once it returns.
+// It must not be called from an action, which would wait on itself.
+func (eq *EventQueue) Close() error {
+	return eq.CloseContext(context.Background())
+}
+
+// CloseContext stops the queue and waits for the workers to process the remaining items and exit,
+// or for the context to end, in which case it returns the context's error and the workers finish on their own.
+func (eq *EventQueue) CloseContext(ctx context.Context) error {
+	eq.syncRoot.Lock()
+	if !eq.running {
+		eq.syncRoot.Unlock()
+		return nil
+	}
+	eq.running = false
+	close(eq.stop)
+	eq.syncRoot.Unlock()
+
+	// senders waiting for room give up (processing their items themselves) once stopped,
+	// so the lanes can be closed once they're done.
+	eq.senders.Wait()
+	close(eq.items)
+	close(eq.priority)
+
+	exited := make(chan struct{})
+	go func() {
+		eq.workers.Wait()
+		close(exited)
+	}()
+	select {
+	case <-exited:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (eq *EventQueue) runScaler(stop chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			eq.scale()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// scale adds or retires a worker based on the queue depth and drain rate.
+// It is only called from the scaler goroutine (or tests), which owns the scaling state.
+func (eq *EventQueue) scale() {
+	eq.syncRoot.RLock()
+	defer eq.syncRoot.RUnlock()
+	if !eq.running {
+		return
+	}
+
+	processed := atomic.LoadInt64(&eq.processed)
+	drained := processed - eq.lastProcessed
+	eq.lastProcessed = processed
+
+	depth := int64(len(eq.items) + len(eq.priority))
+	numWorkers := eq.NumWorkers()
+	switch {
+	case numWorkers < eq.MinWorkers():
+		eq.idleFor = 0
+		eq.addWorker()
+	case numWorkers > eq.MaxWorkers():
+		eq.retireWorker()
+	case depth > 0:
+		eq.idleFor = 0
+		if depth > drained && numWorkers < eq.MaxWorkers() {
+			eq.addWorker()
+		}
+	default:
+		eq.idleFor++
+		if eq.idleFor >= eq.idleIntervals && numWorkers > eq.MinWorkers() {
+			eq.idleFor = 0
+			eq.retireWorker()
+		}
+	}
+}
+
+func (eq *EventQueue) addWorker() {
+	atomic.AddInt32(&eq.numWorkers, 1)
+	eq.workers.Add(1)
+	go eq.runWorker(eq.items, eq.priority, eq.retire)
+}
+
+// retireWorker signals an idle worker to exit; if every worker is busy it does nothing.
+func (eq *EventQueue) retireWorker() {
+	select {
+	case eq.retire <- struct{}{}:
+	default:
+	}
+}
+
+func (eq *EventQueue) runWorker(items, priority chan queueItem, retire chan struct{}) {
+	defer eq.workers.Done()
+	defer atomic.AddInt32(&eq.numWorkers, -1)
+	pprof.SetGoroutineLabels(workerContext)
+	for items != nil || priority != nil {
+		select {
+		case item, ok := <-priority:
+			if !ok {
+				priority = nil
+				continue
+			}
+			eq.process(item)
+			continue
+		default:
+		}
+
+		select {
+		case item, ok := <-priority:
+			if !ok {
+				priority = nil
+				continue
+			}
+			eq.process(item)
+		case item, ok := <-items:
+			if !ok {
+				items = nil
+				continue
+			}
+			eq.process(item)
+		case <-retire:
+			return
+		}
+	}
+}
+
+// process runs an item's action, recovering if it panics so the worker (and flushes waiting on the item) carry on.
+func (eq *EventQueue) process(item queueItem) {
+	defer func() {
+		if r := recover(); r != nil {
+			eq.onPanic(r)
+		}
+		atomic.AddInt64(&eq.processed, 1)
+		if item.epoch != nil {
+			item.epoch.pending.Done()
+		}
+	}()
+	item.action(item.args...)
+}
+
+// onPanic reports a panicking action to the panic handler, or else to stderr.
+func (eq *EventQueue) onPanic(recovered interface{}) {
+	if handler, _ := eq.panicHandler.Load().(QueuePanicHandler); handler != nil {
+		handler(recovered)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s [%s] queued action panicked: %v\n", time.Now().UTC().Format(DefaultTimeFormat), EventLoggerMeta, recovered)
+}
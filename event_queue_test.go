@@ -0,0 +1,222 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestEventQueueEnqueue(t *testing.T) {
+	assert := assert.New(t)
+
+	eq := NewEventQueue(1, 1)
+	eq.Start()
+	defer eq.Close()
+	assert.True(eq.Running())
+
+	done := make(chan interface{}, 1)
+	eq.Enqueue(func(args ...interface{}) error {
+		done <- args[0]
+		return nil
+	}, "test")
+	assert.Equal("test", <-done)
+}
+
+func TestEventQueueEnqueueNotRunning(t *testing.T) {
+	assert := assert.New(t)
+
+	eq := NewEventQueue(1, 1)
+	var value interface{}
+	eq.Enqueue(func(args ...interface{}) error {
+		value = args[0]
+		return nil
+	}, "test")
+	assert.Equal("test", value)
+}
+
+func TestEventQueueScale(t *testing.T) {
+	assert := assert.New(t)
+
+	eq := NewEventQueue(1, 3)
+	eq.SetScaleInterval(0)
+	eq.Start()
+	defer eq.Close()
+	assert.Equal(1, eq.NumWorkers())
+
+	release := make(chan struct{})
+	block := func(...interface{}) error { <-release; return nil }
+	for x := 0; x < 5; x++ {
+		eq.Enqueue(block)
+	}
+
+	// the backlog isn't draining, so each interval adds a worker up to the max.
+	eq.scale()
+	eq.scale()
+	eq.scale()
+	assert.Equal(3, eq.NumWorkers())
+
+	close(release)
+
+	// workers are retired one at a time after the queue has been idle for a while.
+	for eq.NumWorkers() > 2 {
+		eq.scale()
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(2, eq.NumWorkers())
+}
+
+func TestEventQueueEnqueuePriority(t *testing.T) {
+	assert := assert.New(t)
+
+	eq := NewEventQueue(1, 1)
+	eq.SetScaleInterval(0)
+	eq.Start()
+	defer eq.Close()
+
+	release := make(chan struct{})
+	eq.Enqueue(func(...interface{}) error { <-release; return nil })
+	for eq.Len() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	order := make(chan interface{}, 3)
+	record := func(args ...interface{}) error {
+		order <- args[0]
+		return nil
+	}
+	eq.Enqueue(record, "info")
+	eq.Enqueue(record, "debug")
+	eq.EnqueuePriority(record, "error")
+	assert.Equal(3, eq.Len())
+	assert.Equal(1, eq.PriorityLen())
+
+	close(release)
+	assert.Equal("error", <-order)
+	assert.Equal("info", <-order)
+	assert.Equal("debug", <-order)
+}
+
+func TestEventQueueFlush(t *testing.T) {
+	assert := assert.New(t)
+
+	eq := NewEventQueue(4, 4)
+	eq.SetScaleInterval(0)
+	eq.Start()
+	defer eq.Close()
+
+	var processed int32
+	for x := 0; x < 100; x++ {
+		eq.Enqueue(func(...interface{}) error {
+			time.Sleep(time.Microsecond)
+			atomic.AddInt32(&processed, 1)
+			return nil
+		})
+	}
+	eq.Flush()
+	assert.Equal(100, atomic.LoadInt32(&processed))
+	assert.True(eq.Running())
+
+	// flushing an idle queue returns immediately.
+	eq.Flush()
+}
+
+func TestEventQueuePanic(t *testing.T) {
+	assert := assert.New(t)
+
+	eq := NewEventQueue(1, 1)
+	eq.SetScaleInterval(0)
+	var recovered []interface{}
+	eq.SetPanicHandler(func(r interface{}) { recovered = append(recovered, r) })
+	eq.Start()
+	defer eq.Close()
+
+	var processed int32
+	eq.Enqueue(func(...interface{}) error { panic("listener broke") })
+	eq.Enqueue(func(...interface{}) error {
+		atomic.AddInt32(&processed, 1)
+		return nil
+	})
+	eq.Flush()
+	assert.Equal(1, atomic.LoadInt32(&processed), "the worker carries on after a panic")
+	assert.Equal([]interface{}{"listener broke"}, recovered)
+}
+
+func TestAgentQueuePanicReported(t *testing.T) {
+	assert := assert.New(t)
+
+	da := NewWithWriter(NewEventFlagSet(EventInfo), NewWriter(bytes.NewBuffer(nil)))
+	defer da.Close()
+	meta := bytes.NewBuffer(nil)
+	da.SetMetaOutput(meta)
+
+	da.EventQueue().Enqueue(func(...interface{}) error { panic("formatter broke") })
+	da.Flush()
+	assert.True(strings.Contains(meta.String(), "queued action panicked: formatter broke"), meta.String())
+}
+
+func TestEventQueueFullDoesNotHoldLock(t *testing.T) {
+	assert := assert.New(t)
+
+	eq := NewEventQueue(1, 1)
+	eq.SetScaleInterval(0)
+	eq.SetMaxWorkItems(1)
+	eq.Start()
+	defer eq.Close()
+
+	release := make(chan struct{})
+	var processed int32
+	eq.Enqueue(func(...interface{}) error {
+		<-release
+		eq.Len() // e.g. the agent's saturation check, when a listener logs
+		atomic.AddInt32(&processed, 1)
+		return nil
+	})
+	for eq.Len() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	count := func(...interface{}) error {
+		atomic.AddInt32(&processed, 1)
+		return nil
+	}
+	eq.Enqueue(count)
+
+	// the queue is full, so this waits for room while a flush waits for the queue to drain.
+	go eq.Enqueue(count)
+	time.Sleep(10 * time.Millisecond)
+	flushed := make(chan struct{})
+	go func() {
+		eq.Flush()
+		close(flushed)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	select {
+	case <-flushed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("flush deadlocked behind a blocked enqueue")
+	}
+	assert.Equal(3, atomic.LoadInt32(&processed))
+}
+
+func TestEventQueueCloseWaitsForWorkers(t *testing.T) {
+	assert := assert.New(t)
+
+	eq := NewEventQueue(2, 2)
+	eq.Start()
+	var processed int32
+	for x := 0; x < 100; x++ {
+		eq.Enqueue(func(args ...interface{}) error {
+			time.Sleep(time.Microsecond)
+			atomic.AddInt32(&processed, 1)
+			return nil
+		})
+	}
+	assert.Nil(eq.Close())
+	assert.Equal(int32(100), atomic.LoadInt32(&processed))
+	assert.Equal(0, eq.NumWorkers())
+}
@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"expvar"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// ExpvarPrefix is the prefix of the variables published with expvar.
+	ExpvarPrefix = "logger."
+)
+
+// eventCounters counts the events fired per flag and the failed writes.
+type eventCounters struct {
+	sync.Mutex
+	events      map[EventFlag]int64
+	writeErrors int64
+}
+
+// countEvent counts an enabled event as fired.
+func (da *Agent) countEvent(eventFlag EventFlag) {
+	da.counters.Lock()
+	if da.counters.events == nil {
+		da.counters.events = map[EventFlag]int64{}
+	}
+	da.counters.events[eventFlag]++
+	da.counters.Unlock()
+}
+
+// EventCounts returns the number of enabled events fired per flag.
+func (da *Agent) EventCounts() map[EventFlag]int64 {
+	if da == nil {
+		return nil
+	}
+	da.counters.Lock()
+	defer da.counters.Unlock()
+	counts := make(map[EventFlag]int64, len(da.counters.events))
+	for flag, count := range da.counters.events {
+		counts[flag] = count
+	}
+	return counts
+}
+
+// WriteErrors returns the number of failed writes.
+func (da *Agent) WriteErrors() int64 {
+	if da == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&da.counters.writeErrors)
+}
+
+// QueueDepth returns the number of events waiting in the agent's queue(s).
+func (da *Agent) QueueDepth() int {
+	if da == nil {
+		return 0
+	}
+	depth := da.eventQueue.Len()
+	if orderedQueue, _ := da.orderedQueueAndOrdering(); orderedQueue != nil {
+		depth += orderedQueue.Len()
+	}
+	return depth
+}
+
+// PublishExpvar publishes the agent's counters with expvar under a prefix,
+// as `<prefix>events`, `<prefix>queue_depth`, `<prefix>dropped`, `<prefix>write_errors` and `<prefix>bytes_written`.
+// The default agent's counters are published under `ExpvarPrefix` already; use this for other agents.
+// Like `expvar.Publish` it panics if the names are already published.
+func (da *Agent) PublishExpvar(prefix string) {
+	publishExpvar(prefix, func() *Agent { return da })
+}
+
+func publishExpvar(prefix string, agent func() *Agent) {
+	expvar.Publish(prefix+"events", expvar.Func(func() interface{} {
+		return agent().EventCounts()
+	}))
+	expvar.Publish(prefix+"queue_depth", expvar.Func(func() interface{} {
+		return agent().QueueDepth()
+	}))
+	expvar.Publish(prefix+"dropped", expvar.Func(func() interface{} {
+		if da := agent(); da != nil {
+			return da.DroppedEvents()
+		}
+		return nil
+	}))
+	expvar.Publish(prefix+"write_errors", expvar.Func(func() interface{} {
+		return agent().WriteErrors()
+	}))
+	expvar.Publish(prefix+"bytes_written", expvar.Func(func() interface{} {
+		return agent().Stats().BytesWritten
+	}))
+}
+
+func init() {
+	// published for the default agent (whichever it is when read), so `/debug/vars` picks them up without wiring.
+	publishExpvar(ExpvarPrefix, Default)
+}
@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestAgentEventCounts(t *testing.T) {
+	assert := assert.New(t)
+
+	da := NewWithWriter(NewEventFlagSet(EventInfo, EventError), NewWriter(newSignalOutput()))
+	defer da.Close()
+	da.Infof("one")
+	da.Infof("two")
+	da.Debugf("disabled")
+	da.Sync().Infof("three")
+	da.Flush()
+
+	counts := da.EventCounts()
+	assert.Equal(3, counts[EventInfo])
+	assert.Zero(counts[EventDebug])
+	assert.Zero(da.WriteErrors())
+	assert.Zero(da.QueueDepth())
+}
+
+func TestAgentWriteErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	da := NewWithWriter(NewEventFlagSetAll(), NewWriter(&errorOutput{}))
+	da.SetMetaOutput(nil)
+	defer da.Close()
+	da.Infof("fails")
+	da.Flush()
+	assert.Equal(1, da.WriteErrors())
+}
+
+func TestPublishExpvar(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NotNil(expvar.Get("logger.events"))
+	assert.NotNil(expvar.Get("logger.write_errors"))
+	assert.NotNil(expvar.Get("logger.bytes_written"))
+
+	da := NewWithWriter(NewEventFlagSetAll(), NewWriter(newSignalOutput()))
+	defer da.Close()
+	da.PublishExpvar("logger.test.")
+	da.Sync().Infof("hello")
+
+	var counts map[EventFlag]int64
+	assert.Nil(json.Unmarshal([]byte(expvar.Get("logger.test.events").String()), &counts))
+	assert.Equal(1, counts[EventInfo])
+	assert.Equal("0", expvar.Get("logger.test.queue_depth").String())
+}
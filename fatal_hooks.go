@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultFatalHookTimeout is the default time fatal hooks are given to run.
+	DefaultFatalHookTimeout = 5 * time.Second
+)
+
+// FatalHook is a hook run when a fatal event fires.
+type FatalHook func(err error)
+
+// fatalHooks are the hooks run on fatal events.
+type fatalHooks struct {
+	sync.Mutex
+	hooks      []FatalHook
+	timeout    time.Duration
+	hasTimeout bool
+}
+
+// RegisterOnFatal registers a hook that runs when a fatal event fires (see `Fatal`), e.g. to flush state,
+// mark the process unhealthy or snapshot diagnostics before exiting. Hooks run in the order they were registered,
+// and `Fatal` returns once they've run or the timeout (see `SetFatalHookTimeout`) elapses.
+// Hooks registered on derived agents (see `Clone`) are registered on the root agent.
+func (da *Agent) RegisterOnFatal(hook FatalHook) {
+	root := da.root()
+	root.fatal.Lock()
+	root.fatal.hooks = append(root.fatal.hooks, hook)
+	root.fatal.Unlock()
+}
+
+// FatalHookTimeout returns the time fatal hooks are given to run.
+func (da *Agent) FatalHookTimeout() time.Duration {
+	root := da.root()
+	root.fatal.Lock()
+	defer root.fatal.Unlock()
+	if !root.fatal.hasTimeout {
+		return DefaultFatalHookTimeout
+	}
+	return root.fatal.timeout
+}
+
+// SetFatalHookTimeout sets the time fatal hooks are given to run; zero means they're waited on indefinitely.
+func (da *Agent) SetFatalHookTimeout(timeout time.Duration) {
+	root := da.root()
+	root.fatal.Lock()
+	root.fatal.timeout = timeout
+	root.fatal.hasTimeout = true
+	root.fatal.Unlock()
+}
+
+// onFatal runs the fatal hooks, waiting for them until the timeout elapses.
+func (da *Agent) onFatal(err error) {
+	if da == nil {
+		return
+	}
+	root := da.root()
+	root.fatal.Lock()
+	hooks := root.fatal.hooks
+	root.fatal.Unlock()
+	if len(hooks) == 0 {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, hook := range hooks {
+			da.invokeFatalHook(hook, err)
+		}
+	}()
+
+	timeout := da.FatalHookTimeout()
+	if timeout <= 0 {
+		<-done
+		return
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		da.Metaf("fatal hooks timed out after %v", timeout)
+	}
+}
+
+// invokeFatalHook calls a hook, reporting (rather than propagating) panics.
+func (da *Agent) invokeFatalHook(hook FatalHook, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			da.Metaf("fatal hook panicked: %v", r)
+		}
+	}()
+	hook(err)
+}
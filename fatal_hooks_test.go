@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestAgentRegisterOnFatal(t *testing.T) {
+	assert := assert.New(t)
+
+	da := NewWithWriter(NewEventFlagSetAll(), NewWriter(bytes.NewBuffer(nil)))
+	defer da.Close()
+
+	var calls []string
+	da.RegisterOnFatal(func(err error) { calls = append(calls, "first: "+err.Error()) })
+	da.Clone().RegisterOnFatal(func(err error) { calls = append(calls, "second: "+err.Error()) })
+
+	da.Error(errors.New("not fatal"))
+	assert.Empty(calls)
+
+	da.Fatal(errors.New("boom"))
+	assert.Equal([]string{"first: boom", "second: boom"}, calls)
+	da.Flush()
+}
+
+func TestAgentRegisterOnFatalTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	metaOutput := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSetAll(), NewWriter(bytes.NewBuffer(nil)))
+	defer da.Close()
+	da.SetMetaOutput(NewSyncOutput(metaOutput))
+	assert.Equal(DefaultFatalHookTimeout, da.FatalHookTimeout())
+	da.SetFatalHookTimeout(time.Millisecond)
+
+	release := make(chan struct{})
+	defer close(release)
+	da.RegisterOnFatal(func(err error) { <-release })
+	da.RegisterOnFatal(func(err error) { panic("unreachable") })
+
+	da.Sync().Fatal(errors.New("boom"))
+	assert.True(strings.Contains(metaOutput.String(), "fatal hooks timed out after 1ms"))
+	da.Flush()
+}
+
+func TestAgentRegisterOnFatalPanic(t *testing.T) {
+	assert := assert.New(t)
+
+	metaOutput := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSetAll(), NewWriter(bytes.NewBuffer(nil)))
+	defer da.Close()
+	da.SetMetaOutput(NewSyncOutput(metaOutput))
+
+	ran := false
+	da.RegisterOnFatal(func(err error) { panic("hook failed") })
+	da.RegisterOnFatal(func(err error) { ran = true })
+	da.Fatal(errors.New("boom"))
+	assert.True(ran)
+	assert.True(strings.Contains(metaOutput.String(), "fatal hook panicked: hook failed"))
+	da.Flush()
+}
@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fields are key/value pairs attached to events, e.g. `Fields{"service": "api", "region": "us-east-1"}`.
+type Fields map[string]interface{}
+
+// FormatFields formats fields as `key=value` pairs sorted by key.
+// Values that are empty or contain spaces, quotes, `=` or control characters are quoted.
+// Durations are written with the writer's duration format, see `SetDurationFormat`.
+func (wr *Writer) FormatFields(fields map[string]interface{}) string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := fields[key]
+		if duration, isDuration := value.(time.Duration); isDuration {
+			value = wr.FormatDuration(duration)
+		}
+		pairs = append(pairs, formatFieldValue(key)+"="+formatFieldValue(fmt.Sprint(value)))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// formatFieldValue quotes a value if it would otherwise be ambiguous.
+func formatFieldValue(value string) string {
+	if len(value) == 0 || strings.IndexFunc(value, needsFieldQuoting) >= 0 {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+func needsFieldQuoting(r rune) bool {
+	return r <= ' ' || r == '=' || r == '"' || r == 0x7f
+}
+
+// WriteFields is a helper method to write a label and key/value fields to a writer, for use in custom listeners.
+func WriteFields(writer *Writer, ts TimeSource, label string, fields map[string]interface{}) {
+	buffer := writer.GetBuffer()
+	defer writer.PutBuffer(buffer)
+
+	buffer.WriteString(writer.FormatEvent(EventFlag(label), ColorLightWhite))
+	if len(fields) > 0 {
+		buffer.WriteRune(RuneSpace)
+		buffer.WriteString(writer.FormatFields(fields))
+	}
+	writer.WriteWithTimeSource(ts, buffer.Bytes())
+}
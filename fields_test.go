@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestWriterFormatFields(t *testing.T) {
+	assert := assert.New(t)
+
+	writer := NewWriter(bytes.NewBuffer(nil))
+	assert.Equal(`a=1 b="two words" c="" d="x=y" e="line\nbreak" f=true`, writer.FormatFields(map[string]interface{}{
+		"f": true,
+		"e": "line\nbreak",
+		"d": "x=y",
+		"c": "",
+		"b": "two words",
+		"a": 1,
+	}))
+}
+
+func TestWriterFormatFieldsDurations(t *testing.T) {
+	assert := assert.New(t)
+
+	writer := NewWriter(bytes.NewBuffer(nil))
+	fields := Fields{"elapsed": 1234567 * time.Nanosecond}
+	assert.Equal("elapsed=1.234567ms", writer.FormatFields(fields))
+
+	writer.SetDurationFormat(DurationFormatMilliseconds)
+	writer.SetDurationPrecision(1)
+	assert.Equal("elapsed=1.2ms", writer.FormatFields(fields))
+
+	writer.SetDurationFormat(DurationFormatNanoseconds)
+	assert.Equal("elapsed=1234567", writer.FormatFields(fields))
+}
+
+func TestWriteFields(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	writer := NewWriter(buffer)
+	writer.SetUseAnsiColors(false)
+	writer.SetShowTimestamp(false)
+
+	WriteFields(writer, TimeNow(), "db.query", map[string]interface{}{"rows": 3, "table": "users"})
+	assert.Equal("[db.query] rows=3 table=users\n", buffer.String())
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package logger
+
+import (
+	"os"
+)
+
+// lockFile is a no-op where advisory locks aren't supported.
+func lockFile(file *os.File) error {
+	return nil
+}
+
+// unlockFile is a no-op where advisory locks aren't supported.
+func unlockFile(file *os.File) error {
+	return nil
+}
@@ -7,15 +7,17 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"sync"
+	"time"
 
 	exception "github.com/blendlabs/go-exception"
 )
 
 const (
-	isArchiveFileRegexpFormat           = `%s\.([0-9]+)?`
-	isCompressedArchiveFileRegexpFormat = `%s\.([0-9]+)?\.gz`
+	isArchiveFileRegexpFormat           = `^%s\.([0-9]+)$`
+	isCompressedArchiveFileRegexpFormat = `^%s\.([0-9]+)\.gz$`
 
 	// Kilobyte represents the bytes in a kilobyte.
 	Kilobyte int64 = 1 << 10
@@ -37,6 +39,16 @@ const (
 
 	// FileOutputDefaultMaxArchiveFiles is the default number of archive files (10).
 	FileOutputDefaultMaxArchiveFiles int64 = 10
+
+	// FileOutputUnlimitedArchiveAge is a preset for archive files to be kept regardless of age.
+	FileOutputUnlimitedArchiveAge time.Duration = 0
+
+	// FileOutputUnlimitedArchiveTotalSize is a preset for archive files to be kept regardless of their combined size.
+	FileOutputUnlimitedArchiveTotalSize int64 = 0
+
+	// FileOutputPruneInterval is how often a file output with an archive max age prunes archive files
+	// as it writes, so they're pruned even if it never rotates the file itself (e.g. with an unlimited size).
+	FileOutputPruneInterval = time.Minute
 )
 
 // NewFileOutput creates a new file writer.
@@ -64,6 +76,7 @@ func NewFileOutput(filePath string, shouldCompressArchivedFiles bool, fileMaxSiz
 		shouldCompressArchivedFiles: shouldCompressArchivedFiles,
 		fileMaxSizeBytes:            fileMaxSizeBytes,
 		fileMaxArchiveCount:         fileMaxArchiveCount,
+		syncInterval:                DefaultFileSyncInterval,
 	}, nil
 }
 
@@ -89,6 +102,7 @@ func NewFileOutputWithDefaults(filePath string) (*FileOutput, error) {
 type FileOutput struct {
 	filePath string
 	file     *os.File
+	lockFile *os.File // see `SetProcessLocking`
 
 	syncRoot                    *sync.Mutex
 	shouldCompressArchivedFiles bool
@@ -96,35 +110,185 @@ type FileOutput struct {
 	fileMaxSizeBytes    int64
 	fileMaxArchiveCount int64
 
+	archiveMaxAge       time.Duration
+	archiveMaxTotalSize int64
+	archiveRetentionDir string
+	prunedAt            time.Time
+	pruneErrorHandler   func(error)
+	metaReporter        MetaReporter
+
+	syncPolicy   FileSyncPolicy
+	syncInterval time.Duration
+	syncStop     chan struct{}
+	unsynced     bool
+
+	detectRotation    bool
+	rotationCheckedAt time.Time
+
 	isArchiveFileRegexp *regexp.Regexp
 }
 
+// ArchiveMaxAge returns the age after which archive files are pruned.
+func (fo *FileOutput) ArchiveMaxAge() time.Duration {
+	fo.syncRoot.Lock()
+	defer fo.syncRoot.Unlock()
+	return fo.archiveMaxAge
+}
+
+// SetArchiveMaxAge sets the age after which archive files are pruned. Archive files are pruned when the
+// output rotates the file, and at most every `FileOutputPruneInterval` as it writes.
+func (fo *FileOutput) SetArchiveMaxAge(maxAge time.Duration) {
+	fo.syncRoot.Lock()
+	defer fo.syncRoot.Unlock()
+	fo.archiveMaxAge = maxAge
+}
+
+// ArchiveMaxTotalSize returns the combined size of archive files beyond which the oldest are pruned.
+func (fo *FileOutput) ArchiveMaxTotalSize() int64 {
+	fo.syncRoot.Lock()
+	defer fo.syncRoot.Unlock()
+	return fo.archiveMaxTotalSize
+}
+
+// SetArchiveMaxTotalSize sets the combined size of archive files beyond which the oldest are pruned.
+func (fo *FileOutput) SetArchiveMaxTotalSize(maxTotalSize int64) {
+	fo.syncRoot.Lock()
+	defer fo.syncRoot.Unlock()
+	fo.archiveMaxTotalSize = maxTotalSize
+}
+
+// ArchiveRetentionDir returns the directory pruned archive files are moved to.
+func (fo *FileOutput) ArchiveRetentionDir() string {
+	fo.syncRoot.Lock()
+	defer fo.syncRoot.Unlock()
+	return fo.archiveRetentionDir
+}
+
+// SetArchiveRetentionDir sets a directory pruned archive files are moved to instead of being deleted.
+// It can be on another filesystem, in which case they're copied and then removed.
+func (fo *FileOutput) SetArchiveRetentionDir(dir string) {
+	fo.syncRoot.Lock()
+	defer fo.syncRoot.Unlock()
+	fo.archiveRetentionDir = dir
+}
+
+// SetPruneErrorHandler sets a handler for errors pruning archive files as the output writes, which don't fail
+// the write. By default they're reported by the agent writing to the output (see `OutputMetaReporter`),
+// or written to stderr as `logger.meta` lines if no agent is.
+func (fo *FileOutput) SetPruneErrorHandler(handler func(error)) {
+	fo.syncRoot.Lock()
+	defer fo.syncRoot.Unlock()
+	fo.pruneErrorHandler = handler
+}
+
+// SetMetaReporter sets how the output reports errors pruning archive files, unless it has a prune error handler
+// (see `SetPruneErrorHandler`); agents set it to their `Metaf` for their writer's outputs.
+func (fo *FileOutput) SetMetaReporter(reporter MetaReporter) {
+	fo.syncRoot.Lock()
+	defer fo.syncRoot.Unlock()
+	fo.metaReporter = reporter
+}
+
+// ProcessLocking returns if writes are serialized with other processes writing to the file.
+func (fo *FileOutput) ProcessLocking() bool {
+	fo.syncRoot.Lock()
+	defer fo.syncRoot.Unlock()
+	return fo.lockFile != nil
+}
+
+// SetProcessLocking sets if writes (and rotation) are serialized with other processes writing to the same file
+// (e.g. prefork or cgi style deployments) with an advisory lock on `<path>-lock`, so they can't interleave
+// partial lines or rotate the file from under each other. Every process must enable it.
+// A process that finds the file rotated by another reopens it before writing.
+// Advisory locks aren't supported on windows, where writes are only serialized within the process.
+func (fo *FileOutput) SetProcessLocking(processLocking bool) error {
+	fo.syncRoot.Lock()
+	defer fo.syncRoot.Unlock()
+	if !processLocking {
+		return fo.closeLockFile()
+	}
+	if fo.lockFile != nil {
+		return nil
+	}
+	// not `<path>.lock`, which would be taken for an archive file.
+	lockFile, err := os.OpenFile(fo.filePath+"-lock", os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return exception.Wrap(err)
+	}
+	fo.lockFile = lockFile
+	return nil
+}
+
+// Prune removes (or moves to the retention dir) archive files that exceed the age or total size limits.
+func (fo *FileOutput) Prune() error {
+	fo.syncRoot.Lock()
+	defer fo.syncRoot.Unlock()
+	return fo.pruneArchivedFiles()
+}
+
 // Write writes to the file.
 func (fo *FileOutput) Write(buffer []byte) (int, error) {
+	written, pruneErr, err := fo.write(buffer)
+	if pruneErr != nil {
+		fo.reportPruneError(pruneErr)
+	}
+	return written, err
+}
+
+// write writes to the file, returning (rather than failing the write with) any error pruning archive files,
+// so it's reported once the output is unlocked.
+func (fo *FileOutput) write(buffer []byte) (int, error, error) {
 	fo.syncRoot.Lock()
 	defer fo.syncRoot.Unlock()
 
+	if fo.lockFile != nil {
+		if err := lockFile(fo.lockFile); err != nil {
+			return 0, nil, exception.Wrap(err)
+		}
+		defer unlockFile(fo.lockFile)
+		if err := fo.reopenIfRotated(); err != nil {
+			return 0, nil, exception.Wrap(err)
+		}
+	} else if fo.detectRotation {
+		if err := fo.checkRotation(); err != nil {
+			return 0, nil, exception.Wrap(err)
+		}
+	}
+
+	var rotated bool
 	if fo.fileMaxSizeBytes > 0 {
 		stat, err := fo.file.Stat()
 		if err != nil {
-			return 0, exception.New(err)
+			return 0, nil, exception.New(err)
 		}
 
 		if stat.Size() > fo.fileMaxSizeBytes {
 			err = fo.rotateFile()
 			if err != nil {
-				return 0, exception.New(err)
+				return 0, nil, exception.New(err)
 			}
+			rotated = true
 		}
 	}
+	pruneErr := fo.pruneAfterWrite(rotated)
 
 	written, err := fo.file.Write(buffer)
-	return written, exception.Wrap(err)
+	if err != nil {
+		return written, pruneErr, exception.Wrap(err)
+	}
+	return written, pruneErr, fo.syncAfterWrite()
 }
 
 // Close closes the stream.
 func (fo *FileOutput) Close() error {
+	fo.syncRoot.Lock()
+	defer fo.syncRoot.Unlock()
+	fo.stopSyncLoop()
+	fo.closeLockFile()
 	if fo.file != nil {
+		if fo.syncPolicy != FileSyncNever {
+			fo.sync()
+		}
 		err := fo.file.Close()
 		fo.file = nil
 		return err
@@ -132,6 +296,32 @@ func (fo *FileOutput) Close() error {
 	return nil
 }
 
+func (fo *FileOutput) closeLockFile() error {
+	if fo.lockFile == nil {
+		return nil
+	}
+	err := fo.lockFile.Close()
+	fo.lockFile = nil
+	return err
+}
+
+// reopenIfRotated reopens the file if it was moved or removed (e.g. rotated by another process),
+// so writes don't go to an archived or deleted file.
+func (fo *FileOutput) reopenIfRotated() error {
+	current, err := fo.file.Stat()
+	if err != nil {
+		return err
+	}
+	existing, err := os.Stat(fo.filePath)
+	if err == nil && os.SameFile(current, existing) {
+		return nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return fo.reopen()
+}
+
 func (fo *FileOutput) makeArchiveFilePath(filePath string, index int64) string {
 	return fmt.Sprintf("%s.%d", filePath, index)
 }
@@ -186,7 +376,7 @@ func (fo *FileOutput) isArchivedFile(filePath string) bool {
 }
 
 func (fo *FileOutput) getArchivedFilePaths() ([]string, error) {
-	return File.List(filepath.Dir(fo.filePath), fo.isArchiveFileRegexp)
+	return File.ListDir(filepath.Dir(fo.filePath), fo.isArchiveFileRegexp)
 }
 
 func (fo *FileOutput) getMaxArchivedFileIndex(paths []string) (int64, error) {
@@ -264,10 +454,16 @@ func (fo *FileOutput) rotateFile() error {
 		return err
 	}
 
+	if fo.syncPolicy != FileSyncNever {
+		if err = fo.sync(); err != nil {
+			return err
+		}
+	}
 	err = fo.file.Close()
 	if err != nil {
 		return err
 	}
+	fo.unsynced = false
 
 	if fo.shouldCompressArchivedFiles {
 		err = fo.compressFile(fo.filePath, fo.makeCompressedArchiveFilePath(fo.filePath, 1))
@@ -282,7 +478,7 @@ func (fo *FileOutput) rotateFile() error {
 		err = os.Rename(fo.filePath, fo.makeArchiveFilePath(fo.filePath, 1))
 	}
 
-	file, err := os.Create(fo.filePath)
+	file, err := File.CreateOrOpen(fo.filePath)
 	if err != nil {
 		return err
 	}
@@ -290,10 +486,124 @@ func (fo *FileOutput) rotateFile() error {
 	return nil
 }
 
+// pruneAfterWrite prunes archive files after the file is rotated, or if they have a max age and weren't pruned
+// in the last `FileOutputPruneInterval`.
+func (fo *FileOutput) pruneAfterWrite(rotated bool) error {
+	now := time.Now()
+	if !rotated && (fo.archiveMaxAge <= 0 || now.Sub(fo.prunedAt) < FileOutputPruneInterval) {
+		return nil
+	}
+	fo.prunedAt = now
+	return fo.pruneArchivedFiles()
+}
+
+// reportPruneError reports an error pruning archive files to the prune error handler or the meta reporter,
+// or else to stderr.
+func (fo *FileOutput) reportPruneError(err error) {
+	fo.syncRoot.Lock()
+	handler, reporter := fo.pruneErrorHandler, fo.metaReporter
+	fo.syncRoot.Unlock()
+
+	switch {
+	case handler != nil:
+		handler(err)
+	case reporter != nil:
+		reporter("pruning archive files of `%s` failed: %v", fo.filePath, err)
+	default:
+		fmt.Fprintf(os.Stderr, "%s [%s] pruning archive files of `%s` failed: %v\n", time.Now().UTC().Format(DefaultTimeFormat), EventLoggerMeta, fo.filePath, err)
+	}
+}
+
+func (fo *FileOutput) pruneArchivedFiles() error {
+	if fo.archiveMaxAge <= 0 && fo.archiveMaxTotalSize <= 0 {
+		return nil
+	}
+
+	paths, err := fo.getArchivedFilePaths()
+	if err != nil {
+		return err
+	}
+
+	archives := make([]archivedFile, 0, len(paths))
+	for _, path := range paths {
+		index, err := fo.extractArchivedFileIndex(path)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		archives = append(archives, archivedFile{path: path, index: index, info: info})
+	}
+
+	// lower indexes are more recent.
+	sort.Slice(archives, func(i, j int) bool {
+		return archives[i].index < archives[j].index
+	})
+
+	cutoff := time.Now().Add(-fo.archiveMaxAge)
+	var totalSize int64
+	for _, archive := range archives {
+		totalSize += archive.info.Size()
+		if (fo.archiveMaxAge > 0 && archive.info.ModTime().Before(cutoff)) ||
+			(fo.archiveMaxTotalSize > 0 && totalSize > fo.archiveMaxTotalSize) {
+			if err = fo.retireArchivedFile(archive); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (fo *FileOutput) retireArchivedFile(archive archivedFile) error {
+	if len(fo.archiveRetentionDir) == 0 {
+		return os.Remove(archive.path)
+	}
+	retiredPath := filepath.Join(fo.archiveRetentionDir, fmt.Sprintf("%s.%d", filepath.Base(archive.path), archive.info.ModTime().UnixNano()))
+	err := os.Rename(archive.path, retiredPath)
+	if err == nil {
+		return nil
+	}
+	// the retention dir may be on another filesystem, which a rename can't move files to.
+	if copyErr := copyFile(archive.path, retiredPath); copyErr != nil {
+		return err
+	}
+	return os.Remove(archive.path)
+}
+
+// copyFile copies a file, removing the copy if it fails part way.
+func copyFile(fromPath, toPath string) error {
+	from, err := os.Open(fromPath)
+	if err != nil {
+		return err
+	}
+	defer from.Close()
+
+	to, err := os.OpenFile(toPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(to, from)
+	if closeErr := to.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(toPath)
+	}
+	return err
+}
+
+type archivedFile struct {
+	path  string
+	index int64
+	info  os.FileInfo
+}
+
 func createIsArchivedFileRegexp(filePath string) (*regexp.Regexp, error) {
-	return regexp.Compile(fmt.Sprintf(isArchiveFileRegexpFormat, filepath.Base(filePath)))
+	return regexp.Compile(fmt.Sprintf(isArchiveFileRegexpFormat, regexp.QuoteMeta(filepath.Base(filePath))))
 }
 
 func createIsCompressedArchiveFileRegexp(filePath string) (*regexp.Regexp, error) {
-	return regexp.Compile(fmt.Sprintf(isCompressedArchiveFileRegexpFormat, filepath.Base(filePath)))
+	return regexp.Compile(fmt.Sprintf(isCompressedArchiveFileRegexpFormat, regexp.QuoteMeta(filepath.Base(filePath))))
 }
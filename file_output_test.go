@@ -1,8 +1,14 @@
 package logger
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"os"
 
@@ -26,7 +32,7 @@ func TestNewFileOutputUncompressed(t *testing.T) {
 	assert.False(uncompressed.shouldCompressArchivedFiles)
 	assert.Equal(0, uncompressed.fileMaxSizeBytes)
 	assert.Equal(0, uncompressed.fileMaxArchiveCount)
-	assert.Equal(fmt.Sprintf(isArchiveFileRegexpFormat, filepath.Base(tempFile)), uncompressed.isArchiveFileRegexp.String())
+	assert.Equal(fmt.Sprintf(isArchiveFileRegexpFormat, regexp.QuoteMeta(filepath.Base(tempFile))), uncompressed.isArchiveFileRegexp.String())
 
 	stat, err := uncompressed.file.Stat()
 	assert.Nil(err)
@@ -53,7 +59,7 @@ func TestNewFileOutputCompressed(t *testing.T) {
 	assert.True(compressed.shouldCompressArchivedFiles)
 	assert.Equal(0, compressed.fileMaxSizeBytes)
 	assert.Equal(0, compressed.fileMaxArchiveCount)
-	assert.Equal(fmt.Sprintf(isCompressedArchiveFileRegexpFormat, filepath.Base(tempFile)), compressed.isArchiveFileRegexp.String())
+	assert.Equal(fmt.Sprintf(isCompressedArchiveFileRegexpFormat, regexp.QuoteMeta(filepath.Base(tempFile))), compressed.isArchiveFileRegexp.String())
 
 	stat, err := compressed.file.Stat()
 	assert.Nil(err)
@@ -80,7 +86,7 @@ func TestNewFileOutputCompressedArchived(t *testing.T) {
 	assert.True(archived.shouldCompressArchivedFiles)
 	assert.Equal(Kilobyte, archived.fileMaxSizeBytes)
 	assert.Equal(0, archived.fileMaxArchiveCount)
-	assert.Equal(fmt.Sprintf(isCompressedArchiveFileRegexpFormat, filepath.Base(tempFile)), archived.isArchiveFileRegexp.String())
+	assert.Equal(fmt.Sprintf(isCompressedArchiveFileRegexpFormat, regexp.QuoteMeta(filepath.Base(tempFile))), archived.isArchiveFileRegexp.String())
 
 	stat, err := archived.file.Stat()
 	assert.Nil(err)
@@ -261,3 +267,198 @@ func TestFileOutputExtractArchivedFileIndex(t *testing.T) {
 	assert.NotNil(err)
 	assert.Equal(0, index)
 }
+
+func TestFileOutputPrune(t *testing.T) {
+	assert := assert.New(t)
+
+	td, err := ioutil.TempDir("", "file_output_prune")
+	assert.Nil(err)
+	defer os.RemoveAll(td)
+
+	filePath := filepath.Join(td, "stdout")
+	output, err := NewFileOutput(filePath, false, FileOutputUnlimitedSize, FileOutputUnlimitedArchiveFiles)
+	assert.Nil(err)
+	defer output.Close()
+
+	for index := 1; index <= 4; index++ {
+		archivePath := output.makeArchiveFilePath(filePath, int64(index))
+		assert.Nil(ioutil.WriteFile(archivePath, make([]byte, Kilobyte), 0644))
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	assert.Nil(os.Chtimes(output.makeArchiveFilePath(filePath, 4), old, old))
+
+	output.SetArchiveMaxAge(24 * time.Hour)
+	assert.Nil(output.Prune())
+	files, err := output.getArchivedFilePaths()
+	assert.Nil(err)
+	assert.Len(files, 3)
+
+	retentionDir, err := ioutil.TempDir("", "file_output_retained")
+	assert.Nil(err)
+	defer os.RemoveAll(retentionDir)
+	output.SetArchiveRetentionDir(retentionDir)
+	output.SetArchiveMaxTotalSize(2 * Kilobyte)
+	assert.Nil(output.Prune())
+	files, err = output.getArchivedFilePaths()
+	assert.Nil(err)
+	assert.Len(files, 2)
+	assert.Equal(output.makeArchiveFilePath(filePath, 1), files[0])
+
+	retained, err := ioutil.ReadDir(retentionDir)
+	assert.Nil(err)
+	assert.Len(retained, 1)
+}
+
+func TestFileOutputPruneNestedRetentionDir(t *testing.T) {
+	assert := assert.New(t)
+
+	td, err := ioutil.TempDir("", "file_output_prune_nested")
+	assert.Nil(err)
+	defer os.RemoveAll(td)
+
+	filePath := filepath.Join(td, "stdout")
+	output, err := NewFileOutput(filePath, false, FileOutputUnlimitedSize, FileOutputUnlimitedArchiveFiles)
+	assert.Nil(err)
+	defer output.Close()
+
+	retentionDir := filepath.Join(td, "retained")
+	assert.Nil(os.Mkdir(retentionDir, 0755))
+	assert.Nil(ioutil.WriteFile(filepath.Join(retentionDir, "stdout.1.1500000000000000000"), nil, 0644))
+	assert.Nil(ioutil.WriteFile(output.makeArchiveFilePath(filePath, 1), nil, 0644))
+	assert.Nil(ioutil.WriteFile(output.makeTempArchiveFilePath(filePath, 2), nil, 0644))
+
+	files, err := output.getArchivedFilePaths()
+	assert.Nil(err)
+	assert.Len(files, 1)
+	assert.Equal(output.makeArchiveFilePath(filePath, 1), files[0])
+}
+
+func TestFileOutputPruneErrorDoesNotFailWrite(t *testing.T) {
+	assert := assert.New(t)
+
+	td, err := ioutil.TempDir("", "file_output_prune_error")
+	assert.Nil(err)
+	defer os.RemoveAll(td)
+
+	filePath := filepath.Join(td, "stdout")
+	output, err := NewFileOutput(filePath, false, 10, FileOutputUnlimitedArchiveFiles)
+	assert.Nil(err)
+	defer output.Close()
+
+	var pruneErr error
+	output.SetPruneErrorHandler(func(err error) { pruneErr = err })
+	output.SetArchiveMaxTotalSize(1)
+	output.SetArchiveRetentionDir(filepath.Join(td, "missing"))
+
+	_, err = output.Write([]byte("this is only a test\n"))
+	assert.Nil(err)
+	written, err := output.Write([]byte("this is only a test\n"))
+	assert.Nil(err)
+	assert.Equal(20, written)
+	assert.NotNil(pruneErr)
+}
+
+func TestFileOutputPruneErrorReportedByAgent(t *testing.T) {
+	assert := assert.New(t)
+
+	td, err := ioutil.TempDir("", "file_output_prune_meta")
+	assert.Nil(err)
+	defer os.RemoveAll(td)
+
+	output, err := NewFileOutput(filepath.Join(td, "stdout"), false, 10, FileOutputUnlimitedArchiveFiles)
+	assert.Nil(err)
+	defer output.Close()
+	output.SetArchiveMaxTotalSize(1)
+	output.SetArchiveRetentionDir(filepath.Join(td, "missing"))
+
+	writer := NewWriter(output)
+	writer.SetUseAnsiColors(false)
+	da := NewWithWriter(NewEventFlagSet(EventInfo), writer)
+	defer da.Close()
+	meta := bytes.NewBuffer(nil)
+	da.SetMetaOutput(meta)
+
+	da.Infof("this is only a test")
+	da.Infof("this is only a test")
+	da.Flush()
+	assert.True(strings.Contains(meta.String(), "[logger.meta] pruning archive files of"), meta.String())
+}
+
+func TestFileOutputPrunesByAgeWithoutRotation(t *testing.T) {
+	assert := assert.New(t)
+
+	td, err := ioutil.TempDir("", "file_output_prune_age")
+	assert.Nil(err)
+	defer os.RemoveAll(td)
+
+	filePath := filepath.Join(td, "stdout")
+	output, err := NewFileOutput(filePath, false, FileOutputUnlimitedSize, FileOutputUnlimitedArchiveFiles)
+	assert.Nil(err)
+	defer output.Close()
+
+	// e.g. rotated by logrotate.
+	archivePath := output.makeArchiveFilePath(filePath, 1)
+	assert.Nil(ioutil.WriteFile(archivePath, nil, 0644))
+	old := time.Now().Add(-48 * time.Hour)
+	assert.Nil(os.Chtimes(archivePath, old, old))
+
+	output.SetArchiveMaxAge(24 * time.Hour)
+	_, err = output.Write([]byte("this is only a test\n"))
+	assert.Nil(err)
+
+	_, err = os.Stat(archivePath)
+	assert.True(os.IsNotExist(err))
+}
+
+func TestFileOutputProcessLocking(t *testing.T) {
+	assert := assert.New(t)
+
+	td, err := ioutil.TempDir("", "file_output_locking")
+	assert.Nil(err)
+	defer os.RemoveAll(td)
+
+	// two outputs for the same file stand in for two processes; they hold separate locks and descriptors.
+	filePath := filepath.Join(td, "stdout")
+	var outputs []*FileOutput
+	for index := 0; index < 2; index++ {
+		output, err := NewFileOutput(filePath, false, Kilobyte, FileOutputUnlimitedArchiveFiles)
+		assert.Nil(err)
+		defer output.Close()
+		assert.Nil(output.SetProcessLocking(true))
+		assert.True(output.ProcessLocking())
+		outputs = append(outputs, output)
+	}
+
+	line := strings.Repeat("x", 99) + "\n"
+	var wg sync.WaitGroup
+	for _, output := range outputs {
+		wg.Add(1)
+		go func(output *FileOutput) {
+			defer wg.Done()
+			for index := 0; index < 50; index++ {
+				output.Write([]byte(line))
+			}
+		}(output)
+	}
+	wg.Wait()
+
+	archives, err := outputs[0].getArchivedFilePaths()
+	assert.Nil(err)
+	assert.NotEmpty(archives)
+
+	var lines int
+	for _, path := range append(archives, filePath) {
+		contents, err := ioutil.ReadFile(path)
+		assert.Nil(err)
+		for _, written := range strings.SplitAfter(string(contents), "\n") {
+			if len(written) > 0 {
+				assert.Equal(line, written)
+				lines++
+			}
+		}
+	}
+	assert.Equal(100, lines)
+
+	assert.Nil(outputs[0].SetProcessLocking(false))
+	assert.False(outputs[0].ProcessLocking())
+}
@@ -0,0 +1,150 @@
+package logger
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	exception "github.com/blendlabs/go-exception"
+)
+
+const (
+	// FileOutputRotationCheckInterval is how often a file output detecting rotation (see `SetDetectRotation`)
+	// checks its file is still at its path.
+	FileOutputRotationCheckInterval = time.Second
+)
+
+// OutputReopener is implemented by outputs that can reopen their files, e.g. after they're rotated
+// by logrotate, see `Agent.Reopen`.
+type OutputReopener interface {
+	Reopen() error
+}
+
+// ReopenOutput reopens an output if it can reopen its files (is an `OutputReopener`).
+func ReopenOutput(output io.Writer) error {
+	if reopener, isReopener := output.(OutputReopener); isReopener {
+		return reopener.Reopen()
+	}
+	return nil
+}
+
+// Reopen closes the file and opens (or creates) the file at the output's path, e.g. from logrotate's
+// `postrotate` (via `ReopenOnSignal`) once it has moved the file, so lines aren't written to the archived
+// (or deleted) file. It does nothing if the output is closed.
+func (fo *FileOutput) Reopen() error {
+	fo.syncRoot.Lock()
+	defer fo.syncRoot.Unlock()
+	if fo.file == nil {
+		return nil
+	}
+	return exception.Wrap(fo.reopen())
+}
+
+// DetectRotation returns if the output checks if its file was moved or removed (see `SetDetectRotation`).
+func (fo *FileOutput) DetectRotation() bool {
+	fo.syncRoot.Lock()
+	defer fo.syncRoot.Unlock()
+	return fo.detectRotation
+}
+
+// SetDetectRotation sets if the output checks its file is still at its path before writing
+// (at most every `FileOutputRotationCheckInterval`), reopening it if it was moved or removed, e.g. by logrotate
+// without a `postrotate` script. Outputs with process locking (see `SetProcessLocking`) always check.
+func (fo *FileOutput) SetDetectRotation(detectRotation bool) {
+	fo.syncRoot.Lock()
+	defer fo.syncRoot.Unlock()
+	fo.detectRotation = detectRotation
+	fo.rotationCheckedAt = time.Time{}
+}
+
+// checkRotation reopens the file if it was moved or removed, if it hasn't been checked recently.
+func (fo *FileOutput) checkRotation() error {
+	now := time.Now()
+	if now.Sub(fo.rotationCheckedAt) < FileOutputRotationCheckInterval {
+		return nil
+	}
+	fo.rotationCheckedAt = now
+	return fo.reopenIfRotated()
+}
+
+// reopen opens the file at the output's path, syncing and closing the current file once it's open.
+func (fo *FileOutput) reopen() error {
+	file, err := File.CreateOrOpen(fo.filePath)
+	if err != nil {
+		return err
+	}
+	if fo.syncPolicy != FileSyncNever {
+		fo.sync()
+	}
+	fo.file.Close()
+	fo.file = file
+	fo.unsynced = false
+	return nil
+}
+
+// Reopen reopens the output and error output, if they can reopen their files.
+func (wr *Writer) Reopen() error {
+	if err := ReopenOutput(wr.Output); err != nil {
+		return err
+	}
+	return ReopenOutput(wr.ErrorOutput)
+}
+
+// SetMetaReporter sets how the output and error output report problems that don't fail writes, see `OutputMetaReporter`.
+func (wr *Writer) SetMetaReporter(reporter MetaReporter) {
+	if wr == nil {
+		return
+	}
+	SetOutputMetaReporter(wr.Output, reporter)
+	SetOutputMetaReporter(wr.ErrorOutput, reporter)
+}
+
+// Reopen writes the queued events and reopens the files of the agent's writer and the writers
+// categories are routed to, see `FileOutput.Reopen`.
+func (da *Agent) Reopen() error {
+	if da == nil {
+		return nil
+	}
+	root := da.root()
+	root.Flush()
+	var err error
+	for _, writer := range root.loadCategories().writers {
+		if writer == da.Writer() {
+			continue
+		}
+		if reopenErr := writer.Reopen(); reopenErr != nil && err == nil {
+			err = reopenErr
+		}
+	}
+	if writer := da.Writer(); writer != nil {
+		if reopenErr := writer.Reopen(); reopenErr != nil && err == nil {
+			err = reopenErr
+		}
+	}
+	return err
+}
+
+// reopenOnSignals reopens the agent's files for each signal received, until the returned func is called
+// (which waits for a reopen in progress).
+func reopenOnSignals(agent *Agent, signals chan os.Signal) (stop func()) {
+	done, exited := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(exited)
+		for {
+			select {
+			case sig := <-signals:
+				if err := agent.Reopen(); err != nil {
+					agent.Warningf("received %v; reopening files failed: %v", sig, err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+		<-exited
+	}
+}
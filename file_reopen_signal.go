@@ -0,0 +1,18 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ReopenOnSignal reopens the agent's files (see `Agent.Reopen`) whenever the process receives SIGUSR2, e.g. from
+// logrotate's `postrotate` script (`kill -USR2 $(cat /var/run/app.pid)`), until the returned func is called.
+func ReopenOnSignal(agent *Agent) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR2)
+	return reopenOnSignals(agent, signals)
+}
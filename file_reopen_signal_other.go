@@ -0,0 +1,9 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package logger
+
+// ReopenOnSignal does nothing on platforms without SIGUSR2; call `Agent.Reopen` instead.
+func ReopenOnSignal(agent *Agent) (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestFileOutputReopen(t *testing.T) {
+	assert := assert.New(t)
+
+	tempFile := UUIDv4()
+	defer os.Remove(tempFile)
+	defer os.Remove(tempFile + ".1")
+
+	output, err := NewFileOutput(tempFile, false, FileOutputUnlimitedSize, FileOutputUnlimitedArchiveFiles)
+	assert.Nil(err)
+	writer := NewWriter(NewAnsiStripOutput(output))
+	writer.SetUseAnsiColors(false)
+	da := NewWithWriter(NewEventFlagSet(EventInfo), writer)
+	defer da.Close()
+
+	da.Infof("before rotation")
+	da.Flush()
+	assert.Nil(os.Rename(tempFile, tempFile+".1"))
+	da.Infof("during rotation")
+	assert.Nil(da.Reopen())
+	da.Infof("after rotation")
+	da.Flush()
+
+	archived, err := ioutil.ReadFile(tempFile + ".1")
+	assert.Nil(err)
+	assert.True(strings.Contains(string(archived), "before rotation"), string(archived))
+	assert.True(strings.Contains(string(archived), "during rotation"), string(archived))
+
+	current, err := ioutil.ReadFile(tempFile)
+	assert.Nil(err)
+	assert.Equal(1, strings.Count(string(current), "\n"), string(current))
+	assert.True(strings.Contains(string(current), "after rotation"), string(current))
+}
+
+func TestFileOutputDetectRotation(t *testing.T) {
+	assert := assert.New(t)
+
+	tempFile := UUIDv4()
+	defer os.Remove(tempFile)
+	defer os.Remove(tempFile + ".1")
+
+	output, err := NewFileOutput(tempFile, false, FileOutputUnlimitedSize, FileOutputUnlimitedArchiveFiles)
+	assert.Nil(err)
+	defer output.Close()
+	output.SetDetectRotation(true)
+	assert.True(output.DetectRotation())
+
+	output.Write([]byte("before\n"))
+	assert.Nil(os.Remove(tempFile))
+	output.Write([]byte("skipped check\n"))
+
+	output.syncRoot.Lock()
+	output.rotationCheckedAt = time.Now().Add(-FileOutputRotationCheckInterval)
+	output.syncRoot.Unlock()
+	output.Write([]byte("after\n"))
+
+	current, err := ioutil.ReadFile(tempFile)
+	assert.Nil(err)
+	assert.Equal("after\n", string(current))
+}
+
+func TestReopenOnSignal(t *testing.T) {
+	assert := assert.New(t)
+
+	tempFile := UUIDv4()
+	defer os.Remove(tempFile)
+	defer os.Remove(tempFile + ".1")
+
+	output, err := NewFileOutput(tempFile, false, FileOutputUnlimitedSize, FileOutputUnlimitedArchiveFiles)
+	assert.Nil(err)
+	da := NewWithWriter(NewEventFlagSet(EventInfo), NewWriter(output))
+	defer da.Close()
+
+	signals := make(chan os.Signal, 1)
+	stop := reopenOnSignals(da, signals)
+	defer stop()
+
+	assert.Nil(os.Rename(tempFile, tempFile+".1"))
+	signals <- os.Interrupt
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if _, err = os.Stat(tempFile); err == nil {
+			break
+		}
+	}
+	assert.Nil(err)
+}
@@ -0,0 +1,140 @@
+package logger
+
+import (
+	"strings"
+	"time"
+
+	exception "github.com/blendlabs/go-exception"
+)
+
+const (
+	// DefaultFileSyncInterval is the interval files are synced at with `FileSyncInterval`.
+	DefaultFileSyncInterval = time.Second
+)
+
+// FileSyncPolicy is when a file output syncs (fsyncs) what it has written to stable storage,
+// trading durability against throughput.
+type FileSyncPolicy int
+
+const (
+	// FileSyncNever leaves syncing to the operating system, which is the fastest but can lose recent lines on a crash.
+	FileSyncNever FileSyncPolicy = iota
+	// FileSyncInterval syncs in the background at the output's sync interval (see `SetSyncInterval`), if it has written since.
+	FileSyncInterval
+	// FileSyncOnSeverity syncs when the output is flushed, which writers do as soon as they write an event
+	// at or above their flush severity (see `Writer.SetFlushSeverity`), e.g. errors.
+	FileSyncOnSeverity
+	// FileSyncEveryWrite syncs after every write, e.g. for audit logs that mustn't lose a line.
+	FileSyncEveryWrite
+)
+
+// ParseFileSyncPolicy parses a sync policy, `never`, `interval`, `severity` or `always`, as read from `LOG_OUT_FSYNC`
+// and `LOG_ERR_FSYNC`. Anything else is `FileSyncNever`.
+func ParseFileSyncPolicy(value string) FileSyncPolicy {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "interval":
+		return FileSyncInterval
+	case "severity":
+		return FileSyncOnSeverity
+	case "always":
+		return FileSyncEveryWrite
+	default:
+		return FileSyncNever
+	}
+}
+
+// SyncPolicy returns when the output syncs what it has written, see `FileSyncPolicy`.
+func (fo *FileOutput) SyncPolicy() FileSyncPolicy {
+	fo.syncRoot.Lock()
+	defer fo.syncRoot.Unlock()
+	return fo.syncPolicy
+}
+
+// SetSyncPolicy sets when the output syncs what it has written, see `FileSyncPolicy`.
+// To sync a signed or encrypted file, set the policy on the file output it wraps, e.g.
+// `NewSignedOutput(fileOutput, key)` for a file output with `FileSyncEveryWrite`.
+func (fo *FileOutput) SetSyncPolicy(policy FileSyncPolicy) {
+	fo.syncRoot.Lock()
+	defer fo.syncRoot.Unlock()
+	fo.syncPolicy = policy
+	fo.stopSyncLoop()
+	if policy == FileSyncInterval {
+		fo.syncStop = make(chan struct{})
+		go fo.syncEvery(fo.syncInterval, fo.syncStop)
+	}
+}
+
+// SyncInterval returns the interval the output syncs at with `FileSyncInterval`.
+func (fo *FileOutput) SyncInterval() time.Duration {
+	fo.syncRoot.Lock()
+	defer fo.syncRoot.Unlock()
+	return fo.syncInterval
+}
+
+// SetSyncInterval sets the interval the output syncs at with `FileSyncInterval`; set it before the policy.
+func (fo *FileOutput) SetSyncInterval(interval time.Duration) {
+	fo.syncRoot.Lock()
+	defer fo.syncRoot.Unlock()
+	fo.syncInterval = interval
+}
+
+// Sync syncs what the output has written to stable storage.
+func (fo *FileOutput) Sync() error {
+	fo.syncRoot.Lock()
+	defer fo.syncRoot.Unlock()
+	return fo.sync()
+}
+
+// Flush syncs what the output has written with `FileSyncOnSeverity`, and does nothing otherwise.
+func (fo *FileOutput) Flush() error {
+	fo.syncRoot.Lock()
+	defer fo.syncRoot.Unlock()
+	if fo.syncPolicy != FileSyncOnSeverity {
+		return nil
+	}
+	return fo.sync()
+}
+
+// sync syncs the file if it was written to since it was last synced.
+func (fo *FileOutput) sync() error {
+	if fo.file == nil || !fo.unsynced {
+		return nil
+	}
+	if err := fo.file.Sync(); err != nil {
+		return exception.Wrap(err)
+	}
+	fo.unsynced = false
+	return nil
+}
+
+// syncAfterWrite marks the file as written to, syncing it with `FileSyncEveryWrite`.
+func (fo *FileOutput) syncAfterWrite() error {
+	fo.unsynced = true
+	if fo.syncPolicy == FileSyncEveryWrite {
+		return fo.sync()
+	}
+	return nil
+}
+
+func (fo *FileOutput) syncEvery(interval time.Duration, stop chan struct{}) {
+	if interval <= 0 {
+		interval = DefaultFileSyncInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			fo.Sync()
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (fo *FileOutput) stopSyncLoop() {
+	if fo.syncStop != nil {
+		close(fo.syncStop)
+		fo.syncStop = nil
+	}
+}
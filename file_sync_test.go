@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestParseFileSyncPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(FileSyncNever, ParseFileSyncPolicy(""))
+	assert.Equal(FileSyncNever, ParseFileSyncPolicy("never"))
+	assert.Equal(FileSyncInterval, ParseFileSyncPolicy("interval"))
+	assert.Equal(FileSyncOnSeverity, ParseFileSyncPolicy(" Severity "))
+	assert.Equal(FileSyncEveryWrite, ParseFileSyncPolicy("ALWAYS"))
+	assert.Equal(FileSyncNever, ParseFileSyncPolicy("sometimes"))
+}
+
+func TestFileOutputSyncEveryWrite(t *testing.T) {
+	assert := assert.New(t)
+
+	tempFile := UUIDv4()
+	defer os.Remove(tempFile)
+	output, err := NewFileOutput(tempFile, false, FileOutputUnlimitedSize, FileOutputUnlimitedArchiveFiles)
+	assert.Nil(err)
+	defer output.Close()
+
+	assert.Equal(FileSyncNever, output.SyncPolicy())
+	output.Write([]byte("unsynced\n"))
+	assert.True(output.unsynced)
+
+	output.SetSyncPolicy(FileSyncEveryWrite)
+	_, err = output.Write([]byte("synced\n"))
+	assert.Nil(err)
+	assert.False(output.unsynced)
+}
+
+func TestFileOutputSyncOnSeverity(t *testing.T) {
+	assert := assert.New(t)
+
+	tempFile := UUIDv4()
+	defer os.Remove(tempFile)
+	output, err := NewFileOutput(tempFile, false, FileOutputUnlimitedSize, FileOutputUnlimitedArchiveFiles)
+	assert.Nil(err)
+	defer output.Close()
+	output.SetSyncPolicy(FileSyncOnSeverity)
+
+	writer := NewWriter(output)
+	writer.SetUseAnsiColors(false)
+	writer.SetFlushSeverity(EventError)
+
+	da := NewWithWriter(NewEventFlagSet(EventInfo, EventError), writer)
+	defer da.Close()
+
+	da.Infof("hello")
+	da.Flush()
+	output.syncRoot.Lock()
+	assert.True(output.unsynced)
+	output.syncRoot.Unlock()
+
+	da.Errorf("it broke")
+	da.Flush()
+	output.syncRoot.Lock()
+	assert.False(output.unsynced)
+	output.syncRoot.Unlock()
+}
+
+func TestFileOutputSyncInterval(t *testing.T) {
+	assert := assert.New(t)
+
+	tempFile := UUIDv4()
+	defer os.Remove(tempFile)
+	output, err := NewFileOutput(tempFile, false, FileOutputUnlimitedSize, FileOutputUnlimitedArchiveFiles)
+	assert.Nil(err)
+	defer output.Close()
+
+	output.SetSyncInterval(time.Millisecond)
+	assert.Equal(time.Millisecond, output.SyncInterval())
+	output.SetSyncPolicy(FileSyncInterval)
+	output.Write([]byte("hello\n"))
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		output.syncRoot.Lock()
+		unsynced := output.unsynced
+		output.syncRoot.Unlock()
+		if !unsynced {
+			break
+		}
+	}
+	output.syncRoot.Lock()
+	assert.False(output.unsynced)
+	output.syncRoot.Unlock()
+
+	output.SetSyncPolicy(FileSyncNever)
+	assert.Nil(output.syncStop)
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -13,13 +14,10 @@ var File = &fileUtil{}
 
 type fileUtil struct{}
 
-// CreateOrOpen creates or opens a file.
+// CreateOrOpen creates or opens a file for appending, so each write lands at the end of the file
+// even if other processes are writing to it.
 func (fu fileUtil) CreateOrOpen(filePath string) (*os.File, error) {
-	f, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, os.ModeAppend)
-	if os.IsNotExist(err) {
-		return os.Create(filePath)
-	}
-	return f, err
+	return os.OpenFile(filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0666)
 }
 
 // CreateAndClose creates and closes a file.
@@ -60,6 +58,25 @@ func (fu fileUtil) List(path string, expr *regexp.Regexp) ([]string, error) {
 	return files, err
 }
 
+// ListDir returns the paths of the files directly in a directory (not in its subdirectories, unlike `List`)
+// whose names match an expression (or all of them if it's nil), sorted by name.
+func (fu fileUtil) ListDir(path string, expr *regexp.Regexp) ([]string, error) {
+	infos, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		if expr == nil || expr.MatchString(info.Name()) {
+			files = append(files, filepath.Join(path, info.Name()))
+		}
+	}
+	return files, nil
+}
+
 func (fu fileUtil) ParseSize(fileSizeValue string, defaultFileSize int64) int64 {
 	if len(fileSizeValue) == 0 {
 		return defaultFileSize
@@ -104,3 +121,40 @@ func (fu fileUtil) FormatSize(sizeBytes int) string {
 	}
 	return strconv.Itoa(sizeBytes)
 }
+
+// SizeUnits are the units file sizes are formatted in.
+type SizeUnits int
+
+const (
+	// SizeUnitsLegacy formats sizes as whole binary multiples with lower case suffixes, e.g. `1kb` for 1024 bytes.
+	SizeUnitsLegacy SizeUnits = iota
+	// SizeUnitsBinary formats sizes in IEC binary units, e.g. `1.5KiB` for 1536 bytes.
+	SizeUnitsBinary
+	// SizeUnitsDecimal formats sizes in SI decimal units, e.g. `1.5kB` for 1500 bytes.
+	SizeUnitsDecimal
+)
+
+// FormatSizeWithUnits returns a string representation of a file size in bytes with given units,
+// and (for binary and decimal units) a given number of decimal places.
+func (fu fileUtil) FormatSizeWithUnits(sizeBytes int, units SizeUnits, precision int) string {
+	var base float64
+	var suffixes []string
+	switch units {
+	case SizeUnitsBinary:
+		base, suffixes = 1024, []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	case SizeUnitsDecimal:
+		base, suffixes = 1000, []string{"B", "kB", "MB", "GB", "TB"}
+	default:
+		return fu.FormatSize(sizeBytes)
+	}
+
+	value := float64(sizeBytes)
+	unit := 0
+	for ; unit < len(suffixes)-1 && (value >= base || value <= -base); unit++ {
+		value = value / base
+	}
+	if unit == 0 {
+		return strconv.Itoa(sizeBytes) + suffixes[0]
+	}
+	return strconv.FormatFloat(value, 'f', precision, 64) + suffixes[unit]
+}
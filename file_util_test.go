@@ -1,7 +1,10 @@
 package logger
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"regexp"
 	"testing"
 
 	assert "github.com/blendlabs/go-assert"
@@ -57,3 +60,34 @@ func TestFileParseSize(t *testing.T) {
 	assert.Equal(12345, File.ParseSize("12345", 1))
 	assert.Equal(1, File.ParseSize("", 1))
 }
+
+func TestFileFormatSizeWithUnits(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("1kb", File.FormatSizeWithUnits(1536, SizeUnitsLegacy, 1))
+	assert.Equal("512B", File.FormatSizeWithUnits(512, SizeUnitsBinary, 1))
+	assert.Equal("1.5KiB", File.FormatSizeWithUnits(1536, SizeUnitsBinary, 1))
+	assert.Equal("2MiB", File.FormatSizeWithUnits(2<<20, SizeUnitsBinary, 0))
+	assert.Equal("1.50kB", File.FormatSizeWithUnits(1500, SizeUnitsDecimal, 2))
+	assert.Equal("3.2GB", File.FormatSizeWithUnits(3200000000, SizeUnitsDecimal, 1))
+}
+
+func TestFileListDir(t *testing.T) {
+	assert := assert.New(t)
+
+	td, err := ioutil.TempDir("", "file_list_dir")
+	assert.Nil(err)
+	defer os.RemoveAll(td)
+	assert.Nil(os.Mkdir(filepath.Join(td, "nested"), 0755))
+	assert.Nil(File.CreateAndClose(filepath.Join(td, "app.log.1")))
+	assert.Nil(File.CreateAndClose(filepath.Join(td, "nested", "app.log.2")))
+
+	expr := regexp.MustCompile(`^app\.log\.[0-9]+$`)
+	files, err := File.ListDir(td, expr)
+	assert.Nil(err)
+	assert.Equal([]string{filepath.Join(td, "app.log.1")}, files)
+
+	files, err = File.List(td, expr)
+	assert.Nil(err)
+	assert.Len(files, 2)
+}
@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// maxFormatCacheEvents bounds the cached event labels, as `FormatEvent` is also used for arbitrary labels.
+	maxFormatCacheEvents = 1024
+)
+
+// formatCache holds the rendered (colorized) invariant parts of lines: event labels and the label / namespace / fields prefix.
+// Writers reset it whenever a setting that changes them is set.
+type formatCache struct {
+	events      sync.Map // formatCacheKey => string
+	eventsCount int32
+	prefix      atomic.Value // string
+}
+
+type formatCacheKey struct {
+	event EventFlag
+	color AnsiColorCode
+}
+
+// loadFormatCache returns the writer's format cache, creating it on first use.
+func (wr *Writer) loadFormatCache() *formatCache {
+	if cache, isCache := wr.formatCache.Load().(*formatCache); isCache {
+		return cache
+	}
+	wr.formatCache.CompareAndSwap(nil, &formatCache{})
+	return wr.formatCache.Load().(*formatCache)
+}
+
+// resetFormatCache drops the cached formatting, e.g. after the colors or labels change.
+func (wr *Writer) resetFormatCache() {
+	wr.formatCache.Store(&formatCache{})
+}
+
+// formatEventLabel returns the (cached) colorized glyph and label of an event.
+func (wr *Writer) formatEventLabel(event EventFlag, color AnsiColorCode) string {
+	cache := wr.loadFormatCache()
+	key := formatCacheKey{event: event, color: color}
+	if formatted, hasFormatted := cache.events.Load(key); hasFormatted {
+		return formatted.(string)
+	}
+
+	formatted := "[" + wr.Colorize(wr.EventLabel(event), color) + "]"
+	if glyph, hasGlyph := wr.eventGlyphs[event]; hasGlyph {
+		formatted = wr.Colorize(glyph, color) + " " + formatted
+	}
+	if atomic.AddInt32(&cache.eventsCount, 1) <= maxFormatCacheEvents {
+		cache.events.Store(key, formatted)
+	}
+	return formatted
+}
+
+// formatPrefix returns the (cached) label, namespace and static fields written after the timestamp of each line.
+func (wr *Writer) formatPrefix() string {
+	cache := wr.loadFormatCache()
+	if prefix, hasPrefix := cache.prefix.Load().(string); hasPrefix {
+		return prefix
+	}
+	prefix := wr.renderPrefix(wr.namespace)
+	cache.prefix.Store(prefix)
+	return prefix
+}
+
+// renderPrefix renders the label, a given namespace and static fields written after the timestamp of a line.
+// Lines of agents with their own namespace (see `Agent.SetNamespace`) render it uncached.
+func (wr *Writer) renderPrefix(namespace string) string {
+	prefix := bytes.NewBuffer(nil)
+	if wr.showLabel && len(wr.label) > 0 {
+		prefix.WriteString(wr.FormatLabel())
+		prefix.WriteRune(RuneSpace)
+	}
+	if len(namespace) > 0 {
+		prefix.WriteString(wr.formatNamespace(namespace))
+		prefix.WriteRune(RuneSpace)
+	}
+	if len(wr.fields) > 0 {
+		prefix.WriteString(wr.FormatFields(wr.fields))
+		prefix.WriteRune(RuneSpace)
+	}
+	return prefix.String()
+}
+
+// formatMessage formats a message, skipping `fmt.Sprintf` for constant messages (no args or verbs).
+func formatMessage(format string, args ...interface{}) string {
+	if len(args) == 0 && strings.IndexByte(format, '%') < 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestWriterFormatCache(t *testing.T) {
+	assert := assert.New(t)
+
+	writer := NewWriter(bytes.NewBuffer(nil))
+	writer.SetUseAnsiColors(false)
+	assert.Equal("[info]", writer.FormatEvent(EventInfo, ColorLightWhite))
+	assert.Equal("[info]", writer.FormatEvent(EventInfo, ColorLightWhite))
+
+	writer.SetEventLabels(ShortEventLabels)
+	assert.Equal("[INF]", writer.FormatEvent(EventInfo, ColorLightWhite))
+
+	writer.SetEventGlyphs(DefaultEventGlyphs)
+	assert.Equal("✓ [INF]", writer.FormatEvent(EventInfo, ColorLightWhite))
+
+	writer.SetUseAnsiColors(true)
+	assert.Equal(ColorLightWhite.Apply("✓")+" ["+ColorLightWhite.Apply("INF")+"]", writer.FormatEvent(EventInfo, ColorLightWhite))
+	assert.Equal(ColorRed.Apply("✓")+" ["+ColorRed.Apply("INF")+"]", writer.FormatEvent(EventInfo, ColorRed))
+}
+
+func TestWriterFormatCachePrefix(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	writer := NewWriter(buffer)
+	writer.SetUseAnsiColors(false)
+	writer.SetShowTimestamp(false)
+	writer.Printf("one")
+	writer.SetNamespace("acme")
+	writer.Printf("two")
+	writer.SetLabel("api")
+	writer.SetShowLabel(true)
+	writer.Printf("three %d%%", 100)
+	assert.Equal("one\n{acme} two\napi {acme} three 100%\n", buffer.String())
+}
+
+func TestFormatMessage(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("constant", formatMessage("constant"))
+	assert.Equal("100%", formatMessage("100%%"))
+	assert.Equal("a=1", formatMessage("a=%d", 1))
+}
+
+func BenchmarkWriterFormatEvent(b *testing.B) {
+	writer := NewWriter(bytes.NewBuffer(nil))
+	writer.SetEventGlyphs(DefaultEventGlyphs)
+	b.ReportAllocs()
+	for x := 0; x < b.N; x++ {
+		writer.FormatEvent(EventInfo, ColorLightWhite)
+	}
+}
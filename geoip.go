@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// GeoLocation is the geographic location of an ip address.
+type GeoLocation struct {
+	Country string
+	Region  string
+	City    string
+}
+
+// GeoIPResolver resolves ip addresses to locations.
+// Adapt a MaxMind (or similar) database reader to this interface to use it with `NewGeoIPRequestListener`.
+type GeoIPResolver interface {
+	Lookup(ip net.IP) (*GeoLocation, error)
+}
+
+// GeoIPResolverFunc is a function that implements GeoIPResolver.
+type GeoIPResolverFunc func(ip net.IP) (*GeoLocation, error)
+
+// Lookup implements GeoIPResolver.
+func (grf GeoIPResolverFunc) Lookup(ip net.IP) (*GeoLocation, error) {
+	return grf(ip)
+}
+
+// NewCIDRGeoIPResolver returns a resolver backed by a fixed table of cidr ranges (e.g. `10.0.0.0/8`).
+func NewCIDRGeoIPResolver(ranges map[string]GeoLocation) (*CIDRGeoIPResolver, error) {
+	resolver := &CIDRGeoIPResolver{}
+	for cidr, location := range ranges {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		resolver.networks = append(resolver.networks, network)
+		resolver.locations = append(resolver.locations, location)
+	}
+	return resolver, nil
+}
+
+// CIDRGeoIPResolver resolves ip addresses against a fixed table of cidr ranges.
+type CIDRGeoIPResolver struct {
+	networks  []*net.IPNet
+	locations []GeoLocation
+}
+
+// Lookup implements GeoIPResolver. It returns nil if the ip is not in any range.
+func (cr *CIDRGeoIPResolver) Lookup(ip net.IP) (*GeoLocation, error) {
+	for x := 0; x < len(cr.networks); x++ {
+		if cr.networks[x].Contains(ip) {
+			location := cr.locations[x]
+			return &location, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetGeoLocation resolves the client ip of a request (see `GetIP`) to a location.
+func GetGeoLocation(resolver GeoIPResolver, req *http.Request) (*GeoLocation, error) {
+	ip := net.ParseIP(GetIP(req))
+	if ip == nil {
+		return nil, nil
+	}
+	return resolver.Lookup(ip)
+}
+
+// GeoIPRequestListener is a listener for request events enriched with the client location.
+// The location is nil if the client ip could not be resolved.
+type GeoIPRequestListener func(writer *Writer, ts TimeSource, req *http.Request, statusCode, contentLengthBytes int, elapsed time.Duration, location *GeoLocation)
+
+// NewGeoIPRequestListener returns a new handler for request events that resolves the client location.
+func NewGeoIPRequestListener(resolver GeoIPResolver, listener GeoIPRequestListener) EventListener {
+	return NewRequestListener(func(writer *Writer, ts TimeSource, req *http.Request, statusCode, contentLengthBytes int, elapsed time.Duration) {
+		location, _ := GetGeoLocation(resolver, req)
+		listener(writer, ts, req, statusCode, contentLengthBytes, elapsed, location)
+	})
+}
+
+// WriteRequestWithGeoLocation is a helper method to write request complete events with the client location to a writer.
+func WriteRequestWithGeoLocation(writer *Writer, ts TimeSource, req *http.Request, statusCode, contentLengthBytes int, elapsed time.Duration, location *GeoLocation) {
+	if location == nil {
+		WriteRequest(writer, ts, req, statusCode, contentLengthBytes, elapsed)
+		return
+	}
+	writeRequestWithSuffix(writer, ts, EventWebRequest, req, statusCode, contentLengthBytes, elapsed, location.String())
+}
+
+// String returns the location as `country/region/city`, omitting empty parts.
+func (gl GeoLocation) String() string {
+	var value string
+	for _, part := range []string{gl.Country, gl.Region, gl.City} {
+		if len(part) > 0 {
+			if len(value) > 0 {
+				value = value + "/"
+			}
+			value = value + part
+		}
+	}
+	return value
+}
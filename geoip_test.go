@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestCIDRGeoIPResolver(t *testing.T) {
+	assert := assert.New(t)
+
+	resolver, err := NewCIDRGeoIPResolver(map[string]GeoLocation{
+		"10.0.0.0/8": {Country: "US", Region: "CA"},
+	})
+	assert.Nil(err)
+
+	req := &http.Request{Header: http.Header{}}
+	req.Header.Set("X-Forwarded-For", "10.1.2.3")
+	location, err := GetGeoLocation(resolver, req)
+	assert.Nil(err)
+	assert.NotNil(location)
+	assert.Equal("US/CA", location.String())
+
+	req.Header.Set("X-Forwarded-For", "192.168.1.1")
+	location, err = GetGeoLocation(resolver, req)
+	assert.Nil(err)
+	assert.Nil(location)
+
+	_, err = NewCIDRGeoIPResolver(map[string]GeoLocation{"not a cidr": {}})
+	assert.NotNil(err)
+}
+
+func TestGeoIPRequestListener(t *testing.T) {
+	assert := assert.New(t)
+
+	resolver, err := NewCIDRGeoIPResolver(map[string]GeoLocation{
+		"10.0.0.0/8": {Country: "US"},
+	})
+	assert.Nil(err)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventWebRequest), NewWriter(buffer))
+	da.Writer().SetUseAnsiColors(false)
+	da.AddEventListener(EventWebRequest, NewGeoIPRequestListener(resolver, WriteRequestWithGeoLocation))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.1.2.3:1234"
+	da.Sync().OnEvent(EventWebRequest, req, http.StatusOK, 10, time.Millisecond)
+	assert.True(strings.HasSuffix(buffer.String(), " US\n"), buffer.String())
+}
@@ -0,0 +1,280 @@
+package grpcforward
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	// DefaultOutputBufferSize is the number of lines an output buffers before writes block.
+	DefaultOutputBufferSize = 1024
+	// DefaultOutputBatchSize is the most lines an output sends in a batch.
+	DefaultOutputBatchSize = 128
+	// DefaultOutputMaxRetryDelay is the longest an output waits between attempts to send a batch.
+	DefaultOutputMaxRetryDelay = 5 * time.Second
+	// DefaultOutputAckTimeout is the longest an output waits for the gateway to acknowledge a batch before retrying it.
+	DefaultOutputAckTimeout = 10 * time.Second
+
+	// outputMinRetryDelay is the first delay before an output retries a batch; it doubles up to the max.
+	outputMinRetryDelay = 100 * time.Millisecond
+)
+
+// NewOutput returns an output that forwards the lines written to it to a gateway (see `NewServer`)
+// over a grpc connection, e.g.
+//
+//	conn, err := grpc.NewClient("logs.internal:5141", grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+//	writer := logger.NewWriter(grpcforward.NewOutput(conn))
+//	writer.SetOutputFormat(logger.OutputFormatJSON)
+//
+// Lines are buffered and sent in batches, each retried (with backoff) until the gateway acknowledges it,
+// so a line may be delivered twice if a connection breaks before its ack. When the buffer is full writes block,
+// passing backpressure on to the agent (see `logger.Agent.SetDropWhenSaturated`).
+// Secrets are redacted by the writer's secret scanner before lines reach the output (see `logger.Writer.SetSecretScanner`),
+// and by the gateway agent's scanner as it receives them.
+func NewOutput(conn grpc.ClientConnInterface) *Output {
+	return NewOutputWithBufferSize(conn, DefaultOutputBufferSize)
+}
+
+// NewOutputWithBufferSize returns an output that buffers a given number of lines, see `NewOutput`.
+func NewOutputWithBufferSize(conn grpc.ClientConnInterface, bufferSize int) *Output {
+	ctx, cancel := context.WithCancel(context.Background())
+	output := &Output{
+		conn:          conn,
+		batchSize:     DefaultOutputBatchSize,
+		maxRetryDelay: DefaultOutputMaxRetryDelay,
+		ackTimeout:    DefaultOutputAckTimeout,
+		items:         make(chan outputItem, bufferSize),
+		closing:       make(chan struct{}),
+		done:          make(chan struct{}),
+		ctx:           ctx,
+		cancelAll:     cancel,
+	}
+	go output.send()
+	return output
+}
+
+// Output forwards lines to a gateway, see `NewOutput`.
+type Output struct {
+	conn          grpc.ClientConnInterface
+	batchSize     int
+	maxRetryDelay time.Duration
+	ackTimeout    time.Duration
+
+	items     chan outputItem
+	closeOnce sync.Once
+	closeLock sync.RWMutex
+	closed    bool
+	closing   chan struct{}
+	done      chan struct{}
+	ctx       context.Context
+	cancelAll context.CancelFunc
+
+	stream  grpc.ClientStream
+	cancel  context.CancelFunc
+	sent    int64
+	dropped int64
+	lastErr atomic.Value // error
+}
+
+// outputItem is a line, or a marker closed once the lines written before it are sent (see `Flush`).
+type outputItem struct {
+	line    string
+	flushed chan struct{}
+}
+
+// BatchSize returns the most lines sent in a batch.
+func (o *Output) BatchSize() int { return o.batchSize }
+
+// SetBatchSize sets the most lines sent in a batch.
+func (o *Output) SetBatchSize(batchSize int) { o.batchSize = batchSize }
+
+// MaxRetryDelay returns the longest the output waits between attempts to send a batch.
+func (o *Output) MaxRetryDelay() time.Duration { return o.maxRetryDelay }
+
+// SetMaxRetryDelay sets the longest the output waits between attempts to send a batch.
+func (o *Output) SetMaxRetryDelay(maxRetryDelay time.Duration) { o.maxRetryDelay = maxRetryDelay }
+
+// AckTimeout returns the longest the output waits for a batch to be acknowledged.
+func (o *Output) AckTimeout() time.Duration { return o.ackTimeout }
+
+// SetAckTimeout sets the longest the output waits for a batch to be acknowledged; a batch that isn't
+// is retried on a new stream. It also bounds how long `Close` waits for the lines already written.
+func (o *Output) SetAckTimeout(ackTimeout time.Duration) { o.ackTimeout = ackTimeout }
+
+// Sent returns the number of lines the gateway has acknowledged.
+func (o *Output) Sent() int64 { return atomic.LoadInt64(&o.sent) }
+
+// Dropped returns the number of lines dropped because the output was closed before they could be sent.
+func (o *Output) Dropped() int64 { return atomic.LoadInt64(&o.dropped) }
+
+// Err returns the last error sending a batch, if any.
+func (o *Output) Err() error {
+	err, _ := o.lastErr.Load().(error)
+	return err
+}
+
+// Write queues a line to be forwarded, blocking while the buffer is full.
+func (o *Output) Write(contents []byte) (int, error) {
+	if !o.enqueue(outputItem{line: string(contents)}) {
+		return 0, errors.New("Output closed")
+	}
+	return len(contents), nil
+}
+
+// Flush blocks until the lines written before it have been sent (or dropped, if the output is closed).
+func (o *Output) Flush() error {
+	flushed := make(chan struct{})
+	if !o.enqueue(outputItem{flushed: flushed}) {
+		return nil
+	}
+	select {
+	case <-flushed:
+	case <-o.done:
+	}
+	return nil
+}
+
+// Close sends the lines already written, giving up on those it can't send at the first failure, and closes the stream.
+// If they aren't sent within the ack timeout (see `SetAckTimeout`), the stream is cancelled and the rest are dropped.
+// It doesn't close the grpc connection.
+func (o *Output) Close() error {
+	o.closeOnce.Do(func() {
+		close(o.closing)
+		o.closeLock.Lock()
+		o.closed = true
+		close(o.items)
+		o.closeLock.Unlock()
+	})
+	select {
+	case <-o.done:
+	case <-time.After(o.ackTimeout):
+		o.cancelAll()
+		<-o.done
+	}
+	o.cancelAll()
+	return nil
+}
+
+// enqueue queues an item, blocking while the buffer is full; it returns false if the output is closed.
+func (o *Output) enqueue(item outputItem) bool {
+	o.closeLock.RLock()
+	defer o.closeLock.RUnlock()
+	if o.closed {
+		return false
+	}
+	select {
+	case o.items <- item:
+		return true
+	case <-o.closing:
+		return false
+	}
+}
+
+// send sends batches of queued lines until the output is closed.
+func (o *Output) send() {
+	defer close(o.done)
+	defer o.closeStream()
+
+	var batch []string
+	var flushes []chan struct{}
+	for item := range o.items {
+		batch, flushes = o.collect(item, batch[:0], flushes[:0])
+		if len(batch) > 0 && !o.sendWithRetry(batch) {
+			atomic.AddInt64(&o.dropped, int64(len(batch)))
+		}
+		for _, flushed := range flushes {
+			close(flushed)
+		}
+	}
+}
+
+// collect adds an item and those queued after it (up to the batch size) to a batch,
+// stopping at a flush marker so a flush waits only for the lines written before it.
+func (o *Output) collect(item outputItem, batch []string, flushes []chan struct{}) ([]string, []chan struct{}) {
+	for {
+		if item.flushed != nil {
+			return batch, append(flushes, item.flushed)
+		}
+		batch = append(batch, item.line)
+		if len(batch) >= o.batchSize {
+			return batch, flushes
+		}
+		select {
+		case next, ok := <-o.items:
+			if !ok {
+				return batch, flushes
+			}
+			item = next
+		default:
+			return batch, flushes
+		}
+	}
+}
+
+// sendWithRetry sends a batch until it's acknowledged, backing off between attempts;
+// once the output is closing it makes a single attempt. It returns if the batch was sent.
+func (o *Output) sendWithRetry(batch []string) bool {
+	delay := outputMinRetryDelay
+	for {
+		err := o.sendBatch(batch)
+		if err == nil {
+			atomic.AddInt64(&o.sent, int64(len(batch)))
+			return true
+		}
+		o.lastErr.Store(err)
+		o.closeStream()
+
+		select {
+		case <-o.closing:
+			return false
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > o.maxRetryDelay {
+			delay = o.maxRetryDelay
+		}
+	}
+}
+
+// sendBatch sends a batch and waits for its ack, cancelling the stream if it isn't acknowledged within the ack timeout.
+func (o *Output) sendBatch(batch []string) error {
+	if o.stream == nil {
+		ctx, cancel := context.WithCancel(o.ctx)
+		stream, err := o.conn.NewStream(ctx, &serviceDesc.Streams[0], ForwardMethod, grpc.CallContentSubtype(CodecName))
+		if err != nil {
+			cancel()
+			return err
+		}
+		o.stream, o.cancel = stream, cancel
+	}
+	timedOut := int32(0)
+	cancel := o.cancel
+	timer := time.AfterFunc(o.ackTimeout, func() {
+		atomic.StoreInt32(&timedOut, 1)
+		cancel()
+	})
+	defer timer.Stop()
+
+	err := o.stream.SendMsg(&Batch{Lines: batch})
+	if err == nil {
+		var ack Ack
+		err = o.stream.RecvMsg(&ack)
+	}
+	if err != nil && atomic.LoadInt32(&timedOut) == 1 {
+		return errors.New("Batch not acknowledged within the ack timeout")
+	}
+	return err
+}
+
+func (o *Output) closeStream() {
+	if o.stream == nil {
+		return
+	}
+	o.stream.CloseSend()
+	o.cancel()
+	o.stream, o.cancel = nil, nil
+}
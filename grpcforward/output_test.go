@@ -0,0 +1,172 @@
+package grpcforward
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+	logger "github.com/blendlabs/go-logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// lockedBuffer is a buffer that's safe to read while an agent writes to it.
+type lockedBuffer struct {
+	sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (lb *lockedBuffer) Write(contents []byte) (int, error) {
+	lb.Lock()
+	defer lb.Unlock()
+	return lb.buffer.Write(contents)
+}
+
+func (lb *lockedBuffer) String() string {
+	lb.Lock()
+	defer lb.Unlock()
+	return lb.buffer.String()
+}
+
+// selfSignedTLS returns a server certificate for 127.0.0.1 and a pool trusting it.
+func selfSignedTLS(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(parsed)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestForwardOverTLS(t *testing.T) {
+	assert := assert.New(t)
+
+	gatewayOutput := &lockedBuffer{}
+	gateway := logger.NewWithWriter(logger.NewEventFlagSet(logger.EventInfo, logger.EventError), logger.NewWriter(gatewayOutput))
+	defer gateway.Close()
+	gateway.Writer().SetUseAnsiColors(false)
+
+	certificate, pool := selfSignedTLS(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(err)
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{certificate}})))
+	NewServer(gateway).Register(server)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{RootCAs: pool})))
+	assert.Nil(err)
+	defer conn.Close()
+
+	output := NewOutput(conn)
+	writer := logger.NewWriter(output)
+	writer.SetOutputFormat(logger.OutputFormatJSON)
+	edge := logger.NewWithWriter(logger.NewEventFlagSet(logger.EventInfo, logger.EventError), writer)
+	edge.SetDefaultFields(logger.Fields{"service": "edge"})
+
+	edge.Infof("hello from the edge")
+	edge.Errorf("it broke")
+	edge.Flush()
+	assert.Nil(output.Flush())
+	gateway.Flush()
+
+	written := gatewayOutput.String()
+	assert.True(strings.Contains(written, "[info] hello from the edge service=edge"), written)
+	assert.True(strings.Contains(written, "[error] it broke service=edge"), written)
+	assert.Equal(2, output.Sent())
+
+	assert.Nil(output.Close())
+	_, err = output.Write([]byte("too late"))
+	assert.NotNil(err)
+}
+
+func TestOutputDropsOnCloseWhenUnreachable(t *testing.T) {
+	assert := assert.New(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{})))
+	assert.Nil(err)
+	defer conn.Close()
+
+	output := NewOutput(conn)
+	output.SetMaxRetryDelay(10 * time.Millisecond)
+	output.Write([]byte("lost"))
+	for output.Err() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Nil(output.Close())
+	assert.Equal(1, output.Dropped())
+	assert.Zero(output.Sent())
+}
+
+func TestOutputAckTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	stalled := make(chan struct{})
+	defer close(stalled)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(err)
+	server := grpc.NewServer(grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		<-stalled
+		return nil
+	}))
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nil(err)
+	defer conn.Close()
+
+	output := NewOutput(conn)
+	output.SetAckTimeout(50 * time.Millisecond)
+	output.SetMaxRetryDelay(10 * time.Millisecond)
+	output.Write([]byte("unacknowledged"))
+	for output.Err() == nil {
+		time.Sleep(time.Millisecond)
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		output.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("close waited on a gateway that never acknowledges")
+	}
+	assert.Equal(1, output.Dropped())
+	assert.Zero(output.Sent())
+}
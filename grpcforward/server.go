@@ -0,0 +1,47 @@
+package grpcforward
+
+import (
+	"io"
+
+	logger "github.com/blendlabs/go-logger"
+	"google.golang.org/grpc"
+)
+
+// NewServer returns a server for the forwarding service that re-emits received lines through an agent
+// (see `logger.Receiver.Receive`), e.g. `grpcforward.NewServer(agent).Register(grpcServer)`.
+// A batch is acknowledged once its lines are queued with the agent, so an agent whose queue is full (and blocks,
+// see `logger.Agent.SetDropWhenSaturated`) slows forwarders down rather than growing without bound.
+func NewServer(agent *logger.Agent) *Server {
+	return &Server{receiver: logger.NewReceiver(agent, "", "")}
+}
+
+// Server serves the forwarding service, see `NewServer`.
+type Server struct {
+	receiver *logger.Receiver
+}
+
+// Receiver returns the receiver lines are parsed and emitted with, e.g. to set the event of plain text lines.
+func (s *Server) Receiver() *logger.Receiver { return s.receiver }
+
+// Register registers the forwarding service with a grpc server (or any other `grpc.ServiceRegistrar`).
+func (s *Server) Register(server grpc.ServiceRegistrar) {
+	server.RegisterService(&serviceDesc, s)
+}
+
+func (s *Server) forward(stream grpc.ServerStream) error {
+	for {
+		var batch Batch
+		if err := stream.RecvMsg(&batch); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		for _, line := range batch.Lines {
+			s.receiver.Receive([]byte(line))
+		}
+		if err := stream.SendMsg(&Ack{Received: len(batch.Lines)}); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Package grpcforward forwards log lines between agents over grpc, e.g. from edge services to a central logging gateway.
+//
+// The gateway serves the `logger.Forwarder` service (see `NewServer`), re-emitting the lines it receives through
+// its own agent; edge services write to an `Output` (see `NewOutput`), typically with a writer set to json output
+// so the gateway sees each event's flag, time and fields. Use grpc's transport credentials for tls.
+//
+// Messages are encoded as json (content subtype `logger-json`), so the package has no generated code; other
+// languages can call the service with a json codec and the `Batch` and `Ack` shapes.
+//
+// grpc (`google.golang.org/grpc`) isn't vendored, so install it to build this package; the grpc types it takes
+// (`grpc.ServiceRegistrar` and `grpc.ClientConnInterface`) are your own module's.
+package grpcforward
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+const (
+	// ServiceName is the name of the forwarding service.
+	ServiceName = "logger.Forwarder"
+
+	// ForwardMethod is the full name of the forwarding method, a bidirectional stream of batches and acks.
+	ForwardMethod = "/" + ServiceName + "/Forward"
+
+	// CodecName is the content subtype messages are encoded with.
+	CodecName = "logger-json"
+)
+
+// Batch is a batch of lines sent to the gateway; each is a line of json output (see `logger.Record`),
+// a syslog line or plain text.
+type Batch struct {
+	Lines []string `json:"lines"`
+}
+
+// Ack acknowledges a batch once its lines have been emitted by the gateway's agent.
+type Ack struct {
+	Received int `json:"received"`
+}
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// jsonCodec encodes messages as json.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+func (jsonCodec) Name() string { return CodecName }
+
+// forwarder is implemented by the service's server.
+type forwarder interface {
+	forward(stream grpc.ServerStream) error
+}
+
+// serviceDesc describes the forwarding service, as generated code would.
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*forwarder)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Forward",
+			Handler:       forwardHandler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+}
+
+func forwardHandler(server interface{}, stream grpc.ServerStream) error {
+	return server.(forwarder).forward(stream)
+}
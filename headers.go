@@ -0,0 +1,160 @@
+package logger
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaskHeaders returns a copy of a header collection with sensitive values masked.
+// `Cookie` and `Set-Cookie` keep only cookie names, `Authorization` and `Proxy-Authorization` keep only the scheme,
+// and any additional headers given are masked entirely.
+func MaskHeaders(header http.Header, additional ...string) http.Header {
+	masked := make(http.Header, len(header))
+	for key, values := range header {
+		canonicalKey := http.CanonicalHeaderKey(key)
+		maskedValues := make([]string, len(values))
+		for x, value := range values {
+			maskedValues[x] = maskHeaderValue(canonicalKey, value, additional)
+		}
+		masked[key] = maskedValues
+	}
+	return masked
+}
+
+func maskHeaderValue(key, value string, additional []string) string {
+	switch key {
+	case "Authorization", "Proxy-Authorization":
+		if index := strings.IndexRune(value, RuneSpace); index > 0 {
+			return value[:index] + " " + RedactedValue
+		}
+		return RedactedValue
+	case "Cookie":
+		cookies := strings.Split(value, ";")
+		for x, cookie := range cookies {
+			cookies[x] = maskCookie(cookie)
+		}
+		return strings.Join(cookies, "; ")
+	case "Set-Cookie":
+		return maskCookie(strings.SplitN(value, ";", 2)[0])
+	}
+	for _, header := range additional {
+		if CaseInsensitiveEquals(key, header) {
+			return RedactedValue
+		}
+	}
+	return value
+}
+
+func maskCookie(cookie string) string {
+	name := strings.TrimSpace(strings.SplitN(cookie, "=", 2)[0])
+	return name + "=" + RedactedValue
+}
+
+// FormatHeaders returns a header collection as a single line of sorted `Key="value"` pairs,
+// with sensitive values masked (see `MaskHeaders` and `Writer.SetMaskedHeaders`).
+func (wr *Writer) FormatHeaders(header http.Header) string {
+	masked := MaskHeaders(header, wr.maskedHeaders...)
+	keys := make([]string, 0, len(masked))
+	for key := range masked {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range masked[key] {
+			pairs = append(pairs, key+"="+strconv.Quote(value))
+		}
+	}
+	return strings.Join(pairs, " ")
+}
+
+// MaskedHeaders returns the additional headers whose values are masked entirely.
+func (wr *Writer) MaskedHeaders() []string { return wr.maskedHeaders }
+
+// SetMaskedHeaders sets additional headers whose values are masked entirely.
+func (wr *Writer) SetMaskedHeaders(headers ...string) { wr.maskedHeaders = headers }
+
+// WriteRequestHeaders is a helper method to write request header events to a writer.
+func WriteRequestHeaders(writer *Writer, ts TimeSource, req *http.Request) {
+	buffer := writer.GetBuffer()
+	defer writer.PutBuffer(buffer)
+
+	buffer.WriteString(writer.FormatEvent(EventWebRequestHeaders, ColorGreen))
+	buffer.WriteRune(RuneSpace)
+	buffer.WriteString(writer.FormatHeaders(req.Header))
+	writer.WriteWithTimeSource(ts, buffer.Bytes())
+}
+
+const (
+	// EventWebResponseHeaders fires with the allowlisted headers of a response, see `Middleware.SetResponseHeaders`.
+	EventWebResponseHeaders EventFlag = "web.response.headers"
+)
+
+var (
+	// DefaultResponseHeaders are response headers useful for debugging caching, e.g. at a CDN.
+	DefaultResponseHeaders = []string{"Age", "Cache-Control", "Content-Type", "ETag", "Expires", "Last-Modified", "Vary"}
+)
+
+// FilterHeaders returns a copy of a header collection with only the allowed headers.
+func FilterHeaders(header http.Header, allowed ...string) http.Header {
+	filtered := http.Header{}
+	for _, key := range allowed {
+		if values := header[http.CanonicalHeaderKey(key)]; len(values) > 0 {
+			filtered[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+		}
+	}
+	return filtered
+}
+
+// ResponseHeadersListener is a listener for response header events.
+type ResponseHeadersListener func(writer *Writer, ts TimeSource, req *http.Request, statusCode int, header http.Header)
+
+// NewResponseHeadersListener returns a new handler for response header events,
+// e.g. `NewResponseHeadersListener(WriteResponseHeaders)`.
+func NewResponseHeadersListener(listener ResponseHeadersListener) EventListener {
+	return func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		if len(state) < 3 {
+			return
+		}
+
+		req, err := stateAsRequest(state[0])
+		if err != nil {
+			return
+		}
+
+		statusCode, err := stateAsInteger(state[1])
+		if err != nil {
+			return
+		}
+
+		header, isHeader := state[2].(http.Header)
+		if !isHeader {
+			return
+		}
+
+		listener(writer, ts, req, statusCode, header)
+	}
+}
+
+// WriteResponseHeaders is a helper method to write response header events to a writer,
+// e.g. `[web.response.headers] GET /index.html 200 Cache-Control="max-age=60" Content-Type="text/html"`.
+func WriteResponseHeaders(writer *Writer, ts TimeSource, req *http.Request, statusCode int, header http.Header) {
+	buffer := writer.GetBuffer()
+	defer writer.PutBuffer(buffer)
+
+	buffer.WriteString(writer.FormatEvent(EventWebResponseHeaders, ColorGreen))
+	buffer.WriteRune(RuneSpace)
+	buffer.WriteString(writer.Colorize(writer.Sanitize(req.Method), ColorBlue))
+	buffer.WriteRune(RuneSpace)
+	buffer.WriteString(writer.FormatRequestURI(req))
+	buffer.WriteRune(RuneSpace)
+	buffer.WriteString(writer.ColorizeByStatusCode(statusCode, strconv.Itoa(statusCode)))
+	if len(header) > 0 {
+		buffer.WriteRune(RuneSpace)
+		buffer.WriteString(writer.FormatHeaders(header))
+	}
+	writer.WriteWithTimeSource(ts, buffer.Bytes())
+}
@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestMaskHeaders(t *testing.T) {
+	assert := assert.New(t)
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer abc.def.ghi")
+	header.Set("Cookie", "session=abc; theme=dark")
+	header.Set("Set-Cookie", "session=abc; Path=/; HttpOnly")
+	header.Set("X-Api-Key", "secret")
+	header.Set("Accept", "*/*")
+
+	masked := MaskHeaders(header, "x-api-key")
+	assert.Equal("Bearer "+RedactedValue, masked.Get("Authorization"))
+	assert.Equal("session="+RedactedValue+"; theme="+RedactedValue, masked.Get("Cookie"))
+	assert.Equal("session="+RedactedValue, masked.Get("Set-Cookie"))
+	assert.Equal(RedactedValue, masked.Get("X-Api-Key"))
+	assert.Equal("*/*", masked.Get("Accept"))
+	assert.Equal("Bearer abc.def.ghi", header.Get("Authorization"))
+}
+
+func TestWriterFormatHeaders(t *testing.T) {
+	assert := assert.New(t)
+
+	header := http.Header{}
+	header.Set("Authorization", "token")
+	header.Set("Accept", "*/*")
+	header.Set("X-Secret", "shh")
+
+	writer := NewWriter(bytes.NewBuffer(nil))
+	writer.SetMaskedHeaders("X-Secret")
+	assert.Equal(`Accept="*/*" Authorization="`+RedactedValue+`" X-Secret="`+RedactedValue+`"`, writer.FormatHeaders(header))
+}
+
+func TestFilterHeaders(t *testing.T) {
+	assert := assert.New(t)
+
+	header := http.Header{}
+	header.Set("Cache-Control", "max-age=60")
+	header.Set("X-Internal", "hidden")
+	filtered := FilterHeaders(header, "cache-control", "Vary")
+	assert.Equal(http.Header{"Cache-Control": {"max-age=60"}}, filtered)
+}
+
+func TestMiddlewareResponseHeaders(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventWebResponseHeaders), NewWriter(buffer))
+	defer da.Close()
+	da.Writer().SetUseAnsiColors(false)
+	da.AddEventListener(EventWebResponseHeaders, NewResponseHeadersListener(WriteResponseHeaders))
+
+	mw := NewMiddleware(da)
+	mw.SetResponseHeaders(append(DefaultResponseHeaders, "X-Cache", "Set-Cookie")...)
+	handler := mw.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Cache-Control", "max-age=60")
+		res.Header().Set("X-Cache", "HIT")
+		res.Header().Set("X-Internal", "hidden")
+		res.Header().Set("Set-Cookie", "session=abc")
+		res.WriteHeader(http.StatusOK)
+	})
+	handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/index.html", nil))
+	da.Flush()
+
+	output := buffer.String()
+	assert.True(strings.Contains(output, `GET /index.html 200 Cache-Control="max-age=60" Set-Cookie="session=`+RedactedValue+`" X-Cache="HIT"`), output)
+	assert.False(strings.Contains(output, "hidden"), output)
+}
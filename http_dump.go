@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+)
+
+const (
+	// EventWebDump fires with full http request and response dumps (headers and body), for deep debugging.
+	// It's only fired by middleware and round trippers with dumps enabled (see `Middleware.SetHTTPDump`).
+	EventWebDump EventFlag = "web.dump"
+
+	// DefaultHTTPDumpMaxBytes is the number of body bytes included in http dumps.
+	DefaultHTTPDumpMaxBytes = 64 << 10
+)
+
+// HTTPDumpKind is what an http dump is of.
+type HTTPDumpKind string
+
+const (
+	// HTTPDumpRequest is an inbound request.
+	HTTPDumpRequest HTTPDumpKind = "request"
+	// HTTPDumpResponse is the response to an inbound request.
+	HTTPDumpResponse HTTPDumpKind = "response"
+	// HTTPDumpClientRequest is an outbound request.
+	HTTPDumpClientRequest HTTPDumpKind = "client.request"
+	// HTTPDumpClientResponse is the response to an outbound request.
+	HTTPDumpClientResponse HTTPDumpKind = "client.response"
+)
+
+// HTTPDump is the state of an `EventWebDump` event: the wire format of a request or response,
+// with sensitive headers masked and the body capped.
+type HTTPDump struct {
+	Kind HTTPDumpKind
+	Dump []byte
+	// Truncated is set if the body was longer than the part included in the dump.
+	Truncated bool
+}
+
+// DumpRequest returns the wire format of a request, with sensitive headers masked (see `MaskHeaders`)
+// and at most `maxBytes` of its body. The body is left readable in full.
+func DumpRequest(req *http.Request, maxBytes int, maskedHeaders ...string) HTTPDump {
+	dump, _ := dumpRequest(req, maxBytes, maskedHeaders...)
+	return dump
+}
+
+// dumpRequest dumps a request (see `DumpRequest`), also returning the (capped) body.
+func dumpRequest(req *http.Request, maxBytes int, maskedHeaders ...string) (HTTPDump, []byte) {
+	body, restored, truncated := peekBody(req.Body, maxBytes)
+	req.Body = restored
+
+	dumped := *req
+	dumped.Header = MaskHeaders(req.Header, maskedHeaders...)
+	dumped.Body = nil
+	head, _ := httputil.DumpRequest(&dumped, false)
+	return HTTPDump{Kind: HTTPDumpRequest, Dump: appendDumpBody(head, body, truncated), Truncated: truncated}, body
+}
+
+// DumpResponse returns the wire format of a response, with sensitive headers masked (see `MaskHeaders`)
+// and at most `maxBytes` of its body. The body is left readable in full.
+func DumpResponse(res *http.Response, maxBytes int, maskedHeaders ...string) HTTPDump {
+	body, restored, truncated := peekBody(res.Body, maxBytes)
+	res.Body = restored
+
+	dumped := *res
+	dumped.Header = MaskHeaders(res.Header, maskedHeaders...)
+	dumped.Body = nil
+	head, _ := httputil.DumpResponse(&dumped, false)
+	return HTTPDump{Kind: HTTPDumpResponse, Dump: appendDumpBody(head, body, truncated), Truncated: truncated}
+}
+
+// dumpResponseWriter returns the wire format of a response written through a response writer
+// with body capture set (see `ResponseWriter.SetBodyCapture`). If `includeBody` isn't set, the body is left out
+// (and the dump marked truncated if there was one).
+func dumpResponseWriter(req *http.Request, rw *ResponseWriter, includeBody bool, maskedHeaders ...string) HTTPDump {
+	res := &http.Response{
+		Status:        strconv.Itoa(rw.StatusCode()) + " " + http.StatusText(rw.StatusCode()),
+		StatusCode:    rw.StatusCode(),
+		Proto:         req.Proto,
+		ProtoMajor:    req.ProtoMajor,
+		ProtoMinor:    req.ProtoMinor,
+		Header:        MaskHeaders(rw.Header(), maskedHeaders...),
+		ContentLength: int64(rw.ContentLength()),
+		Request:       req,
+	}
+	head, _ := httputil.DumpResponse(res, false)
+	if !includeBody {
+		truncated := rw.ContentLength() > 0
+		return HTTPDump{Kind: HTTPDumpResponse, Dump: appendDumpBody(head, nil, truncated), Truncated: truncated}
+	}
+	truncated := rw.CapturedBodyTruncated()
+	return HTTPDump{Kind: HTTPDumpResponse, Dump: appendDumpBody(head, rw.CapturedBody(), truncated), Truncated: truncated}
+}
+
+func appendDumpBody(head, body []byte, truncated bool) []byte {
+	dump := append(head, body...)
+	if truncated {
+		dump = append(dump, []byte("\n... (truncated)")...)
+	}
+	return dump
+}
+
+// peekBody reads up to `maxBytes` of a body, returning them and a body that still reads in full.
+func peekBody(body io.ReadCloser, maxBytes int) ([]byte, io.ReadCloser, bool) {
+	if body == nil || body == http.NoBody {
+		return nil, body, false
+	}
+	peeked, _ := ioutil.ReadAll(io.LimitReader(body, int64(maxBytes)+1))
+	restored := struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peeked), body), body}
+	if len(peeked) > maxBytes {
+		return peeked[:maxBytes], restored, true
+	}
+	return peeked, restored, false
+}
+
+// HTTPDumpListener is a listener for http dump events.
+type HTTPDumpListener func(writer *Writer, ts TimeSource, dump HTTPDump)
+
+// NewHTTPDumpListener returns a new handler for http dump events, e.g. `NewHTTPDumpListener(WriteHTTPDump)`.
+func NewHTTPDumpListener(listener HTTPDumpListener) EventListener {
+	return func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		if len(state) < 1 {
+			return
+		}
+		if dump, isDump := state[0].(HTTPDump); isDump {
+			listener(writer, ts, dump)
+		}
+	}
+}
+
+// WriteHTTPDump is a helper method to write http dump events to a writer, e.g. `[web.dump] request` followed
+// by the dump on continuation lines.
+func WriteHTTPDump(writer *Writer, ts TimeSource, dump HTTPDump) {
+	buffer := writer.GetBuffer()
+	defer writer.PutBuffer(buffer)
+
+	buffer.WriteString(writer.FormatEvent(EventWebDump, ColorLightBlue))
+	buffer.WriteRune(RuneSpace)
+	buffer.WriteString(string(dump.Kind))
+	buffer.WriteRune(RuneNewline)
+	lines := strings.Split(strings.TrimRight(strings.Replace(string(dump.Dump), "\r\n", "\n", -1), "\n"), "\n")
+	for x, line := range lines {
+		if x > 0 {
+			buffer.WriteRune(RuneNewline)
+		}
+		buffer.WriteString(writer.Sanitize(line))
+	}
+	writer.WriteWithTimeSource(ts, buffer.Bytes())
+}
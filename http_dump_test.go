@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestDumpRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	req := httptest.NewRequest("POST", "/orders?id=1", strings.NewReader("0123456789"))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "application/json")
+
+	dump := DumpRequest(req, 4)
+	assert.Equal(HTTPDumpRequest, dump.Kind)
+	assert.True(dump.Truncated)
+	assert.True(strings.HasPrefix(string(dump.Dump), "POST /orders?id=1 HTTP/1.1\r\n"), string(dump.Dump))
+	assert.True(strings.Contains(string(dump.Dump), "Authorization: Bearer "+RedactedValue), string(dump.Dump))
+	assert.False(strings.Contains(string(dump.Dump), "secret"))
+	assert.True(strings.HasSuffix(string(dump.Dump), "\r\n\r\n0123\n... (truncated)"), string(dump.Dump))
+
+	body, err := ioutil.ReadAll(req.Body)
+	assert.Nil(err)
+	assert.Equal("0123456789", string(body), "the body is left readable in full")
+}
+
+func TestDumpResponse(t *testing.T) {
+	assert := assert.New(t)
+
+	res := &http.Response{
+		StatusCode: http.StatusNotFound, ProtoMajor: 1, ProtoMinor: 1,
+		Header: http.Header{"Content-Type": {"text/plain"}},
+		Body:   ioutil.NopCloser(strings.NewReader("not found")),
+	}
+	dump := DumpResponse(res, 64)
+	assert.False(dump.Truncated)
+	assert.True(strings.HasPrefix(string(dump.Dump), "HTTP/1.1 404 Not Found\r\n"), string(dump.Dump))
+	assert.True(strings.HasSuffix(string(dump.Dump), "not found"), string(dump.Dump))
+
+	body, _ := ioutil.ReadAll(res.Body)
+	assert.Equal("not found", string(body))
+}
+
+func TestMiddlewareHTTPDump(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventWebDump), NewWriter(buffer))
+	defer da.Close()
+	da.AddEventListener(EventWebDump, NewHTTPDumpListener(WriteHTTPDump))
+
+	middleware := NewMiddleware(da)
+	middleware.SetHTTPDump(true)
+	middleware.SetHTTPDumpMaxBytes(8)
+	handler := middleware.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		res.Header().Set("Cache-Control", "no-store")
+		res.WriteHeader(http.StatusCreated)
+		res.Write(append([]byte("created "), body...))
+	})
+
+	res := httptest.NewRecorder()
+	handler(res, httptest.NewRequest("PUT", "/items/1", strings.NewReader(`{"name":"widget"}`)))
+	da.Flush()
+
+	assert.Equal(`created {"name":"widget"}`, res.Body.String())
+	output := buffer.String()
+	assert.True(strings.Contains(output, "web.dump"), output)
+	assert.True(strings.Contains(output, "] request\nPUT /items/1 HTTP/1.1\n"), output)
+	assert.True(strings.Contains(output, "{\"name\":\n... (truncated)"), output)
+	assert.True(strings.Contains(output, "] response\nHTTP/1.1 201 Created\n"), output)
+	assert.True(strings.Contains(output, "Cache-Control: no-store"), output)
+	assert.True(strings.Contains(output, "created \n... (truncated)"), output)
+}
+
+func TestRoundTripperHTTPDump(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		res.Write(append([]byte("echo "), body...))
+	}))
+	defer server.Close()
+
+	dumps := make(chan HTTPDump, 2)
+	da := NewWithWriter(NewEventFlagSet(EventWebDump), NewWriter(bytes.NewBuffer(nil)))
+	defer da.Close()
+	da.AddEventListener(EventWebDump, NewHTTPDumpListener(func(_ *Writer, _ TimeSource, dump HTTPDump) {
+		dumps <- dump
+	}))
+
+	transport := NewRoundTripper(da, nil)
+	transport.SetHTTPDump(true)
+	client := &http.Client{Transport: transport}
+	res, err := client.Post(server.URL, "text/plain", strings.NewReader("ping"))
+	assert.Nil(err)
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	assert.Equal("echo ping", string(body))
+
+	request, response := <-dumps, <-dumps
+	assert.Equal(HTTPDumpClientRequest, request.Kind)
+	assert.True(strings.HasSuffix(string(request.Dump), "ping"), string(request.Dump))
+	assert.Equal(HTTPDumpClientResponse, response.Kind)
+	assert.True(strings.HasSuffix(string(response.Dump), "echo ping"), string(response.Dump))
+}
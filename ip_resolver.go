@@ -0,0 +1,255 @@
+package logger
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	// HeaderForwarded is the RFC 7239 forwarding header.
+	HeaderForwarded = "Forwarded"
+	// HeaderXForwardedFor is the de facto forwarding header.
+	HeaderXForwardedFor = "X-Forwarded-For"
+	// HeaderXRealIP is the single client ip header set by some proxies (e.g. nginx).
+	HeaderXRealIP = "X-Real-Ip"
+)
+
+var (
+	// DefaultTrustedHeaders are the headers a resolver with trusted proxies reads the client ip from, in order.
+	DefaultTrustedHeaders = []string{HeaderXForwardedFor, HeaderXRealIP}
+)
+
+var (
+	_defaultIPResolver     = &IPResolver{}
+	_defaultIPResolverLock sync.RWMutex
+)
+
+// DefaultIPResolver returns the resolver used by `GetIP`.
+func DefaultIPResolver() *IPResolver {
+	_defaultIPResolverLock.RLock()
+	defer _defaultIPResolverLock.RUnlock()
+	return _defaultIPResolver
+}
+
+// SetDefaultIPResolver sets the resolver used by `GetIP`.
+func SetDefaultIPResolver(resolver *IPResolver) {
+	_defaultIPResolverLock.Lock()
+	defer _defaultIPResolverLock.Unlock()
+	_defaultIPResolver = resolver
+}
+
+// SetTrustedProxies sets the trusted proxy cidrs (e.g. `10.0.0.0/8`) of the resolver used by `GetIP`.
+func SetTrustedProxies(cidrs ...string) error {
+	resolver, err := NewIPResolver(cidrs...)
+	if err != nil {
+		return err
+	}
+	SetDefaultIPResolver(resolver)
+	return nil
+}
+
+// NewIPResolver returns a new client ip resolver that only honors forwarding headers
+// from the given trusted proxy cidrs. Bare ips are treated as single address ranges.
+// It reads the `DefaultTrustedHeaders`; set the headers the proxies actually set with `SetTrustedHeaders`.
+func NewIPResolver(trustedProxies ...string) (*IPResolver, error) {
+	resolver := &IPResolver{trustedHeaders: DefaultTrustedHeaders}
+	for _, cidr := range trustedProxies {
+		if !strings.ContainsRune(cidr, '/') {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr = cidr + "/32"
+			} else {
+				cidr = cidr + "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		resolver.trustedProxies = append(resolver.trustedProxies, network)
+	}
+	return resolver, nil
+}
+
+// IPResolver resolves the client ip of requests.
+// With no trusted proxies configured, forwarding headers are honored from any peer.
+type IPResolver struct {
+	trustedProxies []*net.IPNet
+	trustedHeaders []string
+}
+
+// TrustedProxies returns the trusted proxy ranges.
+func (ir *IPResolver) TrustedProxies() []*net.IPNet { return ir.trustedProxies }
+
+// TrustedHeaders returns the headers the client ip is read from when the peer is a trusted proxy, in order.
+func (ir *IPResolver) TrustedHeaders() []string { return ir.trustedHeaders }
+
+// SetTrustedHeaders sets the headers the client ip is read from when the peer is a trusted proxy, in order,
+// e.g. `HeaderForwarded` for proxies that set the RFC 7239 header. Only set the headers the proxies set (or replace):
+// clients can send the others, and they'd be read as if a proxy had set them.
+func (ir *IPResolver) SetTrustedHeaders(headers ...string) { ir.trustedHeaders = headers }
+
+// IsTrustedProxy returns if an ip is within the trusted proxy ranges.
+func (ir *IPResolver) IsTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	if parsed == nil {
+		return false
+	}
+	for _, network := range ir.trustedProxies {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetIP gets the origin/client ip for a request.
+// With no trusted proxies, the RFC 7239 `Forwarded` header is preferred, then X-FORWARDED-FOR, then X-REAL-IP,
+// then r.RemoteAddr. With trusted proxies, only the trusted headers (see `SetTrustedHeaders`) are read,
+// and only from a trusted proxy.
+func (ir *IPResolver) GetIP(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+
+	remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteIP = r.RemoteAddr
+	}
+
+	if len(ir.trustedProxies) == 0 {
+		if forwarded := ParseForwarded(r.Header.Get(HeaderForwarded)); len(forwarded) > 0 && len(forwarded[0].For) > 0 {
+			return forwarded[0].For
+		}
+		for _, header := range []string{HeaderXForwardedFor, HeaderXRealIP} {
+			if headerVal := r.Header.Get(header); len(headerVal) > 0 {
+				return strings.TrimSpace(strings.SplitN(headerVal, ",", 2)[0])
+			}
+		}
+		return remoteIP
+	}
+
+	if !ir.IsTrustedProxy(remoteIP) {
+		return remoteIP
+	}
+	for _, header := range ir.trustedHeaders {
+		if hops := forwardedHops(r.Header, header); len(hops) > 0 {
+			return ir.clientHop(hops, remoteIP)
+		}
+	}
+	return remoteIP
+}
+
+// clientHop walks a forwarding chain from the nearest hop, skipping our own proxies, and returns the first
+// hop that isn't one. If a hop isn't an ip (e.g. `unknown`), the chain before it can't be trusted,
+// so the nearest trusted hop is returned.
+func (ir *IPResolver) clientHop(hops []string, remoteIP string) string {
+	nearest := remoteIP
+	for x := len(hops) - 1; x >= 0; x-- {
+		if net.ParseIP(hops[x]) == nil {
+			return nearest
+		}
+		if !ir.IsTrustedProxy(hops[x]) || x == 0 {
+			return hops[x]
+		}
+		nearest = hops[x]
+	}
+	return nearest
+}
+
+// forwardedHops returns the client ips of a forwarding header, farthest first. Each line of a header sent
+// more than once is read, as a proxy appending its hop on a line of its own follows the lines a client sent.
+func forwardedHops(header http.Header, name string) []string {
+	value := strings.Join(header[http.CanonicalHeaderKey(name)], ",")
+	if len(value) == 0 {
+		return nil
+	}
+	var hops []string
+	if http.CanonicalHeaderKey(name) == HeaderForwarded {
+		for _, element := range ParseForwarded(value) {
+			hops = append(hops, element.For)
+		}
+		return hops
+	}
+	for _, hop := range strings.Split(value, ",") {
+		hops = append(hops, strings.TrimSpace(hop))
+	}
+	return hops
+}
+
+// ForwardedElement is a single hop of an RFC 7239 `Forwarded` header.
+type ForwardedElement struct {
+	For   string
+	By    string
+	Proto string
+	Host  string
+}
+
+// ParseForwarded parses an RFC 7239 `Forwarded` header value into its elements, nearest client first.
+// Ports and ipv6 brackets are stripped from the `for` and `by` node identifiers, and identifiers that
+// aren't ips (`unknown` and obfuscated identifiers like `_hidden`) are left empty.
+func ParseForwarded(header string) []ForwardedElement {
+	if len(header) == 0 {
+		return nil
+	}
+	var elements []ForwardedElement
+	for _, rawElement := range splitUnquoted(header, ',') {
+		var element ForwardedElement
+		for _, pair := range splitUnquoted(rawElement, ';') {
+			parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			value := strings.Trim(strings.TrimSpace(parts[1]), `"`)
+			switch strings.ToLower(parts[0]) {
+			case "for":
+				element.For = forwardedNode(value)
+			case "by":
+				element.By = forwardedNode(value)
+			case "proto":
+				element.Proto = strings.ToLower(value)
+			case "host":
+				element.Host = value
+			}
+		}
+		elements = append(elements, element)
+	}
+	return elements
+}
+
+// splitUnquoted splits a header value around a separator that isn't within a quoted string, e.g. `for="a,b"`.
+func splitUnquoted(value string, separator byte) []string {
+	var parts []string
+	var quoted, escaped bool
+	start := 0
+	for x := 0; x < len(value); x++ {
+		switch c := value[x]; {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c == separator:
+			parts = append(parts, value[start:x])
+			start = x + 1
+		}
+	}
+	return append(parts, value[start:])
+}
+
+// forwardedNode strips the port and ipv6 brackets from a node identifier, returning "" if it isn't an ip.
+func forwardedNode(value string) string {
+	if strings.HasPrefix(value, "[") {
+		if end := strings.IndexRune(value, ']'); end > 0 {
+			value = value[1:end]
+		}
+	} else if strings.Count(value, ":") == 1 {
+		value = value[:strings.IndexRune(value, ':')]
+	}
+	if net.ParseIP(value) == nil {
+		return ""
+	}
+	return value
+}
@@ -0,0 +1,111 @@
+package logger
+
+import (
+	"net/http"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestIPResolverTrustedProxies(t *testing.T) {
+	assert := assert.New(t)
+
+	resolver, err := NewIPResolver("10.0.0.0/8", "192.168.1.1")
+	assert.Nil(err)
+	assert.True(resolver.IsTrustedProxy("10.1.2.3"))
+	assert.True(resolver.IsTrustedProxy("192.168.1.1"))
+	assert.False(resolver.IsTrustedProxy("192.168.1.2"))
+
+	hdr := http.Header{}
+	hdr.Set("X-Forwarded-For", "6.6.6.6")
+	assert.Equal("1.2.3.4", resolver.GetIP(&http.Request{Header: hdr, RemoteAddr: "1.2.3.4:1234"}))
+
+	hdr = http.Header{}
+	hdr.Set("X-Forwarded-For", "6.6.6.6, 5.5.5.5, 10.0.0.2")
+	assert.Equal("5.5.5.5", resolver.GetIP(&http.Request{Header: hdr, RemoteAddr: "10.0.0.1:1234"}))
+
+	hdr = http.Header{}
+	hdr.Set("X-Forwarded-For", "10.0.0.3, 10.0.0.2")
+	assert.Equal("10.0.0.3", resolver.GetIP(&http.Request{Header: hdr, RemoteAddr: "10.0.0.1:1234"}))
+
+	// a client's own line comes first, the trusted proxy appends its hop on a line of its own.
+	hdr = http.Header{}
+	hdr.Add("X-Forwarded-For", "9.9.9.9")
+	hdr.Add("X-Forwarded-For", "5.5.5.5")
+	assert.Equal("5.5.5.5", resolver.GetIP(&http.Request{Header: hdr, RemoteAddr: "10.0.0.1:1234"}))
+
+	hdr = http.Header{}
+	hdr.Set("X-Real-Ip", "5.5.5.5")
+	assert.Equal("5.5.5.5", resolver.GetIP(&http.Request{Header: hdr, RemoteAddr: "10.0.0.1:1234"}))
+
+	_, err = NewIPResolver("not an ip")
+	assert.NotNil(err)
+}
+
+func TestSetTrustedProxies(t *testing.T) {
+	assert := assert.New(t)
+
+	defer SetDefaultIPResolver(DefaultIPResolver())
+	assert.Nil(SetTrustedProxies("10.0.0.0/8"))
+
+	hdr := http.Header{}
+	hdr.Set("X-Forwarded-For", "6.6.6.6")
+	assert.Equal("1.2.3.4", GetIP(&http.Request{Header: hdr, RemoteAddr: "1.2.3.4:1234"}))
+	assert.Equal("6.6.6.6", GetIP(&http.Request{Header: hdr, RemoteAddr: "10.0.0.1:1234"}))
+}
+
+func TestParseForwarded(t *testing.T) {
+	assert := assert.New(t)
+
+	elements := ParseForwarded(`for=192.0.2.60;proto=HTTP;by=203.0.113.43, for="[2001:db8:cafe::17]:4711";host=example.com`)
+	assert.Len(elements, 2)
+	assert.Equal("192.0.2.60", elements[0].For)
+	assert.Equal("203.0.113.43", elements[0].By)
+	assert.Equal("http", elements[0].Proto)
+	assert.Equal("2001:db8:cafe::17", elements[1].For)
+	assert.Equal("example.com", elements[1].Host)
+	assert.Empty(ParseForwarded(""))
+}
+
+func TestIPResolverForwarded(t *testing.T) {
+	assert := assert.New(t)
+
+	hdr := http.Header{}
+	hdr.Set("Forwarded", "for=5.5.5.5:1234, for=10.0.0.2")
+	hdr.Set("X-Forwarded-For", "6.6.6.6")
+	assert.Equal("5.5.5.5", (&IPResolver{}).GetIP(&http.Request{Header: hdr}))
+
+	resolver, err := NewIPResolver("10.0.0.0/8")
+	assert.Nil(err)
+	// the proxy sets x-forwarded-for, so the client's forwarded header is ignored.
+	assert.Equal("6.6.6.6", resolver.GetIP(&http.Request{Header: hdr, RemoteAddr: "10.0.0.1:1234"}))
+	assert.Equal("1.2.3.4", resolver.GetIP(&http.Request{Header: hdr, RemoteAddr: "1.2.3.4:1234"}))
+
+	resolver.SetTrustedHeaders(HeaderForwarded)
+	assert.Equal("5.5.5.5", resolver.GetIP(&http.Request{Header: hdr, RemoteAddr: "10.0.0.1:1234"}))
+
+	hdr = http.Header{}
+	hdr.Set("Forwarded", "for=5.5.5.5, for=unknown, for=10.0.0.2")
+	assert.Equal("10.0.0.2", resolver.GetIP(&http.Request{Header: hdr, RemoteAddr: "10.0.0.1:1234"}))
+
+	hdr = http.Header{}
+	hdr.Set("Forwarded", "for=_hidden")
+	assert.Equal("10.0.0.1", resolver.GetIP(&http.Request{Header: hdr, RemoteAddr: "10.0.0.1:1234"}))
+}
+
+func TestParseForwardedNodes(t *testing.T) {
+	assert := assert.New(t)
+
+	elements := ParseForwarded(`for=2001:db8::17, for=unknown, for=_hidden, for="192.0.2.60:80"`)
+	assert.Len(elements, 4)
+	assert.Equal("2001:db8::17", elements[0].For)
+	assert.Empty(elements[1].For)
+	assert.Empty(elements[2].For)
+	assert.Equal("192.0.2.60", elements[3].For)
+
+	elements = ParseForwarded(`for="5.5.5.5,6.6.6.6";host="a;b", for=10.0.0.2`)
+	assert.Len(elements, 2)
+	assert.Empty(elements[0].For)
+	assert.Equal("a;b", elements[0].Host)
+	assert.Equal("10.0.0.2", elements[1].For)
+}
@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultKubernetesPodInfoPath is where the downward API volume is conventionally mounted.
+	DefaultKubernetesPodInfoPath = "/etc/podinfo"
+
+	// KubernetesFieldPod is the field the pod name is stamped as.
+	KubernetesFieldPod = "k8s.pod"
+	// KubernetesFieldNamespace is the field the pod namespace is stamped as.
+	KubernetesFieldNamespace = "k8s.namespace"
+	// KubernetesFieldNode is the field the node name is stamped as.
+	KubernetesFieldNode = "k8s.node"
+	// KubernetesFieldLabelPrefix prefixes the fields pod labels are stamped as, e.g. `k8s.label.app`.
+	KubernetesFieldLabelPrefix = "k8s.label."
+)
+
+// KubernetesMetadata is the workload a process runs as, stamped on output with `Writer.EnrichWithKubernetes`.
+type KubernetesMetadata struct {
+	Pod       string
+	Namespace string
+	Node      string
+	Labels    map[string]string
+}
+
+// KubernetesMetadataFromEnvironment reads the pod's metadata from the environment (`POD_NAME`, `POD_NAMESPACE`
+// and `NODE_NAME`, set from the downward API) and the downward API volume at `LOG_K8S_PODINFO_PATH`
+// (or `DefaultKubernetesPodInfoPath`), whose `name`, `namespace`, `nodename` and `labels` files fill anything unset.
+func KubernetesMetadataFromEnvironment() KubernetesMetadata {
+	podInfoPath := os.Getenv(EnvironmentVariableKubernetesPodInfoPath)
+	if len(podInfoPath) == 0 {
+		podInfoPath = DefaultKubernetesPodInfoPath
+	}
+	metadata := ReadKubernetesMetadata(podInfoPath)
+	if pod := os.Getenv(EnvironmentVariableKubernetesPodName); len(pod) > 0 {
+		metadata.Pod = pod
+	}
+	if namespace := os.Getenv(EnvironmentVariableKubernetesNamespace); len(namespace) > 0 {
+		metadata.Namespace = namespace
+	}
+	if node := os.Getenv(EnvironmentVariableKubernetesNodeName); len(node) > 0 {
+		metadata.Node = node
+	}
+	return metadata
+}
+
+// ReadKubernetesMetadata reads the pod's metadata from the `name`, `namespace`, `nodename` and `labels`
+// files of a downward API volume; missing files are skipped.
+func ReadKubernetesMetadata(podInfoPath string) KubernetesMetadata {
+	return KubernetesMetadata{
+		Pod:       readPodInfoFile(podInfoPath, "name"),
+		Namespace: readPodInfoFile(podInfoPath, "namespace"),
+		Node:      readPodInfoFile(podInfoPath, "nodename"),
+		Labels:    ParseDownwardAPILabels(readPodInfoFile(podInfoPath, "labels")),
+	}
+}
+
+func readPodInfoFile(podInfoPath, name string) string {
+	contents, err := ioutil.ReadFile(filepath.Join(podInfoPath, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(contents))
+}
+
+// ParseDownwardAPILabels parses the downward API's `key="value"` label lines.
+func ParseDownwardAPILabels(contents string) map[string]string {
+	labels := map[string]string{}
+	for _, line := range strings.Split(contents, "\n") {
+		key, value, hasValue := strings.Cut(strings.TrimSpace(line), "=")
+		if !hasValue || len(key) == 0 {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		labels[key] = value
+	}
+	return labels
+}
+
+// IsEmpty returns if no metadata was found, e.g. outside a cluster.
+func (km KubernetesMetadata) IsEmpty() bool {
+	return len(km.Pod) == 0 && len(km.Namespace) == 0 && len(km.Node) == 0 && len(km.Labels) == 0
+}
+
+// Fields returns the metadata as fields, e.g. `k8s.pod`, `k8s.namespace`, `k8s.node` and `k8s.label.app`.
+func (km KubernetesMetadata) Fields() map[string]interface{} {
+	fields := map[string]interface{}{}
+	if len(km.Pod) > 0 {
+		fields[KubernetesFieldPod] = km.Pod
+	}
+	if len(km.Namespace) > 0 {
+		fields[KubernetesFieldNamespace] = km.Namespace
+	}
+	if len(km.Node) > 0 {
+		fields[KubernetesFieldNode] = km.Node
+	}
+	for key, value := range km.Labels {
+		fields[KubernetesFieldLabelPrefix+key] = value
+	}
+	return fields
+}
+
+// EnrichWithKubernetes adds the metadata's fields to the writer's static fields, so every line carries the workload.
+func (wr *Writer) EnrichWithKubernetes(metadata KubernetesMetadata) {
+	fields := map[string]interface{}{}
+	for key, value := range wr.fields {
+		fields[key] = value
+	}
+	for key, value := range metadata.Fields() {
+		fields[key] = value
+	}
+	wr.SetStaticFields(fields)
+}
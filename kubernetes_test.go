@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestParseDownwardAPILabels(t *testing.T) {
+	assert := assert.New(t)
+
+	labels := ParseDownwardAPILabels("app=\"checkout\"\npod-template-hash=\"5d8f\"\n\nbad line\n")
+	assert.Len(labels, 2)
+	assert.Equal("checkout", labels["app"])
+	assert.Equal("5d8f", labels["pod-template-hash"])
+}
+
+func TestKubernetesMetadataFromEnvironment(t *testing.T) {
+	assert := assert.New(t)
+
+	podInfo, err := ioutil.TempDir("", "podinfo")
+	assert.Nil(err)
+	defer os.RemoveAll(podInfo)
+	assert.Nil(ioutil.WriteFile(filepath.Join(podInfo, "name"), []byte("checkout-5d8f-x2\n"), 0644))
+	assert.Nil(ioutil.WriteFile(filepath.Join(podInfo, "namespace"), []byte("shop\n"), 0644))
+	assert.Nil(ioutil.WriteFile(filepath.Join(podInfo, "labels"), []byte("app=\"checkout\"\n"), 0644))
+
+	defer os.Unsetenv(EnvironmentVariableKubernetesPodInfoPath)
+	defer os.Unsetenv(EnvironmentVariableKubernetesNodeName)
+	os.Setenv(EnvironmentVariableKubernetesPodInfoPath, podInfo)
+	os.Setenv(EnvironmentVariableKubernetesNodeName, "node-1")
+
+	metadata := KubernetesMetadataFromEnvironment()
+	assert.False(metadata.IsEmpty())
+	assert.Equal("checkout-5d8f-x2", metadata.Pod)
+	assert.Equal("shop", metadata.Namespace)
+	assert.Equal("node-1", metadata.Node)
+
+	fields := metadata.Fields()
+	assert.Equal("checkout", fields["k8s.label.app"])
+	assert.Equal("node-1", fields[KubernetesFieldNode])
+
+	assert.True(ReadKubernetesMetadata(filepath.Join(podInfo, "missing")).IsEmpty())
+}
+
+func TestWriterEnrichWithKubernetes(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	writer := NewWriter(buffer)
+	writer.SetStaticFields(map[string]interface{}{"service": "checkout"})
+	writer.EnrichWithKubernetes(KubernetesMetadata{Pod: "checkout-1", Namespace: "shop", Labels: map[string]string{"app": "checkout"}})
+	writer.Printf("hello")
+
+	assert.True(strings.Contains(buffer.String(), "k8s.label.app=checkout k8s.namespace=shop k8s.pod=checkout-1 service=checkout hello"), buffer.String())
+}
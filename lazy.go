@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Lazy returns a message argument (or field value) that's computed only when it's formatted, i.e. when the event
+// is written, so expensive values cost nothing for disabled, sampled out or dropped events.
+// e.g. `da.Debugf("cart: %s", logger.Lazy(func() interface{} { return cart.Dump() }))`.
+// The value is computed at most once; listeners receive the `*LazyValue` and can call `Value`.
+func Lazy(compute func() interface{}) *LazyValue {
+	return &LazyValue{compute: compute}
+}
+
+// LazyValue is a deferred argument, see `Lazy`.
+type LazyValue struct {
+	compute func() interface{}
+	once    sync.Once
+	value   interface{}
+}
+
+// Value computes (once) and returns the value.
+func (lv *LazyValue) Value() interface{} {
+	lv.once.Do(func() {
+		if lv.compute != nil {
+			lv.value = lv.compute()
+		}
+	})
+	return lv.value
+}
+
+// Format implements fmt.Formatter, formatting the computed value with the same verb and flags.
+func (lv *LazyValue) Format(state fmt.State, verb rune) {
+	fmt.Fprintf(state, fmt.FormatString(state, verb), lv.Value())
+}
@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestLazy(t *testing.T) {
+	assert := assert.New(t)
+
+	var computed int32
+	value := Lazy(func() interface{} {
+		atomic.AddInt32(&computed, 1)
+		return 3.14159
+	})
+	assert.Zero(atomic.LoadInt32(&computed))
+	assert.Equal("3.14", fmt.Sprintf("%.2f", value))
+	assert.Equal("pi=3.14159", NewWriter(bytes.NewBuffer(nil)).FormatFields(map[string]interface{}{"pi": value}))
+	assert.Equal(int32(1), atomic.LoadInt32(&computed))
+}
+
+func TestAgentLazyArgs(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventInfo), NewWriter(buffer))
+	defer da.Close()
+	da.Writer().SetSanitizeControlChars(true)
+
+	var computed int32
+	expensive := func() interface{} {
+		atomic.AddInt32(&computed, 1)
+		return "expensive\nvalue"
+	}
+
+	da.Debugf("disabled %s", Lazy(expensive))
+
+	sampling := NewAdaptiveSampling(EventInfo)
+	sampling.SetInterval(time.Hour)
+	sampling.SetMinRate(0)
+	da.StartAdaptiveSampling(sampling)
+	for x := 0; x < 64; x++ {
+		sampling.adjust(sampling.HighWater(), 0)
+	}
+	da.Infof("sampled out %s", Lazy(expensive))
+	da.StopAdaptiveSampling()
+
+	da.Infof("written %s", Lazy(expensive))
+	da.Flush()
+
+	assert.Equal(int32(1), atomic.LoadInt32(&computed))
+	assert.True(strings.Contains(buffer.String(), `written expensive\nvalue`), buffer.String())
+}
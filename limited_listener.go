@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ListenerOverflowPolicy is what a limited listener does with an event when it's at its concurrency limit.
+type ListenerOverflowPolicy int
+
+const (
+	// ListenerOverflowQueue queues the event; it's handled by whichever invocation finishes next.
+	ListenerOverflowQueue ListenerOverflowPolicy = iota
+	// ListenerOverflowDrop drops the event.
+	ListenerOverflowDrop
+)
+
+const (
+	// DefaultLimitedListenerMaxQueued is the default number of events a limited listener queues before dropping.
+	DefaultLimitedListenerMaxQueued = 1 << 10
+)
+
+type listenerInvocation struct {
+	writer    *Writer
+	ts        TimeSource
+	eventFlag EventFlag
+	state     []interface{}
+}
+
+// NewLimitedListener returns a listener that caps the number of concurrent invocations of an inner listener,
+// e.g. an http exporter limited to 2 requests in flight. Register it with `AddEventListener(flag, limited.Listen)`.
+func NewLimitedListener(listener EventListener, maxConcurrent int, policy ListenerOverflowPolicy) *LimitedListener {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	return &LimitedListener{
+		listener:      listener,
+		maxConcurrent: maxConcurrent,
+		maxQueued:     DefaultLimitedListenerMaxQueued,
+		policy:        policy,
+	}
+}
+
+// LimitedListener wraps a listener with a concurrency limit.
+// Queued events don't hold a worker; they're handled by the invocations already in flight as they finish.
+type LimitedListener struct {
+	listener      EventListener
+	maxConcurrent int
+	maxQueued     int
+	policy        ListenerOverflowPolicy
+
+	syncRoot sync.Mutex
+	inFlight int
+	queued   []listenerInvocation
+	dropped  int64
+}
+
+// MaxQueued returns the number of events queued (with `ListenerOverflowQueue`) before events are dropped.
+func (ll *LimitedListener) MaxQueued() int { return ll.maxQueued }
+
+// SetMaxQueued sets the number of events queued (with `ListenerOverflowQueue`) before events are dropped.
+func (ll *LimitedListener) SetMaxQueued(maxQueued int) { ll.maxQueued = maxQueued }
+
+// InFlight returns the number of invocations in flight.
+func (ll *LimitedListener) InFlight() int {
+	ll.syncRoot.Lock()
+	defer ll.syncRoot.Unlock()
+	return ll.inFlight
+}
+
+// Dropped returns the number of events dropped because the listener was at its limit.
+func (ll *LimitedListener) Dropped() int64 {
+	return atomic.LoadInt64(&ll.dropped)
+}
+
+// Listen is the EventListener.
+func (ll *LimitedListener) Listen(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+	invocation := listenerInvocation{writer: writer, ts: ts, eventFlag: eventFlag, state: state}
+
+	ll.syncRoot.Lock()
+	if ll.inFlight >= ll.maxConcurrent {
+		if ll.policy == ListenerOverflowQueue && len(ll.queued) < ll.maxQueued {
+			invocation.state = append([]interface{}{}, state...)
+			ll.queued = append(ll.queued, invocation)
+		} else {
+			atomic.AddInt64(&ll.dropped, 1)
+		}
+		ll.syncRoot.Unlock()
+		return
+	}
+	ll.inFlight++
+	ll.syncRoot.Unlock()
+
+	ll.run(invocation)
+}
+
+// run invokes the listener, then any queued events, until the queue is empty.
+func (ll *LimitedListener) run(invocation listenerInvocation) {
+	defer func() {
+		if r := recover(); r != nil {
+			ll.syncRoot.Lock()
+			ll.inFlight--
+			ll.syncRoot.Unlock()
+			panic(r)
+		}
+	}()
+
+	for {
+		ll.listener(invocation.writer, invocation.ts, invocation.eventFlag, invocation.state...)
+
+		ll.syncRoot.Lock()
+		if len(ll.queued) == 0 {
+			ll.inFlight--
+			ll.syncRoot.Unlock()
+			return
+		}
+		invocation = ll.queued[0]
+		ll.queued = ll.queued[1:]
+		ll.syncRoot.Unlock()
+	}
+}
@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"sync"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestLimitedListener(t *testing.T) {
+	assert := assert.New(t)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var handled []interface{}
+	var handledLock sync.Mutex
+	limited := NewLimitedListener(func(wr *Writer, ts TimeSource, e EventFlag, state ...interface{}) {
+		if state[0] == "first" {
+			close(started)
+			<-release
+		}
+		handledLock.Lock()
+		handled = append(handled, state[0])
+		handledLock.Unlock()
+	}, 1, ListenerOverflowQueue)
+	limited.SetMaxQueued(1)
+
+	done := make(chan struct{})
+	go func() {
+		limited.Listen(nil, TimeNow(), EventInfo, "first")
+		close(done)
+	}()
+	<-started
+	assert.Equal(1, limited.InFlight())
+
+	// these return immediately; one is queued and one is dropped.
+	limited.Listen(nil, TimeNow(), EventInfo, "second")
+	limited.Listen(nil, TimeNow(), EventInfo, "third")
+	assert.Equal(1, limited.Dropped())
+
+	close(release)
+	<-done
+	assert.Equal(0, limited.InFlight())
+	assert.Equal([]interface{}{"first", "second"}, handled)
+}
+
+func TestLimitedListenerDrop(t *testing.T) {
+	assert := assert.New(t)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	limited := NewLimitedListener(func(wr *Writer, ts TimeSource, e EventFlag, state ...interface{}) {
+		close(started)
+		<-release
+	}, 1, ListenerOverflowDrop)
+
+	go limited.Listen(nil, TimeNow(), EventInfo)
+	<-started
+	limited.Listen(nil, TimeNow(), EventInfo)
+	assert.Equal(1, limited.Dropped())
+	close(release)
+}
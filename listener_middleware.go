@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"time"
+)
+
+// ListenerMiddleware wraps a listener with a cross-cutting concern (timing, retries, filtering, enrichment),
+// like http middleware wraps a handler.
+type ListenerMiddleware func(EventListener) EventListener
+
+// ChainListener wraps a listener in middleware, the first middleware being the outermost, e.g.
+// `ChainListener(exporter, ListenerTiming(observe), ListenerRetry(3, time.Second))`.
+func ChainListener(listener EventListener, middleware ...ListenerMiddleware) EventListener {
+	for x := len(middleware) - 1; x >= 0; x-- {
+		listener = middleware[x](listener)
+	}
+	return listener
+}
+
+// ListenerTiming returns middleware that reports how long each invocation of the listener took.
+func ListenerTiming(observe func(eventFlag EventFlag, elapsed time.Duration)) ListenerMiddleware {
+	return func(listener EventListener) EventListener {
+		return func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+			start := time.Now()
+			defer func() { observe(eventFlag, time.Since(start)) }()
+			listener(writer, ts, eventFlag, state...)
+		}
+	}
+}
+
+// ListenerRetry returns middleware that retries the listener when it panics, up to `attempts` invocations
+// in all, sleeping `backoff` (doubled each retry) in between. The last attempt's panic is re-raised,
+// so the agent reports it (see `EventLoggerMeta`).
+func ListenerRetry(attempts int, backoff time.Duration) ListenerMiddleware {
+	return func(listener EventListener) EventListener {
+		return func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+			delay := backoff
+			for attempt := 1; attempt < attempts; attempt++ {
+				if invokeRecovered(listener, writer, ts, eventFlag, state...) == nil {
+					return
+				}
+				time.Sleep(delay)
+				delay *= 2
+			}
+			listener(writer, ts, eventFlag, state...)
+		}
+	}
+}
+
+// invokeRecovered invokes a listener, returning what it panicked with (if anything).
+func invokeRecovered(listener EventListener, writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) (recovered interface{}) {
+	defer func() {
+		recovered = recover()
+	}()
+	listener(writer, ts, eventFlag, state...)
+	return nil
+}
+
+// ListenerFilter returns middleware that only invokes the listener for events the predicate accepts.
+func ListenerFilter(predicate func(eventFlag EventFlag, state ...interface{}) bool) ListenerMiddleware {
+	return func(listener EventListener) EventListener {
+		return func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+			if predicate(eventFlag, state...) {
+				listener(writer, ts, eventFlag, state...)
+			}
+		}
+	}
+}
+
+// ListenerEnrich returns middleware that replaces the state handed to the listener, e.g. to append fields
+// or wrap errors with context.
+func ListenerEnrich(enrich func(eventFlag EventFlag, state ...interface{}) []interface{}) ListenerMiddleware {
+	return func(listener EventListener) EventListener {
+		return func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+			listener(writer, ts, eventFlag, enrich(eventFlag, state...)...)
+		}
+	}
+}
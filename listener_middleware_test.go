@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestChainListener(t *testing.T) {
+	assert := assert.New(t)
+
+	var calls []string
+	trace := func(name string) ListenerMiddleware {
+		return func(listener EventListener) EventListener {
+			return func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+				calls = append(calls, name)
+				listener(writer, ts, eventFlag, state...)
+			}
+		}
+	}
+	listener := ChainListener(func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		calls = append(calls, "listener")
+	}, trace("outer"), trace("inner"))
+
+	listener(nil, TimeNow(), EventInfo)
+	assert.Equal([]string{"outer", "inner", "listener"}, calls)
+}
+
+func TestListenerTiming(t *testing.T) {
+	assert := assert.New(t)
+
+	var observed EventFlag
+	var elapsed time.Duration
+	listener := ChainListener(func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		time.Sleep(time.Millisecond)
+	}, ListenerTiming(func(eventFlag EventFlag, d time.Duration) {
+		observed, elapsed = eventFlag, d
+	}))
+
+	listener(nil, TimeNow(), EventError)
+	assert.Equal(EventError, observed)
+	assert.True(elapsed >= time.Millisecond)
+}
+
+func TestListenerRetry(t *testing.T) {
+	assert := assert.New(t)
+
+	var attempts int
+	flaky := func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		attempts++
+		if attempts < 3 {
+			panic("unavailable")
+		}
+	}
+	ChainListener(flaky, ListenerRetry(3, time.Microsecond))(nil, TimeNow(), EventError)
+	assert.Equal(3, attempts)
+
+	attempts = -10
+	var recovered interface{}
+	func() {
+		defer func() { recovered = recover() }()
+		ChainListener(flaky, ListenerRetry(2, time.Microsecond))(nil, TimeNow(), EventError)
+	}()
+	assert.Equal("unavailable", recovered)
+	assert.Equal(-8, attempts)
+}
+
+func TestListenerFilterAndEnrich(t *testing.T) {
+	assert := assert.New(t)
+
+	var received []interface{}
+	listener := ChainListener(func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		received = state
+	},
+		ListenerFilter(func(eventFlag EventFlag, state ...interface{}) bool { return eventFlag == EventError }),
+		ListenerEnrich(func(eventFlag EventFlag, state ...interface{}) []interface{} {
+			return append([]interface{}{fmt.Errorf("checkout: %v", state[0])}, state[1:]...)
+		}),
+	)
+
+	listener(nil, TimeNow(), EventInfo, "skipped")
+	assert.Nil(received)
+	listener(nil, TimeNow(), EventError, "failed", "extra")
+	assert.Len(received, 2)
+	assert.Equal("checkout: failed", received[0].(error).Error())
+}
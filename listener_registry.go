@@ -0,0 +1,99 @@
+package logger
+
+// listenerRegistry is an immutable set of listeners; changes build a new registry (copy-on-write)
+// so readers can load the current one without taking a lock.
+type listenerRegistry struct {
+	events map[EventFlag][]EventListener
+	debug  []EventListener
+	// inherit is set for derived agents, which also fire the listeners of the agent they were derived from,
+	// except for masked events (see `Agent.MaskListeners`).
+	inherit bool
+	masked  map[EventFlag]bool
+}
+
+func newListenerRegistry() *listenerRegistry {
+	return &listenerRegistry{events: map[EventFlag][]EventListener{}, masked: map[EventFlag]bool{}}
+}
+
+// listeners returns the listeners for an event.
+func (lr *listenerRegistry) listeners(eventFlag EventFlag) []EventListener {
+	if lr == nil {
+		return nil
+	}
+	return lr.events[eventFlag]
+}
+
+// hasListeners returns if there are listeners for an event, or debug listeners (which fire for every event).
+func (lr *listenerRegistry) hasListeners(eventFlag EventFlag) bool {
+	if lr == nil {
+		return false
+	}
+	return len(lr.events[eventFlag]) > 0 || len(lr.debug) > 0
+}
+
+// inherits returns if the listeners of the agent the registry's agent was derived from fire for an event.
+func (lr *listenerRegistry) inherits(eventFlag EventFlag) bool {
+	return lr != nil && lr.inherit && !lr.masked[eventFlag]
+}
+
+// withInherit returns a copy of the registry that does (or doesn't) inherit listeners.
+func (lr *listenerRegistry) withInherit(inherit bool) *listenerRegistry {
+	copied := lr.copy()
+	copied.inherit = inherit
+	return copied
+}
+
+// withMasked returns a copy of the registry with inherited listeners for events masked (or unmasked).
+func (lr *listenerRegistry) withMasked(masked bool, eventFlags ...EventFlag) *listenerRegistry {
+	copied := lr.copy()
+	for _, eventFlag := range eventFlags {
+		if masked {
+			copied.masked[eventFlag] = true
+		} else {
+			delete(copied.masked, eventFlag)
+		}
+	}
+	return copied
+}
+
+// withListener returns a copy of the registry with a listener added for an event.
+func (lr *listenerRegistry) withListener(eventFlag EventFlag, listener EventListener) *listenerRegistry {
+	copied := lr.copy()
+	listeners := make([]EventListener, len(copied.events[eventFlag]), len(copied.events[eventFlag])+1)
+	copy(listeners, copied.events[eventFlag])
+	copied.events[eventFlag] = append(listeners, listener)
+	return copied
+}
+
+// withDebugListener returns a copy of the registry with a listener added for all events.
+func (lr *listenerRegistry) withDebugListener(listener EventListener) *listenerRegistry {
+	copied := lr.copy()
+	debug := make([]EventListener, len(copied.debug), len(copied.debug)+1)
+	copy(debug, copied.debug)
+	copied.debug = append(debug, listener)
+	return copied
+}
+
+// withoutListeners returns a copy of the registry with the listeners for an event removed and inherited ones masked.
+func (lr *listenerRegistry) withoutListeners(eventFlag EventFlag) *listenerRegistry {
+	copied := lr.copy()
+	delete(copied.events, eventFlag)
+	copied.masked[eventFlag] = true
+	return copied
+}
+
+// copy returns a shallow copy of the registry; listener slices are shared, so they must be copied before appending.
+func (lr *listenerRegistry) copy() *listenerRegistry {
+	if lr == nil {
+		return newListenerRegistry()
+	}
+	events := make(map[EventFlag][]EventListener, len(lr.events))
+	for eventFlag, listeners := range lr.events {
+		events[eventFlag] = listeners
+	}
+	masked := make(map[EventFlag]bool, len(lr.masked))
+	for eventFlag := range lr.masked {
+		masked[eventFlag] = true
+	}
+	return &listenerRegistry{events: events, debug: lr.debug, inherit: lr.inherit, masked: masked}
+}
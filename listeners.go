@@ -6,6 +6,7 @@ import (
 )
 
 // EventListener is a listener for a specific event as given by its flag.
+// The state slice is only valid for the duration of the call; listeners that keep it must copy it.
 type EventListener func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{})
 
 // ErrorListener is a handler for error events.
@@ -110,16 +111,32 @@ func NewRequestBodyListener(listener RequestBodyListener) EventListener {
 // ResponseListener is a handler for response body events.
 type ResponseListener func(writer *Writer, ts TimeSource, body []byte)
 
-// NewResponseListener creates a new listener for response body events.
+// NewResponseListener creates a new listener for response body (`EventWebResponse`) events,
+// e.g. `NewResponseListener(WriteResponseBody)`.
 func NewResponseListener(listener ResponseListener) EventListener {
 	return func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
 		if len(state) < 1 {
 			return
 		}
-		res, err := stateAsBytes(state[0])
+		body, err := stateAsBytes(state[0])
 		if err != nil {
 			return
 		}
-		listener(writer, ts, res)
+		listener(writer, ts, body)
+	}
+}
+
+// NewNamespaceListener returns a listener that dispatches to the listener registered for the event's namespace
+// (the one of the agent that fired it, see `Agent.SetNamespace`, or else the writer's), falling back to
+// `defaultListener` (if set) for unregistered namespaces.
+func NewNamespaceListener(listeners map[string]EventListener, defaultListener EventListener) EventListener {
+	return func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		if listener, hasListener := listeners[writer.namespaceFor(ts)]; hasListener {
+			listener(writer, ts, eventFlag, state...)
+			return
+		}
+		if defaultListener != nil {
+			defaultListener(writer, ts, eventFlag, state...)
+		}
 	}
 }
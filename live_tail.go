@@ -0,0 +1,180 @@
+package logger
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultLiveTailPath is the path the live tail handler is conventionally mounted at.
+	DefaultLiveTailPath = "/debug/logs"
+
+	// DefaultLiveTailBufferSize is the number of events buffered per client before events are skipped for it.
+	DefaultLiveTailBufferSize = 256
+
+	// LiveTailFlagsParam is the query parameter holding the csv of flags to tail, e.g. `?flags=error,web.request`.
+	LiveTailFlagsParam = "flags"
+)
+
+// LiveTailAuthorizer returns if a request may tail the agent's events.
+type LiveTailAuthorizer func(req *http.Request) bool
+
+// NewLiveTailHandler returns an http handler that streams an agent's events to clients as server-sent events,
+// e.g. `http.Handle(logger.DefaultLiveTailPath, logger.NewLiveTailHandler(agent, authorizer))`.
+// Only server-sent events are supported, there's no websocket endpoint. Requests are refused unless the authorizer
+// allows them. Lines are redacted as the writer's output is: secrets by its scanner (see `Writer.SetSecretScanner`)
+// and headers masked (see `Writer.SetMaskedHeaders`). Only enabled events are streamed; once a client has
+// connected the handler's (debug) listener stays registered, so each enabled event triggers listeners.
+func NewLiveTailHandler(agent *Agent, authorizer LiveTailAuthorizer) *LiveTailHandler {
+	return &LiveTailHandler{
+		agent:       agent,
+		authorizer:  authorizer,
+		bufferSize:  DefaultLiveTailBufferSize,
+		subscribers: map[*liveTailSubscriber]bool{},
+	}
+}
+
+// LiveTailHandler streams events to http clients, see `NewLiveTailHandler`.
+type LiveTailHandler struct {
+	sync.Mutex
+	agent       *Agent
+	authorizer  LiveTailAuthorizer
+	bufferSize  int
+	subscribers map[*liveTailSubscriber]bool
+	registered  bool
+}
+
+type liveTailSubscriber struct {
+	flags  *EventFlagSet
+	events chan liveTailEvent
+}
+
+type liveTailEvent struct {
+	flag EventFlag
+	line string
+}
+
+// BufferSize returns the number of events buffered per client.
+func (lt *LiveTailHandler) BufferSize() int { return lt.bufferSize }
+
+// SetBufferSize sets the number of events buffered per client; events are skipped for clients that fall behind.
+func (lt *LiveTailHandler) SetBufferSize(bufferSize int) { lt.bufferSize = bufferSize }
+
+// ServeHTTP implements http.Handler.
+func (lt *LiveTailHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if lt.authorizer == nil || !lt.authorizer(req) {
+		http.Error(res, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	flusher, isFlusher := res.(http.Flusher)
+	if !isFlusher {
+		http.Error(res, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	flags := NewEventFlagSetAll()
+	if flagCSV := req.URL.Query().Get(LiveTailFlagsParam); len(flagCSV) > 0 {
+		flags = NewEventFlagSetFromCSV(flagCSV)
+	}
+	subscriber := &liveTailSubscriber{flags: flags, events: make(chan liveTailEvent, lt.bufferSize)}
+	lt.subscribe(subscriber)
+	defer lt.unsubscribe(subscriber)
+
+	res.Header().Set("Content-Type", "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case event := <-subscriber.events:
+			if _, err := fmt.Fprintf(res, "event: %s\ndata: %s\n\n", event.flag, event.line); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
+
+// Subscribers returns the number of connected clients.
+func (lt *LiveTailHandler) Subscribers() int {
+	lt.Lock()
+	defer lt.Unlock()
+	return len(lt.subscribers)
+}
+
+func (lt *LiveTailHandler) subscribe(subscriber *liveTailSubscriber) {
+	lt.Lock()
+	defer lt.Unlock()
+	if !lt.registered {
+		lt.agent.AddDebugListener(lt.listener)
+		lt.registered = true
+	}
+	lt.subscribers[subscriber] = true
+}
+
+func (lt *LiveTailHandler) unsubscribe(subscriber *liveTailSubscriber) {
+	lt.Lock()
+	delete(lt.subscribers, subscriber)
+	lt.Unlock()
+}
+
+// listener fans events out to the subscribers that want them, skipping subscribers whose buffer is full.
+func (lt *LiveTailHandler) listener(writer *Writer, _ TimeSource, eventFlag EventFlag, state ...interface{}) {
+	lt.Lock()
+	defer lt.Unlock()
+	var line string
+	for subscriber := range lt.subscribers {
+		if !subscriber.flags.IsEnabled(eventFlag) {
+			continue
+		}
+		if len(line) == 0 {
+			line = formatLiveTailLine(writer, state...)
+		}
+		select {
+		case subscriber.events <- liveTailEvent{flag: eventFlag, line: line}:
+		default:
+		}
+	}
+}
+
+// formatLiveTailLine renders an event's state as a single line, with headers masked and secrets redacted.
+func formatLiveTailLine(writer *Writer, state ...interface{}) string {
+	if len(state) == 0 {
+		return ""
+	}
+	var line string
+	switch typed := state[0].(type) {
+	case string:
+		line = fmt.Sprintf(typed, writer.SanitizeArgs(typed, maskLiveTailHeaders(writer, state[1:])...)...)
+	case error:
+		line = fmt.Sprintf("%+v", typed)
+	case *http.Request:
+		line = typed.Method + " " + writer.FormatRequestURI(typed)
+		if len(state) > 1 {
+			line += " " + fmt.Sprint(maskLiveTailHeaders(writer, state[1:])...)
+		}
+	default:
+		line = fmt.Sprint(maskLiveTailHeaders(writer, state)...)
+	}
+	return writer.SecretScanner().ScanString(liveTailLineReplacer.Replace(writer.Sanitize(line)))
+}
+
+// maskLiveTailHeaders returns an event's state with header collections formatted with their sensitive values masked.
+func maskLiveTailHeaders(writer *Writer, state []interface{}) []interface{} {
+	masked := make([]interface{}, len(state))
+	for x, value := range state {
+		masked[x] = value
+		if header, isHeader := value.(http.Header); isHeader {
+			masked[x] = writer.FormatHeaders(header)
+		}
+	}
+	return masked
+}
+
+// liveTailLineReplacer keeps multi-line messages on a single `data:` line.
+var liveTailLineReplacer = strings.NewReplacer("\r", " ", "\n", " ")
@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestLiveTailHandlerUnauthorized(t *testing.T) {
+	assert := assert.New(t)
+
+	da := None()
+	defer da.Close()
+
+	res := httptest.NewRecorder()
+	NewLiveTailHandler(da, nil).ServeHTTP(res, httptest.NewRequest("GET", DefaultLiveTailPath, nil))
+	assert.Equal(http.StatusForbidden, res.Code)
+
+	res = httptest.NewRecorder()
+	NewLiveTailHandler(da, func(req *http.Request) bool { return false }).ServeHTTP(res, httptest.NewRequest("GET", DefaultLiveTailPath, nil))
+	assert.Equal(http.StatusForbidden, res.Code)
+}
+
+func TestLiveTailHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	da := NewWithWriter(NewEventFlagSet(EventInfo, EventError), NewWriter(bytes.NewBuffer(nil)))
+	defer da.Close()
+
+	handler := NewLiveTailHandler(da, func(req *http.Request) bool { return req.Header.Get("X-Token") == "secret" })
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequest("GET", server.URL+DefaultLiveTailPath+"?flags=error", nil)
+	req.Header.Set("X-Token", "secret")
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	assert.Nil(err)
+	defer res.Body.Close()
+	assert.Equal(http.StatusOK, res.StatusCode)
+	assert.Equal("text/event-stream", res.Header.Get("Content-Type"))
+
+	for handler.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	da.Infof("not tailed")
+	da.Errorf("multi\nline %s", "failure")
+
+	reader := bufio.NewReader(res.Body)
+	event, _ := reader.ReadString('\n')
+	data, _ := reader.ReadString('\n')
+	assert.Equal("event: error\n", event)
+	assert.True(strings.HasPrefix(data, "data: multi"), data)
+	assert.True(strings.HasSuffix(data, "line failure\n"), data)
+
+	cancel()
+	for handler.Subscribers() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	da.Flush()
+}
+
+func TestFormatLiveTailLineRedacted(t *testing.T) {
+	assert := assert.New(t)
+
+	writer := NewWriter(bytes.NewBuffer(nil))
+	writer.SetSecretScanner(NewSecretScanner())
+	assert.Nil(writer.SecretScanner().AddPatternString(`sk_live_[a-z0-9]+`))
+
+	assert.Equal("charging with "+RedactedValue+" failed",
+		formatLiveTailLine(writer, "charging with %s failed", "sk_live_abc123"))
+	assert.Equal("headers: Authorization=\"Bearer "+RedactedValue+"\"",
+		formatLiveTailLine(writer, "headers: %v", http.Header{"Authorization": []string{"Bearer token"}}))
+	assert.Equal("key "+RedactedValue, formatLiveTailLine(writer, errors.New("key sk_live_abc123")))
+}
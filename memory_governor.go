@@ -0,0 +1,181 @@
+package logger
+
+import (
+	"math"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultMemoryGovernorInterval is how often the governor checks memory.
+	DefaultMemoryGovernorInterval = 5 * time.Second
+
+	// DefaultMemoryGovernorRestoreRatio is the fraction of the limits usage must fall under before restoring.
+	DefaultMemoryGovernorRestoreRatio = 0.8
+)
+
+var (
+	// DefaultMemoryGovernorEvents are the (verbose, body capturing) events disabled while degraded.
+	DefaultMemoryGovernorEvents = []EventFlag{EventDebug, EventWebRequestPostBody, EventWebResponse, EventWebDump}
+)
+
+// NewMemoryGovernor returns a governor that degrades logging while the process heap (in bytes) or the agent's
+// queue depth (in events) is over its limit; a zero limit isn't checked.
+// While degraded the governor's events (`DefaultMemoryGovernorEvents` by default) are disabled and
+// an adaptive sampler (see `StartAdaptiveSampling`), if one is started, drops to its min rate.
+// It restores the events once usage falls under the restore ratio of the limits.
+func NewMemoryGovernor(heapLimit uint64, queueLimit int) *MemoryGovernor {
+	return &MemoryGovernor{
+		heapLimit:    heapLimit,
+		queueLimit:   queueLimit,
+		interval:     DefaultMemoryGovernorInterval,
+		restoreRatio: DefaultMemoryGovernorRestoreRatio,
+		events:       DefaultMemoryGovernorEvents,
+		readHeap:     readHeapInUse,
+	}
+}
+
+// MemoryGovernor degrades logging under memory pressure, see `NewMemoryGovernor`.
+type MemoryGovernor struct {
+	sync.Mutex
+	heapLimit    uint64
+	queueLimit   int
+	interval     time.Duration
+	restoreRatio float64
+	events       []EventFlag
+	readHeap     func() uint64
+
+	degraded bool
+	disabled []EventFlag
+}
+
+// Interval returns how often memory is checked.
+func (mg *MemoryGovernor) Interval() time.Duration { return mg.interval }
+
+// SetInterval sets how often memory is checked.
+func (mg *MemoryGovernor) SetInterval(interval time.Duration) { mg.interval = interval }
+
+// RestoreRatio returns the fraction of the limits usage must fall under before restoring.
+func (mg *MemoryGovernor) RestoreRatio() float64 { return mg.restoreRatio }
+
+// SetRestoreRatio sets the fraction of the limits usage must fall under before restoring.
+func (mg *MemoryGovernor) SetRestoreRatio(ratio float64) { mg.restoreRatio = ratio }
+
+// Events returns the events disabled while degraded.
+func (mg *MemoryGovernor) Events() []EventFlag { return mg.events }
+
+// SetEvents sets the events disabled while degraded.
+func (mg *MemoryGovernor) SetEvents(events ...EventFlag) { mg.events = events }
+
+// Degraded returns if the governor is currently degrading logging.
+func (mg *MemoryGovernor) Degraded() bool {
+	mg.Lock()
+	defer mg.Unlock()
+	return mg.degraded
+}
+
+// check degrades or restores the agent based on the current heap and queue depth.
+func (mg *MemoryGovernor) check(agent *Agent) {
+	heap, depth := mg.readHeap(), agent.QueueDepth()
+	over := (mg.heapLimit > 0 && heap > mg.heapLimit) || (mg.queueLimit > 0 && depth > mg.queueLimit)
+	under := (mg.heapLimit == 0 || float64(heap) < float64(mg.heapLimit)*mg.restoreRatio) &&
+		(mg.queueLimit == 0 || float64(depth) < float64(mg.queueLimit)*mg.restoreRatio)
+
+	mg.Lock()
+	defer mg.Unlock()
+	if over && !mg.degraded {
+		mg.degraded = true
+		mg.disabled = nil
+		for _, event := range mg.events {
+			if agent.IsEnabled(event) {
+				agent.DisableEvent(event)
+				mg.disabled = append(mg.disabled, event)
+			}
+		}
+		if sampling := agent.AdaptiveSampling(); sampling != nil {
+			atomic.StoreUint64(&sampling.rate, math.Float64bits(sampling.minRate))
+		}
+		agent.Metaf("memory pressure (heap %s, queue depth %s); degrading logging, disabled events: %s",
+			File.FormatSizeWithUnits(int(heap), SizeUnitsBinary, 1), FormatCount(int64(depth)), formatEventFlags(mg.disabled))
+		return
+	}
+	if under && mg.degraded {
+		mg.degraded = false
+		for _, event := range mg.disabled {
+			agent.EnableEvent(event)
+		}
+		agent.Metaf("memory pressure subsided (heap %s, queue depth %s); restored events: %s",
+			File.FormatSizeWithUnits(int(heap), SizeUnitsBinary, 1), FormatCount(int64(depth)), formatEventFlags(mg.disabled))
+		mg.disabled = nil
+	}
+}
+
+// MemoryGovernor returns the agent's memory governor, if one is started.
+func (da *Agent) MemoryGovernor() *MemoryGovernor {
+	if da == nil {
+		return nil
+	}
+	root := da.root()
+	root.eventsLock.Lock()
+	defer root.eventsLock.Unlock()
+	return root.governor
+}
+
+// StartMemoryGovernor starts checking memory on the governor's interval (see `NewMemoryGovernor`).
+// The governor acts on the root agent; it replaces any governor already started.
+func (da *Agent) StartMemoryGovernor(governor *MemoryGovernor) {
+	root := da.root()
+	root.StopMemoryGovernor()
+
+	stop := make(chan struct{})
+	root.eventsLock.Lock()
+	root.governor = governor
+	root.governorStop = stop
+	root.eventsLock.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(governor.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				governor.check(root)
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopMemoryGovernor stops checking memory; events disabled by the governor stay disabled until re-enabled.
+func (da *Agent) StopMemoryGovernor() {
+	root := da.root()
+	root.eventsLock.Lock()
+	defer root.eventsLock.Unlock()
+	if root.governorStop != nil {
+		close(root.governorStop)
+		root.governorStop = nil
+	}
+	root.governor = nil
+}
+
+// readHeapInUse returns the bytes in in-use heap spans.
+func readHeapInUse() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse
+}
+
+func formatEventFlags(events []EventFlag) string {
+	if len(events) == 0 {
+		return "none"
+	}
+	flags := make([]string, len(events))
+	for x, event := range events {
+		flags[x] = string(event)
+	}
+	return strings.Join(flags, ", ")
+}
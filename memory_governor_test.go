@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestMemoryGovernorCheck(t *testing.T) {
+	assert := assert.New(t)
+
+	meta := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventInfo, EventDebug, EventWebResponse), NewWriter(bytes.NewBuffer(nil)))
+	da.SetMetaOutput(meta)
+	defer da.Close()
+
+	sampling := NewAdaptiveSampling(EventInfo)
+	sampling.SetInterval(time.Hour)
+	sampling.SetMinRate(0.1)
+	da.StartAdaptiveSampling(sampling)
+
+	var heap uint64 = 10
+	governor := NewMemoryGovernor(100, 0)
+	governor.readHeap = func() uint64 { return heap }
+
+	governor.check(da)
+	assert.False(governor.Degraded())
+	assert.True(da.IsEnabled(EventDebug))
+
+	heap = 200
+	governor.check(da)
+	assert.True(governor.Degraded())
+	assert.False(da.IsEnabled(EventDebug))
+	assert.False(da.IsEnabled(EventWebResponse))
+	assert.True(da.IsEnabled(EventInfo))
+	assert.Equal(0.1, sampling.Rate())
+	assert.True(strings.Contains(meta.String(), "degrading logging, disabled events: debug, web.response"), meta.String())
+
+	heap = 90
+	governor.check(da)
+	assert.True(governor.Degraded(), "usage must fall under the restore ratio before restoring")
+
+	heap = 50
+	governor.check(da)
+	assert.False(governor.Degraded())
+	assert.True(da.IsEnabled(EventDebug))
+	assert.True(da.IsEnabled(EventWebResponse))
+	assert.False(da.IsEnabled(EventWebRequestPostBody), "events disabled before degrading stay disabled")
+	assert.True(strings.Contains(meta.String(), "restored events: debug, web.response"), meta.String())
+}
+
+func TestAgentStartMemoryGovernor(t *testing.T) {
+	assert := assert.New(t)
+
+	da := NewWithWriter(NewEventFlagSet(EventDebug), NewWriter(bytes.NewBuffer(nil)))
+	da.SetMetaOutput(bytes.NewBuffer(nil))
+	defer da.Close()
+
+	governor := NewMemoryGovernor(1, 0)
+	governor.SetInterval(time.Millisecond)
+	da.Clone().StartMemoryGovernor(governor)
+	assert.Equal(governor, da.MemoryGovernor())
+
+	deadline := time.Now().Add(time.Second)
+	for !governor.Degraded() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.True(governor.Degraded())
+	assert.False(da.IsEnabled(EventDebug))
+
+	da.StopMemoryGovernor()
+	assert.Nil(da.MemoryGovernor())
+}
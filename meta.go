@@ -0,0 +1,144 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// EventLoggerMeta fires when the agent itself has a problem (write failures, listener panics, queue saturation).
+	// Meta events are always written to the agent's meta output (stderr by default), regardless of verbosity.
+	EventLoggerMeta EventFlag = "logger.meta"
+
+	// DefaultMetaSaturationInterval is the minimum time between queue saturation meta events.
+	DefaultMetaSaturationInterval = time.Second
+)
+
+// MetaReporter reports a problem as a meta message, e.g. an agent's `Metaf`.
+type MetaReporter func(format string, args ...interface{})
+
+// OutputMetaReporter is implemented by outputs with problems that don't fail writes (e.g. a `FileOutput` failing
+// to prune its archive files), and by outputs wrapping other outputs. Agents report them for their writer's outputs.
+type OutputMetaReporter interface {
+	SetMetaReporter(reporter MetaReporter)
+}
+
+// SetOutputMetaReporter sets how an output reports problems that don't fail writes (if it's an `OutputMetaReporter`).
+func SetOutputMetaReporter(output io.Writer, reporter MetaReporter) {
+	if metaReporter, isMetaReporter := output.(OutputMetaReporter); isMetaReporter {
+		metaReporter.SetMetaReporter(reporter)
+	}
+}
+
+// MetaOutput returns the output meta events are written to.
+func (da *Agent) MetaOutput() io.Writer {
+	return da.metaOutput
+}
+
+// SetMetaOutput sets the output meta events are written to; it should not depend on the agent's writer.
+func (da *Agent) SetMetaOutput(output io.Writer) {
+	da.metaOutput = output
+}
+
+// Metaf reports a problem with the agent itself.
+// It writes synchronously to the meta output and fires any `EventLoggerMeta` listeners in the calling goroutine.
+func (da *Agent) Metaf(format string, args ...interface{}) {
+	if da == nil {
+		return
+	}
+	ts := TimeNow()
+	message := fmt.Sprintf(format, args...)
+	if da.metaOutput != nil {
+		fmt.Fprintf(da.metaOutput, "%s [%s] %s\n", ts.UTCNow().Format(DefaultTimeFormat), EventLoggerMeta, message)
+	}
+	if da.IsEnabled(EventLoggerMeta) && da.HasListener(EventLoggerMeta) {
+		for agent := da; agent != nil; agent = agent.listenerParentFor(EventLoggerMeta) {
+			for _, listener := range agent.loadListeners().listeners(EventLoggerMeta) {
+				da.invokeMetaListener(listener, ts, message)
+			}
+		}
+	}
+}
+
+// enqueue adds an action to the event queue, reporting if the queue is saturated.
+// The action state is expected to start with the time source and event flag.
+// Priority events (see `SetPriorityEvents`) skip ahead of other queued events and are never dropped.
+// The action owns the state (see `acquireState`), so dropped events release it here.
+func (da *Agent) enqueue(action func(...interface{}) error, args ...interface{}) {
+	if orderedQueue, ordering := da.orderedQueueAndOrdering(); orderedQueue != nil {
+		orderedQueue.Enqueue(orderingKey(ordering, args...), action, args...)
+		return
+	}
+	if len(args) > 1 {
+		if eventFlag, err := stateAsEventFlag(args[1]); err == nil && da.IsPriorityEvent(eventFlag) {
+			da.eventQueue.EnqueuePriority(action, args...)
+			return
+		}
+	}
+	if maxWorkItems := da.eventQueue.MaxWorkItems(); maxWorkItems > 0 && da.eventQueue.Len() >= maxWorkItems {
+		dropWhenSaturated := da.DropWhenSaturated()
+		now := time.Now().UnixNano()
+		last := atomic.LoadInt64(&da.lastSaturationReport)
+		if now-last >= int64(DefaultMetaSaturationInterval) && atomic.CompareAndSwapInt64(&da.lastSaturationReport, last, now) {
+			if dropWhenSaturated {
+				da.Metaf("event queue saturated (%d items); dropping events", da.eventQueue.Len())
+			} else {
+				da.Metaf("event queue saturated (%d items); enqueuing will block", da.eventQueue.Len())
+			}
+		}
+		if dropWhenSaturated {
+			if len(args) > 1 {
+				if eventFlag, err := stateAsEventFlag(args[1]); err == nil {
+					da.recordDropped(eventFlag)
+				}
+			}
+			releaseState(args)
+			return
+		}
+	}
+	da.eventQueue.Enqueue(action, args...)
+}
+
+// invokeListener calls a listener, reporting (rather than propagating) panics.
+func (da *Agent) invokeListener(listener EventListener, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+	if da.ProfilerLabels() {
+		withProfilerLabels(eventFlag, listener, func(context.Context) { da.invokeListenerUnlabeled(listener, ts, eventFlag, state...) })
+		return
+	}
+	da.invokeListenerUnlabeled(listener, ts, eventFlag, state...)
+}
+
+func (da *Agent) invokeListenerUnlabeled(listener EventListener, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			da.Metaf("listener for `%s` panicked: %v", eventFlag, r)
+		}
+	}()
+	listener(da.writerFor(eventFlag), ts, eventFlag, state...)
+}
+
+func (da *Agent) invokeMetaListener(listener EventListener, ts TimeSource, message string) {
+	defer func() {
+		if r := recover(); r != nil && da.metaOutput != nil {
+			fmt.Fprintf(da.metaOutput, "%s [%s] meta listener panicked: %v\n", ts.UTCNow().Format(DefaultTimeFormat), EventLoggerMeta, r)
+		}
+	}()
+	listener(da.Writer(), ts, EventLoggerMeta, message)
+}
+
+// onWriteError reports a write error, and counts the event as dropped.
+func (da *Agent) onWriteError(err error, actionState ...interface{}) error {
+	if err != nil {
+		atomic.AddInt64(&da.counters.writeErrors, 1)
+		da.Metaf("write failed: %v", err)
+		if len(actionState) > 1 {
+			if eventFlag, flagErr := stateAsEventFlag(actionState[1]); flagErr == nil {
+				da.recordDropped(eventFlag)
+			}
+		}
+	}
+	return err
+}
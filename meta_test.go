@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+type errorOutput struct{}
+
+func (eo errorOutput) Write(b []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestAgentMetaListenerPanic(t *testing.T) {
+	assert := assert.New(t)
+
+	meta := bytes.NewBuffer(nil)
+	da := New(NewEventFlagSetAll())
+	defer da.Close()
+	da.SetMetaOutput(meta)
+
+	da.AddEventListener(EventInfo, func(wr *Writer, ts TimeSource, e EventFlag, state ...interface{}) {
+		panic("oh no")
+	})
+	da.Sync().OnEvent(EventInfo)
+	assert.True(strings.Contains(meta.String(), "[logger.meta] listener for `info` panicked: oh no"), meta.String())
+}
+
+func TestAgentMetaWriteFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	meta := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSetAll(), NewWriter(errorOutput{}))
+	defer da.Close()
+	da.SetMetaOutput(meta)
+
+	var message string
+	da.AddEventListener(EventLoggerMeta, func(wr *Writer, ts TimeSource, e EventFlag, state ...interface{}) {
+		message = state[0].(string)
+	})
+
+	da.Sync().Infof("hello")
+	assert.True(strings.Contains(meta.String(), "write failed: disk full"), meta.String())
+	assert.Equal("write failed: disk full", message)
+}
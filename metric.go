@@ -0,0 +1,177 @@
+package logger
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// EventMetric fires for metrics (counters, gauges and timers), see `Agent.Counter`.
+	EventMetric EventFlag = "metric"
+)
+
+// MetricType is the kind of a metric.
+type MetricType string
+
+const (
+	// MetricTypeCounter is a count to add to a counter.
+	MetricTypeCounter MetricType = "c"
+	// MetricTypeGauge is the current value of a gauge.
+	MetricTypeGauge MetricType = "g"
+	// MetricTypeTimer is a timing, in milliseconds.
+	MetricTypeTimer MetricType = "ms"
+)
+
+// Metric is the state of an `EventMetric` event.
+type Metric struct {
+	Name  string
+	Type  MetricType
+	Value float64
+	// Tags are `key:value` (or bare) tags, written by dogstatsd sinks.
+	Tags []string
+}
+
+// Counter fires a metric event adding a value to a counter.
+func (da *Agent) Counter(name string, value int64, tags ...string) {
+	if da == nil {
+		return
+	}
+	da.OnEvent(EventMetric, Metric{Name: name, Type: MetricTypeCounter, Value: float64(value), Tags: tags})
+}
+
+// Gauge fires a metric event setting a gauge.
+func (da *Agent) Gauge(name string, value float64, tags ...string) {
+	if da == nil {
+		return
+	}
+	da.OnEvent(EventMetric, Metric{Name: name, Type: MetricTypeGauge, Value: value, Tags: tags})
+}
+
+// Timer fires a metric event recording a timing.
+func (da *Agent) Timer(name string, elapsed time.Duration, tags ...string) {
+	if da == nil {
+		return
+	}
+	da.OnEvent(EventMetric, Metric{Name: name, Type: MetricTypeTimer, Value: Milliseconds(elapsed), Tags: tags})
+}
+
+// MetricListener is a listener for metric events.
+type MetricListener func(writer *Writer, ts TimeSource, metric Metric)
+
+// NewMetricListener returns a new handler for metric events.
+func NewMetricListener(listener MetricListener) EventListener {
+	return func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		if len(state) < 1 {
+			return
+		}
+		if metric, isMetric := state[0].(Metric); isMetric {
+			listener(writer, ts, metric)
+		}
+	}
+}
+
+// NewStatsdSink returns a sink that sends metric events to a statsd server over udp,
+// e.g. `sink.Register(agent)`. Metric names are prefixed with `prefix` (e.g. `myapp.`).
+func NewStatsdSink(addr, prefix string) (*StatsdSink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &StatsdSink{conn: conn, prefix: prefix}, nil
+}
+
+// StatsdSink sends metrics to a statsd (or dogstatsd) server.
+type StatsdSink struct {
+	sync.Mutex
+	conn      net.Conn
+	prefix    string
+	dogstatsd bool
+}
+
+// Dogstatsd returns if tags are sent (in the dogstatsd format).
+func (ss *StatsdSink) Dogstatsd() bool {
+	ss.Lock()
+	defer ss.Unlock()
+	return ss.dogstatsd
+}
+
+// SetDogstatsd sets if tags are sent in the dogstatsd format, e.g. `requests:1|c|#route:/users,status:200`.
+func (ss *StatsdSink) SetDogstatsd(dogstatsd bool) {
+	ss.Lock()
+	ss.dogstatsd = dogstatsd
+	ss.Unlock()
+}
+
+// Register adds the sink's listener for `EventMetric` to an agent.
+func (ss *StatsdSink) Register(agent *Agent) {
+	agent.AddEventListener(EventMetric, ss.Listener())
+}
+
+// Listener returns a listener that sends metric events, with secrets in their names and tags redacted
+// by the writer's secret scanner (see `Writer.SetSecretScanner`).
+func (ss *StatsdSink) Listener() EventListener {
+	return NewMetricListener(func(writer *Writer, _ TimeSource, metric Metric) {
+		ss.Send(redactMetric(writer.SecretScanner(), metric))
+	})
+}
+
+// redactMetric returns a metric with secrets in its name and tags redacted.
+func redactMetric(scanner *SecretScanner, metric Metric) Metric {
+	if scanner == nil {
+		return metric
+	}
+	metric.Name = scanner.ScanString(metric.Name)
+	if len(metric.Tags) > 0 {
+		tags := make([]string, len(metric.Tags))
+		for x, tag := range metric.Tags {
+			tags[x] = scanner.ScanString(tag)
+		}
+		metric.Tags = tags
+	}
+	return metric
+}
+
+// Send sends a metric as a single packet.
+func (ss *StatsdSink) Send(metric Metric) error {
+	ss.Lock()
+	defer ss.Unlock()
+	_, err := ss.conn.Write(ss.format(metric))
+	return err
+}
+
+// Close closes the connection.
+func (ss *StatsdSink) Close() error {
+	return ss.conn.Close()
+}
+
+// format formats a metric in the statsd line protocol.
+func (ss *StatsdSink) format(metric Metric) []byte {
+	line := bytes.NewBuffer(nil)
+	line.WriteString(ss.prefix)
+	line.WriteString(statsdNameReplacer.Replace(metric.Name))
+	line.WriteRune(':')
+	line.WriteString(strconv.FormatFloat(metric.Value, 'f', -1, 64))
+	line.WriteRune('|')
+	line.WriteString(string(metric.Type))
+	if ss.dogstatsd && len(metric.Tags) > 0 {
+		line.WriteString("|#")
+		for x, tag := range metric.Tags {
+			if x > 0 {
+				line.WriteRune(',')
+			}
+			line.WriteString(statsdTagReplacer.Replace(tag))
+		}
+	}
+	return line.Bytes()
+}
+
+var (
+	// statsdNameReplacer replaces the characters that delimit the statsd line protocol in metric names.
+	statsdNameReplacer = strings.NewReplacer(":", "_", "|", "_", "\n", "_", "#", "_", ",", "_", "@", "_")
+	// statsdTagReplacer replaces the characters that delimit the dogstatsd line protocol in tags.
+	statsdTagReplacer = strings.NewReplacer("|", "_", "\n", "_", "#", "_", ",", "_", "@", "_")
+)
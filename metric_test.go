@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestAgentMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	da := NewWithWriter(NewEventFlagSet(EventMetric), NewWriter(bytes.NewBuffer(nil)))
+	defer da.Close()
+
+	metrics := make(chan Metric, 3)
+	da.AddEventListener(EventMetric, NewMetricListener(func(_ *Writer, _ TimeSource, metric Metric) {
+		metrics <- metric
+	}))
+	da.Sync().OnEvent(EventMetric, Metric{Name: "sync", Type: MetricTypeCounter, Value: 1})
+	assert.Equal("sync", (<-metrics).Name)
+
+	da.Counter("requests", 2, "route:/users")
+	da.Flush()
+	assert.Equal(Metric{Name: "requests", Type: MetricTypeCounter, Value: 2, Tags: []string{"route:/users"}}, <-metrics)
+
+	da.Timer("latency", 1500*time.Microsecond)
+	da.Flush()
+	assert.Equal(Metric{Name: "latency", Type: MetricTypeTimer, Value: 1.5}, <-metrics)
+}
+
+func TestStatsdSink(t *testing.T) {
+	assert := assert.New(t)
+
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(err)
+	defer server.Close()
+
+	sink, err := NewStatsdSink(server.LocalAddr().String(), "app.")
+	assert.Nil(err)
+	defer sink.Close()
+
+	da := NewWithWriter(NewEventFlagSet(EventMetric), NewWriter(bytes.NewBuffer(nil)))
+	defer da.Close()
+	sink.Register(da)
+
+	packet := make([]byte, 512)
+	server.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	da.Gauge("queue:depth", 12.5, "shard:1")
+	n, _, err := server.ReadFrom(packet)
+	assert.Nil(err)
+	assert.Equal("app.queue_depth:12.5|g", string(packet[:n]))
+
+	sink.SetDogstatsd(true)
+	da.Counter("requests", 1, "route:/users", "status:200")
+	n, _, err = server.ReadFrom(packet)
+	assert.Nil(err)
+	assert.Equal("app.requests:1|c|#route:/users,status:200", string(packet[:n]))
+	da.Flush()
+}
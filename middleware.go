@@ -0,0 +1,414 @@
+package logger
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderRequestID is the default header used to read and propagate request (correlation) ids.
+	HeaderRequestID = "X-Request-ID"
+
+	// MaxRequestIDLength is the longest inbound request id that's accepted; longer ids are replaced.
+	MaxRequestIDLength = 128
+)
+
+// RequestValueProvider returns a value (route, user, tenant etc.) for a request.
+type RequestValueProvider func(req *http.Request) string
+
+// NewMiddleware returns a new http middleware that logs requests to an agent.
+func NewMiddleware(agent *Agent) *Middleware {
+	return &Middleware{
+		agent:            agent,
+		requestIDHeader:  HeaderRequestID,
+		httpDumpMaxBytes: DefaultHTTPDumpMaxBytes,
+		curlHeaders:      DefaultCurlHeaders,
+
+		bodyCaptureMaxBytes: DefaultBodyCaptureMaxBytes,
+	}
+}
+
+// Middleware is an http middleware that fires request events for each request,
+// and stores a request agent in the request context for handlers (see `ForRequest`).
+// The request agent carries the request id and the W3C trace context of the request.
+type Middleware struct {
+	agent           *Agent
+	requestIDHeader string
+
+	routeProvider  RequestValueProvider
+	userProvider   RequestValueProvider
+	tenantProvider RequestValueProvider
+	tailSampling   *TailSampling
+
+	httpDump         bool
+	httpDumpMaxBytes int
+	curlRepro        bool
+	curlHeaders      []string
+
+	requestBodyCapture  bool
+	bodyCaptureMaxBytes int
+
+	bodyCaptureContentTypes         []string
+	bodyCaptureExcludedContentTypes []string
+
+	skippedPaths        map[string]bool
+	skippedPathPrefixes []string
+	skippedPathPatterns []*regexp.Regexp
+
+	requestLogStatusCode int
+	requestLogLatency    time.Duration
+	requestCounters      []EventListener
+
+	slowRequestThreshold time.Duration
+	errorOn5xx           bool
+	responseHeaders      []string
+	requestDetails       []RequestDetail
+}
+
+// ResponseStatusError is the error logged for a 5xx response the handler didn't log an error for,
+// see `Middleware.SetErrorOn5xx`. Its path is escaped (see `url.URL.EscapedPath`), so it can't carry control characters.
+type ResponseStatusError struct {
+	Method     string
+	Path       string
+	StatusCode int
+}
+
+// Error implements error.
+func (rse ResponseStatusError) Error() string {
+	return rse.Method + " " + rse.Path + " responded " + strconv.Itoa(rse.StatusCode) + " " + http.StatusText(rse.StatusCode)
+}
+
+// Agent returns the agent requests are logged to.
+func (m *Middleware) Agent() *Agent { return m.agent }
+
+// RequestIDHeader returns the header request ids are read from and written to.
+func (m *Middleware) RequestIDHeader() string { return m.requestIDHeader }
+
+// SetRequestIDHeader sets the header request ids are read from and written to.
+func (m *Middleware) SetRequestIDHeader(header string) { m.requestIDHeader = header }
+
+// SetRouteProvider sets the provider for the matched route of a request.
+func (m *Middleware) SetRouteProvider(provider RequestValueProvider) { m.routeProvider = provider }
+
+// SetUserProvider sets the provider for the user of a request.
+func (m *Middleware) SetUserProvider(provider RequestValueProvider) { m.userProvider = provider }
+
+// SetTenantProvider sets the provider for the tenant of a request.
+func (m *Middleware) SetTenantProvider(provider RequestValueProvider) { m.tenantProvider = provider }
+
+// TailSampling returns the tail sampling policy for requests, if any.
+func (m *Middleware) TailSampling() *TailSampling { return m.tailSampling }
+
+// SetTailSampling sets a policy that buffers each request's detailed events (fired through its request agent)
+// and only writes them for requests that fail or are slow.
+func (m *Middleware) SetTailSampling(sampling *TailSampling) { m.tailSampling = sampling }
+
+// HTTPDump returns if full request and response dumps are fired (see `EventWebDump`).
+func (m *Middleware) HTTPDump() bool { return m.httpDump }
+
+// SetHTTPDump sets if full request and response dumps (headers and capped bodies) are fired as `EventWebDump`
+// events, while that event is enabled.
+func (m *Middleware) SetHTTPDump(httpDump bool) { m.httpDump = httpDump }
+
+// HTTPDumpMaxBytes returns the number of body bytes included in dumps.
+func (m *Middleware) HTTPDumpMaxBytes() int { return m.httpDumpMaxBytes }
+
+// SetHTTPDumpMaxBytes sets the number of body bytes included in dumps.
+func (m *Middleware) SetHTTPDumpMaxBytes(maxBytes int) { m.httpDumpMaxBytes = maxBytes }
+
+// CurlRepro returns if curl commands reproducing failed requests are fired (see `EventWebRequestCurl`).
+func (m *Middleware) CurlRepro() bool { return m.curlRepro }
+
+// SetCurlRepro sets if a curl command reproducing the request is fired as an `EventWebRequestCurl` event
+// (while that event is enabled) for requests with a 5xx response, or whose request agent fired an error.
+// The command includes the request body if it was captured (see `SetHTTPDump` and `SetRequestBodyCapture`).
+func (m *Middleware) SetCurlRepro(curlRepro bool) { m.curlRepro = curlRepro }
+
+// CurlHeaders returns the request headers included in curl commands.
+func (m *Middleware) CurlHeaders() []string { return m.curlHeaders }
+
+// SetCurlHeaders sets the request headers included in curl commands; sensitive values are still masked.
+func (m *Middleware) SetCurlHeaders(headers ...string) { m.curlHeaders = headers }
+
+// RequestBodyCapture returns if request bodies are captured as the handler reads them
+// (see `EventWebRequestPostBody`).
+func (m *Middleware) RequestBodyCapture() bool { return m.requestBodyCapture }
+
+// SetRequestBodyCapture sets if the request body is captured (up to `BodyCaptureMaxBytes`) as the handler
+// reads it and fired as an `EventWebRequestPostBody` event once the handler returns, while that event is enabled.
+// The body is streamed through to the handler, so large uploads aren't buffered twice.
+func (m *Middleware) SetRequestBodyCapture(capture bool) { m.requestBodyCapture = capture }
+
+// BodyCaptureMaxBytes returns the number of body bytes captured.
+func (m *Middleware) BodyCaptureMaxBytes() int { return m.bodyCaptureMaxBytes }
+
+// SetBodyCaptureMaxBytes sets the number of body bytes captured.
+func (m *Middleware) SetBodyCaptureMaxBytes(maxBytes int) { m.bodyCaptureMaxBytes = maxBytes }
+
+// BodyCaptureContentTypes returns the content types bodies are captured for (all if empty).
+func (m *Middleware) BodyCaptureContentTypes() []string { return m.bodyCaptureContentTypes }
+
+// SetBodyCaptureContentTypes restricts request body capture and the bodies in http dumps to the given content types,
+// e.g. `SetBodyCaptureContentTypes("application/json", "text/*")`. Bodies without a content type are then left out.
+func (m *Middleware) SetBodyCaptureContentTypes(contentTypes ...string) {
+	m.bodyCaptureContentTypes = contentTypes
+}
+
+// BodyCaptureExcludedContentTypes returns the content types bodies are never captured for.
+func (m *Middleware) BodyCaptureExcludedContentTypes() []string {
+	return m.bodyCaptureExcludedContentTypes
+}
+
+// SetBodyCaptureExcludedContentTypes sets content types bodies are never captured for, e.g. binary uploads
+// (see `DefaultBodyCaptureExcludedContentTypes`). Exclusions win over `SetBodyCaptureContentTypes`.
+func (m *Middleware) SetBodyCaptureExcludedContentTypes(contentTypes ...string) {
+	m.bodyCaptureExcludedContentTypes = contentTypes
+}
+
+// capturesBody returns if a body with the given content type is captured.
+func (m *Middleware) capturesBody(contentType string) bool {
+	return capturesContentType(contentType, m.bodyCaptureContentTypes, m.bodyCaptureExcludedContentTypes)
+}
+
+// SkippedPaths returns the paths requests aren't logged for.
+func (m *Middleware) SkippedPaths() []string {
+	paths := make([]string, 0, len(m.skippedPaths))
+	for path := range m.skippedPaths {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// SetSkippedPaths sets exact paths requests aren't logged for, e.g. `SetSkippedPaths("/healthz", "/readyz")`.
+// Skipped requests still get a request agent (and request id) for the handler, but fire no request events.
+func (m *Middleware) SetSkippedPaths(paths ...string) {
+	m.skippedPaths = map[string]bool{}
+	for _, path := range paths {
+		m.skippedPaths[path] = true
+	}
+}
+
+// SkippedPathPrefixes returns the path prefixes requests aren't logged for.
+func (m *Middleware) SkippedPathPrefixes() []string { return m.skippedPathPrefixes }
+
+// SetSkippedPathPrefixes sets path prefixes requests aren't logged for, e.g. `SetSkippedPathPrefixes("/metrics")`.
+func (m *Middleware) SetSkippedPathPrefixes(prefixes ...string) { m.skippedPathPrefixes = prefixes }
+
+// SkippedPathPatterns returns the path patterns requests aren't logged for.
+func (m *Middleware) SkippedPathPatterns() []*regexp.Regexp { return m.skippedPathPatterns }
+
+// AddSkippedPathPattern adds a pattern for paths requests aren't logged for.
+func (m *Middleware) AddSkippedPathPattern(pattern *regexp.Regexp) {
+	m.skippedPathPatterns = append(m.skippedPathPatterns, pattern)
+}
+
+// AddSkippedPathPatternString compiles and adds a pattern for paths requests aren't logged for,
+// e.g. `AddSkippedPathPatternString("^/debug/pprof/")`.
+func (m *Middleware) AddSkippedPathPatternString(pattern string) error {
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	m.AddSkippedPathPattern(compiled)
+	return nil
+}
+
+// Skips returns if requests for a path aren't logged.
+func (m *Middleware) Skips(path string) bool {
+	if m.skippedPaths[path] {
+		return true
+	}
+	for _, prefix := range m.skippedPathPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	for _, pattern := range m.skippedPathPatterns {
+		if pattern.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestLogThreshold returns the status code and latency a request must meet for `EventWebRequest` to fire,
+// see `SetRequestLogThreshold`.
+func (m *Middleware) RequestLogThreshold() (statusCode int, latency time.Duration) {
+	return m.requestLogStatusCode, m.requestLogLatency
+}
+
+// SetRequestLogThreshold sets the middleware to only fire `EventWebRequest` for requests with a status code
+// of at least `statusCode` (e.g. 400), or slower than `latency` (if positive). A zero status code fires it for
+// every request. Aggregators added with `AddRequestCounter` still see every request.
+func (m *Middleware) SetRequestLogThreshold(statusCode int, latency time.Duration) {
+	m.requestLogStatusCode = statusCode
+	m.requestLogLatency = latency
+}
+
+// RequestCounters returns the listeners handed every completed request.
+func (m *Middleware) RequestCounters() []EventListener { return m.requestCounters }
+
+// AddRequestCounter adds a listener handed every completed request (with `EventWebRequest` state), whether or not
+// the event fires, e.g. `AddRequestCounter(rollup.Listener())`. Counters are called inline, so must be cheap;
+// register them here instead of on the agent to avoid counting requests twice.
+func (m *Middleware) AddRequestCounter(listener EventListener) {
+	m.requestCounters = append(m.requestCounters, listener)
+}
+
+// SlowRequestThreshold returns the duration past which `EventWebRequestSlow` fires.
+func (m *Middleware) SlowRequestThreshold() time.Duration { return m.slowRequestThreshold }
+
+// SetSlowRequestThreshold sets the duration past which an `EventWebRequestSlow` event fires for a request,
+// while that event is enabled. A zero threshold disables slow request events.
+func (m *Middleware) SetSlowRequestThreshold(threshold time.Duration) {
+	m.slowRequestThreshold = threshold
+}
+
+// ErrorOn5xx returns if an error is logged for 5xx responses the handler didn't log one for.
+func (m *Middleware) ErrorOn5xx() bool { return m.errorOn5xx }
+
+// SetErrorOn5xx sets if an `EventError` (a `ResponseStatusError`, with the request attached) is logged through
+// the request agent when the handler writes a 5xx status without logging an error itself, so silent failures
+// are still captured.
+func (m *Middleware) SetErrorOn5xx(errorOn5xx bool) { m.errorOn5xx = errorOn5xx }
+
+// ResponseHeaders returns the response headers fired as `EventWebResponseHeaders` events.
+func (m *Middleware) ResponseHeaders() []string { return m.responseHeaders }
+
+// SetResponseHeaders sets the response headers (e.g. `DefaultResponseHeaders`, or `Cache-Control` and custom ones)
+// fired as an `EventWebResponseHeaders` event once the handler returns, while that event is enabled.
+// With no headers set, the event isn't fired; sensitive values are still masked.
+func (m *Middleware) SetResponseHeaders(headers ...string) { m.responseHeaders = headers }
+
+// RequestDetails returns the request details included in request complete events.
+func (m *Middleware) RequestDetails() []RequestDetail { return m.requestDetails }
+
+// SetRequestDetails sets details (e.g. `RequestDetailReferer`, `RequestDetailUserAgent` and `RequestDetailHost`) included
+// in `EventWebRequest` events, as fields after the usual state; write them with `NewRequestCompleteListener(WriteRequestComplete)`.
+func (m *Middleware) SetRequestDetails(details ...RequestDetail) { m.requestDetails = details }
+
+// logsRequest returns if `EventWebRequest` fires for a completed request, see `SetRequestLogThreshold`.
+func (m *Middleware) logsRequest(statusCode int, elapsed time.Duration) bool {
+	if m.requestLogStatusCode == 0 || statusCode >= m.requestLogStatusCode {
+		return true
+	}
+	return m.requestLogLatency > 0 && elapsed > m.requestLogLatency
+}
+
+// Handler wraps an http.Handler.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(m.HandlerFunc(next.ServeHTTP))
+}
+
+// HandlerFunc wraps an http.HandlerFunc.
+func (m *Middleware) HandlerFunc(next http.HandlerFunc) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+
+		ra := m.newRequestAgent(req)
+		req = req.WithContext(WithRequestAgent(req.Context(), ra))
+		ra.req = req
+		res.Header().Set(m.requestIDHeader, ra.RequestID())
+		if m.Skips(req.URL.Path) {
+			ra.SetTailSampling(nil)
+			next(res, req)
+			return
+		}
+
+		ra.OnEvent(EventWebRequestStart, req)
+		ra.OnEvent(EventWebRequestHeaders, req)
+		rw := NewResponseWriter(res)
+		capturesRequestBody := m.capturesBody(req.Header.Get("Content-Type"))
+		httpDump := m.httpDump && m.agent.IsEnabled(EventWebDump)
+		if httpDump {
+			dumpMaxBytes := m.httpDumpMaxBytes
+			if !capturesRequestBody {
+				dumpMaxBytes = 0
+			}
+			var dump HTTPDump
+			dump, ra.body = dumpRequest(req, dumpMaxBytes, m.agent.Writer().MaskedHeaders()...)
+			ra.OnEvent(EventWebDump, dump)
+			rw.SetBodyCapture(m.httpDumpMaxBytes)
+		}
+		var requestBody *cappedBuffer
+		if m.requestBodyCapture && capturesRequestBody && m.agent.IsEnabled(EventWebRequestPostBody) {
+			req.Body, requestBody = teeBody(req.Body, m.bodyCaptureMaxBytes)
+		}
+		next(rw, req)
+		elapsed := time.Now().Sub(start)
+		if requestBody != nil && requestBody.buffer.Len() > 0 {
+			ra.OnEvent(EventWebRequestPostBody, requestBody.buffer.Bytes())
+			if ra.body == nil {
+				ra.body = requestBody.buffer.Bytes()
+			}
+		}
+		if httpDump {
+			ra.OnEvent(EventWebDump, dumpResponseWriter(req, rw, m.capturesBody(rw.Header().Get("Content-Type")), m.agent.Writer().MaskedHeaders()...))
+		}
+		if len(m.responseHeaders) > 0 && m.agent.IsEnabled(EventWebResponseHeaders) {
+			ra.OnEvent(EventWebResponseHeaders, req, rw.StatusCode(), FilterHeaders(rw.Header(), m.responseHeaders...))
+		}
+		if m.errorOn5xx && rw.StatusCode() >= http.StatusInternalServerError && !ra.Errored() {
+			ra.Error(ResponseStatusError{Method: req.Method, Path: req.URL.EscapedPath(), StatusCode: rw.StatusCode()})
+		}
+		if m.curlRepro && (rw.StatusCode() >= http.StatusInternalServerError || ra.Errored()) {
+			ra.OnEvent(EventWebRequestCurl, CurlRepro{Request: req, StatusCode: rw.StatusCode(), Headers: m.curlHeaders, Body: ra.body})
+		}
+		if m.slowRequestThreshold > 0 && elapsed > m.slowRequestThreshold {
+			ra.OnEvent(EventWebRequestSlow, req, elapsed, m.slowRequestThreshold)
+		}
+		ra.CompleteTailSampling(rw.StatusCode(), elapsed)
+		for _, counter := range m.requestCounters {
+			counter(m.agent.Writer(), ra.now(), EventWebRequest, req, rw.StatusCode(), rw.ContentLength(), elapsed)
+		}
+		if m.logsRequest(rw.StatusCode(), elapsed) {
+			if len(m.requestDetails) > 0 {
+				ra.OnEvent(EventWebRequest, req, rw.StatusCode(), rw.ContentLength(), elapsed, RequestDetailFields(req, m.requestDetails...))
+			} else {
+				ra.OnEvent(EventWebRequest, req, rw.StatusCode(), rw.ContentLength(), elapsed)
+			}
+		}
+	}
+}
+
+func (m *Middleware) newRequestAgent(req *http.Request) *RequestAgent {
+	requestID := req.Header.Get(m.requestIDHeader)
+	if !IsValidRequestID(requestID) {
+		requestID = UUIDv4()
+	}
+	ra := NewRequestAgent(m.agent, req, requestID)
+	ra.SetTraceContext(TraceContextFromRequest(req))
+	ra.SetTailSampling(m.tailSampling)
+	if m.routeProvider != nil {
+		ra.SetRoute(m.routeProvider(req))
+	}
+	if m.userProvider != nil {
+		ra.SetUser(m.userProvider(req))
+	}
+	if m.tenantProvider != nil {
+		ra.SetTenant(m.tenantProvider(req))
+	}
+	return ra
+}
+
+// IsValidRequestID returns if an inbound request id can be logged and echoed back as is: it's not empty,
+// at most `MaxRequestIDLength` long, and only has letters, digits and `-`, `_`, `.`, `:`, `+`, `/` or `=`
+// (which covers uuids, trace ids and base64 ids).
+func IsValidRequestID(requestID string) bool {
+	if len(requestID) == 0 || len(requestID) > MaxRequestIDLength {
+		return false
+	}
+	for x := 0; x < len(requestID); x++ {
+		c := requestID[x]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '+', c == '/', c == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,306 @@
+package logger
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestMiddlewareRequestAgent(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := newSignalOutput()
+	da := NewWithWriter(NewEventFlagSet(EventInfo), NewWriter(buffer))
+	defer da.Close()
+	da.Writer().SetShowTimestamp(false)
+	da.Writer().SetUseAnsiColors(false)
+
+	mw := NewMiddleware(da)
+	mw.SetRouteProvider(func(req *http.Request) string { return "/users/:id" })
+	mw.SetTenantProvider(func(req *http.Request) string { return req.Header.Get("X-Tenant") })
+
+	var requestID string
+	handler := mw.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		ra := ForRequest(req.Context())
+		assert.NotNil(ra)
+		assert.Equal(req, ra.Request())
+		requestID = ra.RequestID()
+		ra.SetTraceContext(nil)
+		ra.SetUser("bob")
+		ra.Infof("hello %s", "world")
+		res.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/users/123", nil)
+	req.Header.Set("X-Tenant", "acme")
+	handler(httptest.NewRecorder(), req)
+	<-buffer.written
+
+	assert.NotEmpty(requestID)
+	assert.True(strings.HasSuffix(buffer.String(), "[request_id="+requestID+" route=/users/:id tenant=acme user=bob] hello world\n"), buffer.String())
+}
+
+func TestMiddlewareRequestCompleteFields(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	writer := NewWriter(buffer)
+	writer.SetOutputFormat(OutputFormatJSON)
+	da := NewWithWriter(NewEventFlagSet(EventWebRequest), writer)
+	defer da.Close()
+	da.AddEventListener(EventWebRequest, NewRequestCompleteListener(WriteRequestComplete))
+
+	mw := NewMiddleware(da)
+	mw.SetRouteProvider(func(req *http.Request) string { return "/users/:id" })
+	mw.SetTailSampling(NewTailSampling(0, EventWebRequest))
+	handler := mw.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+	})
+	req := httptest.NewRequest("GET", "/users/123", nil)
+	req.Header.Set(HeaderRequestID, "req-1")
+	handler(httptest.NewRecorder(), req)
+	da.Flush()
+
+	record, err := ParseRecord(bytes.TrimSpace(buffer.Bytes()))
+	assert.Nil(err, buffer.String())
+	assert.Equal(EventWebRequest, record.Flag)
+	assert.Equal("req-1", record.Fields["request_id"])
+	assert.Equal("/users/:id", record.Fields["route"])
+}
+
+func TestRequestAgentJSONFields(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	writer := NewWriter(buffer)
+	writer.SetOutputFormat(OutputFormatJSON)
+	da := NewWithWriter(NewEventFlagSet(EventInfo), writer)
+	defer da.Close()
+	da.SetDefaultFields(Fields{"service": "api"})
+
+	ra := NewRequestAgent(da, httptest.NewRequest("GET", "/users/123", nil), "req-1")
+	ra.SetRoute("/users/:id")
+	ra.SetTenant("acme")
+	ra.Infof("hello")
+	da.Flush()
+
+	record, err := ParseRecord(bytes.TrimSpace(buffer.Bytes()))
+	assert.Nil(err)
+	assert.Equal("hello", record.Message)
+	assert.Equal(Fields{"service": "api", "request_id": "req-1", "route": "/users/:id", "tenant": "acme"}, record.Fields)
+}
+
+func TestRequestAgentSettersDuringWrites(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventInfo, EventError), NewWriter(buffer))
+	defer da.Close()
+
+	ra := NewRequestAgent(da, httptest.NewRequest("GET", "/", nil), "req-1")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for x := 0; x < 100; x++ {
+			ra.Infof("hello")
+			ra.Errorf("failed")
+		}
+	}()
+	for x := 0; x < 100; x++ {
+		ra.SetRoute("/")
+		ra.SetUser("bob")
+		ra.SetTenant("acme")
+		ra.SetTraceContext(nil)
+	}
+	<-done
+	da.Flush()
+	assert.Equal("bob", ra.User())
+}
+
+func TestForRequestMissing(t *testing.T) {
+	assert := assert.New(t)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	ra := ForRequest(req.Context())
+	assert.Nil(ra)
+	ra.Infof("does nothing")
+	assert.Empty(ra.RequestID())
+}
+
+func TestMiddlewareRequestIDHeader(t *testing.T) {
+	assert := assert.New(t)
+
+	da := None()
+	defer da.Close()
+
+	var requestID string
+	handler := NewMiddleware(da).HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		requestID = GetRequestID(req)
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderRequestID, "abc123")
+	res := httptest.NewRecorder()
+	handler(res, req)
+	assert.Equal("abc123", requestID)
+	assert.Equal("abc123", res.Header().Get(HeaderRequestID))
+
+	res = httptest.NewRecorder()
+	handler(res, httptest.NewRequest("GET", "/", nil))
+	assert.NotEmpty(requestID)
+	assert.NotEqual("abc123", requestID)
+
+	for _, invalid := range []string{"abc\r\n[info] forged", "abc 123", strings.Repeat("a", MaxRequestIDLength+1)} {
+		req = httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(HeaderRequestID, invalid)
+		res = httptest.NewRecorder()
+		handler(res, req)
+		assert.NotEqual(invalid, requestID)
+		assert.True(IsValidRequestID(requestID), requestID)
+		assert.Equal(requestID, res.Header().Get(HeaderRequestID))
+	}
+	assert.Equal(requestID, res.Header().Get(HeaderRequestID))
+}
+
+func TestSetRequestRoute(t *testing.T) {
+	assert := assert.New(t)
+
+	da := None()
+	defer da.Close()
+
+	writer := NewWriter(newSignalOutput())
+	writer.SetShowRoute(true)
+
+	var formatted string
+	handler := NewMiddleware(da).HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		assert.Equal("/users/123", GetRoute(req))
+		SetRequestRoute(req, "/users/:id")
+		formatted = writer.FormatRequestURI(req)
+	})
+	handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/123", nil))
+	assert.Equal("/users/:id", formatted)
+}
+
+func TestMiddlewareSkips(t *testing.T) {
+	assert := assert.New(t)
+
+	da := NewWithWriter(NewEventFlagSet(EventWebRequestStart, EventWebRequest, EventInfo), NewWriter(bytes.NewBuffer(nil)))
+	defer da.Close()
+
+	var lock sync.Mutex
+	var paths []string
+	da.AddEventListener(EventWebRequest, NewRequestListener(func(writer *Writer, ts TimeSource, req *http.Request, statusCode, contentLengthBytes int, elapsed time.Duration) {
+		lock.Lock()
+		defer lock.Unlock()
+		paths = append(paths, req.URL.Path)
+	}))
+
+	mw := NewMiddleware(da)
+	mw.SetSkippedPaths("/healthz")
+	mw.SetSkippedPathPrefixes("/metrics")
+	assert.Nil(mw.AddSkippedPathPatternString(`^/debug/.+`))
+	assert.NotNil(mw.AddSkippedPathPatternString(`(`))
+	assert.Equal([]string{"/healthz"}, mw.SkippedPaths())
+	assert.True(mw.Skips("/metrics/prometheus"))
+	assert.False(mw.Skips("/healthz/deep"))
+
+	var requestID string
+	handler := mw.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		requestID = GetRequestID(req)
+	})
+	for _, path := range []string{"/healthz", "/metrics", "/debug/pprof", "/users", "/healthz/deep"} {
+		handler(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	da.Flush()
+
+	assert.NotEmpty(requestID)
+	sort.Strings(paths)
+	assert.Equal([]string{"/healthz/deep", "/users"}, paths)
+}
+
+func TestMiddlewareRequestLogThreshold(t *testing.T) {
+	assert := assert.New(t)
+
+	da := NewWithWriter(NewEventFlagSet(EventWebRequest), NewWriter(bytes.NewBuffer(nil)))
+	defer da.Close()
+
+	var lock sync.Mutex
+	var logged []int
+	da.AddEventListener(EventWebRequest, NewRequestListener(func(writer *Writer, ts TimeSource, req *http.Request, statusCode, contentLengthBytes int, elapsed time.Duration) {
+		lock.Lock()
+		defer lock.Unlock()
+		logged = append(logged, statusCode)
+	}))
+
+	mw := NewMiddleware(da)
+	mw.SetRequestLogThreshold(http.StatusBadRequest, 50*time.Millisecond)
+	rollup := NewRollup()
+	mw.AddRequestCounter(rollup.Listener())
+	handler := mw.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/missing":
+			res.WriteHeader(http.StatusNotFound)
+		case "/slow":
+			time.Sleep(60 * time.Millisecond)
+			res.WriteHeader(http.StatusCreated)
+		}
+	})
+	for _, path := range []string{"/", "/", "/missing", "/slow"} {
+		handler(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	da.Flush()
+
+	sort.Ints(logged)
+	assert.Equal([]int{http.StatusCreated, http.StatusNotFound}, logged)
+	rollup.Lock()
+	assert.Equal(4, rollup.requests)
+	rollup.Unlock()
+}
+
+func TestMiddlewareErrorOn5xx(t *testing.T) {
+	assert := assert.New(t)
+
+	da := NewWithWriter(NewEventFlagSet(EventError), NewWriter(bytes.NewBuffer(nil)))
+	defer da.Close()
+
+	var lock sync.Mutex
+	var logged []string
+	da.AddEventListener(EventError, NewErrorWithRequestListener(func(writer *Writer, ts TimeSource, err error, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		assert.NotNil(req)
+		logged = append(logged, err.Error())
+	}))
+
+	mw := NewMiddleware(da)
+	mw.SetErrorOn5xx(true)
+	handler := mw.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/silent", "/silent\n2017-01-01T00:00:00Z [info] forged":
+			res.WriteHeader(http.StatusBadGateway)
+		case "/logged":
+			ForRequest(req.Context()).Errorf("upstream failed")
+			res.WriteHeader(http.StatusInternalServerError)
+		case "/missing":
+			res.WriteHeader(http.StatusNotFound)
+		}
+	})
+	for _, path := range []string{"/silent", "/logged", "/missing", "/silent%0A2017-01-01T00:00:00Z%20[info]%20forged"} {
+		handler(httptest.NewRecorder(), httptest.NewRequest("GET", path+"?token=secret", nil))
+	}
+	da.Flush()
+
+	sort.Strings(logged)
+	assert.Equal([]string{
+		"GET /silent responded 502 Bad Gateway",
+		"GET /silent%0A2017-01-01T00:00:00Z%20[info]%20forged responded 502 Bad Gateway",
+		"upstream failed",
+	}, logged)
+}
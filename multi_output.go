@@ -6,6 +6,7 @@ import (
 )
 
 // NewMultiOutputFromEnvironment creates a new multiplexed stdout writer.
+// Ansi escape sequences are stripped from the file output, and from stdout if it isn't a terminal.
 func NewMultiOutputFromEnvironment() io.Writer {
 	primary := os.Stdout
 	filePath := os.Getenv(EnvironmentVariableLogOutFile)
@@ -19,12 +20,15 @@ func NewMultiOutputFromEnvironment() io.Writer {
 		if err != nil {
 			panic(err)
 		}
-		return NewMultiOutput(primary, secondary)
+		secondary.SetSyncPolicy(ParseFileSyncPolicy(os.Getenv(EnvironmentVariableLogOutFsync)))
+		secondary.SetDetectRotation(envFlagIsSet(EnvironmentVariableLogFileDetectRotation, false))
+		return NewMultiOutput(NewTerminalAwareOutput(primary), NewAnsiStripOutput(secondary))
 	}
-	return NewSyncOutput(primary)
+	return NewSyncOutput(NewTerminalAwareOutput(primary))
 }
 
 // NewErrorMultiOutputFromEnvironment creates a new multiplexed stderr writer.
+// Ansi escape sequences are stripped from the file output, and from stderr if it isn't a terminal.
 func NewErrorMultiOutputFromEnvironment() io.Writer {
 	primary := os.Stderr
 	filePath := os.Getenv(EnvironmentVariableLogErrFile)
@@ -38,9 +42,11 @@ func NewErrorMultiOutputFromEnvironment() io.Writer {
 		if err != nil {
 			panic(err)
 		}
-		return NewMultiOutput(primary, secondary)
+		secondary.SetSyncPolicy(ParseFileSyncPolicy(os.Getenv(EnvironmentVariableLogErrFsync)))
+		secondary.SetDetectRotation(envFlagIsSet(EnvironmentVariableLogFileDetectRotation, false))
+		return NewMultiOutput(NewTerminalAwareOutput(primary), NewAnsiStripOutput(secondary))
 	}
-	return NewSyncOutput(primary)
+	return NewSyncOutput(NewTerminalAwareOutput(primary))
 }
 
 // NewMultiOutput creates a new MultiOutput that wraps an array of writers.
@@ -67,6 +73,35 @@ func (mo MultiOutput) Write(buffer []byte) (int, error) {
 	return written, err
 }
 
+// Flush flushes all of the inner writers (if they buffer writes).
+func (mo MultiOutput) Flush() error {
+	var err error
+	for x := 0; x < len(mo.outputs); x++ {
+		if flushErr := FlushOutput(mo.outputs[x]); flushErr != nil {
+			err = flushErr
+		}
+	}
+	return err
+}
+
+// Reopen reopens all of the inner writers (if they can reopen their files).
+func (mo MultiOutput) Reopen() error {
+	var err error
+	for x := 0; x < len(mo.outputs); x++ {
+		if reopenErr := ReopenOutput(mo.outputs[x]); reopenErr != nil {
+			err = reopenErr
+		}
+	}
+	return err
+}
+
+// SetMetaReporter sets how the inner writers report problems that don't fail writes, see `OutputMetaReporter`.
+func (mo MultiOutput) SetMetaReporter(reporter MetaReporter) {
+	for x := 0; x < len(mo.outputs); x++ {
+		SetOutputMetaReporter(mo.outputs[x], reporter)
+	}
+}
+
 // Close closes all of the inner writers (if they are io.WriteClosers).
 func (mo MultiOutput) Close() error {
 	var err error
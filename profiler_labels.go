@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// ProfilerLabelWorker is the pprof label set on event queue workers.
+	ProfilerLabelWorker = "logger.worker"
+	// ProfilerLabelEvent is the pprof label for the event being processed.
+	ProfilerLabelEvent = "logger.event"
+	// ProfilerLabelListener is the pprof label for the listener being invoked.
+	ProfilerLabelListener = "logger.listener"
+)
+
+var (
+	// workerContext carries the labels every queue worker runs with.
+	workerContext = pprof.WithLabels(context.Background(), pprof.Labels(ProfilerLabelWorker, "event_queue"))
+
+	listenerNames sync.Map // uintptr => string
+)
+
+// ProfilerLabels returns if events are processed with pprof labels for their flag (and listener).
+// Like `IsEnabled` it takes no locks.
+func (da *Agent) ProfilerLabels() bool {
+	if da == nil {
+		return false
+	}
+	return atomic.LoadInt32(&da.profilerLabels) == 1
+}
+
+// SetProfilerLabels sets if events are processed with pprof labels (`logger.event` and `logger.listener`),
+// so cpu profiles attribute logging cost to specific flags and listeners. Queue workers are always labeled `logger.worker`.
+// Events processed synchronously (e.g. with `Sync()`) are labeled in the calling goroutine, replacing its labels.
+func (da *Agent) SetProfilerLabels(profilerLabels bool) {
+	atomic.StoreInt32(&da.profilerLabels, boolAsInt32(profilerLabels))
+}
+
+// profilerLabelFor returns the event flag of an action's state, and if it should be processed with profiler labels.
+func (da *Agent) profilerLabelFor(actionState ...interface{}) (EventFlag, bool) {
+	if len(actionState) < 2 || !da.ProfilerLabels() {
+		return "", false
+	}
+	eventFlag, err := stateAsEventFlag(actionState[1])
+	return eventFlag, err == nil
+}
+
+// withProfilerLabels runs an action with the labels for an event (and listener, if set).
+func withProfilerLabels(eventFlag EventFlag, listener EventListener, action func(context.Context)) {
+	labels := pprof.Labels(ProfilerLabelEvent, string(eventFlag))
+	if listener != nil {
+		labels = pprof.Labels(ProfilerLabelEvent, string(eventFlag), ProfilerLabelListener, ListenerName(listener))
+	}
+	pprof.Do(workerContext, labels, action)
+}
+
+// ListenerName returns the name of a listener's function, e.g. `main.main.func1`.
+func ListenerName(listener EventListener) string {
+	if listener == nil {
+		return ""
+	}
+	pc := reflect.ValueOf(listener).Pointer()
+	if name, hasName := listenerNames.Load(pc); hasName {
+		return name.(string)
+	}
+	name := "unknown"
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		name = fn.Name()
+	}
+	listenerNames.Store(pc, name)
+	return name
+}
@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"context"
+	"runtime/pprof"
+	"strings"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestAgentProfilerLabels(t *testing.T) {
+	assert := assert.New(t)
+
+	output := newSignalOutput()
+	writer := NewWriter(output)
+	writer.SetUseAnsiColors(false)
+	writer.SetShowTimestamp(false)
+	da := NewWithWriter(NewEventFlagSetAll(), writer)
+	defer da.Close()
+	assert.False(da.ProfilerLabels())
+	da.SetProfilerLabels(true)
+	assert.True(da.ProfilerLabels())
+
+	fired := make(chan struct{}, 1)
+	da.AddEventListener(EventInfo, func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		fired <- struct{}{}
+	})
+	da.Infof("hello")
+	<-fired
+	da.Flush()
+	assert.Equal("[info] hello\n", output.String())
+}
+
+func TestWithProfilerLabels(t *testing.T) {
+	assert := assert.New(t)
+
+	listener := func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {}
+	var worker, event, name string
+	withProfilerLabels(EventInfo, listener, func(ctx context.Context) {
+		worker, _ = pprof.Label(ctx, ProfilerLabelWorker)
+		event, _ = pprof.Label(ctx, ProfilerLabelEvent)
+		name, _ = pprof.Label(ctx, ProfilerLabelListener)
+	})
+	assert.Equal("event_queue", worker)
+	assert.Equal(string(EventInfo), event)
+	assert.True(strings.HasSuffix(name, ".TestWithProfilerLabels.func1"), name)
+	assert.Equal(name, ListenerName(listener))
+	assert.Empty(ListenerName(nil))
+}
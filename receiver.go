@@ -0,0 +1,312 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultReceiverMaxLineBytes is the longest line a receiver reads; longer lines are dropped.
+	DefaultReceiverMaxLineBytes = 64 << 10
+)
+
+// NewReceiver returns a receiver that listens on a network address for lines written by other processes
+// (e.g. sidecar binaries) and re-emits them through an agent, e.g. `NewReceiver(agent, "tcp", ":5140")`.
+// Stream networks (`tcp`, `unix`) read newline delimited lines; packet networks (`udp`, `unixgram`) read
+// one or more lines per datagram. Call `Start` to listen and `Close` to stop.
+//
+// Lines can be json output of this package (see `Record`, of any schema version), syslog lines (whose `<PRI>`
+// prefix sets the event) or plain text, which is emitted as the receiver's default event (see `SetDefaultEvent`).
+// Received events are subject to the agent's flags, and fire its listeners.
+func NewReceiver(agent *Agent, network, address string) *Receiver {
+	return &Receiver{
+		agent:        agent,
+		network:      network,
+		address:      address,
+		defaultEvent: EventInfo,
+		maxLineBytes: DefaultReceiverMaxLineBytes,
+		connections:  map[net.Conn]bool{},
+	}
+}
+
+// Receiver re-emits lines received over the network through an agent, see `NewReceiver`.
+type Receiver struct {
+	sync.Mutex
+	agent        *Agent
+	network      string
+	address      string
+	defaultEvent EventFlag
+	maxLineBytes int
+
+	listener    net.Listener
+	packetConn  net.PacketConn
+	connections map[net.Conn]bool
+	closed      bool
+	serving     sync.WaitGroup
+}
+
+// DefaultEvent returns the event plain text lines are emitted as.
+func (r *Receiver) DefaultEvent() EventFlag { return r.defaultEvent }
+
+// SetDefaultEvent sets the event plain text lines (and json lines without a flag) are emitted as.
+func (r *Receiver) SetDefaultEvent(eventFlag EventFlag) { r.defaultEvent = eventFlag }
+
+// MaxLineBytes returns the longest line the receiver reads.
+func (r *Receiver) MaxLineBytes() int { return r.maxLineBytes }
+
+// SetMaxLineBytes sets the longest line the receiver reads; longer lines are dropped.
+func (r *Receiver) SetMaxLineBytes(maxLineBytes int) { r.maxLineBytes = maxLineBytes }
+
+// Addr returns the address the receiver listens on, once started.
+func (r *Receiver) Addr() net.Addr {
+	r.Lock()
+	defer r.Unlock()
+	if r.listener != nil {
+		return r.listener.Addr()
+	}
+	if r.packetConn != nil {
+		return r.packetConn.LocalAddr()
+	}
+	return nil
+}
+
+// Start listens on the receiver's address and serves connections in the background.
+func (r *Receiver) Start() error {
+	r.Lock()
+	defer r.Unlock()
+	if r.listener != nil || r.packetConn != nil {
+		return errors.New("Receiver already started")
+	}
+	if r.closed {
+		return errors.New("Receiver closed")
+	}
+
+	switch r.network {
+	case "udp", "udp4", "udp6", "unixgram":
+		packetConn, err := net.ListenPacket(r.network, r.address)
+		if err != nil {
+			return err
+		}
+		r.packetConn = packetConn
+		r.serving.Add(1)
+		go r.servePackets(packetConn)
+	default:
+		listener, err := net.Listen(r.network, r.address)
+		if err != nil {
+			return err
+		}
+		r.listener = listener
+		r.serving.Add(1)
+		go r.serveStreams(listener)
+	}
+	return nil
+}
+
+// Close stops listening, closes open connections and waits for the lines being read to be emitted.
+func (r *Receiver) Close() error {
+	r.Lock()
+	r.closed = true
+	var err error
+	if r.listener != nil {
+		err = r.listener.Close()
+	}
+	if r.packetConn != nil {
+		err = r.packetConn.Close()
+	}
+	for conn := range r.connections {
+		conn.Close()
+	}
+	r.Unlock()
+
+	r.serving.Wait()
+	return err
+}
+
+func (r *Receiver) serveStreams(listener net.Listener) {
+	defer r.serving.Done()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if r.isClosed() {
+				return
+			}
+			r.agent.Metaf("receiver: accept failed: %v", err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		if !r.track(conn) {
+			conn.Close()
+			return
+		}
+		r.serving.Add(1)
+		go r.serveStream(conn)
+	}
+}
+
+func (r *Receiver) serveStream(conn net.Conn) {
+	defer r.serving.Done()
+	defer r.untrack(conn)
+
+	reader := bufio.NewReaderSize(conn, 4096)
+	var line []byte
+	var overlong bool
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			return
+		}
+		if !overlong {
+			line = append(line, chunk...)
+			overlong = len(line) > r.maxLineBytes
+		}
+		if isPrefix {
+			continue
+		}
+		if overlong {
+			r.agent.Metaf("receiver: dropped a line from %s over %d bytes", conn.RemoteAddr(), r.maxLineBytes)
+		} else {
+			r.Receive(line)
+		}
+		line, overlong = line[:0], false
+	}
+}
+
+func (r *Receiver) servePackets(packetConn net.PacketConn) {
+	defer r.serving.Done()
+	packet := make([]byte, r.maxLineBytes)
+	for {
+		length, _, err := packetConn.ReadFrom(packet)
+		if err != nil {
+			if r.isClosed() {
+				return
+			}
+			continue
+		}
+		for _, line := range bytes.Split(packet[:length], []byte{'\n'}) {
+			r.Receive(line)
+		}
+	}
+}
+
+func (r *Receiver) isClosed() bool {
+	r.Lock()
+	defer r.Unlock()
+	return r.closed
+}
+
+func (r *Receiver) track(conn net.Conn) bool {
+	r.Lock()
+	defer r.Unlock()
+	if r.closed {
+		return false
+	}
+	r.connections[conn] = true
+	return true
+}
+
+func (r *Receiver) untrack(conn net.Conn) {
+	r.Lock()
+	delete(r.connections, conn)
+	r.Unlock()
+	conn.Close()
+}
+
+// Receive parses a line (json, syslog or plain text) and emits it through the receiver's agent.
+func (r *Receiver) Receive(line []byte) {
+	line = bytes.TrimRight(line, "\r\n")
+	if len(bytes.TrimSpace(line)) == 0 {
+		return
+	}
+	if line[0] == '{' {
+		if record, err := ParseRecord(line); err == nil {
+			r.emit(r.recordEvent(record), record.Time, r.recordMessage(record))
+			return
+		}
+	}
+	if eventFlag, message, isSyslog := parseSyslogLine(line); isSyslog {
+		r.emit(eventFlag, time.Time{}, message)
+		return
+	}
+	r.emit(r.defaultEvent, time.Time{}, string(line))
+}
+
+func (r *Receiver) recordEvent(record Record) EventFlag {
+	if len(record.Flag) > 0 {
+		return record.Flag
+	}
+	return r.defaultEvent
+}
+
+// recordMessage returns a record's message, followed by its fields.
+func (r *Receiver) recordMessage(record Record) string {
+	if len(record.Fields) == 0 {
+		return record.Message
+	}
+	return record.Message + " " + r.agent.Writer().FormatFields(record.Fields)
+}
+
+// emit writes a received message as an event (at the time it was written, if known) and fires its listeners,
+// which are handed the message as an error for warnings and errors, and as a message otherwise.
+func (r *Receiver) emit(eventFlag EventFlag, written time.Time, message string) {
+	da := r.agent
+	if !da.IsEnabled(eventFlag) || !da.shouldWrite(eventFlag) {
+		return
+	}
+	ts := TimeNow()
+	if !written.IsZero() {
+		ts = TimeInstance(written)
+	}
+	ts = da.numbered(ts)
+	color, hasColor := severityColors[eventFlag]
+	if !hasColor {
+		color = ColorLightWhite
+	}
+
+	// the lines' writer may not have scanned them, and listeners don't go through this agent's writer.
+	message = da.Writer().SecretScanner().ScanString(message)
+	da.countEvent(eventFlag)
+	da.recordRecent(eventFlag, "%s", message)
+	isError := IsSeverityAtLeast(eventFlag, EventWarning)
+	if isError {
+		da.queueWriteErrorWithTimeSource(ts, eventFlag, color, "%s", message)
+	} else {
+		da.queueWriteWithTimeSource(ts, eventFlag, color, "%s", message)
+	}
+	if da.HasListener(eventFlag) {
+		if isError {
+			da.enqueue(da.triggerListeners, acquireState(nil, ts, eventFlag, errors.New(message))...)
+			return
+		}
+		da.enqueue(da.triggerListeners, acquireState([]interface{}{message}, ts, eventFlag, "%s")...)
+	}
+}
+
+// syslogSeverityEvents are the events of the syslog severities, from emergency (0) to debug (7).
+var syslogSeverityEvents = []EventFlag{EventError, EventError, EventError, EventError, EventWarning, EventInfo, EventInfo, EventDebug}
+
+// parseSyslogLine parses the `<PRI>` prefix of a syslog line (rfc 3164 or 5424) into the event of its severity,
+// returning the rest of the line as the message.
+func parseSyslogLine(line []byte) (EventFlag, string, bool) {
+	if len(line) < 3 || line[0] != '<' {
+		return "", "", false
+	}
+	end := bytes.IndexByte(line, '>')
+	if end < 2 || end > 4 {
+		return "", "", false
+	}
+	priority, err := strconv.Atoi(string(line[1:end]))
+	if err != nil || priority < 0 || priority > 191 {
+		return "", "", false
+	}
+	message := string(line[end+1:])
+	if strings.HasPrefix(message, "1 ") {
+		message = message[2:]
+	}
+	return syslogSeverityEvents[priority%8], strings.TrimSpace(message), true
+}
@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+// lockedBuffer is a buffer that's safe to read while an agent writes to it.
+type lockedBuffer struct {
+	sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (lb *lockedBuffer) Write(contents []byte) (int, error) {
+	lb.Lock()
+	defer lb.Unlock()
+	return lb.buffer.Write(contents)
+}
+
+func (lb *lockedBuffer) String() string {
+	lb.Lock()
+	defer lb.Unlock()
+	return lb.buffer.String()
+}
+
+// waitForOutput waits for a number of lines to be written to a buffer.
+func waitForOutput(da *Agent, output *lockedBuffer, lines int) string {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		da.Flush()
+		if strings.Count(output.String(), "\n") >= lines {
+			break
+		}
+	}
+	return output.String()
+}
+
+func TestParseSyslogLine(t *testing.T) {
+	assert := assert.New(t)
+
+	eventFlag, message, isSyslog := parseSyslogLine([]byte("<11>Jan  1 00:00:00 host app: it broke"))
+	assert.True(isSyslog)
+	assert.Equal(EventError, eventFlag)
+	assert.Equal("Jan  1 00:00:00 host app: it broke", message)
+
+	eventFlag, message, isSyslog = parseSyslogLine([]byte("<166>1 2018-01-01T00:00:00Z host app - - - hello"))
+	assert.True(isSyslog)
+	assert.Equal(EventInfo, eventFlag)
+	assert.Equal("2018-01-01T00:00:00Z host app - - - hello", message)
+
+	_, _, isSyslog = parseSyslogLine([]byte("<html>"))
+	assert.False(isSyslog)
+	_, _, isSyslog = parseSyslogLine([]byte("plain"))
+	assert.False(isSyslog)
+}
+
+func TestReceiverTCP(t *testing.T) {
+	assert := assert.New(t)
+
+	output := &lockedBuffer{}
+	da := NewWithWriter(NewEventFlagSet(EventInfo, EventWarning, EventError), NewWriter(output))
+	defer da.Close()
+	da.Writer().SetUseAnsiColors(false)
+	da.Writer().SetShowTimestamp(false)
+
+	var lock sync.Mutex
+	var received []error
+	da.AddEventListener(EventError, NewErrorListener(func(writer *Writer, ts TimeSource, err error) {
+		lock.Lock()
+		defer lock.Unlock()
+		received = append(received, err)
+	}))
+
+	receiver := NewReceiver(da, "tcp", "127.0.0.1:0")
+	assert.Nil(receiver.Start())
+	defer receiver.Close()
+	assert.NotNil(receiver.Start())
+
+	conn, err := net.Dial("tcp", receiver.Addr().String())
+	assert.Nil(err)
+	conn.Write([]byte(strings.Join([]string{
+		`{"schema_version":1,"time":"2018-01-01T00:00:00Z","flag":"warning","message":"from json","fields":{"pod":"sidecar"}}`,
+		`<11>sidecar: it broke`,
+		`plain line`,
+		`<15>sidecar: debug is disabled`,
+		strings.Repeat("x", DefaultReceiverMaxLineBytes+1),
+		"",
+	}, "\n")))
+	conn.Close()
+
+	written := waitForOutput(da, output, 3)
+	assert.True(strings.Contains(written, "[warning] from json pod=sidecar"), written)
+	assert.True(strings.Contains(written, "[error] sidecar: it broke"), written)
+	assert.True(strings.Contains(written, "[info] plain line"), written)
+	assert.False(strings.Contains(written, "debug is disabled"), written)
+	assert.False(strings.Contains(written, "xxx"), written)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal([]error{errors.New("sidecar: it broke")}, received)
+}
+
+func TestReceiverUDP(t *testing.T) {
+	assert := assert.New(t)
+
+	output := &lockedBuffer{}
+	da := NewWithWriter(NewEventFlagSet(EventInfo, EventWebRequest), NewWriter(output))
+	defer da.Close()
+	da.Writer().SetUseAnsiColors(false)
+
+	receiver := NewReceiver(da, "udp", "127.0.0.1:0")
+	receiver.SetDefaultEvent(EventWebRequest)
+	assert.Nil(receiver.Start())
+
+	conn, err := net.Dial("udp", receiver.Addr().String())
+	assert.Nil(err)
+	defer conn.Close()
+	conn.Write([]byte("GET / 200\nGET /health 200"))
+
+	written := waitForOutput(da, output, 2)
+	assert.Nil(receiver.Close())
+	assert.True(strings.Contains(written, "[web.request] GET / 200"), written)
+	assert.True(strings.Contains(written, "[web.request] GET /health 200"), written)
+}
@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RecentEvent is an event kept in the agent's ring buffer, see `SetRecentEventsSize`.
+type RecentEvent struct {
+	Timestamp time.Time
+	Flag      EventFlag
+	// Message is the formatted message, with secrets redacted if the agent's writer has a secret scanner,
+	// or empty for events that only trigger listeners.
+	Message string
+	// State is the listener state of events that only trigger listeners.
+	State []interface{}
+}
+
+// recentEvents is a ring buffer of the last events fired.
+type recentEvents struct {
+	sync.Mutex
+	size   int32
+	events []recentEvent
+	next   int
+	full   bool
+}
+
+// recentEvent is an unformatted recent event; messages are formatted when they're read.
+type recentEvent struct {
+	timestamp time.Time
+	flag      EventFlag
+	format    string
+	args      []interface{}
+}
+
+// RecentEventsSize returns the number of recent events kept, or zero if they aren't kept.
+func (da *Agent) RecentEventsSize() int {
+	if da == nil {
+		return 0
+	}
+	recent := &da.root().recent
+	recent.Lock()
+	defer recent.Unlock()
+	return len(recent.events)
+}
+
+// SetRecentEventsSize sets the number of enabled events kept in memory, regardless of the writer and listeners,
+// so crash handlers and debug endpoints can show what happened right before a failure (see `Recent`).
+// The buffer is shared by the root agent and the agents derived from it; zero stops keeping events.
+// Message arguments are kept as given and formatted when they're read.
+func (da *Agent) SetRecentEventsSize(size int) {
+	recent := &da.root().recent
+	recent.Lock()
+	defer recent.Unlock()
+	recent.events = make([]recentEvent, size)
+	recent.next, recent.full = 0, false
+	atomic.StoreInt32(&recent.size, int32(size))
+}
+
+// Recent returns up to the last `n` recent events, oldest first, for a flag (or for all events with `EventAll`).
+func (da *Agent) Recent(flag EventFlag, n int) []RecentEvent {
+	if da == nil {
+		return nil
+	}
+	recent := &da.root().recent
+	recent.Lock()
+	var matching []recentEvent
+	for x := 0; x < len(recent.events) && len(matching) < n; x++ {
+		index := recent.next - 1 - x
+		if index < 0 {
+			if !recent.full {
+				break
+			}
+			index += len(recent.events)
+		}
+		if event := recent.events[index]; flag == EventAll || event.flag == flag {
+			matching = append(matching, event)
+		}
+	}
+	recent.Unlock()
+
+	scanner := da.root().Writer().SecretScanner()
+	events := make([]RecentEvent, len(matching))
+	for x, event := range matching {
+		events[len(matching)-1-x] = event.render(scanner)
+	}
+	return events
+}
+
+// recordRecent keeps an event that just fired in the root agent's ring buffer, if recent events are kept.
+func (da *Agent) recordRecent(flag EventFlag, format string, args ...interface{}) {
+	if atomic.LoadInt32(&da.root().recent.size) == 0 {
+		return
+	}
+	da.recordRecentAt(SystemClock, flag, format, args...)
+}
+
+// recordRecentAt keeps an event that fired at a given time in the root agent's ring buffer, if recent events are kept.
+func (da *Agent) recordRecentAt(ts TimeSource, flag EventFlag, format string, args ...interface{}) {
+	recent := &da.root().recent
+	if atomic.LoadInt32(&recent.size) == 0 {
+		return
+	}
+	recent.Lock()
+	defer recent.Unlock()
+	if len(recent.events) == 0 {
+		return
+	}
+	recent.events[recent.next] = recentEvent{timestamp: ts.UTCNow(), flag: flag, format: format, args: args}
+	recent.next++
+	if recent.next == len(recent.events) {
+		recent.next, recent.full = 0, true
+	}
+}
+
+// render formats a recent event's message, redacting secrets with a scanner (see `Writer.SetSecretScanner`).
+func (re recentEvent) render(scanner *SecretScanner) RecentEvent {
+	rendered := RecentEvent{Timestamp: re.timestamp, Flag: re.flag}
+	if len(re.format) == 0 {
+		rendered.State = re.args
+		return rendered
+	}
+	rendered.Message = scanner.ScanString(formatMessage(re.format, re.args...))
+	return rendered
+}
@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestAgentRecent(t *testing.T) {
+	assert := assert.New(t)
+
+	da := NewWithWriter(NewEventFlagSet(EventInfo, EventError, EventWebRequest), NewWriter(bytes.NewBuffer(nil)))
+	defer da.Close()
+
+	da.Infof("not kept")
+	assert.Empty(da.Recent(EventAll, 10))
+
+	da.AddEventListener(EventWebRequest, func(_ *Writer, _ TimeSource, _ EventFlag, _ ...interface{}) {})
+	da.SetRecentEventsSize(3)
+	assert.Equal(3, da.RecentEventsSize())
+	da.Infof("one")
+	da.Debugf("disabled")
+	da.Clone().Infof("two %d", 2)
+	da.Errorf("three")
+	da.OnEvent(EventWebRequest, "state")
+	da.Sync().Infof("five")
+
+	recent := da.Recent(EventAll, 10)
+	assert.Len(recent, 3)
+	assert.Equal(EventError, recent[0].Flag)
+	assert.Equal("three", recent[0].Message)
+	assert.Equal(EventWebRequest, recent[1].Flag)
+	assert.Empty(recent[1].Message)
+	assert.Equal([]interface{}{"state"}, recent[1].State)
+	assert.Equal("five", recent[2].Message)
+	assert.False(recent[2].Timestamp.IsZero())
+
+	info := da.Recent(EventInfo, 10)
+	assert.Len(info, 1)
+	assert.Equal("five", info[0].Message)
+
+	latest := da.Recent(EventAll, 1)
+	assert.Len(latest, 1)
+	assert.Equal("five", latest[0].Message)
+
+	da.SetRecentEventsSize(0)
+	da.Infof("six")
+	da.Flush()
+	assert.Empty(da.Recent(EventAll, 10))
+}
+
+func TestAgentRecentPartial(t *testing.T) {
+	assert := assert.New(t)
+
+	da := NewWithWriter(NewEventFlagSet(EventInfo), NewWriter(bytes.NewBuffer(nil)))
+	defer da.Close()
+	da.SetRecentEventsSize(8)
+	for x := 0; x < 3; x++ {
+		da.Infof("%d", x)
+	}
+	recent := da.Recent(EventInfo, 10)
+	assert.Len(recent, 3)
+	for x, event := range recent {
+		assert.Equal(fmt.Sprint(x), event.Message)
+	}
+	da.Flush()
+}
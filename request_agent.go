@@ -0,0 +1,342 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+type requestAgentKey struct{}
+
+// WithRequestAgent returns a copy of the context that carries a request agent.
+func WithRequestAgent(ctx context.Context, ra *RequestAgent) context.Context {
+	return context.WithValue(ctx, requestAgentKey{}, ra)
+}
+
+// ForRequest returns the request agent stored in a context by the middleware.
+// It returns nil if there isn't one; a nil request agent is safe to call but does nothing.
+func ForRequest(ctx context.Context) *RequestAgent {
+	if ctx == nil {
+		return nil
+	}
+	if typed, isTyped := ctx.Value(requestAgentKey{}).(*RequestAgent); isTyped {
+		return typed
+	}
+	return nil
+}
+
+// RequestIDFromContext returns the request id of the request agent stored in a context.
+func RequestIDFromContext(ctx context.Context) string {
+	return ForRequest(ctx).RequestID()
+}
+
+// GetRequestID returns the request id for a request, either from the request agent
+// in its context or from the request id header if it's valid (see `IsValidRequestID`).
+func GetRequestID(req *http.Request) string {
+	if req == nil {
+		return ""
+	}
+	if requestID := RequestIDFromContext(req.Context()); len(requestID) > 0 {
+		return requestID
+	}
+	if requestID := req.Header.Get(HeaderRequestID); IsValidRequestID(requestID) {
+		return requestID
+	}
+	return ""
+}
+
+// GetRoute returns the matched route for a request from the request agent in its context,
+// falling back to the request path.
+func GetRoute(req *http.Request) string {
+	if req == nil {
+		return ""
+	}
+	if route := ForRequest(req.Context()).Route(); len(route) > 0 {
+		return route
+	}
+	return req.URL.Path
+}
+
+// SetRequestRoute sets the matched route pattern (e.g. `/users/:id`) on the request agent in a request's context.
+// Routers (or adapters for them) should call it once a route is matched so request events and
+// stats are keyed by the pattern rather than the raw path.
+func SetRequestRoute(req *http.Request, route string) {
+	if req == nil {
+		return
+	}
+	if ra := ForRequest(req.Context()); ra != nil {
+		ra.SetRoute(route)
+	}
+}
+
+// NewRequestAgent returns a new request scoped view of an agent.
+func NewRequestAgent(agent *Agent, req *http.Request, requestID string) *RequestAgent {
+	return &RequestAgent{
+		a:         agent,
+		req:       req,
+		requestID: requestID,
+	}
+}
+
+// RequestAgent is a view of an agent scoped to a single request.
+// Its events carry the request id, trace, route, user and tenant as fields (see `FieldsFromTimeSource`),
+// and errors carry the request as state for listeners.
+// The route, user, tenant and trace context can be set while its events are being written.
+type RequestAgent struct {
+	a         *Agent
+	req       *http.Request
+	requestID string
+	tail      *tailBuffer
+	errored   int32
+	body      []byte
+
+	fieldsLock sync.RWMutex
+	route      string
+	user       string
+	tenant     string
+	trace      *TraceContext
+}
+
+// Agent returns the underlying agent.
+func (ra *RequestAgent) Agent() *Agent {
+	if ra == nil {
+		return nil
+	}
+	return ra.a
+}
+
+// Request returns the request.
+func (ra *RequestAgent) Request() *http.Request {
+	if ra == nil {
+		return nil
+	}
+	return ra.req
+}
+
+// RequestID returns the request id.
+func (ra *RequestAgent) RequestID() string {
+	if ra == nil {
+		return ""
+	}
+	return ra.requestID
+}
+
+// TraceContext returns the trace context.
+func (ra *RequestAgent) TraceContext() *TraceContext {
+	if ra == nil {
+		return nil
+	}
+	ra.fieldsLock.RLock()
+	defer ra.fieldsLock.RUnlock()
+	return ra.trace
+}
+
+// SetTraceContext sets the trace context.
+func (ra *RequestAgent) SetTraceContext(trace *TraceContext) {
+	ra.fieldsLock.Lock()
+	defer ra.fieldsLock.Unlock()
+	ra.trace = trace
+}
+
+// Route returns the matched route.
+func (ra *RequestAgent) Route() string {
+	if ra == nil {
+		return ""
+	}
+	ra.fieldsLock.RLock()
+	defer ra.fieldsLock.RUnlock()
+	return ra.route
+}
+
+// SetRoute sets the matched route.
+func (ra *RequestAgent) SetRoute(route string) {
+	ra.fieldsLock.Lock()
+	defer ra.fieldsLock.Unlock()
+	ra.route = route
+}
+
+// User returns the user.
+func (ra *RequestAgent) User() string {
+	if ra == nil {
+		return ""
+	}
+	ra.fieldsLock.RLock()
+	defer ra.fieldsLock.RUnlock()
+	return ra.user
+}
+
+// SetUser sets the user, e.g. once a handler has authenticated the request.
+func (ra *RequestAgent) SetUser(user string) {
+	ra.fieldsLock.Lock()
+	defer ra.fieldsLock.Unlock()
+	ra.user = user
+}
+
+// Tenant returns the tenant.
+func (ra *RequestAgent) Tenant() string {
+	if ra == nil {
+		return ""
+	}
+	ra.fieldsLock.RLock()
+	defer ra.fieldsLock.RUnlock()
+	return ra.tenant
+}
+
+// SetTenant sets the tenant.
+func (ra *RequestAgent) SetTenant(tenant string) {
+	ra.fieldsLock.Lock()
+	defer ra.fieldsLock.Unlock()
+	ra.tenant = tenant
+}
+
+// Infof logs an informational message to the output stream.
+func (ra *RequestAgent) Infof(format string, args ...interface{}) {
+	if ra == nil {
+		return
+	}
+	ra.WriteEventf(EventInfo, ColorLightWhite, format, args...)
+}
+
+// Debugf logs a debug message to the output stream.
+func (ra *RequestAgent) Debugf(format string, args ...interface{}) {
+	if ra == nil {
+		return
+	}
+	ra.WriteEventf(EventDebug, ColorLightYellow, format, args...)
+}
+
+// Warningf logs a warning message to the error stream.
+func (ra *RequestAgent) Warningf(format string, args ...interface{}) error {
+	if ra == nil {
+		return nil
+	}
+	return ra.Warning(fmt.Errorf(format, args...))
+}
+
+// Warning logs a warning error to std err.
+func (ra *RequestAgent) Warning(err error) error {
+	if ra == nil {
+		return err
+	}
+	return ra.ErrorEventWithState(EventWarning, ColorLightYellow, err)
+}
+
+// Errorf writes an event to the log and triggers event listeners.
+func (ra *RequestAgent) Errorf(format string, args ...interface{}) error {
+	if ra == nil {
+		return nil
+	}
+	return ra.Error(fmt.Errorf(format, args...))
+}
+
+// Error logs an error to std err.
+func (ra *RequestAgent) Error(err error) error {
+	if ra == nil {
+		return err
+	}
+	return ra.ErrorEventWithState(EventError, ColorRed, err)
+}
+
+// Fatalf writes an event to the log and triggers event listeners.
+func (ra *RequestAgent) Fatalf(format string, args ...interface{}) error {
+	if ra == nil {
+		return nil
+	}
+	return ra.Fatal(fmt.Errorf(format, args...))
+}
+
+// Fatal logs a fatal error to std err.
+func (ra *RequestAgent) Fatal(err error) error {
+	if ra == nil {
+		return err
+	}
+	return ra.ErrorEventWithState(EventFatalError, ColorRed, err)
+}
+
+// WriteEventf writes to the standard output and triggers events.
+func (ra *RequestAgent) WriteEventf(event EventFlag, color AnsiColorCode, format string, args ...interface{}) {
+	if ra == nil || ra.a == nil {
+		return
+	}
+	ts := ra.now()
+	if ra.bufferTail(tailEvent{ts: ts, eventFlag: event, color: color, format: format, state: args}) {
+		return
+	}
+	ra.a.writeEventf(ts, event, color, format, args...)
+}
+
+// ErrorEventWithState writes an error and triggers events with the request and a given state.
+func (ra *RequestAgent) ErrorEventWithState(event EventFlag, color AnsiColorCode, err error, state ...interface{}) error {
+	if ra == nil || ra.a == nil {
+		return err
+	}
+	if err != nil {
+		event, color = ra.a.classifyError(event, color, err)
+		if event == EventError || event == EventFatalError {
+			atomic.StoreInt32(&ra.errored, 1)
+		}
+		if ra.a.IsEnabled(event) && ra.a.shouldWrite(event) {
+			ra.a.errorEvent(ra.now(), event, color, "%+v", err, append([]interface{}{ra.req}, state...)...)
+		}
+		if event == EventFatalError {
+			ra.a.onFatal(err)
+		}
+	}
+	return err
+}
+
+// Errored returns if an error (or fatal) event was fired through the request agent.
+func (ra *RequestAgent) Errored() bool {
+	if ra == nil {
+		return false
+	}
+	return atomic.LoadInt32(&ra.errored) == 1
+}
+
+// OnEvent fires the currently configured event listeners.
+func (ra *RequestAgent) OnEvent(eventFlag EventFlag, state ...interface{}) {
+	if ra == nil || ra.a == nil {
+		return
+	}
+	ts := ra.now()
+	if ra.bufferTail(tailEvent{ts: ts, eventFlag: eventFlag, state: state, listeners: true}) {
+		return
+	}
+	ra.a.onEvent(ts, eventFlag, state...)
+}
+
+// now returns the current time as a time source carrying the request's fields and its context's values
+// (see `FieldsFromTimeSource` and `ContextFromTimeSource`).
+func (ra *RequestAgent) now() TimeSource {
+	ts := withFields(TimeNow(), ra.fields())
+	if ra.req == nil {
+		return ts
+	}
+	return withContext(ts, ra.req.Context())
+}
+
+// fields returns the request id, trace, route, user and tenant as event fields, omitting empty ones.
+func (ra *RequestAgent) fields() Fields {
+	ra.fieldsLock.RLock()
+	defer ra.fieldsLock.RUnlock()
+	var traceID, spanID string
+	if ra.trace != nil {
+		traceID, spanID = ra.trace.TraceID, ra.trace.SpanID
+	}
+	fields := Fields{}
+	for _, field := range [][2]string{
+		{"request_id", ra.requestID},
+		{"trace_id", traceID},
+		{"span_id", spanID},
+		{"route", ra.route},
+		{"user", ra.user},
+		{"tenant", ra.tenant},
+	} {
+		if len(field[1]) > 0 {
+			fields[field[0]] = field[1]
+		}
+	}
+	return fields
+}
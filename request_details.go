@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"net/http"
+	"time"
+)
+
+// RequestDetail is a detail of a request included in request complete events, see `Middleware.SetRequestDetails`.
+type RequestDetail string
+
+const (
+	// RequestDetailReferer is the `Referer` header of a request.
+	RequestDetailReferer RequestDetail = "referer"
+	// RequestDetailUserAgent is the `User-Agent` header of a request.
+	RequestDetailUserAgent RequestDetail = "user_agent"
+	// RequestDetailHost is the host a request was sent to.
+	RequestDetailHost RequestDetail = "host"
+)
+
+// RequestDetailFields returns the given details of a request as fields, leaving out those the request doesn't have.
+func RequestDetailFields(req *http.Request, details ...RequestDetail) Fields {
+	fields := Fields{}
+	for _, detail := range details {
+		var value string
+		switch detail {
+		case RequestDetailReferer:
+			value = req.Referer()
+		case RequestDetailUserAgent:
+			value = req.UserAgent()
+		case RequestDetailHost:
+			value = req.Host
+		}
+		if len(value) > 0 {
+			fields[string(detail)] = value
+		}
+	}
+	return fields
+}
+
+// RequestCompleteListener is a listener for request events that's also handed the request's details
+// (see `Middleware.SetRequestDetails`), if any.
+type RequestCompleteListener func(writer *Writer, ts TimeSource, req *http.Request, statusCode, contentLengthBytes int, elapsed time.Duration, details Fields)
+
+// NewRequestCompleteListener returns a new handler for request events with details,
+// e.g. `NewRequestCompleteListener(WriteRequestComplete)`.
+func NewRequestCompleteListener(listener RequestCompleteListener) EventListener {
+	return func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		if len(state) < 4 {
+			return
+		}
+
+		req, err := stateAsRequest(state[0])
+		if err != nil {
+			return
+		}
+
+		statusCode, err := stateAsInteger(state[1])
+		if err != nil {
+			return
+		}
+
+		contentLengthBytes, err := stateAsInteger(state[2])
+		if err != nil {
+			return
+		}
+
+		elapsed, err := stateAsDuration(state[3])
+		if err != nil {
+			return
+		}
+
+		var details Fields
+		if len(state) > 4 {
+			details, _ = state[4].(Fields)
+		}
+		listener(writer, ts, req, statusCode, contentLengthBytes, elapsed, details)
+	}
+}
+
+// WriteRequestComplete is a helper method to write request complete events with their details to a writer,
+// e.g. `[web.request] ... 200 12ms 1.2kB host=example.com user_agent="curl/8.0"`.
+func WriteRequestComplete(writer *Writer, ts TimeSource, req *http.Request, statusCode, contentLengthBytes int, elapsed time.Duration, details Fields) {
+	var suffix string
+	if len(details) > 0 {
+		suffix = writer.FormatFields(details)
+	}
+	writeRequestWithSuffix(writer, ts, EventWebRequest, req, statusCode, contentLengthBytes, elapsed, suffix)
+}
@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestRequestDetailFields(t *testing.T) {
+	assert := assert.New(t)
+
+	req := httptest.NewRequest("GET", "http://shop.example.com/", nil)
+	req.Header.Set("User-Agent", "curl/8.0")
+	assert.Equal(Fields{"user_agent": "curl/8.0", "host": "shop.example.com"},
+		RequestDetailFields(req, RequestDetailReferer, RequestDetailUserAgent, RequestDetailHost))
+}
+
+func TestMiddlewareRequestDetails(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventWebRequest), NewWriter(buffer))
+	defer da.Close()
+	da.Writer().SetUseAnsiColors(false)
+	da.AddEventListener(EventWebRequest, NewRequestCompleteListener(WriteRequestComplete))
+
+	mw := NewMiddleware(da)
+	mw.SetRequestDetails(RequestDetailReferer, RequestDetailUserAgent, RequestDetailHost)
+	handler := mw.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {})
+
+	req := httptest.NewRequest("GET", "/pricing", nil)
+	req.Header.Set("Referer", "https://search.example.com/?q=shop")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11)\r\n")
+	handler(httptest.NewRecorder(), req)
+	da.Flush()
+
+	output := buffer.String()
+	assert.True(strings.Contains(output, `host=example.com referer="https://search.example.com/?q=shop" user_agent="Mozilla/5.0 (X11)\r\n"`), output)
+}
@@ -0,0 +1,178 @@
+package logger
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// EventRequestStats fires for periodic per-route request stats summaries.
+	EventRequestStats EventFlag = "request_stats"
+
+	// DefaultRequestStatsSampleSize is the number of most recent latencies kept per route to compute percentiles.
+	DefaultRequestStatsSampleSize = 1024
+)
+
+// NewRequestStats returns a new per-route request stats aggregator.
+func NewRequestStats() *RequestStats {
+	return &RequestStats{
+		sampleSize: DefaultRequestStatsSampleSize,
+		routes:     map[string]*routeStats{},
+	}
+}
+
+// RequestStats aggregates latency percentiles and status class counts per route from request events.
+// Routes are taken from the request agent (see `Middleware.SetRouteProvider`), falling back to the path.
+type RequestStats struct {
+	sync.Mutex
+	sampleSize int
+	routes     map[string]*routeStats
+
+	stop chan struct{}
+}
+
+// RouteStats are the aggregated stats for a route.
+type RouteStats struct {
+	Route     string
+	Count     int
+	Status2xx int
+	Status3xx int
+	Status4xx int
+	Status5xx int
+	P50       time.Duration
+	P95       time.Duration
+	P99       time.Duration
+}
+
+type routeStats struct {
+	count      int
+	statuses   [6]int
+	latencies  []time.Duration
+	nextSample int
+}
+
+// Register adds the request stats listener for `EventWebRequest` to an agent.
+func (rs *RequestStats) Register(agent *Agent) {
+	agent.AddEventListener(EventWebRequest, rs.Listener())
+}
+
+// Listener returns a listener for request events that records stats.
+func (rs *RequestStats) Listener() EventListener {
+	return NewRequestListener(func(_ *Writer, _ TimeSource, req *http.Request, statusCode, _ int, elapsed time.Duration) {
+		rs.Record(GetRoute(req), statusCode, elapsed)
+	})
+}
+
+// Record records a completed request.
+func (rs *RequestStats) Record(route string, statusCode int, elapsed time.Duration) {
+	rs.Lock()
+	defer rs.Unlock()
+
+	stats, hasStats := rs.routes[route]
+	if !hasStats {
+		stats = &routeStats{}
+		rs.routes[route] = stats
+	}
+	stats.count++
+	if class := statusCode / 100; class > 0 && class < len(stats.statuses) {
+		stats.statuses[class]++
+	}
+	if len(stats.latencies) < rs.sampleSize {
+		stats.latencies = append(stats.latencies, elapsed)
+	} else if rs.sampleSize > 0 {
+		stats.latencies[stats.nextSample] = elapsed
+		stats.nextSample = (stats.nextSample + 1) % rs.sampleSize
+	}
+}
+
+// Route returns the stats for a route.
+func (rs *RequestStats) Route(route string) (RouteStats, bool) {
+	rs.Lock()
+	defer rs.Unlock()
+	stats, hasStats := rs.routes[route]
+	if !hasStats {
+		return RouteStats{}, false
+	}
+	return stats.snapshot(route), true
+}
+
+// Routes returns the stats for all routes, sorted by route.
+func (rs *RequestStats) Routes() []RouteStats {
+	rs.Lock()
+	defer rs.Unlock()
+	routes := make([]RouteStats, 0, len(rs.routes))
+	for route, stats := range rs.routes {
+		routes = append(routes, stats.snapshot(route))
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].Route < routes[j].Route
+	})
+	return routes
+}
+
+// Reset clears all recorded stats.
+func (rs *RequestStats) Reset() {
+	rs.Lock()
+	rs.routes = map[string]*routeStats{}
+	rs.Unlock()
+}
+
+// StartSummary writes an `EventRequestStats` summary line per route to an agent on an interval,
+// resetting the stats after each summary.
+func (rs *RequestStats) StartSummary(agent *Agent, interval time.Duration) {
+	rs.Lock()
+	if rs.stop != nil {
+		rs.Unlock()
+		return
+	}
+	rs.stop = make(chan struct{})
+	stop := rs.stop
+	rs.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				routes := rs.Routes()
+				rs.Reset()
+				for _, route := range routes {
+					agent.WriteEventf(EventRequestStats, ColorLightBlack, "%s count=%d p50=%v p95=%v p99=%v 2xx=%d 3xx=%d 4xx=%d 5xx=%d",
+						route.Route, route.Count, route.P50, route.P95, route.P99, route.Status2xx, route.Status3xx, route.Status4xx, route.Status5xx)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopSummary stops writing periodic summaries.
+func (rs *RequestStats) StopSummary() {
+	rs.Lock()
+	defer rs.Unlock()
+	if rs.stop != nil {
+		close(rs.stop)
+		rs.stop = nil
+	}
+}
+
+func (s *routeStats) snapshot(route string) RouteStats {
+	sorted := make([]time.Duration, len(s.latencies))
+	copy(sorted, s.latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return RouteStats{
+		Route:     route,
+		Count:     s.count,
+		Status2xx: s.statuses[2],
+		Status3xx: s.statuses[3],
+		Status4xx: s.statuses[4],
+		Status5xx: s.statuses[5],
+		P50:       PercentileOfDuration(sorted, 50),
+		P95:       PercentileOfDuration(sorted, 95),
+		P99:       PercentileOfDuration(sorted, 99),
+	}
+}
@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestRequestStatsRecord(t *testing.T) {
+	assert := assert.New(t)
+
+	rs := NewRequestStats()
+	for x := 1; x <= 100; x++ {
+		rs.Record("/users/:id", http.StatusOK, time.Duration(x)*time.Millisecond)
+	}
+	rs.Record("/users/:id", http.StatusNotFound, time.Millisecond)
+	rs.Record("/users/:id", http.StatusInternalServerError, time.Millisecond)
+	rs.Record("/", http.StatusOK, time.Millisecond)
+
+	stats, ok := rs.Route("/users/:id")
+	assert.True(ok)
+	assert.Equal(102, stats.Count)
+	assert.Equal(100, stats.Status2xx)
+	assert.Equal(1, stats.Status4xx)
+	assert.Equal(1, stats.Status5xx)
+	assert.Equal(49*time.Millisecond, stats.P50)
+	assert.Equal(95*time.Millisecond, stats.P95)
+
+	routes := rs.Routes()
+	assert.Len(routes, 2)
+	assert.Equal("/", routes[0].Route)
+
+	rs.Reset()
+	_, ok = rs.Route("/")
+	assert.False(ok)
+}
+
+func TestRequestStatsListener(t *testing.T) {
+	assert := assert.New(t)
+
+	da := New(NewEventFlagSet(EventWebRequest))
+	defer da.Close()
+	rs := NewRequestStats()
+	rs.Register(da)
+
+	da.Sync().OnEvent(EventWebRequest, httptest.NewRequest("GET", "/foo", nil), http.StatusOK, 0, time.Millisecond)
+	stats, ok := rs.Route("/foo")
+	assert.True(ok)
+	assert.Equal(1, stats.Count)
+}
+
+func TestRequestStatsSummary(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := newSignalOutput()
+	da := NewWithWriter(NewEventFlagSet(EventRequestStats), NewWriter(buffer))
+	defer da.Close()
+	da.Writer().SetUseAnsiColors(false)
+
+	rs := NewRequestStats()
+	rs.Record("/foo", http.StatusOK, time.Millisecond)
+	rs.StartSummary(da, time.Millisecond)
+	<-buffer.written
+	rs.StopSummary()
+	assert.True(strings.Contains(buffer.String(), "/foo count=1 p50=1ms"), buffer.String())
+}
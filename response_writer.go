@@ -1,6 +1,10 @@
 package logger
 
-import "net/http"
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
 
 // ResponseWrapper is a type that wraps a response.
 type ResponseWrapper interface {
@@ -14,28 +18,94 @@ func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
 	}
 }
 
-// ResponseWriter a better response writer
+// ResponseWriter a better response writer.
+// It records the status code (an implicit 200 if `WriteHeader` isn't called), the bytes written
+// (including through `ReadFrom`) and if the response was flushed.
 type ResponseWriter struct {
 	innerResponse http.ResponseWriter
 	statusCode    int
 	contentLength int
+	flushed       bool
+
+	captureMaxBytes int
+	captured        *bytes.Buffer
+	truncated       bool
+}
+
+// SetBodyCapture sets the response writer to keep a copy of (at most `maxBytes` of) the body written,
+// see `CapturedBody`. It must be set before the body is written; while capturing, `ReadFrom` can't use sendfile.
+func (rw *ResponseWriter) SetBodyCapture(maxBytes int) {
+	rw.captureMaxBytes = maxBytes
+	rw.captured = bytes.NewBuffer(nil)
+}
+
+// CapturedBody returns the body captured (see `SetBodyCapture`), or nil if capture wasn't set.
+func (rw *ResponseWriter) CapturedBody() []byte {
+	if rw.captured == nil {
+		return nil
+	}
+	return rw.captured.Bytes()
+}
+
+// CapturedBodyTruncated returns if the body was longer than the captured part.
+func (rw *ResponseWriter) CapturedBodyTruncated() bool {
+	return rw.truncated
+}
+
+// capture keeps a copy of a written chunk of the body, up to the capture limit.
+func (rw *ResponseWriter) capture(b []byte) {
+	if rw.captured == nil {
+		return
+	}
+	remaining := rw.captureMaxBytes - rw.captured.Len()
+	if len(b) > remaining {
+		rw.truncated = true
+		b = b[:remaining]
+	}
+	rw.captured.Write(b)
 }
 
 // Write writes the data to the response.
 func (rw *ResponseWriter) Write(b []byte) (int, error) {
+	if rw.statusCode == 0 {
+		rw.statusCode = http.StatusOK
+	}
 	bytesWritten, err := rw.innerResponse.Write(b)
+	rw.capture(b[:bytesWritten])
 	rw.contentLength = rw.contentLength + bytesWritten
 	return bytesWritten, err
 }
 
+// ReadFrom copies a reader to the response, using the inner response's `ReadFrom` (e.g. for sendfile) if it has one.
+func (rw *ResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if rw.statusCode == 0 {
+		rw.statusCode = http.StatusOK
+	}
+	if rw.captured != nil {
+		r = io.TeeReader(r, captureWriter{rw})
+	}
+	var bytesWritten int64
+	var err error
+	if readerFrom, isReaderFrom := rw.innerResponse.(io.ReaderFrom); isReaderFrom {
+		bytesWritten, err = readerFrom.ReadFrom(r)
+	} else {
+		bytesWritten, err = io.Copy(rw.innerResponse, r)
+	}
+	rw.contentLength = rw.contentLength + int(bytesWritten)
+	return bytesWritten, err
+}
+
 // Header accesses the response header collection.
 func (rw *ResponseWriter) Header() http.Header {
 	return rw.innerResponse.Header()
 }
 
 // WriteHeader is actually a terrible name and this writes the status code.
+// Like net/http, only the first status code written is kept.
 func (rw *ResponseWriter) WriteHeader(code int) {
-	rw.statusCode = code
+	if rw.statusCode == 0 {
+		rw.statusCode = code
+	}
 	rw.innerResponse.WriteHeader(code)
 }
 
@@ -44,13 +114,27 @@ func (rw *ResponseWriter) InnerWriter() http.ResponseWriter {
 	return rw.innerResponse
 }
 
-// Flush is a no op on raw response writers.
-func (rw *ResponseWriter) Flush() error {
-	return nil
+// Flush flushes the inner response (if it is an http.Flusher), and records that the response was streamed.
+func (rw *ResponseWriter) Flush() {
+	if rw.statusCode == 0 {
+		rw.statusCode = http.StatusOK
+	}
+	rw.flushed = true
+	if flusher, isFlusher := rw.innerResponse.(http.Flusher); isFlusher {
+		flusher.Flush()
+	}
+}
+
+// Flushed returns if the response was flushed (streamed) by the handler.
+func (rw *ResponseWriter) Flushed() bool {
+	return rw.flushed
 }
 
-// StatusCode returns the status code.
+// StatusCode returns the status code, which is an implicit 200 if the handler never wrote one.
 func (rw *ResponseWriter) StatusCode() int {
+	if rw.statusCode == 0 {
+		return http.StatusOK
+	}
 	return rw.statusCode
 }
 
@@ -58,3 +142,13 @@ func (rw *ResponseWriter) StatusCode() int {
 func (rw *ResponseWriter) ContentLength() int {
 	return rw.contentLength
 }
+
+// captureWriter captures what's read from a reader copied to the response, see `ReadFrom`.
+type captureWriter struct {
+	rw *ResponseWriter
+}
+
+func (cw captureWriter) Write(b []byte) (int, error) {
+	cw.rw.capture(b)
+	return len(b), nil
+}
@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestResponseWriterImplicitStatus(t *testing.T) {
+	assert := assert.New(t)
+
+	rw := NewResponseWriter(httptest.NewRecorder())
+	assert.Equal(http.StatusOK, rw.StatusCode())
+
+	rw = NewResponseWriter(httptest.NewRecorder())
+	rw.WriteHeader(http.StatusNotFound)
+	rw.WriteHeader(http.StatusInternalServerError)
+	assert.Equal(http.StatusNotFound, rw.StatusCode())
+}
+
+func TestResponseWriterStreaming(t *testing.T) {
+	assert := assert.New(t)
+
+	recorder := httptest.NewRecorder()
+	rw := NewResponseWriter(recorder)
+	var _ http.Flusher = rw
+
+	rw.Write([]byte("chunk one "))
+	rw.Flush()
+	written, err := rw.ReadFrom(strings.NewReader("chunk two"))
+	assert.Nil(err)
+	assert.Equal(9, written)
+
+	assert.True(rw.Flushed())
+	assert.True(recorder.Flushed)
+	assert.Equal(19, rw.ContentLength())
+	assert.Equal("chunk one chunk two", recorder.Body.String())
+}
+
+func TestResponseWriterBodyCapture(t *testing.T) {
+	assert := assert.New(t)
+
+	rw := NewResponseWriter(httptest.NewRecorder())
+	assert.Nil(rw.CapturedBody())
+	rw.SetBodyCapture(10)
+	rw.Write([]byte("hello "))
+	rw.ReadFrom(strings.NewReader("streamed world"))
+
+	assert.Equal("hello stre", string(rw.CapturedBody()))
+	assert.True(rw.CapturedBodyTruncated())
+	assert.Equal(20, rw.ContentLength())
+}
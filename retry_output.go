@@ -0,0 +1,274 @@
+package logger
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRetryOutputMaxRetries is the default number of retries for a failed write.
+	DefaultRetryOutputMaxRetries = 3
+	// DefaultRetryOutputInitialBackoff is the default delay before the first retry.
+	DefaultRetryOutputInitialBackoff = 10 * time.Millisecond
+	// DefaultRetryOutputMaxBackoff is the default upper bound on the delay between retries.
+	DefaultRetryOutputMaxBackoff = time.Second
+	// DefaultRetryOutputMaxTotalBackoff is the default upper bound on the delays of all of a write's retries.
+	DefaultRetryOutputMaxTotalBackoff = 2 * time.Second
+	// DefaultRetryOutputBreakerThreshold is the default number of consecutive failed writes that opens the circuit.
+	DefaultRetryOutputBreakerThreshold = 5
+	// DefaultRetryOutputBreakerCooldown is the default time the circuit stays open before a write is attempted again.
+	DefaultRetryOutputBreakerCooldown = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned by a RetryOutput when its circuit is open and it has no fallback.
+var ErrCircuitOpen = errors.New("Output circuit breaker is open")
+
+// NewRetryOutput returns a new output that retries failed writes to the inner output
+// with exponential backoff, and stops writing to it for a cooldown period after
+// consecutive failures.
+func NewRetryOutput(output io.Writer) *RetryOutput {
+	return &RetryOutput{
+		output:           output,
+		maxRetries:       DefaultRetryOutputMaxRetries,
+		initialBackoff:   DefaultRetryOutputInitialBackoff,
+		maxBackoff:       DefaultRetryOutputMaxBackoff,
+		maxTotalBackoff:  DefaultRetryOutputMaxTotalBackoff,
+		breakerThreshold: DefaultRetryOutputBreakerThreshold,
+		breakerCooldown:  DefaultRetryOutputBreakerCooldown,
+		syncRoot:         &sync.Mutex{},
+		writeRoot:        &sync.Mutex{},
+	}
+}
+
+// RetryOutput wraps an output with a retry policy and a circuit breaker.
+// Writes that still fail after retrying (or that arrive while the circuit is open)
+// are sent to the fallback output if one is set.
+type RetryOutput struct {
+	output   io.Writer
+	fallback io.Writer
+
+	maxRetries       int
+	initialBackoff   time.Duration
+	maxBackoff       time.Duration
+	maxTotalBackoff  time.Duration
+	breakerThreshold int
+	breakerCooldown  time.Duration
+
+	syncRoot            *sync.Mutex
+	writeRoot           *sync.Mutex // serializes writes; released while backing off unless a write is partly written.
+	consecutiveFailures int
+	openedAt            time.Time
+}
+
+// Write writes to the inner output, retrying on failure. A retry after a partial write
+// resumes from the first byte that wasn't written, and only the bytes the inner output
+// didn't take are sent to the fallback. Writes are serialized, but other writes go ahead
+// while a write that hasn't written anything yet backs off. Retries stop once their delays
+// would exceed the max total backoff (see `SetMaxTotalBackoff`), which bounds how long a write
+// (and a caller serializing its writes, e.g. a `Writer`) is held up.
+func (ro *RetryOutput) Write(buffer []byte) (int, error) {
+	ro.writeRoot.Lock()
+	defer ro.writeRoot.Unlock()
+
+	if ro.IsOpen() {
+		return ro.writeFallback(buffer, ErrCircuitOpen)
+	}
+
+	ro.syncRoot.Lock()
+	maxRetries, backoff, maxBackoff, maxTotalBackoff := ro.maxRetries, ro.initialBackoff, ro.maxBackoff, ro.maxTotalBackoff
+	ro.syncRoot.Unlock()
+
+	var total int
+	var err error
+	var slept time.Duration
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			if maxTotalBackoff > 0 && slept+backoff > maxTotalBackoff {
+				break
+			}
+			ro.backoff(backoff, total > 0)
+			slept += backoff
+			backoff = backoff << 1
+			if maxBackoff > 0 && backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+		var written int
+		written, err = ro.output.Write(buffer[total:])
+		total += written
+		if err == nil {
+			ro.onSuccess()
+			return total, nil
+		}
+	}
+
+	ro.onFailure()
+	written, err := ro.writeFallback(buffer[total:], err)
+	return total + written, err
+}
+
+// backoff waits before a retry, letting other writes go ahead unless the write is partly written
+// (so its remainder follows the bytes already written). The write lock is held on return.
+func (ro *RetryOutput) backoff(delay time.Duration, partlyWritten bool) {
+	if partlyWritten {
+		time.Sleep(delay)
+		return
+	}
+	ro.writeRoot.Unlock()
+	time.Sleep(delay)
+	ro.writeRoot.Lock()
+}
+
+// IsOpen returns if the circuit is open, i.e. writes are currently short-circuited.
+func (ro *RetryOutput) IsOpen() bool {
+	ro.syncRoot.Lock()
+	defer ro.syncRoot.Unlock()
+	if ro.openedAt.IsZero() {
+		return false
+	}
+	return time.Now().UTC().Sub(ro.openedAt) < ro.breakerCooldown
+}
+
+// Close closes the inner and fallback outputs (if they are io.Closers).
+func (ro *RetryOutput) Close() error {
+	var err error
+	if closer, isCloser := ro.output.(io.Closer); isCloser {
+		err = closer.Close()
+	}
+	if closer, isCloser := ro.Fallback().(io.Closer); isCloser {
+		if closeErr := closer.Close(); closeErr != nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// Fallback returns the output that receives writes the inner output could not take.
+func (ro *RetryOutput) Fallback() io.Writer {
+	ro.syncRoot.Lock()
+	defer ro.syncRoot.Unlock()
+	return ro.fallback
+}
+
+// SetFallback sets the output that receives writes the inner output could not take.
+func (ro *RetryOutput) SetFallback(fallback io.Writer) {
+	ro.syncRoot.Lock()
+	defer ro.syncRoot.Unlock()
+	ro.fallback = fallback
+}
+
+// MaxRetries returns the number of retries after the first failed attempt.
+func (ro *RetryOutput) MaxRetries() int {
+	ro.syncRoot.Lock()
+	defer ro.syncRoot.Unlock()
+	return ro.maxRetries
+}
+
+// SetMaxRetries sets the number of retries after the first failed attempt.
+// A negative value is treated as 0; the first attempt is always made.
+func (ro *RetryOutput) SetMaxRetries(maxRetries int) {
+	ro.syncRoot.Lock()
+	defer ro.syncRoot.Unlock()
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	ro.maxRetries = maxRetries
+}
+
+// InitialBackoff returns the delay before the first retry.
+func (ro *RetryOutput) InitialBackoff() time.Duration {
+	ro.syncRoot.Lock()
+	defer ro.syncRoot.Unlock()
+	return ro.initialBackoff
+}
+
+// SetInitialBackoff sets the delay before the first retry; it doubles for each subsequent retry.
+func (ro *RetryOutput) SetInitialBackoff(backoff time.Duration) {
+	ro.syncRoot.Lock()
+	defer ro.syncRoot.Unlock()
+	ro.initialBackoff = backoff
+}
+
+// MaxBackoff returns the upper bound on the delay between retries.
+func (ro *RetryOutput) MaxBackoff() time.Duration {
+	ro.syncRoot.Lock()
+	defer ro.syncRoot.Unlock()
+	return ro.maxBackoff
+}
+
+// SetMaxBackoff sets the upper bound on the delay between retries.
+func (ro *RetryOutput) SetMaxBackoff(backoff time.Duration) {
+	ro.syncRoot.Lock()
+	defer ro.syncRoot.Unlock()
+	ro.maxBackoff = backoff
+}
+
+// MaxTotalBackoff returns the upper bound on the delays of all of a write's retries.
+func (ro *RetryOutput) MaxTotalBackoff() time.Duration {
+	ro.syncRoot.Lock()
+	defer ro.syncRoot.Unlock()
+	return ro.maxTotalBackoff
+}
+
+// SetMaxTotalBackoff sets the upper bound on the delays of all of a write's retries; a write stops retrying
+// (and goes to the fallback) rather than wait longer. A value <= 0 leaves the retries unbounded but for `SetMaxRetries`.
+func (ro *RetryOutput) SetMaxTotalBackoff(backoff time.Duration) {
+	ro.syncRoot.Lock()
+	defer ro.syncRoot.Unlock()
+	ro.maxTotalBackoff = backoff
+}
+
+// BreakerThreshold returns the number of consecutive failed writes that opens the circuit.
+func (ro *RetryOutput) BreakerThreshold() int {
+	ro.syncRoot.Lock()
+	defer ro.syncRoot.Unlock()
+	return ro.breakerThreshold
+}
+
+// SetBreakerThreshold sets the number of consecutive failed writes that opens the circuit.
+// A value <= 0 disables the circuit breaker.
+func (ro *RetryOutput) SetBreakerThreshold(threshold int) {
+	ro.syncRoot.Lock()
+	defer ro.syncRoot.Unlock()
+	ro.breakerThreshold = threshold
+}
+
+// BreakerCooldown returns how long the circuit stays open.
+func (ro *RetryOutput) BreakerCooldown() time.Duration {
+	ro.syncRoot.Lock()
+	defer ro.syncRoot.Unlock()
+	return ro.breakerCooldown
+}
+
+// SetBreakerCooldown sets how long the circuit stays open.
+func (ro *RetryOutput) SetBreakerCooldown(cooldown time.Duration) {
+	ro.syncRoot.Lock()
+	defer ro.syncRoot.Unlock()
+	ro.breakerCooldown = cooldown
+}
+
+func (ro *RetryOutput) onSuccess() {
+	ro.syncRoot.Lock()
+	ro.consecutiveFailures = 0
+	ro.openedAt = time.Time{}
+	ro.syncRoot.Unlock()
+}
+
+func (ro *RetryOutput) onFailure() {
+	ro.syncRoot.Lock()
+	ro.consecutiveFailures++
+	if ro.breakerThreshold > 0 && ro.consecutiveFailures >= ro.breakerThreshold {
+		ro.openedAt = time.Now().UTC()
+	}
+	ro.syncRoot.Unlock()
+}
+
+func (ro *RetryOutput) writeFallback(buffer []byte, err error) (int, error) {
+	fallback := ro.Fallback()
+	if fallback == nil {
+		return 0, err
+	}
+	return fallback.Write(buffer)
+}
@@ -0,0 +1,225 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+type failingOutput struct {
+	failures int
+	attempts int
+	buffer   bytes.Buffer
+}
+
+func (fo *failingOutput) Write(b []byte) (int, error) {
+	fo.attempts++
+	if fo.failures != 0 {
+		fo.failures--
+		return 0, errors.New("write failed")
+	}
+	return fo.buffer.Write(b)
+}
+
+func TestRetryOutputRetries(t *testing.T) {
+	assert := assert.New(t)
+
+	inner := &failingOutput{failures: 2}
+	output := NewRetryOutput(inner)
+	output.SetInitialBackoff(time.Microsecond)
+
+	written, err := output.Write([]byte("test"))
+	assert.Nil(err)
+	assert.Equal(4, written)
+	assert.Equal(3, inner.attempts)
+	assert.Equal("test", inner.buffer.String())
+	assert.False(output.IsOpen())
+}
+
+func TestRetryOutputFallback(t *testing.T) {
+	assert := assert.New(t)
+
+	inner := &failingOutput{failures: -1}
+	fallback := bytes.NewBuffer(nil)
+	output := NewRetryOutput(inner)
+	output.SetInitialBackoff(time.Microsecond)
+	output.SetMaxRetries(1)
+	output.SetFallback(fallback)
+
+	_, err := output.Write([]byte("test"))
+	assert.Nil(err)
+	assert.Equal(2, inner.attempts)
+	assert.Equal("test", fallback.String())
+}
+
+func TestRetryOutputCircuitBreaker(t *testing.T) {
+	assert := assert.New(t)
+
+	inner := &failingOutput{failures: -1}
+	output := NewRetryOutput(inner)
+	output.SetMaxRetries(0)
+	output.SetBreakerThreshold(2)
+	output.SetBreakerCooldown(time.Hour)
+
+	_, err := output.Write([]byte("one"))
+	assert.NotNil(err)
+	assert.False(output.IsOpen())
+	_, err = output.Write([]byte("two"))
+	assert.NotNil(err)
+	assert.True(output.IsOpen())
+
+	_, err = output.Write([]byte("three"))
+	assert.Equal(ErrCircuitOpen, err)
+	assert.Equal(2, inner.attempts)
+
+	output.SetBreakerCooldown(0)
+	inner.failures = 0
+	_, err = output.Write([]byte("four"))
+	assert.Nil(err)
+	assert.False(output.IsOpen())
+	assert.Equal("four", inner.buffer.String())
+}
+
+// partialOutput writes at most `limit` bytes of the first write, failing it.
+type partialOutput struct {
+	limit  int
+	buffer bytes.Buffer
+}
+
+func (po *partialOutput) Write(b []byte) (int, error) {
+	if po.limit > 0 && len(b) > po.limit {
+		written, _ := po.buffer.Write(b[:po.limit])
+		po.limit = 0
+		return written, errors.New("short write")
+	}
+	return po.buffer.Write(b)
+}
+
+func TestRetryOutputResumesPartialWrite(t *testing.T) {
+	assert := assert.New(t)
+
+	inner := &partialOutput{limit: 3}
+	output := NewRetryOutput(inner)
+	output.SetInitialBackoff(time.Microsecond)
+
+	written, err := output.Write([]byte("hello world"))
+	assert.Nil(err)
+	assert.Equal(11, written)
+	assert.Equal("hello world", inner.buffer.String())
+}
+
+func TestRetryOutputSettersDuringWrites(t *testing.T) {
+	assert := assert.New(t)
+
+	output := NewRetryOutput(bytes.NewBuffer(nil))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for x := 0; x < 100; x++ {
+			output.Write([]byte("test"))
+		}
+	}()
+	for x := 0; x < 100; x++ {
+		output.SetMaxRetries(x)
+		output.SetInitialBackoff(time.Duration(x))
+		output.SetFallback(nil)
+	}
+	<-done
+	assert.Equal(99, output.MaxRetries())
+}
+
+func TestRetryOutputNegativeRetries(t *testing.T) {
+	assert := assert.New(t)
+
+	inner := &failingOutput{failures: -1}
+	output := NewRetryOutput(inner)
+	output.SetMaxRetries(-1)
+	assert.Equal(0, output.MaxRetries())
+
+	_, err := output.Write([]byte("test"))
+	assert.NotNil(err)
+	assert.Equal(1, inner.attempts)
+}
+
+// partialFailingOutput takes `limit` bytes of the first write and fails every write.
+type partialFailingOutput struct {
+	limit  int
+	buffer bytes.Buffer
+}
+
+func (pfo *partialFailingOutput) Write(b []byte) (int, error) {
+	written, _ := pfo.buffer.Write(b[:pfo.limit])
+	pfo.limit = 0
+	return written, errors.New("write failed")
+}
+
+func TestRetryOutputFallbackRemainder(t *testing.T) {
+	assert := assert.New(t)
+
+	inner := &partialFailingOutput{limit: 5}
+	fallback := bytes.NewBuffer(nil)
+	output := NewRetryOutput(inner)
+	output.SetMaxRetries(0)
+	output.SetFallback(fallback)
+
+	written, err := output.Write([]byte("hello world"))
+	assert.Nil(err)
+	assert.Equal(11, written)
+	assert.Equal("hello", inner.buffer.String())
+	assert.Equal(" world", fallback.String())
+}
+
+func TestRetryOutputMaxTotalBackoff(t *testing.T) {
+	assert := assert.New(t)
+
+	inner := &failingOutput{failures: -1}
+	output := NewRetryOutput(inner)
+	output.SetMaxRetries(10)
+	output.SetInitialBackoff(10 * time.Millisecond)
+	output.SetMaxTotalBackoff(25 * time.Millisecond)
+
+	_, err := output.Write([]byte("test"))
+	assert.NotNil(err)
+	assert.Equal(2, inner.attempts, "the second retry would wait past the max total backoff")
+}
+
+// onceFailingOutput fails its first write, signalling it has.
+type onceFailingOutput struct {
+	failed chan struct{}
+	buffer bytes.Buffer
+}
+
+func (ofo *onceFailingOutput) Write(b []byte) (int, error) {
+	select {
+	case <-ofo.failed:
+		return ofo.buffer.Write(b)
+	default:
+		close(ofo.failed)
+		return 0, errors.New("write failed")
+	}
+}
+
+func TestRetryOutputWritesDuringBackoff(t *testing.T) {
+	assert := assert.New(t)
+
+	inner := &onceFailingOutput{failed: make(chan struct{})}
+	output := NewRetryOutput(inner)
+	output.SetInitialBackoff(500 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		output.Write([]byte("retried "))
+	}()
+	<-inner.failed
+
+	start := time.Now()
+	_, err := output.Write([]byte("next "))
+	assert.Nil(err)
+	assert.True(time.Since(start) < 250*time.Millisecond, "a write doesn't wait for another's backoff")
+	<-done
+	assert.Equal("next retried ", inner.buffer.String())
+}
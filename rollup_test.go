@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestRollupSummary(t *testing.T) {
+	assert := assert.New(t)
+
+	writer := NewWriter(bytes.NewBuffer(nil))
+	rollup := NewRollup()
+	assert.Empty(rollup.Summary(writer, time.Minute))
+
+	for x := 0; x < 999; x++ {
+		rollup.RecordRequest(http.StatusOK, time.Duration(x+1)*time.Millisecond)
+	}
+	rollup.RecordRequest(http.StatusBadGateway, time.Second)
+	rollup.Add("cache hits", 1500)
+	rollup.CounterListener("cache misses")(writer, SystemClock, EventFlag("cache.miss"))
+
+	assert.Equal("last 1m0s: 1,000 requests, 0.1% 5xx, p95 950ms, 1,500 cache hits, 1 cache misses", rollup.Summary(writer, time.Minute))
+	assert.Empty(rollup.Summary(writer, time.Minute), "the summary resets the rollup")
+}
+
+func TestRollupStartSummary(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := newSignalOutput()
+	da := NewWithWriter(NewEventFlagSet(EventInfo, EventWebRequest), NewWriter(buffer))
+	defer da.Close()
+	da.Writer().SetUseAnsiColors(false)
+
+	rollup := NewRollup()
+	rollup.Register(da)
+	da.OnEvent(EventWebRequest, &http.Request{}, http.StatusInternalServerError, 0, time.Millisecond)
+	da.Flush()
+
+	rollup.StartSummary(da, time.Millisecond)
+	<-buffer.written
+	rollup.StopSummary()
+	assert.True(strings.Contains(buffer.String(), "[info] last 1ms: 1 requests, 100.0% 5xx, p95 1ms"), buffer.String())
+}
@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"net/http"
+	"time"
+)
+
+// NewRoundTripper returns a new http.RoundTripper that logs outbound requests to an agent
+// and propagates the request id and trace context of the inbound request (if any) on them.
+// If `inner` is nil, `http.DefaultTransport` is used.
+func NewRoundTripper(agent *Agent, inner http.RoundTripper) *RoundTripper {
+	if inner == nil {
+		inner = http.DefaultTransport
+	}
+	return &RoundTripper{
+		agent:            agent,
+		inner:            inner,
+		requestIDHeader:  HeaderRequestID,
+		httpDumpMaxBytes: DefaultHTTPDumpMaxBytes,
+	}
+}
+
+// RoundTripper is an http.RoundTripper that fires `EventWebClientRequest` for outbound requests.
+type RoundTripper struct {
+	agent           *Agent
+	inner           http.RoundTripper
+	requestIDHeader string
+
+	httpDump         bool
+	httpDumpMaxBytes int
+}
+
+// RequestIDHeader returns the header request ids are propagated on.
+func (rt *RoundTripper) RequestIDHeader() string { return rt.requestIDHeader }
+
+// SetRequestIDHeader sets the header request ids are propagated on.
+func (rt *RoundTripper) SetRequestIDHeader(header string) { rt.requestIDHeader = header }
+
+// HTTPDump returns if full request and response dumps are fired (see `EventWebDump`).
+func (rt *RoundTripper) HTTPDump() bool { return rt.httpDump }
+
+// SetHTTPDump sets if full request and response dumps (headers and capped bodies) are fired as `EventWebDump`
+// events, while that event is enabled.
+func (rt *RoundTripper) SetHTTPDump(httpDump bool) { rt.httpDump = httpDump }
+
+// HTTPDumpMaxBytes returns the number of body bytes included in dumps.
+func (rt *RoundTripper) HTTPDumpMaxBytes() int { return rt.httpDumpMaxBytes }
+
+// SetHTTPDumpMaxBytes sets the number of body bytes included in dumps.
+func (rt *RoundTripper) SetHTTPDumpMaxBytes(maxBytes int) { rt.httpDumpMaxBytes = maxBytes }
+
+// RoundTrip executes a single http transaction.
+func (rt *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if ra := ForRequest(req.Context()); ra != nil {
+		req = req.Clone(req.Context())
+		if len(ra.RequestID()) > 0 && len(req.Header.Get(rt.requestIDHeader)) == 0 {
+			req.Header.Set(rt.requestIDHeader, ra.RequestID())
+		}
+		if ra.TraceContext() != nil && len(req.Header.Get(HeaderTraceParent)) == 0 {
+			ra.TraceContext().Child().Inject(req.Header)
+		}
+	}
+
+	httpDump := rt.httpDump && rt.agent.IsEnabled(EventWebDump)
+	if httpDump {
+		// the dump replaces the body, so it mustn't be the caller's request.
+		req = req.Clone(req.Context())
+		dump := DumpRequest(req, rt.httpDumpMaxBytes, rt.agent.Writer().MaskedHeaders()...)
+		dump.Kind = HTTPDumpClientRequest
+		rt.agent.OnEventContext(req.Context(), EventWebDump, dump)
+	}
+
+	start := time.Now()
+	res, err := rt.inner.RoundTrip(req)
+	elapsed := time.Now().Sub(start)
+	if err != nil {
+		rt.agent.WarningWithReq(err, req)
+		return res, err
+	}
+	if httpDump {
+		dump := DumpResponse(res, rt.httpDumpMaxBytes, rt.agent.Writer().MaskedHeaders()...)
+		dump.Kind = HTTPDumpClientResponse
+		rt.agent.OnEventContext(req.Context(), EventWebDump, dump)
+	}
+	rt.agent.OnEvent(EventWebClientRequest, req, res.StatusCode, int(res.ContentLength), elapsed)
+	return res, err
+}
@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestRoundTripperPropagatesRequestID(t *testing.T) {
+	assert := assert.New(t)
+
+	var received, receivedTraceParent string
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		received = req.Header.Get(HeaderRequestID)
+		receivedTraceParent = req.Header.Get(HeaderTraceParent)
+		res.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	da := New(NewEventFlagSet(EventWebClientRequest))
+	defer da.Close()
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	da.AddEventListener(EventWebClientRequest, NewRequestListener(func(wr *Writer, ts TimeSource, req *http.Request, statusCode, contentLengthBytes int, elapsed time.Duration) {
+		defer wg.Done()
+		assert.Equal(http.StatusAccepted, statusCode)
+		assert.Equal("abc123", req.Header.Get(HeaderRequestID))
+	}))
+
+	client := &http.Client{Transport: NewRoundTripper(da, nil)}
+	ra := NewRequestAgent(da, nil, "abc123")
+	ra.SetTraceContext(NewTraceContext())
+	ctx := WithRequestAgent(httptest.NewRequest("GET", "/", nil).Context(), ra)
+	req, err := http.NewRequest("GET", server.URL, nil)
+	assert.Nil(err)
+	res, err := client.Do(req.WithContext(ctx))
+	assert.Nil(err)
+	res.Body.Close()
+	wg.Wait()
+
+	assert.Equal("abc123", received)
+	outbound, err := ParseTraceParent(receivedTraceParent, "")
+	assert.Nil(err)
+	assert.Equal(ra.TraceContext().TraceID, outbound.TraceID)
+	assert.NotEqual(ra.TraceContext().SpanID, outbound.ParentSpanID)
+	assert.Empty(req.Header.Get(HeaderRequestID))
+}
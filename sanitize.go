@@ -0,0 +1,185 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SanitizeControlChars escapes control characters (including CR and LF) in a value, e.g. `\n` is written as `\\n`,
+// so user supplied content can't forge log lines or write terminal escape sequences. Tabs are left as is.
+func SanitizeControlChars(value string) string {
+	return sanitizeControlChars(value, false)
+}
+
+func sanitizeControlChars(value string, keepNewlines bool) string {
+	if !hasControlChars(value, keepNewlines) {
+		return value
+	}
+	buffer := bytes.NewBuffer(nil)
+	for _, r := range value {
+		if isSanitizedControlChar(r, keepNewlines) {
+			quoted := strconv.QuoteRune(r)
+			buffer.WriteString(quoted[1 : len(quoted)-1])
+			continue
+		}
+		buffer.WriteRune(r)
+	}
+	return buffer.String()
+}
+
+func hasControlChars(value string, keepNewlines bool) bool {
+	return strings.IndexFunc(value, func(r rune) bool { return isSanitizedControlChar(r, keepNewlines) }) >= 0
+}
+
+func isSanitizedControlChar(r rune, keepNewlines bool) bool {
+	if r == '\t' || (keepNewlines && r == '\n') {
+		return false
+	}
+	return r < 0x20 || r == 0x7f
+}
+
+// sanitizedArg is a (string) format argument whose value is sanitized when it's formatted as text.
+type sanitizedArg struct {
+	arg          interface{}
+	keepNewlines bool
+}
+
+// Format implements fmt.Formatter. Only the text verbs (`%s`, `%v` and `%q`) are sanitized,
+// other verbs (e.g. `%x`) format the argument as is.
+func (sa sanitizedArg) Format(state fmt.State, verb rune) {
+	directive := formatDirective(state, verb)
+	switch verb {
+	case 's', 'v', 'q':
+		fmt.Fprint(state, sanitizeControlChars(fmt.Sprintf(directive, sa.arg), sa.keepNewlines))
+	default:
+		fmt.Fprintf(state, directive, sa.arg)
+	}
+}
+
+// sanitizedLinesArg is an error (or stack, see `StackProvider`) format argument whose formatted lines are sanitized
+// one by one when it's formatted as text, so its continuation lines (e.g. stack frames) are kept but can't forge log lines.
+type sanitizedLinesArg struct {
+	arg       interface{}
+	separator string
+}
+
+// Format implements fmt.Formatter.
+func (sla sanitizedLinesArg) Format(state fmt.State, verb rune) {
+	lines := strings.Split(fmt.Sprintf(formatDirective(state, verb), sla.arg), "\n")
+	for x, line := range lines {
+		lines[x] = sanitizeControlChars(line, false)
+	}
+	fmt.Fprint(state, strings.Join(lines, sla.separator))
+}
+
+// formatDirective returns the directive (flags, width and precision) a value is formatted with.
+func formatDirective(state fmt.State, verb rune) string {
+	directive := "%"
+	for _, flag := range "+-# 0" {
+		if state.Flag(int(flag)) {
+			directive += string(flag)
+		}
+	}
+	if width, hasWidth := state.Width(); hasWidth {
+		directive += strconv.Itoa(width)
+	}
+	if precision, hasPrecision := state.Precision(); hasPrecision {
+		directive += "." + strconv.Itoa(precision)
+	}
+	return directive + string(verb)
+}
+
+// SanitizeControlChars returns if user supplied content is sanitized before it's written.
+func (wr *Writer) SanitizeControlChars() bool { return wr.sanitizeControlChars }
+
+// SetSanitizeControlChars sets if control characters in user supplied content (request paths, headers, bodies
+// and message arguments) are escaped before they're written. It's on by default (see `DefaultWriterSanitizeControlChars`).
+// If a continuation prefix is set (see `SetContinuationPrefix`) newlines in message arguments are kept, as continuation lines
+// can't be mistaken for new lines. Errors (and their stack traces) are sanitized line by line, and without a continuation prefix
+// their continuation lines are prefixed with `DefaultContinuationPrefix`.
+func (wr *Writer) SetSanitizeControlChars(sanitize bool) { wr.sanitizeControlChars = sanitize }
+
+// Sanitize escapes control characters in a value if the writer sanitizes user supplied content.
+func (wr *Writer) Sanitize(value string) string {
+	if !wr.sanitizeControlChars {
+		return value
+	}
+	return sanitizeControlChars(value, false)
+}
+
+// SanitizeArgs wraps the string (byte slice, lazy, see `Lazy`, `fmt.Stringer` or error) arguments of a format's text verbs (`%s`, `%v` and `%q`)
+// so their formatted values are sanitized, if the writer sanitizes user supplied content. Other arguments are left as is,
+// so verbs like `%T`, `%*d` and `%p` are formatted as usual.
+func (wr *Writer) SanitizeArgs(format string, args ...interface{}) []interface{} {
+	if !wr.sanitizeControlChars || len(args) == 0 {
+		return args
+	}
+	textArgs := formatTextArgs(format, len(args))
+	sanitized := make([]interface{}, len(args))
+	for x, arg := range args {
+		sanitized[x] = arg
+		if !textArgs[x] {
+			continue
+		}
+		switch arg.(type) {
+		case string, []byte, *LazyValue:
+			sanitized[x] = sanitizedArg{arg: arg, keepNewlines: len(wr.continuationPrefix) > 0}
+		case error, stackFormatter:
+			sanitized[x] = sanitizedLinesArg{arg: arg, separator: wr.continuationSeparator()}
+		case fmt.Stringer:
+			sanitized[x] = sanitizedArg{arg: arg, keepNewlines: len(wr.continuationPrefix) > 0}
+		}
+	}
+	return sanitized
+}
+
+// continuationSeparator returns what separates the lines of a sanitized multi-line argument: a newline, that's
+// prefixed when the message is written, or else a newline and `DefaultContinuationPrefix`.
+func (wr *Writer) continuationSeparator() string {
+	if len(wr.continuationPrefix) > 0 {
+		return "\n"
+	}
+	return "\n" + DefaultContinuationPrefix
+}
+
+// formatTextArgs returns which of a format's arguments are formatted with a text verb (`%s`, `%v` or `%q`),
+// following `*` widths and precisions and explicit argument indexes as `fmt` does.
+func formatTextArgs(format string, count int) []bool {
+	textArgs := make([]bool, count)
+	arg := 0
+	for x := 0; x < len(format); x++ {
+		if format[x] != '%' {
+			continue
+		}
+		x++
+	directive:
+		for ; x < len(format); x++ {
+			switch c := format[x]; {
+			case c == '[':
+				end := strings.IndexByte(format[x:], ']')
+				if end < 0 {
+					return textArgs
+				}
+				if index, err := strconv.Atoi(format[x+1 : x+end]); err == nil {
+					arg = index - 1
+				}
+				x += end
+			case c == '*':
+				arg++
+			case strings.IndexByte("+-# 0.123456789", c) >= 0:
+			default:
+				break directive
+			}
+		}
+		if x >= len(format) || format[x] == '%' {
+			continue
+		}
+		if verb := format[x]; arg >= 0 && arg < count && (verb == 's' || verb == 'v' || verb == 'q') {
+			textArgs[arg] = true
+		}
+		arg++
+	}
+	return textArgs
+}
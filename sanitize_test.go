@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestSanitizeControlChars(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("plain\tvalue", SanitizeControlChars("plain\tvalue"))
+	assert.Equal(`forged\r\n2017-01-01 [info] admin logged in`, SanitizeControlChars("forged\r\n2017-01-01 [info] admin logged in"))
+	assert.Equal(`\x1b[31mred`, SanitizeControlChars("\033[31mred"))
+}
+
+func TestWriterSanitizeArgs(t *testing.T) {
+	assert := assert.New(t)
+
+	writer := NewWriter(bytes.NewBuffer(nil))
+	assert.True(writer.SanitizeControlChars())
+
+	format := "user: %q %s %05d"
+	assert.Equal(`user: "bob\nadmin" evil\r\nline 00042`, fmt.Sprintf(format, writer.SanitizeArgs(format, "bob\nadmin", []byte("evil\r\nline"), 42)...))
+	assert.Equal("line\n"+DefaultContinuationPrefix+`break\r`, fmt.Sprintf("%v", writer.SanitizeArgs("%v", errors.New("line\nbreak\r"))...))
+	format = "%T %*d %x %%s %-4.2s|"
+	assert.Equal(`string    42 0a62 %s \nb  |`, fmt.Sprintf(format, writer.SanitizeArgs(format, "\nbob", 5, 42, "\nb", "\nb")...))
+	forged := stringerFunc(func() string { return "bob\r\n2017-01-01T00:00:00Z [info] forged" })
+	assert.Equal(`user bob\r\n2017-01-01T00:00:00Z [info] forged`, fmt.Sprintf("user %v", writer.SanitizeArgs("user %v", forged)...))
+	format = "%[2]s %[1]d"
+	assert.Equal(`\na 1`, fmt.Sprintf(format, writer.SanitizeArgs(format, 1, "\na")...))
+
+	writer.SetContinuationPrefix(DefaultContinuationPrefix)
+	assert.Equal("line\nbreak", fmt.Sprintf("%v", writer.SanitizeArgs("%v", "line\nbreak")...))
+	assert.Equal("line\nbreak", fmt.Sprintf("%v", writer.SanitizeArgs("%v", errors.New("line\nbreak"))...))
+
+	writer.SetSanitizeControlChars(false)
+	assert.Equal("a\rb", writer.Sanitize("a\rb"))
+	assert.Equal("a\nb", fmt.Sprintf("%s", writer.SanitizeArgs("%s", "a\nb")...))
+}
+
+func TestAgentErrorfSanitized(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	writer := NewWriter(buffer)
+	writer.SetUseAnsiColors(false)
+	da := NewWithWriter(NewEventFlagSet(EventError), writer)
+	defer da.Close()
+	da.Sync().Errorf("bad path %s", "/x\r\n2017-01-01T00:00:00Z [info] admin logged in")
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	assert.Len(lines, 2)
+	assert.True(strings.HasPrefix(lines[1], DefaultContinuationPrefix), buffer.String())
+	assert.True(strings.Contains(lines[0], `/x\r`), buffer.String())
+}
+
+// stringerFunc is a `fmt.Stringer` returning a function's result.
+type stringerFunc func() string
+
+func (sf stringerFunc) String() string { return sf() }
+
+func TestWriteRequestSanitized(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	writer := NewWriter(buffer)
+	writer.SetUseAnsiColors(false)
+	writer.SetShowTimestamp(false)
+	writer.SetSanitizeControlChars(true)
+
+	req := httptest.NewRequest("GET", "/foo%0A2017-01-01%20[error]", nil)
+	WriteRequestStart(writer, TimeNow(), req)
+	assert.Equal(1, strings.Count(buffer.String(), "\n"))
+	assert.True(strings.Contains(buffer.String(), `/foo\n2017-01-01 [error]`), buffer.String())
+}
@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// SchemaVersion is the version of the json output schema, written as `schema_version` on each line (see `Record`).
+	// It's incremented when the shape of a line changes in a way parsers need to know about.
+	SchemaVersion = 1
+)
+
+// OutputFormat is how a writer renders lines.
+type OutputFormat int
+
+const (
+	// OutputFormatText renders lines as text, e.g. `2018-01-01T00:00:00Z [info] hello`.
+	OutputFormatText OutputFormat = iota
+	// OutputFormatJSON renders lines as json objects, one per line, in the shape of a `Record`.
+	OutputFormatJSON
+)
+
+// ParseOutputFormat parses an output format, e.g. `json` or `text` as read from `LOG_FORMAT`.
+// Anything but `json` is text.
+func ParseOutputFormat(value string) OutputFormat {
+	if strings.EqualFold(strings.TrimSpace(value), "json") {
+		return OutputFormatJSON
+	}
+	return OutputFormatText
+}
+
+// Record is a line of json output (see `OutputFormatJSON`) in the current schema version, e.g.
+// `{"schema_version":1,"time":"2018-01-01T00:00:00Z","flag":"info","message":"hello","fields":{"service":"api"}}`.
+//
+// The flag is the event a line was written for; lines written with a writer directly have none.
+// The sequence numbers are those of the line's event and of the line, if numbered (see `Agent.SetSequenceNumbers`
+// and `Writer.SetSequenceNumbers`).
+// Fields are the writer's static fields (see `Writer.SetStaticFields`) merged with the agent's default fields
+// (see `Agent.SetDefaultFields`).
+type Record struct {
+	SchemaVersion int       `json:"schema_version"`
+	Time          time.Time `json:"time"`
+	Flag          EventFlag `json:"flag,omitempty"`
+	Label         string    `json:"label,omitempty"`
+	Namespace     string    `json:"namespace,omitempty"`
+	Message       string    `json:"message"`
+	Fields        Fields    `json:"fields,omitempty"`
+	Sequence      uint64    `json:"seq,omitempty"`
+	SinkSequence  uint64    `json:"sink_seq,omitempty"`
+}
+
+// ErrRecordUnversioned is returned when parsing a json line that isn't a record, as it has no schema version.
+var ErrRecordUnversioned = errors.New("Json line has no schema version")
+
+// ParseRecord parses a line of json output of any schema version.
+func ParseRecord(line []byte) (Record, error) {
+	var record Record
+	if err := json.Unmarshal(line, &record); err != nil {
+		return Record{}, err
+	}
+	if record.SchemaVersion == 0 {
+		return Record{}, ErrRecordUnversioned
+	}
+	return record, nil
+}
+
+// OutputFormat returns how the writer renders lines.
+func (wr *Writer) OutputFormat() OutputFormat { return wr.outputFormat }
+
+// SetOutputFormat sets how the writer renders lines, e.g. `OutputFormatJSON` for log pipelines that parse json.
+func (wr *Writer) SetOutputFormat(format OutputFormat) {
+	wr.outputFormat = format
+	wr.resetFormatCache()
+}
+
+// isStructured returns if the writer renders lines as json.
+func (wr *Writer) isStructured() bool {
+	return wr != nil && wr.outputFormat == OutputFormatJSON
+}
+
+// newRecord returns the record for a line body written at a time source, which carries the line's event
+// and fields (see `withEvent` and `withFields`).
+func (wr *Writer) newRecord(ts TimeSource, body []byte) Record {
+	record := Record{
+		SchemaVersion: SchemaVersion,
+		Time:          ts.UTCNow(),
+		Flag:          EventFromTimeSource(ts),
+		Namespace:     wr.namespaceFor(ts),
+		Message:       strings.TrimSpace(StripAnsi(string(body))),
+		Sequence:      SequenceFromTimeSource(ts),
+		SinkSequence:  wr.nextSequence(),
+	}
+	if wr.showLabel {
+		record.Label = wr.label
+	}
+	if fields := FieldsFromTimeSource(ts); len(wr.fields) > 0 || len(fields) > 0 {
+		record.Fields = Fields{}
+		for key, value := range wr.fields {
+			record.Fields[key] = value
+		}
+		for key, value := range fields {
+			record.Fields[key] = value
+		}
+	}
+	return record
+}
+
+// fwriteRecord writes a line body as a json record to a given writer.
+func (wr *Writer) fwriteRecord(ts TimeSource, w io.Writer, body []byte) (int64, error) {
+	contents, err := json.Marshal(wr.newRecord(ts, body))
+	if err != nil {
+		return 0, err
+	}
+
+	buf := wr.bufferPool.Get()
+	defer wr.bufferPool.Put(buf)
+	buf.Write(contents)
+	buf.WriteRune(RuneNewline)
+	return wr.writeBuffer(w, buf)
+}
+
+// recordTimeSource returns the time source an agent writes an event's message with, which carries the agent's
+// namespace (see `SetNamespace`), and the event and the agent's default fields if the writer renders json.
+func (da *Agent) recordTimeSource(writer *Writer, ts TimeSource, eventFlag EventFlag) TimeSource {
+	ts = withNamespace(ts, da.agentNamespace())
+	if !writer.isStructured() {
+		return ts
+	}
+	return withFields(withEvent(ts, eventFlag), da.DefaultFields())
+}
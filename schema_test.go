@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestParseOutputFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(OutputFormatJSON, ParseOutputFormat(" JSON"))
+	assert.Equal(OutputFormatText, ParseOutputFormat("text"))
+	assert.Equal(OutputFormatText, ParseOutputFormat(""))
+}
+
+func TestAgentJSONOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	writer := NewWriter(buffer)
+	writer.SetOutputFormat(OutputFormatJSON)
+	writer.SetStaticFields(map[string]interface{}{"pod": "api-1"})
+	da := NewWithWriter(NewEventFlagSet(EventInfo, EventWebRequestSlow), writer)
+	defer da.Close()
+	da.SetDefaultFields(Fields{"service": "api"})
+	da.AddEventListener(EventWebRequestSlow, NewSlowRequestListener(WriteSlowRequest))
+
+	da.Infof("hello %s", "world")
+	da.Flush()
+	da.OnEvent(EventWebRequestSlow, httptest.NewRequest("GET", "/slow", nil), 30*time.Millisecond, 20*time.Millisecond)
+	da.Flush()
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Len(lines, 2)
+
+	record, err := ParseRecord([]byte(lines[0]))
+	assert.Nil(err)
+	assert.Equal(SchemaVersion, record.SchemaVersion)
+	assert.Equal(EventInfo, record.Flag)
+	assert.Equal("hello world", record.Message)
+	assert.Equal(Fields{"pod": "api-1", "service": "api"}, record.Fields)
+	assert.False(record.Time.IsZero())
+
+	record, err = ParseRecord([]byte(lines[1]))
+	assert.Nil(err)
+	assert.Equal(EventWebRequestSlow, record.Flag)
+	assert.True(strings.HasPrefix(record.Message, "GET /slow"), record.Message)
+}
+
+func TestParseRecordInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := ParseRecord([]byte("not json"))
+	assert.NotNil(err)
+
+	_, err = ParseRecord([]byte(`{"time":"2018-01-01T00:00:00Z","message":"hello"}`))
+	assert.Equal(ErrRecordUnversioned, err)
+}
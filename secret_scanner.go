@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"regexp"
+	"sync"
+)
+
+var (
+	// SecretPatternAWSAccessKeyID matches AWS access key ids.
+	SecretPatternAWSAccessKeyID = regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)
+	// SecretPatternJWT matches JSON web tokens.
+	SecretPatternJWT = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+	// SecretPatternPrivateKey matches PEM encoded private key blocks.
+	SecretPatternPrivateKey = regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`)
+)
+
+// NewSecretScanner returns a new secret scanner with the built in patterns.
+func NewSecretScanner() *SecretScanner {
+	return &SecretScanner{
+		patterns: []*regexp.Regexp{
+			SecretPatternAWSAccessKeyID,
+			SecretPatternJWT,
+			SecretPatternPrivateKey,
+		},
+	}
+}
+
+// SecretScanner replaces matches of a set of patterns with `RedactedValue`.
+// Set it on a writer (see `Writer.SetSecretScanner`) to scan everything before it is written; the agent's sinks
+// (recent events, live tail, received and forwarded lines, and statsd metrics) are scanned with the writer's scanner too.
+// Patterns can be added while it scans.
+type SecretScanner struct {
+	sync.RWMutex
+	patterns []*regexp.Regexp
+}
+
+// Patterns returns the patterns the scanner redacts.
+func (ss *SecretScanner) Patterns() []*regexp.Regexp {
+	ss.RLock()
+	defer ss.RUnlock()
+	return append([]*regexp.Regexp(nil), ss.patterns...)
+}
+
+// AddPattern adds a pattern to redact.
+func (ss *SecretScanner) AddPattern(pattern *regexp.Regexp) {
+	ss.Lock()
+	defer ss.Unlock()
+	ss.patterns = append(ss.patterns, pattern)
+}
+
+// AddPatternString compiles and adds a pattern to redact.
+func (ss *SecretScanner) AddPatternString(pattern string) error {
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	ss.AddPattern(compiled)
+	return nil
+}
+
+// Scan returns the contents with all matches redacted.
+// If nothing matches, the contents are returned as is.
+func (ss *SecretScanner) Scan(contents []byte) []byte {
+	if ss == nil {
+		return contents
+	}
+	ss.RLock()
+	defer ss.RUnlock()
+	redacted := []byte(RedactedValue)
+	for _, pattern := range ss.patterns {
+		if pattern.Match(contents) {
+			contents = pattern.ReplaceAllLiteral(contents, redacted)
+		}
+	}
+	return contents
+}
+
+// ScanString returns a string with all matches redacted.
+func (ss *SecretScanner) ScanString(contents string) string {
+	if ss == nil {
+		return contents
+	}
+	ss.RLock()
+	defer ss.RUnlock()
+	for _, pattern := range ss.patterns {
+		if pattern.MatchString(contents) {
+			contents = pattern.ReplaceAllLiteralString(contents, RedactedValue)
+		}
+	}
+	return contents
+}
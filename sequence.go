@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"bytes"
+	"strconv"
+	"sync/atomic"
+)
+
+// SequenceNumbers returns if the agent stamps a sequence number on each event, see `SetSequenceNumbers`.
+// Like `IsEnabled` it takes no locks.
+func (da *Agent) SequenceNumbers() bool {
+	if da == nil {
+		return false
+	}
+	return atomic.LoadInt32(&da.root().sequenceNumbers) == 1
+}
+
+// SetSequenceNumbers sets if the agent stamps a monotonically increasing sequence number on each event as it's fired,
+// written as `seq=42` (or the `seq` key of json output), so gaps downstream reveal events dropped on the way
+// (e.g. by a saturated queue, see `SetDropWhenSaturated`). The line written for an event and its listeners share
+// its number. Derived agents share their root's sequence.
+// Writers can also number the lines they write, see `Writer.SetSequenceNumbers`.
+func (da *Agent) SetSequenceNumbers(sequenceNumbers bool) {
+	atomic.StoreInt32(&da.root().sequenceNumbers, boolAsInt32(sequenceNumbers))
+}
+
+// Sequence returns the last sequence number the agent stamped on an event.
+func (da *Agent) Sequence() uint64 {
+	if da == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&da.root().sequence)
+}
+
+// numbered stamps the next sequence number on the time source of an event, if enabled.
+// It's called once per event, before the event's write and listeners are queued with the time source.
+func (da *Agent) numbered(ts TimeSource) TimeSource {
+	if !da.SequenceNumbers() {
+		return ts
+	}
+	return withSequence(ts, atomic.AddUint64(&da.root().sequence, 1))
+}
+
+// SequenceNumbers returns if the writer numbers the lines it writes, see `SetSequenceNumbers`.
+func (wr *Writer) SequenceNumbers() bool { return wr.sequenceNumbers }
+
+// SetSequenceNumbers sets if the writer stamps a monotonically increasing sequence number on each line it writes
+// (to either stream), written as `sink_seq=42` (or the `sink_seq` key of json output), so gaps downstream reveal
+// lines lost in transport.
+func (wr *Writer) SetSequenceNumbers(sequenceNumbers bool) { wr.sequenceNumbers = sequenceNumbers }
+
+// Sequence returns the last sequence number the writer stamped on a line.
+func (wr *Writer) Sequence() uint64 {
+	return atomic.LoadUint64(&wr.sequence)
+}
+
+// nextSequence returns the sequence number of the next line written, or 0 if lines aren't numbered.
+func (wr *Writer) nextSequence() uint64 {
+	if !wr.sequenceNumbers {
+		return 0
+	}
+	return atomic.AddUint64(&wr.sequence, 1)
+}
+
+// writeSequence writes the event and line sequence numbers (if any) to a buffer.
+func (wr *Writer) writeSequence(buf *bytes.Buffer, sequence, sinkSequence uint64) {
+	if sequence > 0 {
+		buf.WriteString("seq=" + strconv.FormatUint(sequence, 10))
+		buf.WriteRune(RuneSpace)
+	}
+	if sinkSequence > 0 {
+		buf.WriteString("sink_seq=" + strconv.FormatUint(sinkSequence, 10))
+		buf.WriteRune(RuneSpace)
+	}
+}
@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestAgentSequenceNumbers(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventInfo, EventWebRequestSlow), NewWriter(buffer))
+	defer da.Close()
+	da.Writer().SetUseAnsiColors(false)
+	assert.False(da.SequenceNumbers())
+
+	da.Infof("before")
+	da.Flush()
+	assert.False(strings.Contains(buffer.String(), "seq="), buffer.String())
+
+	clone := da.Clone()
+	clone.SetSequenceNumbers(true)
+	assert.True(da.SequenceNumbers())
+
+	var listenerSequence uint64
+	da.AddEventListener(EventWebRequestSlow, func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		listenerSequence = SequenceFromTimeSource(ts)
+	})
+	da.Infof("first")
+	clone.Infof("second")
+	da.Flush()
+	da.OnEvent(EventWebRequestSlow)
+	da.Flush()
+
+	output := buffer.String()
+	assert.True(strings.Contains(output, "seq=1 [info] first") || strings.Contains(output, "seq=1 [info] second"), output)
+	assert.True(strings.Contains(output, "seq=2 [info]"), output)
+	assert.Equal(3, listenerSequence)
+	assert.Equal(3, da.Sequence())
+}
+
+func TestAgentSequenceNumbersWithListener(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventInfo, EventError), NewWriter(buffer))
+	defer da.Close()
+	da.Writer().SetUseAnsiColors(false)
+	da.SetSequenceNumbers(true)
+
+	var listenerLock sync.Mutex
+	var listenerSequences []uint64
+	da.AddEventListener(EventInfo, func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		listenerLock.Lock()
+		listenerSequences = append(listenerSequences, SequenceFromTimeSource(ts))
+		listenerLock.Unlock()
+	})
+	da.Infof("first")
+	da.Infof("second")
+	da.Error(fmt.Errorf("third"))
+	da.Flush()
+
+	output := buffer.String()
+	assert.True(strings.Contains(output, "seq=1 [info] first"), output)
+	assert.True(strings.Contains(output, "seq=2 [info] second"), output)
+	assert.True(strings.Contains(output, "seq=3 [error] third"), output)
+	sort.Slice(listenerSequences, func(i, j int) bool { return listenerSequences[i] < listenerSequences[j] })
+	assert.Equal([]uint64{1, 2}, listenerSequences)
+	assert.Equal(3, da.Sequence())
+}
+
+func TestWriterSequenceNumbers(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	writer := NewWriter(buffer)
+	writer.SetShowTimestamp(false)
+	writer.SetSequenceNumbers(true)
+	writer.Write([]byte("one"))
+	writer.WriteWithTimeSource(withSequence(SystemClock, 7), []byte("two"))
+
+	assert.Equal("sink_seq=1 one\nseq=7 sink_seq=2 two\n", buffer.String())
+	assert.Equal(2, writer.Sequence())
+
+	buffer.Reset()
+	writer.SetOutputFormat(OutputFormatJSON)
+	writer.WriteWithTimeSource(withSequence(SystemClock, 8), []byte("three"))
+	record, err := ParseRecord(buffer.Bytes())
+	assert.Nil(err)
+	assert.Equal(8, record.Sequence)
+	assert.Equal(3, record.SinkSequence)
+}
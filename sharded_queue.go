@@ -0,0 +1,120 @@
+package logger
+
+import (
+	"context"
+	"hash/fnv"
+)
+
+// NewShardedQueue returns a new queue of single worker shards, splitting `DefaultAgentQueueLength` items between them
+// (see `SetMaxWorkItems`). Items with the same key always land on the same shard, so they're processed in the order
+// they were enqueued.
+func NewShardedQueue(shards int) *ShardedQueue {
+	if shards < 1 {
+		shards = 1
+	}
+	sq := &ShardedQueue{shards: make([]*EventQueue, shards)}
+	for x := range sq.shards {
+		shard := NewEventQueue(1, 1)
+		shard.SetScaleInterval(0)
+		sq.shards[x] = shard
+	}
+	sq.SetMaxWorkItems(DefaultAgentQueueLength)
+	return sq
+}
+
+// ShardedQueue is a set of single worker event queues keyed by a shard key.
+type ShardedQueue struct {
+	shards []*EventQueue
+}
+
+// MaxWorkItems returns the maximum number of items queued across the shards.
+func (sq *ShardedQueue) MaxWorkItems() (total int) {
+	for _, shard := range sq.shards {
+		total += shard.MaxWorkItems()
+	}
+	return
+}
+
+// SetMaxWorkItems splits a maximum number of queued items between the shards; like `EventQueue.SetMaxWorkItems`
+// it must be set before `Start` to change their capacity.
+func (sq *ShardedQueue) SetMaxWorkItems(maxWorkItems int) {
+	perShard := maxWorkItems / len(sq.shards)
+	if perShard < 1 {
+		perShard = 1
+	}
+	for _, shard := range sq.shards {
+		shard.SetMaxWorkItems(perShard)
+	}
+}
+
+// Shards returns the shards.
+func (sq *ShardedQueue) Shards() []*EventQueue {
+	return sq.shards
+}
+
+// SetPanicHandler sets the handler for actions that panic on any shard, see `EventQueue.SetPanicHandler`.
+func (sq *ShardedQueue) SetPanicHandler(handler QueuePanicHandler) {
+	for _, shard := range sq.shards {
+		shard.SetPanicHandler(handler)
+	}
+}
+
+// Start starts the shards.
+func (sq *ShardedQueue) Start() {
+	for _, shard := range sq.shards {
+		shard.Start()
+	}
+}
+
+// Enqueue adds an action to the shard for a key.
+func (sq *ShardedQueue) Enqueue(key string, action QueueAction, args ...interface{}) {
+	sq.Shard(key).Enqueue(action, args...)
+}
+
+// Shard returns the shard for a key.
+func (sq *ShardedQueue) Shard(key string) *EventQueue {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return sq.shards[hash.Sum32()%uint32(len(sq.shards))]
+}
+
+// Len returns the number of queued items across the shards.
+func (sq *ShardedQueue) Len() (total int) {
+	for _, shard := range sq.shards {
+		total += shard.Len()
+	}
+	return
+}
+
+// HighWater returns the largest high water mark of the shards.
+func (sq *ShardedQueue) HighWater() (highWater int) {
+	for _, shard := range sq.shards {
+		if shardHighWater := shard.HighWater(); shardHighWater > highWater {
+			highWater = shardHighWater
+		}
+	}
+	return
+}
+
+// Flush blocks until the items queued before the call have been processed.
+func (sq *ShardedQueue) Flush() {
+	for _, shard := range sq.shards {
+		shard.Flush()
+	}
+}
+
+// Close stops the shards and waits for them to process their remaining items and exit.
+func (sq *ShardedQueue) Close() error {
+	return sq.CloseContext(context.Background())
+}
+
+// CloseContext closes the shards, waiting for their workers until the context ends (see `EventQueue.CloseContext`).
+func (sq *ShardedQueue) CloseContext(ctx context.Context) error {
+	var err error
+	for _, shard := range sq.shards {
+		if closeErr := shard.CloseContext(ctx); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
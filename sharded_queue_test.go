@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestShardedQueueOrdering(t *testing.T) {
+	assert := assert.New(t)
+
+	sq := NewShardedQueue(4)
+	sq.Start()
+	defer sq.Close()
+	assert.Len(sq.Shards(), 4)
+	assert.True(sq.Shard("a") == sq.Shard("a"))
+
+	var order []int
+	for x := 0; x < 100; x++ {
+		sq.Enqueue("a", func(args ...interface{}) error {
+			order = append(order, args[0].(int))
+			return nil
+		}, x)
+	}
+	sq.Flush()
+	assert.Len(order, 100)
+	for x := range order {
+		assert.Equal(x, order[x])
+	}
+}
+
+func TestShardedQueueMaxWorkItems(t *testing.T) {
+	assert := assert.New(t)
+
+	sq := NewShardedQueue(4)
+	assert.Equal(DefaultAgentQueueLength, sq.MaxWorkItems())
+	sq.SetMaxWorkItems(100)
+	assert.Equal(100, sq.MaxWorkItems())
+	for _, shard := range sq.Shards() {
+		assert.Equal(25, shard.MaxWorkItems())
+	}
+}
@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultShutdownTimeout is how long `HandleShutdown` waits for servers and the queue to drain.
+	DefaultShutdownTimeout = 10 * time.Second
+)
+
+var (
+	// DefaultShutdownEvents are the events still written while shutting down (if they were enabled).
+	DefaultShutdownEvents = []EventFlag{EventFatalError, EventError, EventWarning}
+
+	// ErrShutdownTimeout is returned when the queue doesn't drain before the shutdown deadline.
+	ErrShutdownTimeout = errors.New("Shutdown timed out draining the event queue")
+)
+
+// HandleShutdown blocks until the process receives SIGTERM or SIGINT, then shuts the servers and
+// the agent down (see `Agent.Shutdown`) within `DefaultShutdownTimeout`. Call it last in `main`,
+// with the servers listening in other goroutines, so the process exits once the shutdown is done.
+func HandleShutdown(agent *Agent, servers ...*http.Server) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(signals)
+	return awaitShutdown(signals, agent, servers...)
+}
+
+func awaitShutdown(signals chan os.Signal, agent *Agent, servers ...*http.Server) error {
+	sig := <-signals
+	agent.Warningf("received %v; shutting down", sig)
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultShutdownTimeout)
+	defer cancel()
+	return agent.Shutdown(ctx, servers...)
+}
+
+// Shutdown gracefully shuts the agent down: it stops writing events other than `DefaultShutdownEvents`,
+// shuts the servers down (waiting for their in-flight requests), drains the queue, and closes the agent,
+// flushing and closing its outputs. If the context ends before the queue drains the agent is closed
+// anyway, dropping the remaining events, and `ErrShutdownTimeout` is returned.
+func (da *Agent) Shutdown(ctx context.Context, servers ...*http.Server) error {
+	if da == nil {
+		return nil
+	}
+	root := da.root()
+	events := root.Events()
+	shutdownEvents := NewEventFlagSetNone()
+	for _, event := range DefaultShutdownEvents {
+		if events.IsEnabled(event) {
+			shutdownEvents.Enable(event)
+		}
+	}
+	root.SetVerbosity(shutdownEvents)
+
+	var err error
+	for _, server := range servers {
+		if shutdownErr := server.Shutdown(ctx); shutdownErr != nil && err == nil {
+			err = shutdownErr
+		}
+	}
+
+	flushed := make(chan struct{})
+	go func() {
+		root.Flush()
+		close(flushed)
+	}()
+	select {
+	case <-flushed:
+	case <-ctx.Done():
+		root.Metaf("shutdown deadline passed with %d events queued; dropping them", root.QueueDepth())
+		if err == nil {
+			err = ErrShutdownTimeout
+		}
+	}
+
+	if closeErr := root.closeContext(ctx); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return err
+}
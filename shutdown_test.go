@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestAgentShutdown(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventInfo, EventError), NewWriter(buffer))
+	da.Infof("before shutdown")
+
+	assert.Nil(da.Clone().Shutdown(context.Background(), &http.Server{}))
+	assert.False(da.IsEnabled(EventInfo))
+	assert.True(da.IsEnabled(EventError))
+	assert.False(da.IsEnabled(EventWarning), "events that weren't enabled stay disabled")
+	assert.True(strings.Contains(buffer.String(), "before shutdown"), buffer.String())
+}
+
+func TestAgentShutdownTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	meta := bytes.NewBuffer(nil)
+	output := &lockedBuffer{}
+	da := NewWithWriter(NewEventFlagSet(EventError), NewWriter(output))
+	da.SetMetaOutput(meta)
+
+	unblock := make(chan struct{})
+	defer close(unblock)
+	da.AddEventListener(EventError, func(_ *Writer, _ TimeSource, _ EventFlag, _ ...interface{}) {
+		<-unblock
+	})
+	da.Errorf("blocked")
+	// only the listener is left blocking the queue once the line is written.
+	for !strings.Contains(output.String(), "blocked") {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(ErrShutdownTimeout, da.Shutdown(ctx))
+	assert.True(strings.Contains(meta.String(), "shutdown deadline passed"), meta.String())
+}
+
+func TestAwaitShutdown(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventWarning), NewWriter(buffer))
+
+	signals := make(chan os.Signal, 1)
+	signals <- syscall.SIGTERM
+	assert.Nil(awaitShutdown(signals, da))
+	assert.True(strings.Contains(buffer.String(), "shutting down"), buffer.String())
+}
@@ -0,0 +1,239 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+const (
+	// SignatureSeparator separates a record from its signature.
+	SignatureSeparator = " hmac-sha256="
+)
+
+var (
+	// ErrSignatureInvalid is returned when a signed record is missing its signature or was altered.
+	ErrSignatureInvalid = errors.New("Invalid record signature")
+)
+
+// SigningKeyProvider returns the key used to sign records, e.g. from a KMS.
+type SigningKeyProvider func() ([]byte, error)
+
+// NewSignedOutput returns an output that appends an HMAC-SHA256 signature of each record (write) to the record,
+// e.g. `[audit] user=bailey action=delete hmac-sha256=<hex>`, so tampering with the written lines can be detected
+// (see `VerifySignedRecords`). Each signature also covers the signature of the record before it, chaining the records
+// so deleted, reordered or duplicated records break the chain too.
+func NewSignedOutput(output io.Writer, key []byte) *SignedOutput {
+	return &SignedOutput{output: output, key: key}
+}
+
+// NewSignedOutputWithKeyProvider returns a signed output with the key from a provider.
+func NewSignedOutputWithKeyProvider(output io.Writer, provider SigningKeyProvider) (*SignedOutput, error) {
+	key, err := provider()
+	if err != nil {
+		return nil, err
+	}
+	if len(key) == 0 {
+		return nil, errors.New("Signing key is empty")
+	}
+	return NewSignedOutput(output, key), nil
+}
+
+// NewSignedOutputFromEnvironment returns a signed output with the base64 encoded key from `LOG_SIGNING_KEY`.
+func NewSignedOutputFromEnvironment(output io.Writer) (*SignedOutput, error) {
+	return NewSignedOutputWithKeyProvider(output, SigningKeyFromEnvironment)
+}
+
+// SigningKeyFromEnvironment is a key provider that reads the base64 encoded key from `LOG_SIGNING_KEY`.
+func SigningKeyFromEnvironment() ([]byte, error) {
+	encoded := os.Getenv(EnvironmentVariableLogSigningKey)
+	if len(encoded) == 0 {
+		return nil, fmt.Errorf("Environment Variable `%s` required", EnvironmentVariableLogSigningKey)
+	}
+	return base64.StdEncoding.DecodeString(encoded)
+}
+
+// NewSignedFileOutput returns a new rotating file output whose records are signed with the given key.
+// If the file already has records (e.g. from before a restart), the chain continues from its last record.
+func NewSignedFileOutput(filePath string, key []byte) (*SignedOutput, error) {
+	signature, err := lastSignatureOfFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	fileOutput, err := NewFileOutputWithDefaults(filePath)
+	if err != nil {
+		return nil, err
+	}
+	output := NewSignedOutput(fileOutput, key)
+	output.SetSignature(signature)
+	return output, nil
+}
+
+// SignedOutput signs records before writing them to the inner output.
+type SignedOutput struct {
+	output    io.Writer
+	key       []byte
+	signature []byte
+	syncRoot  sync.Mutex
+}
+
+// Write signs a record, chained to the record written before it, and writes it, with its signature, to the inner output.
+func (so *SignedOutput) Write(record []byte) (int, error) {
+	body := bytes.TrimSuffix(record, []byte{'\n'})
+
+	so.syncRoot.Lock()
+	defer so.syncRoot.Unlock()
+	signature := signRecord(so.key, so.signature, body)
+	signed := make([]byte, 0, len(body)+len(SignatureSeparator)+len(signature)+1)
+	signed = append(signed, body...)
+	signed = append(signed, SignatureSeparator...)
+	signed = append(signed, signature...)
+	signed = append(signed, '\n')
+	if _, err := so.output.Write(signed); err != nil {
+		return 0, err
+	}
+	so.signature = signature
+	return len(record), nil
+}
+
+// Signature returns the signature of the last record written, i.e. the head of the chain. Store it elsewhere
+// (e.g. periodically, or on shutdown) as a checkpoint, so records removed from the end of a file can be detected too
+// (see `VerifySignedRecordsChain`).
+func (so *SignedOutput) Signature() []byte {
+	so.syncRoot.Lock()
+	defer so.syncRoot.Unlock()
+	return so.signature
+}
+
+// SetSignature sets the signature the next record is chained to, e.g. that of the last record (see `LastSignature`)
+// of a file a new process appends to, so its records continue the file's chain.
+func (so *SignedOutput) SetSignature(signature []byte) {
+	so.syncRoot.Lock()
+	defer so.syncRoot.Unlock()
+	so.signature = signature
+}
+
+// Flush flushes the inner output (if it buffers writes).
+func (so *SignedOutput) Flush() error {
+	return FlushOutput(so.output)
+}
+
+// Reopen reopens the inner output (if it can reopen its files).
+func (so *SignedOutput) Reopen() error {
+	so.syncRoot.Lock()
+	defer so.syncRoot.Unlock()
+	return ReopenOutput(so.output)
+}
+
+// SetMetaReporter sets how the inner output reports problems that don't fail writes, see `OutputMetaReporter`.
+func (so *SignedOutput) SetMetaReporter(reporter MetaReporter) {
+	SetOutputMetaReporter(so.output, reporter)
+}
+
+// Close closes the inner output (if it is an io.Closer).
+func (so *SignedOutput) Close() error {
+	if closer, isCloser := so.output.(io.Closer); isCloser {
+		return closer.Close()
+	}
+	return nil
+}
+
+// VerifySignedRecords verifies the records written by a `SignedOutput` from the start of its chain, returning the number
+// of valid records read before the first invalid one (and `ErrSignatureInvalid`), or the total number of records if
+// they're all valid. A record is invalid if it was altered, or if records before it were deleted, reordered or duplicated.
+// Multi-line records (see `SetContinuationPrefix`) are signed, and verified, as a whole.
+// Files rotated by the output continue its chain, so verify them in order, e.g. with an `io.MultiReader`
+// or with `VerifySignedRecordsChain`.
+func VerifySignedRecords(input io.Reader, key []byte) (int, error) {
+	verified, _, err := VerifySignedRecordsChain(input, key, nil)
+	return verified, err
+}
+
+// VerifySignedRecordsChain verifies records (see `VerifySignedRecords`) that continue a chain from the record with a given
+// signature, e.g. the last record of the file rotated before `input`, or nil for the start of the chain.
+// It also returns the signature of the last valid record, which for a complete file matches a checkpoint of
+// `SignedOutput.Signature` taken after its last write, and continues the chain in the next file.
+func VerifySignedRecordsChain(input io.Reader, key, previous []byte) (int, []byte, error) {
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var verified int
+	var record []byte
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		separator := bytes.LastIndex(line, []byte(SignatureSeparator))
+		if separator < 0 {
+			// a continuation line of a multi-line record.
+			record = append(append(record, line...), '\n')
+			continue
+		}
+		record = append(record, line[:separator]...)
+		signature := line[separator+len(SignatureSeparator):]
+		if !hmac.Equal(signature, signRecord(key, previous, record)) {
+			return verified, previous, ErrSignatureInvalid
+		}
+		verified++
+		previous = append([]byte(nil), signature...)
+		record = record[:0]
+	}
+	if err := scanner.Err(); err != nil {
+		return verified, previous, err
+	}
+	if len(record) > 0 {
+		return verified, previous, ErrSignatureInvalid
+	}
+	return verified, previous, nil
+}
+
+// LastSignature returns the signature of the last signed record written by a `SignedOutput`, or nil if there isn't one.
+// It doesn't verify the records, see `VerifySignedRecords`.
+func LastSignature(input io.Reader) ([]byte, error) {
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var last []byte
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if separator := bytes.LastIndex(line, []byte(SignatureSeparator)); separator >= 0 {
+			last = append(last[:0], line[separator+len(SignatureSeparator):]...)
+		}
+	}
+	return last, scanner.Err()
+}
+
+// lastSignatureOfFile returns the signature of the last signed record of a file, or nil if it doesn't exist yet.
+func lastSignatureOfFile(filePath string) ([]byte, error) {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LastSignature(file)
+}
+
+// signRecord returns the hex encoded HMAC-SHA256 of a record chained to the signature of the record before it (if any).
+// Each is prefixed with its length, so a signature and record can't be split differently to the same input.
+func signRecord(key, previous, record []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	var length [8]byte
+	for _, part := range [][]byte{previous, record} {
+		binary.BigEndian.PutUint64(length[:], uint64(len(part)))
+		mac.Write(length[:])
+		mac.Write(part)
+	}
+	signature := make([]byte, sha256.Size*2)
+	hex.Encode(signature, mac.Sum(nil))
+	return signature
+}
@@ -0,0 +1,138 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestSignedOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	key := []byte("audit key")
+	signed := bytes.NewBuffer(nil)
+	writer := NewWriter(NewSignedOutput(signed, key))
+	writer.SetUseAnsiColors(false)
+	writer.SetShowTimestamp(false)
+	writer.SetContinuationPrefix(DefaultContinuationPrefix)
+	writer.Printf("user=bailey action=delete")
+	writer.Printf("multi\nline")
+	writer.Printf("amount=100")
+
+	lines := strings.Split(strings.TrimSpace(signed.String()), "\n")
+	assert.Len(lines, 4)
+	assert.True(strings.HasPrefix(lines[0], "user=bailey action=delete"+SignatureSeparator))
+
+	verified, err := VerifySignedRecords(bytes.NewReader(signed.Bytes()), key)
+	assert.Nil(err)
+	assert.Equal(3, verified)
+
+	tampered := strings.Replace(signed.String(), "amount=100", "amount=900", 1)
+	verified, err = VerifySignedRecords(strings.NewReader(tampered), key)
+	assert.Equal(ErrSignatureInvalid, err)
+	assert.Equal(2, verified)
+
+	tampered = strings.Replace(signed.String(), "  | line", "  | lie", 1)
+	verified, err = VerifySignedRecords(strings.NewReader(tampered), key)
+	assert.Equal(ErrSignatureInvalid, err)
+	assert.Equal(1, verified)
+
+	verified, err = VerifySignedRecords(bytes.NewReader(signed.Bytes()), []byte("wrong key"))
+	assert.Equal(ErrSignatureInvalid, err)
+	assert.Zero(verified)
+}
+
+func TestSignedOutputChain(t *testing.T) {
+	assert := assert.New(t)
+
+	key := []byte("audit key")
+	signed := bytes.NewBuffer(nil)
+	output := NewSignedOutput(signed, key)
+	for _, record := range []string{"one", "two", "three", "four"} {
+		output.Write([]byte(record + "\n"))
+	}
+	lines := strings.SplitAfter(signed.String(), "\n")[:4]
+
+	verified, err := VerifySignedRecords(strings.NewReader(lines[0]+lines[2]+lines[3]), key)
+	assert.Equal(ErrSignatureInvalid, err, "a deleted record breaks the chain")
+	assert.Equal(1, verified)
+
+	verified, err = VerifySignedRecords(strings.NewReader(lines[1]+lines[2]+lines[3]), key)
+	assert.Equal(ErrSignatureInvalid, err, "so does deleting the first record")
+	assert.Zero(verified)
+
+	verified, err = VerifySignedRecords(strings.NewReader(lines[0]+lines[2]+lines[1]+lines[3]), key)
+	assert.Equal(ErrSignatureInvalid, err, "a reordered record breaks the chain")
+	assert.Equal(1, verified)
+
+	verified, err = VerifySignedRecords(strings.NewReader(lines[0]+lines[1]+lines[1]+lines[2]), key)
+	assert.Equal(ErrSignatureInvalid, err, "a duplicated record breaks the chain")
+	assert.Equal(2, verified)
+
+	verified, previous, err := VerifySignedRecordsChain(strings.NewReader(lines[0]+lines[1]), key, nil)
+	assert.Nil(err)
+	assert.Equal(2, verified)
+	verified, last, err := VerifySignedRecordsChain(strings.NewReader(lines[2]+lines[3]), key, previous)
+	assert.Nil(err, "a rotated file continues the chain")
+	assert.Equal(2, verified)
+	assert.Equal(output.Signature(), last)
+
+	_, last, err = VerifySignedRecordsChain(strings.NewReader(lines[0]+lines[1]+lines[2]), key, nil)
+	assert.Nil(err)
+	assert.NotEqual(string(output.Signature()), string(last), "a truncated file doesn't match the checkpoint")
+}
+
+func TestSignedFileOutputRestart(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "signed_output")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "audit.log")
+
+	key := []byte("audit key")
+	for _, records := range [][]string{{"one", "two"}, {"three"}} {
+		output, err := NewSignedFileOutput(filePath, key)
+		assert.Nil(err)
+		for _, record := range records {
+			_, err = output.Write([]byte(record + "\n"))
+			assert.Nil(err)
+		}
+		assert.Nil(output.Close())
+	}
+
+	contents, err := ioutil.ReadFile(filePath)
+	assert.Nil(err)
+	verified, err := VerifySignedRecords(bytes.NewReader(contents), key)
+	assert.Nil(err, "a restarted output continues the file's chain")
+	assert.Equal(3, verified)
+}
+
+func TestSignRecordFraming(t *testing.T) {
+	assert := assert.New(t)
+
+	key := []byte("audit key")
+	assert.NotEqual(string(signRecord(key, []byte("ab"), []byte("c"))), string(signRecord(key, []byte("a"), []byte("bc"))))
+}
+
+func TestSignedOutputFromEnvironment(t *testing.T) {
+	assert := assert.New(t)
+
+	oldKey := os.Getenv(EnvironmentVariableLogSigningKey)
+	defer os.Setenv(EnvironmentVariableLogSigningKey, oldKey)
+
+	os.Setenv(EnvironmentVariableLogSigningKey, "")
+	_, err := NewSignedOutputFromEnvironment(bytes.NewBuffer(nil))
+	assert.NotNil(err)
+
+	os.Setenv(EnvironmentVariableLogSigningKey, base64.StdEncoding.EncodeToString([]byte("audit key")))
+	output, err := NewSignedOutputFromEnvironment(bytes.NewBuffer(nil))
+	assert.Nil(err)
+	assert.Equal("audit key", string(output.key))
+}
@@ -0,0 +1,345 @@
+package logger
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// EventSLOBurn fires when a service level objective's error budget burns faster than a threshold,
+	// see `SLOEvaluator`.
+	EventSLOBurn EventFlag = "slo.burn"
+
+	// DefaultSLOShortWindow is the short window burn rates are evaluated over.
+	DefaultSLOShortWindow = 5 * time.Minute
+	// DefaultSLOLongWindow is the long window burn rates are evaluated over.
+	DefaultSLOLongWindow = time.Hour
+	// DefaultSLOWarningBurnRate is the burn rate both windows must exceed for a warning.
+	DefaultSLOWarningBurnRate = 6.0
+	// DefaultSLOCriticalBurnRate is the burn rate both windows must exceed for a critical alert
+	// (2% of a 30 day budget in an hour).
+	DefaultSLOCriticalBurnRate = 14.4
+	// DefaultSLOEvaluationInterval is how often a started evaluator evaluates burn rates.
+	DefaultSLOEvaluationInterval = 30 * time.Second
+
+	// sloBucketsPerShortWindow is the resolution of the short window.
+	sloBucketsPerShortWindow = 10
+)
+
+// SLOSeverity is the severity of a burn rate alert.
+type SLOSeverity string
+
+const (
+	// SLOSeverityNone means the error budget burns within thresholds.
+	SLOSeverityNone SLOSeverity = ""
+	// SLOSeverityWarning means the error budget burns faster than the warning burn rate.
+	SLOSeverityWarning SLOSeverity = "warning"
+	// SLOSeverityCritical means the error budget burns faster than the critical burn rate.
+	SLOSeverityCritical SLOSeverity = "critical"
+)
+
+// SLO is a service level objective for completed requests, e.g. 99.9% of requests succeed (availability),
+// or 99% of requests complete within 300ms (latency).
+type SLO struct {
+	Name string
+	// Objective is the fraction of requests that must be good, e.g. 0.999.
+	Objective float64
+	// Latency makes this a latency objective: requests slower than it are bad. Otherwise requests
+	// with a 5xx status are bad.
+	Latency time.Duration
+}
+
+// NewAvailabilitySLO returns an objective for the fraction of requests without a 5xx status.
+func NewAvailabilitySLO(name string, objective float64) SLO {
+	return SLO{Name: name, Objective: objective}
+}
+
+// NewLatencySLO returns an objective for the fraction of requests completing within a latency.
+func NewLatencySLO(name string, objective float64, latency time.Duration) SLO {
+	return SLO{Name: name, Objective: objective, Latency: latency}
+}
+
+// IsBad returns if a completed request counts against the objective.
+func (slo SLO) IsBad(statusCode int, elapsed time.Duration) bool {
+	if slo.Latency > 0 {
+		return elapsed > slo.Latency
+	}
+	return statusCode >= http.StatusInternalServerError
+}
+
+// SLOBurn is the state of an `EventSLOBurn` event.
+type SLOBurn struct {
+	SLO      SLO
+	Severity SLOSeverity
+	// ShortBurnRate and LongBurnRate are how many times faster than sustainable the error budget burns
+	// over the short and long windows.
+	ShortBurnRate float64
+	LongBurnRate  float64
+}
+
+// NewSLOEvaluator returns a new evaluator for the given objectives, fed completed requests
+// (see `Register` or `Middleware.AddRequestCounter`).
+func NewSLOEvaluator(objectives ...SLO) *SLOEvaluator {
+	return &SLOEvaluator{
+		objectives:       objectives,
+		shortWindow:      DefaultSLOShortWindow,
+		longWindow:       DefaultSLOLongWindow,
+		warningBurnRate:  DefaultSLOWarningBurnRate,
+		criticalBurnRate: DefaultSLOCriticalBurnRate,
+		severities:       map[string]SLOSeverity{},
+		now:              time.Now,
+	}
+}
+
+// SLOEvaluator evaluates multi-window burn rates of service level objectives over the completed request stream.
+// An objective alerts when both its short and long window burn rates exceed a threshold, firing an `EventSLOBurn`
+// event when its severity rises (and again once it has recovered).
+type SLOEvaluator struct {
+	sync.Mutex
+	objectives       []SLO
+	shortWindow      time.Duration
+	longWindow       time.Duration
+	warningBurnRate  float64
+	criticalBurnRate float64
+
+	buckets    []sloBucket
+	severities map[string]SLOSeverity
+	now        func() time.Time
+
+	stop chan struct{}
+}
+
+// sloBucket counts the requests completed in a slice of time.
+type sloBucket struct {
+	id    int64
+	total int64
+	bad   []int64
+}
+
+// Objectives returns the objectives evaluated.
+func (se *SLOEvaluator) Objectives() []SLO { return se.objectives }
+
+// Windows returns the short and long windows burn rates are evaluated over.
+func (se *SLOEvaluator) Windows() (short, long time.Duration) {
+	se.Lock()
+	defer se.Unlock()
+	return se.shortWindow, se.longWindow
+}
+
+// SetWindows sets the short and long windows burn rates are evaluated over; it resets the recorded requests.
+func (se *SLOEvaluator) SetWindows(short, long time.Duration) {
+	se.Lock()
+	defer se.Unlock()
+	se.shortWindow = short
+	se.longWindow = long
+	se.buckets = nil
+}
+
+// BurnRates returns the burn rates both windows must exceed for warning and critical alerts.
+func (se *SLOEvaluator) BurnRates() (warning, critical float64) {
+	se.Lock()
+	defer se.Unlock()
+	return se.warningBurnRate, se.criticalBurnRate
+}
+
+// SetBurnRates sets the burn rates both windows must exceed for warning and critical alerts.
+func (se *SLOEvaluator) SetBurnRates(warning, critical float64) {
+	se.Lock()
+	defer se.Unlock()
+	se.warningBurnRate = warning
+	se.criticalBurnRate = critical
+}
+
+// Register adds the evaluator's request listener for `EventWebRequest` to an agent.
+func (se *SLOEvaluator) Register(agent *Agent) {
+	agent.AddEventListener(EventWebRequest, se.Listener())
+}
+
+// Listener returns a listener for request events that records them.
+func (se *SLOEvaluator) Listener() EventListener {
+	return NewRequestListener(func(_ *Writer, _ TimeSource, _ *http.Request, statusCode, _ int, elapsed time.Duration) {
+		se.Record(statusCode, elapsed)
+	})
+}
+
+// Record records a completed request.
+func (se *SLOEvaluator) Record(statusCode int, elapsed time.Duration) {
+	se.Lock()
+	defer se.Unlock()
+	bucket := se.bucket(se.bucketID(se.now()))
+	bucket.total++
+	for x, objective := range se.objectives {
+		if objective.IsBad(statusCode, elapsed) {
+			bucket.bad[x]++
+		}
+	}
+}
+
+// bucketSize is the duration of a bucket.
+func (se *SLOEvaluator) bucketSize() time.Duration {
+	if size := se.shortWindow / sloBucketsPerShortWindow; size > 0 {
+		return size
+	}
+	return time.Nanosecond
+}
+
+func (se *SLOEvaluator) bucketID(now time.Time) int64 {
+	return now.UnixNano() / int64(se.bucketSize())
+}
+
+// bucket returns the (reset if stale) bucket for an id.
+func (se *SLOEvaluator) bucket(id int64) *sloBucket {
+	if se.buckets == nil {
+		count := int(se.longWindow/se.bucketSize()) + 1
+		if count < sloBucketsPerShortWindow+1 {
+			count = sloBucketsPerShortWindow + 1
+		}
+		se.buckets = make([]sloBucket, count)
+	}
+	bucket := &se.buckets[id%int64(len(se.buckets))]
+	if bucket.id != id || bucket.bad == nil {
+		bucket.id = id
+		bucket.total = 0
+		bucket.bad = make([]int64, len(se.objectives))
+	}
+	return bucket
+}
+
+// burnRate returns the burn rate of an objective over the buckets within a window.
+func (se *SLOEvaluator) burnRate(x int, current int64, window time.Duration) float64 {
+	buckets := int64(window / se.bucketSize())
+	var total, bad int64
+	for _, bucket := range se.buckets {
+		if bucket.bad != nil && bucket.id <= current && bucket.id > current-buckets {
+			total += bucket.total
+			bad += bucket.bad[x]
+		}
+	}
+	budget := 1 - se.objectives[x].Objective
+	if total == 0 || budget <= 0 {
+		return 0
+	}
+	return (float64(bad) / float64(total)) / budget
+}
+
+// Burns returns the current burn rate and severity of each objective.
+func (se *SLOEvaluator) Burns() []SLOBurn {
+	se.Lock()
+	defer se.Unlock()
+	current := se.bucketID(se.now())
+	burns := make([]SLOBurn, 0, len(se.objectives))
+	for x, objective := range se.objectives {
+		burn := SLOBurn{
+			SLO:           objective,
+			ShortBurnRate: se.burnRate(x, current, se.shortWindow),
+			LongBurnRate:  se.burnRate(x, current, se.longWindow),
+		}
+		if burn.ShortBurnRate > se.criticalBurnRate && burn.LongBurnRate > se.criticalBurnRate {
+			burn.Severity = SLOSeverityCritical
+		} else if burn.ShortBurnRate > se.warningBurnRate && burn.LongBurnRate > se.warningBurnRate {
+			burn.Severity = SLOSeverityWarning
+		}
+		burns = append(burns, burn)
+	}
+	return burns
+}
+
+// Evaluate fires an `EventSLOBurn` event for each objective whose severity rose since the last evaluation,
+// or that recovered (with `SLOSeverityNone`).
+func (se *SLOEvaluator) Evaluate(agent *Agent) {
+	for _, burn := range se.Burns() {
+		se.Lock()
+		previous := se.severities[burn.SLO.Name]
+		se.severities[burn.SLO.Name] = burn.Severity
+		se.Unlock()
+		if burn.Severity == previous || (burn.Severity == SLOSeverityWarning && previous == SLOSeverityCritical) {
+			continue
+		}
+		agent.OnEvent(EventSLOBurn, burn)
+	}
+}
+
+// Start evaluates burn rates every interval (`DefaultSLOEvaluationInterval` if zero), firing events to an agent
+// (see `Evaluate`).
+func (se *SLOEvaluator) Start(agent *Agent, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultSLOEvaluationInterval
+	}
+	se.Lock()
+	if se.stop != nil {
+		se.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	se.stop = stop
+	se.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				se.Evaluate(agent)
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops periodic evaluation.
+func (se *SLOEvaluator) Stop() {
+	se.Lock()
+	defer se.Unlock()
+	if se.stop != nil {
+		close(se.stop)
+		se.stop = nil
+	}
+}
+
+// SLOBurnListener is a listener for slo burn events.
+type SLOBurnListener func(writer *Writer, ts TimeSource, burn SLOBurn)
+
+// NewSLOBurnListener returns a new handler for slo burn events, e.g. `NewSLOBurnListener(WriteSLOBurn)`.
+func NewSLOBurnListener(listener SLOBurnListener) EventListener {
+	return func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		if len(state) < 1 {
+			return
+		}
+		if burn, isBurn := state[0].(SLOBurn); isBurn {
+			listener(writer, ts, burn)
+		}
+	}
+}
+
+// WriteSLOBurn is a helper method to write slo burn events to a writer,
+// e.g. `[slo.burn] critical availability (99.9%) burn rate 20.1x short, 15.3x long`.
+func WriteSLOBurn(writer *Writer, ts TimeSource, burn SLOBurn) {
+	buffer := writer.GetBuffer()
+	defer writer.PutBuffer(buffer)
+
+	color := ColorYellow
+	severity := string(burn.Severity)
+	switch burn.Severity {
+	case SLOSeverityCritical:
+		color = ColorRed
+	case SLOSeverityNone:
+		color = ColorGreen
+		severity = "recovered"
+	}
+	buffer.WriteString(writer.FormatEvent(EventSLOBurn, color))
+	buffer.WriteRune(RuneSpace)
+	buffer.WriteString(writer.Colorize(severity, color))
+	buffer.WriteRune(RuneSpace)
+	buffer.WriteString(writer.Sanitize(burn.SLO.Name))
+	buffer.WriteString(" (" + strconv.FormatFloat(burn.SLO.Objective*100, 'f', -1, 64) + "%")
+	if burn.SLO.Latency > 0 {
+		buffer.WriteString(" within " + writer.FormatDuration(burn.SLO.Latency))
+	}
+	buffer.WriteString(fmt.Sprintf(") burn rate %.1fx short, %.1fx long", burn.ShortBurnRate, burn.LongBurnRate))
+	writer.WriteWithTimeSource(ts, buffer.Bytes())
+}
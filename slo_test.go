@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestSLOEvaluator(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	se := NewSLOEvaluator(NewAvailabilitySLO("availability", 0.99), NewLatencySLO("latency", 0.9, 100*time.Millisecond))
+	se.now = func() time.Time { return now }
+
+	for x := 0; x < 100; x++ {
+		se.Record(http.StatusOK, 10*time.Millisecond)
+	}
+	burns := se.Burns()
+	assert.Len(burns, 2)
+	assert.Zero(burns[0].ShortBurnRate)
+	assert.Equal(SLOSeverityNone, burns[0].Severity)
+
+	// 1 in 6 requests erroring burns a 1% budget 16.7x; 1 in 6 slow requests burns a 10% budget 1.7x.
+	for x := 0; x < 25; x++ {
+		se.Record(http.StatusInternalServerError, 10*time.Millisecond)
+		se.Record(http.StatusOK, time.Second)
+	}
+	burns = se.Burns()
+	assert.True(math.Abs(burns[0].ShortBurnRate-16.67) < 0.01, burns[0].ShortBurnRate)
+	assert.True(math.Abs(burns[0].LongBurnRate-16.67) < 0.01, burns[0].LongBurnRate)
+	assert.Equal(SLOSeverityCritical, burns[0].Severity)
+	assert.True(math.Abs(burns[1].ShortBurnRate-1.67) < 0.01, burns[1].ShortBurnRate)
+	assert.Equal(SLOSeverityNone, burns[1].Severity)
+
+	// past the short window, only the long window still burns.
+	now = now.Add(10 * time.Minute)
+	se.Record(http.StatusOK, 10*time.Millisecond)
+	burns = se.Burns()
+	assert.Zero(burns[0].ShortBurnRate)
+	assert.True(burns[0].LongBurnRate > DefaultSLOCriticalBurnRate)
+	assert.Equal(SLOSeverityNone, burns[0].Severity)
+
+	// past the long window, nothing burns.
+	now = now.Add(2 * time.Hour)
+	burns = se.Burns()
+	assert.Zero(burns[0].LongBurnRate)
+}
+
+func TestSLOEvaluatorEvaluate(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventSLOBurn), NewWriter(buffer))
+	defer da.Close()
+	da.Writer().SetUseAnsiColors(false)
+	da.AddEventListener(EventSLOBurn, NewSLOBurnListener(WriteSLOBurn))
+
+	now := time.Now()
+	se := NewSLOEvaluator(NewAvailabilitySLO("checkout", 0.999))
+	se.now = func() time.Time { return now }
+	se.Record(http.StatusOK, time.Millisecond)
+	se.Evaluate(da)
+
+	se.Record(http.StatusBadGateway, time.Millisecond)
+	se.Evaluate(da)
+	se.Evaluate(da)
+	da.Flush()
+	assert.Equal(1, strings.Count(buffer.String(), "critical checkout (99.9%) burn rate 500.0x short, 500.0x long"), buffer.String())
+
+	now = now.Add(2 * time.Hour)
+	se.Evaluate(da)
+	da.Flush()
+	assert.True(strings.Contains(buffer.String(), "recovered checkout"), buffer.String())
+}
@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	// EventWebRequestSlow fires when a request takes longer than the middleware's slow request threshold,
+	// see `Middleware.SetSlowRequestThreshold`.
+	EventWebRequestSlow EventFlag = "web.request.slow"
+)
+
+// SlowRequestListener is a listener for slow request events.
+type SlowRequestListener func(writer *Writer, ts TimeSource, req *http.Request, elapsed, threshold time.Duration)
+
+// NewSlowRequestListener returns a new handler for slow request events, e.g. `NewSlowRequestListener(WriteSlowRequest)`.
+func NewSlowRequestListener(listener SlowRequestListener) EventListener {
+	return func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		if len(state) < 3 {
+			return
+		}
+
+		req, err := stateAsRequest(state[0])
+		if err != nil {
+			return
+		}
+
+		elapsed, err := stateAsDuration(state[1])
+		if err != nil {
+			return
+		}
+
+		threshold, err := stateAsDuration(state[2])
+		if err != nil {
+			return
+		}
+
+		listener(writer, ts, req, elapsed, threshold)
+	}
+}
+
+// WriteSlowRequest is a helper method to write slow request events to a writer,
+// e.g. `[web.request.slow] GET /reports 2.3s (threshold 1s)`.
+func WriteSlowRequest(writer *Writer, ts TimeSource, req *http.Request, elapsed, threshold time.Duration) {
+	buffer := writer.GetBuffer()
+	defer writer.PutBuffer(buffer)
+
+	buffer.WriteString(writer.FormatEvent(EventWebRequestSlow, ColorYellow))
+	buffer.WriteRune(RuneSpace)
+	if requestID := GetRequestID(req); len(requestID) > 0 {
+		buffer.WriteString(writer.Sanitize(requestID))
+		buffer.WriteRune(RuneSpace)
+	}
+	buffer.WriteString(writer.Colorize(writer.Sanitize(req.Method), ColorBlue))
+	buffer.WriteRune(RuneSpace)
+	buffer.WriteString(writer.FormatRequestURI(req))
+	buffer.WriteRune(RuneSpace)
+	buffer.WriteString(writer.FormatDuration(elapsed))
+	buffer.WriteString(" (threshold ")
+	buffer.WriteString(writer.FormatDuration(threshold))
+	buffer.WriteRune(')')
+	writer.WriteWithTimeSource(ts, buffer.Bytes())
+}
@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestMiddlewareSlowRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventWebRequestSlow), NewWriter(buffer))
+	defer da.Close()
+	da.Writer().SetUseAnsiColors(false)
+	da.AddEventListener(EventWebRequestSlow, NewSlowRequestListener(WriteSlowRequest))
+
+	var threshold time.Duration
+	da.AddEventListener(EventWebRequestSlow, NewSlowRequestListener(func(writer *Writer, ts TimeSource, req *http.Request, elapsed, t time.Duration) {
+		threshold = t
+	}))
+
+	mw := NewMiddleware(da)
+	mw.SetSlowRequestThreshold(20 * time.Millisecond)
+	assert.Equal(20*time.Millisecond, mw.SlowRequestThreshold())
+	handler := mw.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/slow" {
+			time.Sleep(30 * time.Millisecond)
+		}
+	})
+	handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/fast", nil))
+	handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil))
+	da.Flush()
+
+	output := buffer.String()
+	assert.Equal(20*time.Millisecond, threshold)
+	assert.False(strings.Contains(output, "/fast"), output)
+	assert.True(strings.Contains(output, "GET /slow"), output)
+	assert.True(strings.Contains(output, "(threshold 20ms)"), output)
+}
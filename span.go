@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// EventSpan fires when a span ends, see `Agent.StartSpan`.
+	EventSpan EventFlag = "span"
+)
+
+type spanKey struct{}
+
+// WithSpan returns a copy of the context that carries a span, so spans started from it are its children.
+func WithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span stored in a context, or nil if there isn't one.
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	if typed, isTyped := ctx.Value(spanKey{}).(*Span); isTyped {
+		return typed
+	}
+	return nil
+}
+
+// StartSpan starts a span that begins a new trace; `End` fires an `EventSpan` event with its duration.
+func (da *Agent) StartSpan(name string) *Span {
+	if da == nil {
+		return nil
+	}
+	return newSpan(da, name, NewTraceContext())
+}
+
+// StartSpanFromContext starts a span that's a child of the span in the context or, failing that,
+// of the trace context of the request agent in the context (see `ForRequest`).
+// It returns the span and a copy of the context that carries it.
+func (da *Agent) StartSpanFromContext(ctx context.Context, name string) (*Span, context.Context) {
+	if da == nil {
+		return nil, ctx
+	}
+	var span *Span
+	if parent := SpanFromContext(ctx); parent != nil {
+		span = newSpan(da, name, parent.Trace.Child())
+	} else if trace := TraceContextFromContext(ctx); trace != nil {
+		span = newSpan(da, name, trace.Child())
+	} else {
+		span = newSpan(da, name, NewTraceContext())
+	}
+	return span, WithSpan(ctx, span)
+}
+
+func newSpan(agent *Agent, name string, trace *TraceContext) *Span {
+	return &Span{agent: agent, Name: name, Trace: trace, Start: time.Now()}
+}
+
+// Span is a timed operation within a trace, for rudimentary latency breakdowns in the log stream.
+type Span struct {
+	agent *Agent
+	ended int32
+
+	Name  string
+	Trace *TraceContext
+	Start time.Time
+	// Elapsed is set when the span ends.
+	Elapsed time.Duration
+}
+
+// StartChild starts a span that's a child of the span.
+func (s *Span) StartChild(name string) *Span {
+	if s == nil {
+		return nil
+	}
+	return newSpan(s.agent, name, s.Trace.Child())
+}
+
+// End ends the span and fires an `EventSpan` event with it; only the first call has an effect.
+func (s *Span) End() time.Duration {
+	if s == nil {
+		return 0
+	}
+	if !atomic.CompareAndSwapInt32(&s.ended, 0, 1) {
+		return s.Elapsed
+	}
+	s.Elapsed = time.Since(s.Start)
+	s.agent.OnEvent(EventSpan, s)
+	return s.Elapsed
+}
+
+// SpanListener is a listener for span events.
+type SpanListener func(writer *Writer, ts TimeSource, span *Span)
+
+// NewSpanListener returns a new handler for span events, e.g. `NewSpanListener(WriteSpan)`.
+func NewSpanListener(listener SpanListener) EventListener {
+	return func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {
+		if len(state) < 1 {
+			return
+		}
+		if span, isSpan := state[0].(*Span); isSpan {
+			listener(writer, ts, span)
+		}
+	}
+}
+
+// WriteSpan is a helper method to write span events to a writer,
+// e.g. `[span] db.query 12.3ms trace_id=... span_id=... parent_id=...`.
+func WriteSpan(writer *Writer, ts TimeSource, span *Span) {
+	buffer := writer.GetBuffer()
+	defer writer.PutBuffer(buffer)
+
+	buffer.WriteString(writer.FormatEvent(EventSpan, ColorLightBlue))
+	buffer.WriteRune(RuneSpace)
+	buffer.WriteString(writer.Sanitize(span.Name))
+	buffer.WriteRune(RuneSpace)
+	buffer.WriteString(writer.FormatDuration(span.Elapsed))
+	buffer.WriteString(" trace_id=" + span.Trace.TraceID + " span_id=" + span.Trace.SpanID)
+	if len(span.Trace.ParentSpanID) > 0 {
+		buffer.WriteString(" parent_id=" + span.Trace.ParentSpanID)
+	}
+	writer.WriteWithTimeSource(ts, buffer.Bytes())
+}
@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestAgentSpans(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventSpan), NewWriter(buffer))
+	defer da.Close()
+	da.Writer().SetUseAnsiColors(false)
+	da.Writer().SetShowTimestamp(false)
+	da.AddEventListener(EventSpan, NewSpanListener(WriteSpan))
+
+	root, ctx := da.StartSpanFromContext(context.Background(), "handler")
+	assert.Equal(root, SpanFromContext(ctx))
+	assert.Empty(root.Trace.ParentSpanID)
+
+	query, _ := da.StartSpanFromContext(ctx, "db.query")
+	assert.Equal(root.Trace.TraceID, query.Trace.TraceID)
+	assert.Equal(root.Trace.SpanID, query.Trace.ParentSpanID)
+	elapsed := query.End()
+	assert.Equal(elapsed, query.End(), "spans only end once")
+
+	render := root.StartChild("render")
+	assert.Equal(root.Trace.SpanID, render.Trace.ParentSpanID)
+	render.End()
+	root.End()
+	da.Flush()
+
+	output := buffer.String()
+	assert.Equal(3, strings.Count(output, "[span]"), output)
+	assert.True(strings.Contains(output, "[span] db.query "+da.Writer().FormatDuration(elapsed)+" trace_id="+root.Trace.TraceID+" span_id="+query.Trace.SpanID+" parent_id="+root.Trace.SpanID+"\n"), output)
+}
+
+func TestAgentSpanFromRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	da := None()
+	defer da.Close()
+
+	ra := NewRequestAgent(da, httptest.NewRequest("GET", "/", nil), "abc")
+	ra.SetTraceContext(NewTraceContext())
+	span, _ := da.StartSpanFromContext(WithRequestAgent(context.Background(), ra), "handler")
+	assert.Equal(ra.TraceContext().TraceID, span.Trace.TraceID)
+	assert.Equal(ra.TraceContext().SpanID, span.Trace.ParentSpanID)
+
+	var nilAgent *Agent
+	assert.Nil(nilAgent.StartSpan("nothing"))
+	assert.Zero(nilAgent.StartSpan("nothing").End())
+}
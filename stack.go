@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+)
+
+// StackProvider is implemented by errors that carry the stack they were created at, so writers can render
+// it whatever library created them, e.g. one frame per line like `main.handler /app/main.go:42`.
+type StackProvider interface {
+	StackFrames() []string
+}
+
+// StackExtractor returns the stack frames of an error, and if it could, for error types that can't implement
+// `StackProvider` themselves (e.g. an adapter rendering pkg/errors' `StackTrace()`).
+type StackExtractor func(err error) ([]string, bool)
+
+var (
+	stackExtractorsLock sync.RWMutex
+	stackExtractors     []StackExtractor
+)
+
+// RegisterStackExtractor registers an extractor for the stacks of errors that don't implement `StackProvider`.
+func RegisterStackExtractor(extractor StackExtractor) {
+	stackExtractorsLock.Lock()
+	defer stackExtractorsLock.Unlock()
+	stackExtractors = append(stackExtractors, extractor)
+}
+
+// providedStack returns the stack frames of the innermost error in an error's wrap chain that supplies them
+// (see `StackProvider` and `RegisterStackExtractor`), and if one did.
+func providedStack(err error) ([]string, bool) {
+	stackExtractorsLock.RLock()
+	extractors := stackExtractors
+	stackExtractorsLock.RUnlock()
+
+	var frames []string
+	var provided bool
+	for _, wrapped := range ErrorChain(err) {
+		if provider, isProvider := wrapped.(StackProvider); isProvider {
+			frames, provided = provider.StackFrames(), true
+			continue
+		}
+		for _, extractor := range extractors {
+			if extracted, extractedStack := extractor(wrapped); extractedStack {
+				frames, provided = extracted, true
+				break
+			}
+		}
+	}
+	return frames, provided
+}
+
+// stackFormatter formats an error for agent output: with `%+v` its message is followed by the frames of
+// a provided stack (see `StackProvider`) as indented lines, or else by the error's own `%+v` format.
+type stackFormatter struct {
+	err error
+}
+
+// Format implements fmt.Formatter.
+func (sf stackFormatter) Format(state fmt.State, verb rune) {
+	if verb == 'v' && state.Flag('+') {
+		if frames, provided := providedStack(sf.err); provided {
+			fmt.Fprint(state, sf.err.Error())
+			for _, frame := range frames {
+				fmt.Fprint(state, "\n"+ErrorIndent+frame)
+			}
+			return
+		}
+		fmt.Fprintf(state, "%+v", sf.err)
+		return
+	}
+	fmt.Fprint(state, sf.err.Error())
+}
@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+type providedStackError struct {
+	message string
+}
+
+func (pse providedStackError) Error() string { return pse.message }
+func (pse providedStackError) StackFrames() []string {
+	return []string{"main.handler app/main.go:42", "main.main app/main.go:10"}
+}
+
+type extractedStackError struct{}
+
+func (ese extractedStackError) Error() string { return "extracted" }
+
+func TestErrorStackProvider(t *testing.T) {
+	assert := assert.New(t)
+
+	err := fmt.Errorf("handling: %w", providedStackError{message: "failed"})
+	assert.Equal([]string{"main.handler app/main.go:42", "main.main app/main.go:10"}, ErrorStack(err))
+	assert.Equal("handling: failed\n"+ErrorIndent+"main.handler app/main.go:42\n"+ErrorIndent+"main.main app/main.go:10",
+		fmt.Sprintf("%+v", stackFormatter{err}))
+	assert.Equal("handling: failed", fmt.Sprintf("%v", stackFormatter{err}))
+	assert.Nil(ErrorStack(errors.New("plain")))
+	assert.Equal("plain", fmt.Sprintf("%+v", stackFormatter{errors.New("plain")}))
+
+	RegisterStackExtractor(func(err error) ([]string, bool) {
+		if _, isExtracted := err.(extractedStackError); isExtracted {
+			return []string{"lib.frame lib.go:1"}, true
+		}
+		return nil, false
+	})
+	assert.Equal([]string{"lib.frame lib.go:1"}, ErrorStack(extractedStackError{}))
+}
+
+func TestAgentWritesProvidedStack(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventError), NewWriterWithError(buffer, buffer))
+	defer da.Close()
+	da.Writer().SetUseAnsiColors(false)
+	da.Writer().SetContinuationPrefix("|")
+
+	da.Error(providedStackError{message: "failed"})
+	da.Flush()
+	assert.True(strings.Contains(buffer.String(), "[error] failed\n|"+ErrorIndent+"main.handler app/main.go:42\n"), buffer.String())
+}
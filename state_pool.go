@@ -0,0 +1,31 @@
+package logger
+
+import "sync"
+
+const (
+	// maxPooledStateLen is the largest action state (by capacity) returned to the pool; bigger ones are left to the gc.
+	maxPooledStateLen = 64
+)
+
+var statePool = sync.Pool{New: func() interface{} {
+	return make([]interface{}, 0, 8)
+}}
+
+// acquireState returns a pooled action state slice holding `values` followed by `state`.
+// The agent's queued actions (`write`, `writeError`, `triggerListeners`) release it once they're done.
+func acquireState(state []interface{}, values ...interface{}) []interface{} {
+	actionState := statePool.Get().([]interface{})
+	actionState = append(actionState, values...)
+	return append(actionState, state...)
+}
+
+// releaseState clears an action state slice and returns it to the pool; it must not be used afterwards.
+func releaseState(actionState []interface{}) {
+	if cap(actionState) > maxPooledStateLen {
+		return
+	}
+	for x := range actionState {
+		actionState[x] = nil
+	}
+	statePool.Put(actionState[:0])
+}
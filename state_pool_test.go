@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestAcquireReleaseState(t *testing.T) {
+	assert := assert.New(t)
+
+	actionState := acquireState([]interface{}{"foo", "bar"}, EventInfo, "%s %s")
+	assert.Equal([]interface{}{EventInfo, "%s %s", "foo", "bar"}, actionState)
+
+	releaseState(actionState)
+	assert.Nil(actionState[0])
+	assert.Nil(actionState[3])
+
+	assert.Empty(acquireState(nil))
+}
+
+func TestAgentPooledStateListener(t *testing.T) {
+	assert := assert.New(t)
+
+	da := New(NewEventFlagSetAll())
+	da.eventQueue.Close()
+
+	batches := make(chan []BatchedEvent, 1)
+	batch := NewBatchListener(func(events []BatchedEvent) { batches <- events }, 2, 0)
+	defer batch.Close()
+	da.AddEventListener(EventInfo, batch.Listen)
+
+	// the queue isn't running, so each event's state is released (and reused) before the next.
+	da.OnEvent(EventInfo, "foo")
+	da.OnEvent(EventInfo, "bar")
+
+	events := <-batches
+	assert.Len(events, 2)
+	assert.Equal([]interface{}{"foo"}, events[0].State)
+	assert.Equal([]interface{}{"bar"}, events[1].State)
+}
+
+func BenchmarkAgentInfofPooledState(b *testing.B) {
+	da := NewWithWriter(NewEventFlagSetAll(), NewWriter(bytes.NewBuffer(nil)))
+	da.eventQueue.Close()
+	da.AddEventListener(EventInfo, func(writer *Writer, ts TimeSource, eventFlag EventFlag, state ...interface{}) {})
+
+	b.ReportAllocs()
+	for iter := 0; iter < b.N; iter++ {
+		da.Infof("hello %s", "world")
+	}
+}
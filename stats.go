@@ -0,0 +1,47 @@
+package logger
+
+import "time"
+
+// AgentStats is a snapshot of an agent's counters, see `Agent.Stats`.
+type AgentStats struct {
+	// Events is the number of enabled events fired per flag.
+	Events map[EventFlag]int64
+	// Dropped is the number of events dropped per flag.
+	Dropped map[EventFlag]int64
+	// BytesWritten is the number of bytes written by the agent's (current) writer.
+	BytesWritten int64
+	// WriteErrors is the number of failed writes.
+	WriteErrors int64
+	// QueueDepth is the number of events waiting in the agent's queue(s).
+	QueueDepth int
+	// QueueHighWater is the largest number of events queued at once.
+	QueueHighWater int
+	// Uptime is the time since the agent was created.
+	Uptime time.Duration
+}
+
+// Stats returns a snapshot of the agent's counters, e.g. to expose them or assert on them in tests.
+func (da *Agent) Stats() AgentStats {
+	if da == nil {
+		return AgentStats{}
+	}
+	stats := AgentStats{
+		Events:      da.EventCounts(),
+		Dropped:     da.DroppedEvents(),
+		WriteErrors: da.WriteErrors(),
+		QueueDepth:  da.QueueDepth(),
+		Uptime:      time.Since(da.started),
+	}
+	if writer := da.Writer(); writer != nil {
+		stats.BytesWritten = writer.BytesWritten()
+	}
+	if da.eventQueue != nil {
+		stats.QueueHighWater = da.eventQueue.HighWater()
+	}
+	if orderedQueue, _ := da.orderedQueueAndOrdering(); orderedQueue != nil {
+		if highWater := orderedQueue.HighWater(); highWater > stats.QueueHighWater {
+			stats.QueueHighWater = highWater
+		}
+	}
+	return stats
+}
@@ -0,0 +1,38 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestAgentStats(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventInfo, EventError), NewWriter(buffer))
+	defer da.Close()
+	da.Writer().SetShowTimestamp(false)
+	da.Writer().SetUseAnsiColors(false)
+
+	da.Infof("one")
+	da.Infof("two")
+	da.Errorf("three")
+	da.Debugf("disabled")
+	da.Flush()
+
+	stats := da.Stats()
+	assert.Equal(int64(2), stats.Events[EventInfo])
+	assert.Equal(int64(1), stats.Events[EventError])
+	assert.Zero(stats.Events[EventDebug])
+	assert.Empty(stats.Dropped)
+	assert.Equal(int64(buffer.Len()), stats.BytesWritten)
+	assert.Zero(stats.WriteErrors)
+	assert.Zero(stats.QueueDepth)
+	assert.True(stats.QueueHighWater >= 1)
+	assert.True(stats.Uptime > 0)
+
+	var nilAgent *Agent
+	assert.Empty(nilAgent.Stats().Events)
+}
@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"regexp"
+	"strings"
+)
+
+// NewSuppressionRule returns a rule that suppresses messages matching a regular expression.
+// If `event` is set the rule only applies to that event.
+func NewSuppressionRule(event EventFlag, pattern string) (*SuppressionRule, error) {
+	expr, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &SuppressionRule{event: event, expr: expr}, nil
+}
+
+// NewGlobSuppressionRule returns a rule that suppresses messages matching a glob,
+// where `*` matches any run of characters and `?` a single character, e.g. `*deprecated api*`.
+// If `event` is set the rule only applies to that event.
+func NewGlobSuppressionRule(event EventFlag, glob string) (*SuppressionRule, error) {
+	return NewSuppressionRule(event, globToPattern(glob))
+}
+
+// SuppressionRule suppresses messages matching a pattern, e.g. known noisy messages from a third party library.
+type SuppressionRule struct {
+	event EventFlag
+	expr  *regexp.Regexp
+}
+
+// Event returns the event the rule applies to; it applies to all events if empty.
+func (sr *SuppressionRule) Event() EventFlag { return sr.event }
+
+// Pattern returns the rule's regular expression.
+func (sr *SuppressionRule) Pattern() string { return sr.expr.String() }
+
+// Matches returns if the rule suppresses a rendered message for an event.
+func (sr *SuppressionRule) Matches(event EventFlag, message []byte) bool {
+	if len(sr.event) > 0 && sr.event != event {
+		return false
+	}
+	return sr.expr.Match(message)
+}
+
+// globToPattern converts a glob to an anchored regular expression.
+func globToPattern(glob string) string {
+	pattern := strings.Builder{}
+	pattern.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			pattern.WriteString(".*")
+		case '?':
+			pattern.WriteString(".")
+		default:
+			pattern.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	pattern.WriteString("$")
+	return "(?s)" + pattern.String()
+}
+
+// Suppressions returns the agent's suppression rules.
+func (da *Agent) Suppressions() []*SuppressionRule {
+	if da == nil {
+		return nil
+	}
+	da.eventsLock.Lock()
+	defer da.eventsLock.Unlock()
+	return da.suppressions
+}
+
+// AddSuppression adds a rule suppressing the writes of matching messages.
+// Rules are matched against the rendered message (without the timestamp or label); listeners still fire.
+func (da *Agent) AddSuppression(rule *SuppressionRule) {
+	da.eventsLock.Lock()
+	suppressions := make([]*SuppressionRule, len(da.suppressions), len(da.suppressions)+1)
+	copy(suppressions, da.suppressions)
+	da.suppressions = append(suppressions, rule)
+	da.eventsLock.Unlock()
+}
+
+// ClearSuppressions removes the agent's suppression rules.
+func (da *Agent) ClearSuppressions() {
+	da.eventsLock.Lock()
+	da.suppressions = nil
+	da.eventsLock.Unlock()
+}
+
+// isSuppressed returns if a rendered message matches any suppression rule.
+func (da *Agent) isSuppressed(event EventFlag, message []byte) bool {
+	for _, rule := range da.Suppressions() {
+		if rule.Matches(event, message) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestSuppressionRule(t *testing.T) {
+	assert := assert.New(t)
+
+	rule, err := NewSuppressionRule(EventWarning, "^deprecated: .*")
+	assert.Nil(err)
+	assert.Equal(EventWarning, rule.Event())
+	assert.True(rule.Matches(EventWarning, []byte("deprecated: use v2")))
+	assert.False(rule.Matches(EventInfo, []byte("deprecated: use v2")))
+	assert.False(rule.Matches(EventWarning, []byte("not deprecated: use v2")))
+
+	_, err = NewSuppressionRule("", "(")
+	assert.NotNil(err)
+
+	glob, err := NewGlobSuppressionRule("", "*connection reset?by peer*")
+	assert.Nil(err)
+	assert.Equal("(?s)^.*connection reset.by peer.*$", glob.Pattern())
+	assert.True(glob.Matches(EventError, []byte("read tcp: connection reset by peer\nretrying")))
+	assert.False(glob.Matches(EventError, []byte("connection refused")))
+}
+
+func TestAgentSuppression(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	writer := NewWriter(buffer)
+	writer.SetUseAnsiColors(false)
+	writer.SetShowTimestamp(false)
+	da := NewWithWriter(NewEventFlagSetAll(), writer)
+	defer da.Close()
+
+	rule, _ := NewGlobSuppressionRule(EventInfo, "noisy *")
+	da.AddSuppression(rule)
+	assert.Len(da.Suppressions(), 1)
+
+	da.Infof("noisy %s", "library")
+	da.Debugf("noisy %s", "debug")
+	da.Infof("important")
+	da.SetEagerFormatting(true)
+	da.Infof("noisy %s", "eager")
+	da.Infof("important eager")
+	da.Flush()
+
+	output := buffer.String()
+	assert.True(strings.Contains(output, "[debug] noisy debug\n"))
+	assert.True(strings.Contains(output, "[info] important\n"))
+	assert.True(strings.Contains(output, "[info] important eager\n"))
+	assert.False(strings.Contains(output, "library"))
+	assert.False(strings.Contains(output, "noisy eager"))
+
+	da.ClearSuppressions()
+	assert.Empty(da.Suppressions())
+}
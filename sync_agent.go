@@ -130,6 +130,8 @@ func (sa *SyncAgent) WriteEventf(event EventFlag, color AnsiColorCode, format st
 		return
 	}
 	if sa.a.IsEnabled(event) {
+		sa.a.countEvent(event)
+		sa.a.recordRecent(event, format, args...)
 		sa.a.write(append([]interface{}{TimeNow(), event, color, format}, args...)...)
 
 		if sa.a.HasListener(event) {
@@ -147,6 +149,8 @@ func (sa *SyncAgent) WriteErrorEventf(event EventFlag, color AnsiColorCode, form
 		return
 	}
 	if sa.a.IsEnabled(event) {
+		sa.a.countEvent(event)
+		sa.a.recordRecent(event, format, args...)
 		sa.a.writeError(append([]interface{}{TimeNow(), event, color, format}, args...)...)
 
 		if sa.a.HasListener(event) {
@@ -164,12 +168,22 @@ func (sa *SyncAgent) ErrorEventWithState(event EventFlag, color AnsiColorCode, e
 		return err
 	}
 	if err != nil {
+		event, color = sa.a.classifyError(event, color, err)
 		if sa.a.IsEnabled(event) {
-			sa.a.writeError(TimeNow(), event, color, "%+v", err)
+			sa.a.countEvent(event)
+			sa.a.recordRecent(event, "%+v", stackFormatter{err})
+			if IsSeverityAtLeast(event, EventWarning) {
+				sa.a.writeError(TimeNow(), event, color, "%+v", stackFormatter{err})
+			} else {
+				sa.a.write(TimeNow(), event, color, "%+v", stackFormatter{err})
+			}
 			if sa.a.HasListener(event) {
 				sa.a.triggerListeners(append([]interface{}{TimeNow(), event, err}, state...)...)
 			}
 		}
+		if event == EventFatalError {
+			sa.a.onFatal(err)
+		}
 	}
 	return err
 }
@@ -182,7 +196,14 @@ func (sa *SyncAgent) OnEvent(eventFlag EventFlag, state ...interface{}) {
 	if sa.a == nil {
 		return
 	}
+	sa.onEvent(TimeNow(), eventFlag, state...)
+}
+
+// onEvent fires the listeners for an event that happened at a given time, synchronously.
+func (sa *SyncAgent) onEvent(ts TimeSource, eventFlag EventFlag, state ...interface{}) {
 	if sa.a.IsEnabled(eventFlag) && sa.a.HasListener(eventFlag) {
-		sa.a.triggerListeners(append([]interface{}{TimeNow(), eventFlag}, state...)...)
+		sa.a.countEvent(eventFlag)
+		sa.a.recordRecent(eventFlag, "", state...)
+		sa.a.triggerListeners(append([]interface{}{ts, eventFlag}, state...)...)
 	}
 }
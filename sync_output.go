@@ -27,6 +27,25 @@ func (so *SyncOutput) Write(buffer []byte) (int, error) {
 	return so.output.Write(buffer)
 }
 
+// Flush flushes the inner writer (if it buffers writes).
+func (so *SyncOutput) Flush() error {
+	so.syncRoot.Lock()
+	defer so.syncRoot.Unlock()
+	return FlushOutput(so.output)
+}
+
+// Reopen reopens the inner writer (if it can reopen its files).
+func (so *SyncOutput) Reopen() error {
+	so.syncRoot.Lock()
+	defer so.syncRoot.Unlock()
+	return ReopenOutput(so.output)
+}
+
+// SetMetaReporter sets how the inner writer reports problems that don't fail writes, see `OutputMetaReporter`.
+func (so *SyncOutput) SetMetaReporter(reporter MetaReporter) {
+	SetOutputMetaReporter(so.output, reporter)
+}
+
 /* experimental; we cannot close stdout or stderr
 otherwise the program crashes
 // Close is a no-op.
@@ -0,0 +1,192 @@
+package logger
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTailSamplingStatusCode is the lowest status code that keeps a request's buffered events.
+	DefaultTailSamplingStatusCode = http.StatusInternalServerError
+
+	// DefaultTailSamplingMaxEvents is the maximum number of events buffered per request.
+	DefaultTailSamplingMaxEvents = 256
+)
+
+var (
+	// DefaultTailSamplingEvents are the events buffered per request if none are given to `NewTailSampling`.
+	DefaultTailSamplingEvents = []EventFlag{EventDebug, EventWebRequestPostBody, EventWebResponse, EventWebDump}
+)
+
+// NewTailSampling returns a tail sampling policy that buffers the given (or default) events for each request,
+// and only writes them if the request completes with an error status or takes longer than `latency`.
+// A zero latency disables the latency threshold.
+func NewTailSampling(latency time.Duration, events ...EventFlag) *TailSampling {
+	if len(events) == 0 {
+		events = DefaultTailSamplingEvents
+	}
+	sampled := make(map[EventFlag]bool, len(events))
+	for _, event := range events {
+		sampled[event] = true
+	}
+	return &TailSampling{
+		events:     sampled,
+		latency:    latency,
+		statusCode: DefaultTailSamplingStatusCode,
+		maxEvents:  DefaultTailSamplingMaxEvents,
+	}
+}
+
+// TailSampling decides which requests keep their detailed (debug, body) events, once the request has completed.
+type TailSampling struct {
+	events     map[EventFlag]bool
+	latency    time.Duration
+	statusCode int
+	maxEvents  int
+}
+
+// Latency returns the latency above which a request's buffered events are kept.
+func (ts *TailSampling) Latency() time.Duration { return ts.latency }
+
+// SetLatency sets the latency above which a request's buffered events are kept.
+func (ts *TailSampling) SetLatency(latency time.Duration) { ts.latency = latency }
+
+// StatusCode returns the lowest status code that keeps a request's buffered events.
+func (ts *TailSampling) StatusCode() int { return ts.statusCode }
+
+// SetStatusCode sets the lowest status code that keeps a request's buffered events, e.g. 400 to keep client errors.
+func (ts *TailSampling) SetStatusCode(statusCode int) { ts.statusCode = statusCode }
+
+// MaxEvents returns the maximum number of events buffered per request.
+func (ts *TailSampling) MaxEvents() int { return ts.maxEvents }
+
+// SetMaxEvents sets the maximum number of events buffered per request; later events are dropped.
+func (ts *TailSampling) SetMaxEvents(maxEvents int) { ts.maxEvents = maxEvents }
+
+// IsSampled returns if an event is buffered until the request completes.
+func (ts *TailSampling) IsSampled(eventFlag EventFlag) bool {
+	return ts.events[eventFlag]
+}
+
+// ShouldKeep returns if a completed request's buffered events should be written.
+func (ts *TailSampling) ShouldKeep(statusCode int, elapsed time.Duration) bool {
+	if statusCode >= ts.statusCode {
+		return true
+	}
+	return ts.latency > 0 && elapsed > ts.latency
+}
+
+// tailEvent is a buffered message write or listener trigger.
+type tailEvent struct {
+	ts        TimeSource
+	eventFlag EventFlag
+	color     AnsiColorCode
+	format    string
+	state     []interface{}
+	listeners bool
+}
+
+// tailBuffer holds a request's sampled events until it completes.
+type tailBuffer struct {
+	sync.Mutex
+	sampling *TailSampling
+	events   []tailEvent
+	dropped  int
+	taken    bool
+}
+
+// add buffers an event, returning if it was (false if the buffer is full), and if the buffer is still buffering
+// events, i.e. they haven't been taken yet as the request completed.
+func (tb *tailBuffer) add(event tailEvent) (added, buffering bool) {
+	tb.Lock()
+	defer tb.Unlock()
+	if tb.taken {
+		return false, false
+	}
+	if tb.sampling.maxEvents > 0 && len(tb.events) >= tb.sampling.maxEvents {
+		tb.dropped++
+		return false, true
+	}
+	tb.events = append(tb.events, event)
+	return true, true
+}
+
+// take returns and clears the buffered events; later events aren't buffered.
+func (tb *tailBuffer) take() ([]tailEvent, int) {
+	tb.Lock()
+	defer tb.Unlock()
+	events, dropped := tb.events, tb.dropped
+	tb.events, tb.dropped, tb.taken = nil, 0, true
+	return events, dropped
+}
+
+// TailSampling returns the request's tail sampling policy, if any.
+func (ra *RequestAgent) TailSampling() *TailSampling {
+	if ra == nil || ra.tail == nil {
+		return nil
+	}
+	return ra.tail.sampling
+}
+
+// SetTailSampling sets a tail sampling policy for the request; sampled events are buffered
+// until `CompleteTailSampling` is called (the middleware calls it once the handler returns).
+func (ra *RequestAgent) SetTailSampling(sampling *TailSampling) {
+	if sampling == nil {
+		ra.tail = nil
+		return
+	}
+	ra.tail = &tailBuffer{sampling: sampling}
+}
+
+// CompleteTailSampling writes the request's buffered events if the policy keeps a request with the given
+// status code and elapsed time, and discards them otherwise. It returns if they were kept.
+// Events fired through the request agent afterwards (e.g. the middleware's `EventWebRequest`) are written as they're fired.
+func (ra *RequestAgent) CompleteTailSampling(statusCode int, elapsed time.Duration) bool {
+	if ra == nil || ra.a == nil || ra.tail == nil {
+		return false
+	}
+	events, dropped := ra.tail.take()
+	if !ra.tail.sampling.ShouldKeep(statusCode, elapsed) {
+		return false
+	}
+	for _, event := range events {
+		ts := ra.a.numbered(event.ts)
+		ra.a.countEvent(event.eventFlag)
+		if event.listeners {
+			ra.a.recordRecentAt(event.ts, event.eventFlag, "", event.state...)
+			ra.a.enqueue(ra.a.triggerListeners, acquireState(event.state, ts, event.eventFlag)...)
+			continue
+		}
+		ra.a.recordRecentAt(event.ts, event.eventFlag, event.format, event.state...)
+		ra.a.queueWriteWithTimeSource(ts, event.eventFlag, event.color, event.format, event.state...)
+		if ra.a.HasListener(event.eventFlag) {
+			ra.a.enqueue(ra.a.triggerListeners, acquireState(event.state, ts, event.eventFlag, event.format)...)
+		}
+	}
+	if dropped > 0 {
+		ra.a.Metaf("request %s: dropped %d tail sampled events over the limit of %d", ra.requestID, dropped, ra.tail.sampling.maxEvents)
+	}
+	return true
+}
+
+// bufferTail buffers a sampled event, returning false if the event isn't sampled (and should be written now).
+func (ra *RequestAgent) bufferTail(event tailEvent) bool {
+	if ra.tail == nil || !ra.tail.sampling.IsSampled(event.eventFlag) {
+		return false
+	}
+	if !ra.a.IsEnabled(event.eventFlag) {
+		return true
+	}
+	if event.listeners && !ra.a.HasListener(event.eventFlag) {
+		return true
+	}
+	added, buffering := ra.tail.add(event)
+	if !buffering {
+		return false
+	}
+	if !added {
+		ra.a.recordDropped(event.eventFlag)
+	}
+	return true
+}
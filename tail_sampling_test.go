@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestTailSamplingShouldKeep(t *testing.T) {
+	assert := assert.New(t)
+
+	sampling := NewTailSampling(time.Second)
+	assert.True(sampling.IsSampled(EventDebug))
+	assert.False(sampling.IsSampled(EventInfo))
+	assert.False(sampling.ShouldKeep(http.StatusOK, time.Millisecond))
+	assert.False(sampling.ShouldKeep(http.StatusNotFound, time.Millisecond))
+	assert.True(sampling.ShouldKeep(http.StatusBadGateway, time.Millisecond))
+	assert.True(sampling.ShouldKeep(http.StatusOK, 2*time.Second))
+
+	sampling.SetStatusCode(http.StatusBadRequest)
+	assert.True(sampling.ShouldKeep(http.StatusNotFound, time.Millisecond))
+
+	sampling.SetLatency(0)
+	assert.False(sampling.ShouldKeep(http.StatusOK, time.Hour))
+}
+
+func TestMiddlewareTailSampling(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	da := NewWithWriter(NewEventFlagSet(EventInfo, EventDebug), NewWriter(buffer))
+	defer da.Close()
+	da.Writer().SetShowTimestamp(false)
+	da.Writer().SetUseAnsiColors(false)
+
+	mw := NewMiddleware(da)
+	mw.SetTailSampling(NewTailSampling(time.Minute))
+	handler := mw.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		ra := ForRequest(req.Context())
+		ra.Infof("handling %s", req.URL.Path)
+		ra.Debugf("details for %s", req.URL.Path)
+		if req.URL.Path == "/fails" {
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+	})
+
+	handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/works", nil))
+	handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/fails", nil))
+	da.Flush()
+
+	output := buffer.String()
+	assert.True(strings.Contains(output, "handling /works"), output)
+	assert.False(strings.Contains(output, "details for /works"), output)
+	assert.True(strings.Contains(output, "handling /fails"), output)
+	assert.True(strings.Contains(output, "details for /fails"), output)
+}
+
+func TestRequestAgentTailSamplingListeners(t *testing.T) {
+	assert := assert.New(t)
+
+	da := All(NewWriter(bytes.NewBuffer(nil)))
+	defer da.Close()
+
+	bodies := make(chan string, 2)
+	da.AddEventListener(EventWebRequestPostBody, NewRequestBodyListener(func(writer *Writer, ts TimeSource, body []byte) {
+		bodies <- string(body)
+	}))
+
+	sampling := NewTailSampling(0)
+	sampling.SetMaxEvents(1)
+	ra := NewRequestAgent(da, httptest.NewRequest("POST", "/", nil), "abc")
+	ra.SetTailSampling(sampling)
+	ra.OnEvent(EventWebRequestPostBody, []byte("first"))
+	ra.OnEvent(EventWebRequestPostBody, []byte("second"))
+	da.Flush()
+	assert.Empty(bodies)
+
+	assert.True(ra.CompleteTailSampling(http.StatusServiceUnavailable, time.Millisecond))
+	da.Flush()
+	assert.Equal("first", <-bodies)
+	assert.Empty(bodies)
+	assert.Equal(int64(1), da.DroppedEvents()[EventWebRequestPostBody])
+	assert.False(ra.CompleteTailSampling(http.StatusOK, time.Millisecond))
+}
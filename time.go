@@ -1,6 +1,10 @@
 package logger
 
-import "time"
+import (
+	"math"
+	"strconv"
+	"time"
+)
 
 const (
 	// NanosecondsPerSecond is the number of nanoseconds in a second.
@@ -22,6 +26,38 @@ func Microseconds(d time.Duration) float64 {
 	return float64(d) / float64(time.Microsecond)
 }
 
+// DurationFormat is how durations are rendered.
+type DurationFormat int
+
+const (
+	// DurationFormatDefault renders durations with `time.Duration.String()`, e.g. `1.234567ms`.
+	DurationFormatDefault DurationFormat = iota
+	// DurationFormatMilliseconds renders durations as a fixed number of milliseconds, e.g. `1.23ms`.
+	DurationFormatMilliseconds
+	// DurationFormatSeconds renders durations as a fixed number of seconds, e.g. `0.001s`.
+	DurationFormatSeconds
+	// DurationFormatMicroseconds renders durations as a fixed number of microseconds, e.g. `1234.57µs`.
+	DurationFormatMicroseconds
+	// DurationFormatNanoseconds renders durations as a raw integer number of nanoseconds, without a unit, e.g. `1234567`.
+	DurationFormatNanoseconds
+)
+
+// FormatDuration renders a duration in a given format, with a number of decimal places for the fixed formats.
+func FormatDuration(d time.Duration, format DurationFormat, precision int) string {
+	switch format {
+	case DurationFormatMilliseconds:
+		return strconv.FormatFloat(Milliseconds(d), 'f', precision, 64) + "ms"
+	case DurationFormatSeconds:
+		return strconv.FormatFloat(Seconds(d), 'f', precision, 64) + "s"
+	case DurationFormatMicroseconds:
+		return strconv.FormatFloat(Microseconds(d), 'f', precision, 64) + "µs"
+	case DurationFormatNanoseconds:
+		return strconv.FormatInt(int64(d), 10)
+	default:
+		return d.String()
+	}
+}
+
 // UnixNano returns both the unix timestamp (in seconds), and the
 // nanosecond remainder.
 func UnixNano(t time.Time) (int64, int64) {
@@ -51,3 +87,18 @@ func MeanOfDuration(input []time.Duration) time.Duration {
 	mean := uint64(sum) / uint64(len(input))
 	return time.Duration(mean)
 }
+
+// PercentileOfDuration returns the nearest-rank percentile (0-100) of a sorted slice of durations.
+func PercentileOfDuration(sorted []time.Duration, percentile float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(percentile/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
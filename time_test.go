@@ -44,3 +44,25 @@ func TestUnixNano(t *testing.T) {
 	checkedDate := time.Unix(unix, nano)
 	assert.Equal(2010, checkedDate.Year())
 }
+
+func TestPercentileOfDuration(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(0, PercentileOfDuration(nil, 50))
+	values := []time.Duration{1, 2, 3, 4}
+	assert.Equal(2, PercentileOfDuration(values, 50))
+	assert.Equal(4, PercentileOfDuration(values, 99))
+	assert.Equal(1, PercentileOfDuration(values, 0))
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert := assert.New(t)
+
+	elapsed := 1234567 * time.Nanosecond
+	assert.Equal("1.234567ms", FormatDuration(elapsed, DurationFormatDefault, 2))
+	assert.Equal("1.23ms", FormatDuration(elapsed, DurationFormatMilliseconds, 2))
+	assert.Equal("1ms", FormatDuration(elapsed, DurationFormatMilliseconds, 0))
+	assert.Equal("0.001s", FormatDuration(elapsed, DurationFormatSeconds, 3))
+	assert.Equal("1234.57µs", FormatDuration(elapsed, DurationFormatMicroseconds, 2))
+	assert.Equal("1234567", FormatDuration(elapsed, DurationFormatNanoseconds, 2))
+}
@@ -0,0 +1,128 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	// HeaderTraceParent is the W3C trace context header carrying the trace and parent span ids.
+	HeaderTraceParent = "traceparent"
+	// HeaderTraceState is the W3C trace context header carrying vendor specific trace state.
+	HeaderTraceState = "tracestate"
+
+	traceParentVersion = "00"
+	traceFlagsSampled  = "01"
+)
+
+var errInvalidTraceParent = errors.New("Invalid traceparent header")
+
+// NewTraceContext returns a new trace context that starts a new trace.
+func NewTraceContext() *TraceContext {
+	return &TraceContext{
+		TraceID: randomHex(16),
+		SpanID:  randomHex(8),
+		Flags:   traceFlagsSampled,
+	}
+}
+
+// ParseTraceParent parses a W3C `traceparent` header value (and optional `tracestate`) into a
+// trace context for a new span that is a child of the span in the header.
+func ParseTraceParent(traceParent, traceState string) (*TraceContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceParent), "-")
+	if len(parts) < 4 {
+		return nil, errInvalidTraceParent
+	}
+	version, traceID, parentID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isLowerHex(version, 2) || version == "ff" || (version == traceParentVersion && len(parts) != 4) {
+		return nil, errInvalidTraceParent
+	}
+	if !isLowerHex(traceID, 32) || traceID == strings.Repeat("0", 32) {
+		return nil, errInvalidTraceParent
+	}
+	if !isLowerHex(parentID, 16) || parentID == strings.Repeat("0", 16) {
+		return nil, errInvalidTraceParent
+	}
+	if !isLowerHex(flags, 2) {
+		return nil, errInvalidTraceParent
+	}
+	return &TraceContext{
+		TraceID:      traceID,
+		SpanID:       randomHex(8),
+		ParentSpanID: parentID,
+		Flags:        flags,
+		State:        traceState,
+	}, nil
+}
+
+// TraceContextFromRequest returns the trace context from a request's `traceparent` header,
+// or a new trace context if the header is missing or invalid.
+func TraceContextFromRequest(req *http.Request) *TraceContext {
+	if traceParent := req.Header.Get(HeaderTraceParent); len(traceParent) > 0 {
+		if tc, err := ParseTraceParent(traceParent, req.Header.Get(HeaderTraceState)); err == nil {
+			return tc
+		}
+	}
+	return NewTraceContext()
+}
+
+// TraceContextFromContext returns the trace context of the request agent stored in a context.
+func TraceContextFromContext(ctx context.Context) *TraceContext {
+	return ForRequest(ctx).TraceContext()
+}
+
+// TraceContext is a W3C trace context; the trace id, the id of the current span and the id of its parent.
+type TraceContext struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Flags        string
+	State        string
+}
+
+// Child returns a trace context for a new span within the same trace, with this span as its parent.
+func (tc *TraceContext) Child() *TraceContext {
+	return &TraceContext{
+		TraceID:      tc.TraceID,
+		SpanID:       randomHex(8),
+		ParentSpanID: tc.SpanID,
+		Flags:        tc.Flags,
+		State:        tc.State,
+	}
+}
+
+// TraceParent returns the `traceparent` header value for the span.
+func (tc *TraceContext) TraceParent() string {
+	return traceParentVersion + "-" + tc.TraceID + "-" + tc.SpanID + "-" + tc.Flags
+}
+
+// Inject sets the `traceparent` and `tracestate` headers for the span on a header collection.
+func (tc *TraceContext) Inject(header http.Header) {
+	header.Set(HeaderTraceParent, tc.TraceParent())
+	if len(tc.State) > 0 {
+		header.Set(HeaderTraceState, tc.State)
+	}
+}
+
+func randomHex(length int) string {
+	b := make([]byte, length)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func isLowerHex(value string, length int) bool {
+	if len(value) != length {
+		return false
+	}
+	for x := 0; x < len(value); x++ {
+		c := value[x]
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
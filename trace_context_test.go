@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	assert := assert.New(t)
+
+	tc, err := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "congo=t61rcWkgMzE")
+	assert.Nil(err)
+	assert.Equal("4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceID)
+	assert.Equal("00f067aa0ba902b7", tc.ParentSpanID)
+	assert.Len(tc.SpanID, 16)
+	assert.NotEqual(tc.ParentSpanID, tc.SpanID)
+	assert.Equal("01", tc.Flags)
+	assert.Equal("congo=t61rcWkgMzE", tc.State)
+	assert.Equal("00-4bf92f3577b34da6a3ce929d0e0e4736-"+tc.SpanID+"-01", tc.TraceParent())
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		_, err = ParseTraceParent(invalid, "")
+		assert.NotNil(err, invalid)
+	}
+}
+
+func TestTraceContextChild(t *testing.T) {
+	assert := assert.New(t)
+
+	parent := NewTraceContext()
+	assert.Len(parent.TraceID, 32)
+	child := parent.Child()
+	assert.Equal(parent.TraceID, child.TraceID)
+	assert.Equal(parent.SpanID, child.ParentSpanID)
+	assert.NotEqual(parent.SpanID, child.SpanID)
+
+	header := http.Header{}
+	child.Inject(header)
+	assert.Equal(child.TraceParent(), header.Get(HeaderTraceParent))
+	assert.Empty(header.Get(HeaderTraceState))
+}
+
+func TestMiddlewareTraceContext(t *testing.T) {
+	assert := assert.New(t)
+
+	da := None()
+	defer da.Close()
+
+	var tc *TraceContext
+	handler := NewMiddleware(da).HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		tc = TraceContextFromContext(req.Context())
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderTraceParent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler(httptest.NewRecorder(), req)
+	assert.NotNil(tc)
+	assert.Equal("4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceID)
+	assert.Equal("00f067aa0ba902b7", tc.ParentSpanID)
+
+	handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	assert.NotNil(tc)
+	assert.NotEqual("4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceID)
+	assert.Empty(tc.ParentSpanID)
+}
@@ -4,8 +4,8 @@ import (
 	"crypto/rand"
 	"errors"
 	"fmt"
-	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -17,28 +17,36 @@ import (
 // X-REAL-IP is checked. If multiple IPs are included the first one is returned
 // Finally r.RemoteAddr is used
 // Only benevolent services will allow access to the real IP.
+// Configure trusted proxies (see `SetTrustedProxies`) so the headers are only honored from known proxies.
 func GetIP(r *http.Request) string {
-	if r == nil {
-		return ""
-	}
-	tryHeader := func(key string) (string, bool) {
-		if headerVal := r.Header.Get(key); len(headerVal) > 0 {
-			if !strings.ContainsRune(headerVal, ',') {
-				return headerVal, true
-			}
-			return strings.SplitN(headerVal, ",", 2)[0], true
-		}
-		return "", false
+	return DefaultIPResolver().GetIP(r)
+}
+
+// ScrubQuery redacts the values of the given parameters (case insensitive) in a raw query string,
+// preserving the order of the parameters.
+func ScrubQuery(rawQuery string, params ...string) string {
+	if len(params) == 0 || len(rawQuery) == 0 {
+		return rawQuery
 	}
 
-	for _, header := range []string{"X-FORWARDED-FOR", "X-REAL-IP"} {
-		if headerVal, ok := tryHeader(header); ok {
-			return headerVal
+	pairs := strings.Split(rawQuery, "&")
+	for x, pair := range pairs {
+		rawKey := pair
+		if index := strings.IndexRune(pair, '='); index >= 0 {
+			rawKey = pair[:index]
+		}
+		key := rawKey
+		if unescaped, err := url.QueryUnescape(rawKey); err == nil {
+			key = unescaped
+		}
+		for _, param := range params {
+			if CaseInsensitiveEquals(key, param) {
+				pairs[x] = rawKey + "=" + RedactedValue
+				break
+			}
 		}
 	}
-
-	ip, _, _ := net.SplitHostPort(r.RemoteAddr)
-	return ip
+	return strings.Join(pairs, "&")
 }
 
 var errTypeConversion = errors.New("Invalid event state type conversion")
@@ -238,3 +246,11 @@ func UUIDv4() string {
 	uuid[8] = (uuid[8] & 0x3f) | 0x80 // set variant 10
 	return fmt.Sprintf("%x", uuid[:])
 }
+
+// boolAsInt32 returns a flag as 1 or 0, for flags read with `sync/atomic`.
+func boolAsInt32(value bool) int32 {
+	if value {
+		return 1
+	}
+	return 0
+}
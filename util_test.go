@@ -57,3 +57,11 @@ func TestGetIP(t *testing.T) {
 	}
 	assert.Equal("1", GetIP(&r))
 }
+
+func TestScrubQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("a=1&token="+RedactedValue+"&b=2&API_KEY="+RedactedValue, ScrubQuery("a=1&token=abc&b=2&API_KEY=def", "token", "api_key"))
+	assert.Equal("password="+RedactedValue, ScrubQuery("password", "password"))
+	assert.Equal("a=1", ScrubQuery("a=1"))
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,7 +15,7 @@ func WriteEventf(writer *Writer, ts TimeSource, event EventFlag, color AnsiColor
 
 	buffer.WriteString(writer.FormatEvent(event, color))
 	buffer.WriteRune(RuneSpace)
-	buffer.WriteString(fmt.Sprintf(format, args...))
+	buffer.WriteString(fmt.Sprintf(format, writer.SanitizeArgs(format, args...)...))
 	buffer.WriteRune(RuneSpace)
 
 	writer.WriteWithTimeSource(ts, buffer.Bytes())
@@ -27,53 +28,154 @@ func WriteRequestStart(writer *Writer, ts TimeSource, req *http.Request) {
 
 	buffer.WriteString(writer.FormatEvent(EventWebRequestStart, ColorGreen))
 	buffer.WriteRune(RuneSpace)
-	buffer.WriteString(GetIP(req))
+	if requestID := GetRequestID(req); len(requestID) > 0 {
+		buffer.WriteString(writer.Sanitize(requestID))
+		buffer.WriteRune(RuneSpace)
+	}
+	buffer.WriteString(writer.Sanitize(GetIP(req)))
 	buffer.WriteRune(RuneSpace)
-	buffer.WriteString(writer.Colorize(req.Method, ColorBlue))
+	buffer.WriteString(writer.Colorize(writer.Sanitize(req.Method), ColorBlue))
 	buffer.WriteRune(RuneSpace)
-	buffer.WriteString(req.URL.Path)
+	buffer.WriteString(writer.FormatRequestURI(req))
 
 	writer.WriteWithTimeSource(ts, buffer.Bytes())
 }
 
 // WriteRequest is a helper method to write request complete events to a writer.
 func WriteRequest(writer *Writer, ts TimeSource, req *http.Request, statusCode, contentLengthBytes int, elapsed time.Duration) {
+	writeRequest(writer, ts, EventWebRequest, req, statusCode, contentLengthBytes, elapsed)
+}
+
+// WriteClientRequest is a helper method to write outbound request events to a writer.
+func WriteClientRequest(writer *Writer, ts TimeSource, req *http.Request, statusCode, contentLengthBytes int, elapsed time.Duration) {
+	writeRequest(writer, ts, EventWebClientRequest, req, statusCode, contentLengthBytes, elapsed)
+}
+
+func writeRequest(writer *Writer, ts TimeSource, event EventFlag, req *http.Request, statusCode, contentLengthBytes int, elapsed time.Duration) {
+	writeRequestWithSuffix(writer, ts, event, req, statusCode, contentLengthBytes, elapsed, "")
+}
+
+func writeRequestWithSuffix(writer *Writer, ts TimeSource, event EventFlag, req *http.Request, statusCode, contentLengthBytes int, elapsed time.Duration, suffix string) {
 	buffer := writer.GetBuffer()
 	defer writer.PutBuffer(buffer)
 
-	buffer.WriteString(writer.FormatEvent(EventWebRequest, ColorGreen))
+	buffer.WriteString(writer.FormatEvent(event, ColorGreen))
 	buffer.WriteRune(RuneSpace)
-	buffer.WriteString(GetIP(req))
+	if requestID := GetRequestID(req); len(requestID) > 0 {
+		buffer.WriteString(writer.Sanitize(requestID))
+		buffer.WriteRune(RuneSpace)
+	}
+	buffer.WriteString(writer.Sanitize(GetIP(req)))
 	buffer.WriteRune(RuneSpace)
-	buffer.WriteString(writer.Colorize(req.Method, ColorBlue))
+	buffer.WriteString(writer.Colorize(writer.Sanitize(req.Method), ColorBlue))
 	buffer.WriteRune(RuneSpace)
-	buffer.WriteString(req.URL.Path)
+	buffer.WriteString(writer.FormatRequestURI(req))
 	buffer.WriteRune(RuneSpace)
 	buffer.WriteString(writer.ColorizeByStatusCode(statusCode, strconv.Itoa(statusCode)))
 	buffer.WriteRune(RuneSpace)
-	buffer.WriteString(elapsed.String())
+	buffer.WriteString(writer.FormatDuration(elapsed))
 	buffer.WriteRune(RuneSpace)
-	buffer.WriteString(File.FormatSize(contentLengthBytes))
+	buffer.WriteString(writer.FormatSize(contentLengthBytes))
+	if len(suffix) > 0 {
+		buffer.WriteRune(RuneSpace)
+		buffer.WriteString(suffix)
+	}
 
 	writer.WriteWithTimeSource(ts, buffer.Bytes())
 }
 
-// WriteRequestBody is a helper method to write request start events to a writer.
+// WriteRequestBody is a helper method to write request body events to a writer.
 func WriteRequestBody(writer *Writer, ts TimeSource, body []byte) {
 	buffer := writer.GetBuffer()
 	defer writer.PutBuffer(buffer)
-	buffer.WriteString("[" + writer.Colorize(string(EventWebRequestPostBody), ColorGreen) + "]")
+	buffer.WriteString(writer.FormatEvent(EventWebRequestPostBody, ColorGreen))
 	buffer.WriteRune(RuneSpace)
-	buffer.Write(body)
+	buffer.WriteString(writer.Sanitize(string(body)))
 	writer.WriteWithTimeSource(ts, buffer.Bytes())
 }
 
-// WriteResponseBody is a helper method to write request start events to a writer.
+// WriteResponseBody is a helper method to write response body events to a writer.
 func WriteResponseBody(writer *Writer, ts TimeSource, body []byte) {
 	buffer := writer.GetBuffer()
 	defer writer.PutBuffer(buffer)
-	buffer.WriteString("[" + writer.Colorize(string(EventWebResponse), ColorGreen) + "]")
+	buffer.WriteString(writer.FormatEvent(EventWebResponse, ColorGreen))
 	buffer.WriteRune(RuneSpace)
-	buffer.Write(body)
+	buffer.WriteString(writer.Sanitize(string(body)))
 	writer.WriteWithTimeSource(ts, buffer.Bytes())
 }
+
+// WriteError is a helper method to write an error, its wrap chain and (if it has one) its stack
+// as an indented block, for use in custom error listeners.
+func WriteError(writer *Writer, ts TimeSource, event EventFlag, err error) {
+	if err == nil {
+		return
+	}
+	buffer := writer.GetBuffer()
+	defer writer.PutBuffer(buffer)
+
+	message := err.Error()
+	buffer.WriteString(writer.FormatEvent(event, ColorRed))
+	buffer.WriteRune(RuneSpace)
+	buffer.WriteString(writer.Sanitize(message))
+
+	for _, cause := range ErrorChain(err)[1:] {
+		if causeMessage := cause.Error(); causeMessage != message {
+			buffer.WriteString("\n" + ErrorIndent + "caused by: " + writer.Sanitize(causeMessage))
+			message = causeMessage
+		}
+	}
+	for _, frame := range ErrorStack(err) {
+		buffer.WriteString("\n" + ErrorIndent + writer.Sanitize(frame))
+	}
+
+	writer.WriteWithTimeSource(ts, buffer.Bytes())
+}
+
+// ErrorIndent is the indent for the causes and stack frames written by `WriteError`.
+const ErrorIndent = "    "
+
+// maxErrorChainLength bounds the wrap chain walked by `ErrorChain`, in case an error wraps itself.
+const maxErrorChainLength = 32
+
+// ErrorChain returns an error followed by the errors it wraps, outermost first.
+// It follows `Unwrap() error`, `Cause() error` and `Inner() error`.
+func ErrorChain(err error) []error {
+	var chain []error
+	for err != nil && len(chain) < maxErrorChainLength {
+		chain = append(chain, err)
+		switch typed := err.(type) {
+		case interface{ Unwrap() error }:
+			err = typed.Unwrap()
+		case interface{ Cause() error }:
+			err = typed.Cause()
+		case interface{ Inner() error }:
+			err = typed.Inner()
+		default:
+			err = nil
+		}
+	}
+	return chain
+}
+
+// ErrorStack returns the stack frames of an error: those supplied by its wrap chain (see `StackProvider`),
+// or else as rendered by its `%+v` format, or nil if it doesn't render one.
+func ErrorStack(err error) []string {
+	if err == nil {
+		return nil
+	}
+	if frames, provided := providedStack(err); provided {
+		return frames
+	}
+	message := err.Error()
+	detailed := fmt.Sprintf("%+v", err)
+	if detailed == message || !strings.HasPrefix(detailed, message) {
+		return nil
+	}
+	var frames []string
+	for _, line := range strings.Split(strings.TrimPrefix(detailed, message), "\n") {
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			frames = append(frames, line)
+		}
+	}
+	return frames
+}
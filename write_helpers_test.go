@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+	exception "github.com/blendlabs/go-exception"
+)
+
+func TestWriteResponseBody(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := newSignalOutput()
+	writer := NewWriter(buffer)
+	writer.SetUseAnsiColors(false)
+	writer.SetShowTimestamp(false)
+
+	da := NewWithWriter(NewEventFlagSetAll(), writer)
+	defer da.Close()
+	da.AddEventListener(EventWebRequestPostBody, NewRequestBodyListener(WriteRequestBody))
+	da.AddEventListener(EventWebResponse, NewResponseListener(WriteResponseBody))
+
+	da.OnEvent(EventWebRequestPostBody, []byte(`{"name":"request"}`))
+	<-buffer.written
+	assert.Equal("[web.request.postbody] {\"name\":\"request\"}\n", buffer.String())
+
+	buffer.Reset()
+	da.OnEvent(EventWebResponse, []byte(`{"name":"response"}`))
+	<-buffer.written
+	assert.Equal("[web.response] {\"name\":\"response\"}\n", buffer.String())
+	da.Flush()
+}
+
+func TestNewResponseListener(t *testing.T) {
+	assert := assert.New(t)
+
+	var body []byte
+	listener := NewResponseListener(func(wr *Writer, ts TimeSource, b []byte) { body = b })
+	listener(NewWriter(bytes.NewBuffer(nil)), TimeNow(), EventWebResponse, []byte("test"))
+	assert.Equal("test", string(body))
+}
+
+type stackError struct {
+	message string
+	inner   error
+}
+
+func (se stackError) Error() string { return se.message }
+func (se stackError) Unwrap() error { return se.inner }
+func (se stackError) Format(s fmt.State, verb rune) {
+	fmt.Fprint(s, se.message)
+	if s.Flag('+') {
+		fmt.Fprint(s, "\nmain.handler\n\t/app/main.go:10")
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	writer := NewWriter(buffer)
+	writer.SetUseAnsiColors(false)
+	writer.SetShowTimestamp(false)
+
+	err := stackError{message: "request failed", inner: exception.New(errors.New("connection refused"))}
+	assert.Len(ErrorChain(err), 3)
+	assert.Equal([]string{"main.handler", "/app/main.go:10"}, ErrorStack(err))
+
+	WriteError(writer, TimeNow(), EventError, err)
+	assert.Equal("[error] request failed\n"+
+		"    caused by: connection refused\n"+
+		"    main.handler\n"+
+		"    /app/main.go:10\n", buffer.String())
+
+	buffer.Reset()
+	WriteError(writer, TimeNow(), EventError, nil)
+	assert.Empty(buffer.String())
+}
@@ -6,7 +6,10 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strings"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
 const (
@@ -22,16 +25,53 @@ const (
 	DefaultWriterShowTimestamp = true
 	// DefaultWriterShowLabel is a default setting for writers.
 	DefaultWriterShowLabel = false
+	// DefaultWriterShowQuery is a default setting for writers.
+	DefaultWriterShowQuery = false
+	// DefaultWriterSanitizeControlChars is a default setting for writers.
+	DefaultWriterSanitizeControlChars = true
+
+	// DefaultContinuationPrefix is a prefix for the continuation lines of multi-line messages, for use with `SetContinuationPrefix`.
+	DefaultContinuationPrefix = "  | "
+
+	// TruncationMarker is appended to lines truncated to the max line length.
+	TruncationMarker = "...[truncated]"
+
+	// RedactedValue is written in place of sensitive values.
+	RedactedValue = "[REDACTED]"
+)
+
+var (
+	// ShortEventLabels are three letter labels for the severity events, for use with `SetEventLabels`.
+	ShortEventLabels = map[EventFlag]string{
+		EventFatalError: "FTL",
+		EventError:      "ERR",
+		EventWarning:    "WRN",
+		EventInfo:       "INF",
+		EventDebug:      "DBG",
+	}
+
+	// DefaultEventGlyphs are glyphs for the severity events, for use with `SetEventGlyphs`.
+	DefaultEventGlyphs = map[EventFlag]string{
+		EventFatalError: "✖",
+		EventError:      "✖",
+		EventWarning:    "⚠",
+		EventInfo:       "✓",
+		EventDebug:      "•",
+	}
+
+	// DefaultScrubbedQueryParams are the query parameters whose values are redacted by default when queries are shown.
+	DefaultScrubbedQueryParams = []string{"token", "access_token", "api_key", "apikey", "password", "secret"}
 )
 
 // NewWriter returns a new writer with combined standard and error outputs.
 func NewWriter(output io.Writer) *Writer {
 	agent := &Writer{
-		Output:        NewSyncOutput(output),
-		useAnsiColors: DefaultWriterUseAnsiColors,
-		showTimestamp: DefaultWriterShowTimestamp,
-		showLabel:     DefaultWriterShowLabel,
-		bufferPool:    NewBufferPool(DefaultBufferPoolSize),
+		Output:               NewSyncOutput(output),
+		useAnsiColors:        DefaultWriterUseAnsiColors,
+		showTimestamp:        DefaultWriterShowTimestamp,
+		showLabel:            DefaultWriterShowLabel,
+		sanitizeControlChars: DefaultWriterSanitizeControlChars,
+		bufferPool:           NewBufferPool(DefaultBufferPoolSize),
 	}
 	return agent
 }
@@ -39,27 +79,42 @@ func NewWriter(output io.Writer) *Writer {
 // NewWriterWithError returns a new writer with a dedicated error output.
 func NewWriterWithError(output, errorOutput io.Writer) *Writer {
 	agent := &Writer{
-		Output:        NewSyncOutput(output),
-		ErrorOutput:   NewSyncOutput(errorOutput),
-		useAnsiColors: DefaultWriterUseAnsiColors,
-		showTimestamp: DefaultWriterShowTimestamp,
-		showLabel:     DefaultWriterShowLabel,
-		bufferPool:    NewBufferPool(DefaultBufferPoolSize),
+		Output:               NewSyncOutput(output),
+		ErrorOutput:          NewSyncOutput(errorOutput),
+		useAnsiColors:        DefaultWriterUseAnsiColors,
+		showTimestamp:        DefaultWriterShowTimestamp,
+		showLabel:            DefaultWriterShowLabel,
+		sanitizeControlChars: DefaultWriterSanitizeControlChars,
+		bufferPool:           NewBufferPool(DefaultBufferPoolSize),
 	}
 	return agent
 }
 
 // NewWriterFromEnvironment initializes a log writer from the environment.
 func NewWriterFromEnvironment() *Writer {
-	return &Writer{
-		Output:        NewMultiOutputFromEnvironment(),
-		ErrorOutput:   NewErrorMultiOutputFromEnvironment(),
-		useAnsiColors: envFlagIsSet(EnvironmentVariableUseAnsiColors, DefaultWriterUseAnsiColors),
-		showTimestamp: envFlagIsSet(EnvironmentVariableShowTimestamp, DefaultWriterShowTimestamp),
-		showLabel:     envFlagIsSet(EnvironmentVariableShowLabel, DefaultWriterShowLabel),
-		label:         os.Getenv(EnvironmentVariableLogLabel),
-		bufferPool:    NewBufferPool(DefaultBufferPoolSize),
+	writer := &Writer{
+		Output:               NewMultiOutputFromEnvironment(),
+		ErrorOutput:          NewErrorMultiOutputFromEnvironment(),
+		useAnsiColors:        useAnsiColorsFromEnvironment(),
+		colorProfile:         DetectColorProfile(),
+		showTimestamp:        envFlagIsSet(EnvironmentVariableShowTimestamp, DefaultWriterShowTimestamp),
+		showLabel:            envFlagIsSet(EnvironmentVariableShowLabel, DefaultWriterShowLabel),
+		label:                os.Getenv(EnvironmentVariableLogLabel),
+		namespace:            os.Getenv(EnvironmentVariableLogNamespace),
+		sanitizeControlChars: DefaultWriterSanitizeControlChars,
+		bufferPool:           NewBufferPool(DefaultBufferPoolSize),
+		outputFormat:         ParseOutputFormat(os.Getenv(EnvironmentVariableLogFormat)),
+	}
+	if envFlagIsSet(EnvironmentVariableScanSecrets, false) {
+		writer.secretScanner = NewSecretScanner()
 	}
+	if errorEvents := os.Getenv(EnvironmentVariableLogErrorEvents); len(errorEvents) > 0 {
+		writer.errorStreamEvents = NewEventFlagSetFromCSV(errorEvents)
+	}
+	if envFlagIsSet(EnvironmentVariableLogKubernetesMetadata, false) {
+		writer.EnrichWithKubernetes(KubernetesMetadataFromEnvironment())
+	}
+	return writer
 }
 
 // NewWriterToFile creates a new writer that writes to stdout + stderr and a file.
@@ -69,12 +124,16 @@ func NewWriterToFile(path string) *Writer {
 		panic(err)
 	}
 	return &Writer{
-		Output:        NewMultiOutput(NewSyncOutput(os.Stdout), fileoutput),
-		useAnsiColors: envFlagIsSet(EnvironmentVariableUseAnsiColors, DefaultWriterUseAnsiColors),
-		showTimestamp: envFlagIsSet(EnvironmentVariableShowTimestamp, DefaultWriterShowTimestamp),
-		showLabel:     envFlagIsSet(EnvironmentVariableShowLabel, DefaultWriterShowLabel),
-		label:         os.Getenv(EnvironmentVariableLogLabel),
-		bufferPool:    NewBufferPool(DefaultBufferPoolSize),
+		Output:               NewMultiOutput(NewSyncOutput(NewTerminalAwareOutput(os.Stdout)), NewAnsiStripOutput(fileoutput)),
+		useAnsiColors:        useAnsiColorsFromEnvironment(),
+		colorProfile:         DetectColorProfile(),
+		showTimestamp:        envFlagIsSet(EnvironmentVariableShowTimestamp, DefaultWriterShowTimestamp),
+		showLabel:            envFlagIsSet(EnvironmentVariableShowLabel, DefaultWriterShowLabel),
+		label:                os.Getenv(EnvironmentVariableLogLabel),
+		namespace:            os.Getenv(EnvironmentVariableLogNamespace),
+		sanitizeControlChars: DefaultWriterSanitizeControlChars,
+		bufferPool:           NewBufferPool(DefaultBufferPoolSize),
+		outputFormat:         ParseOutputFormat(os.Getenv(EnvironmentVariableLogFormat)),
 	}
 }
 
@@ -91,14 +150,27 @@ func NewWriterToFileWithError(output, errorOutput string) *Writer {
 	}
 
 	return &Writer{
-		Output:        NewMultiOutput(NewSyncOutput(os.Stdout), fileOutput),
-		ErrorOutput:   NewMultiOutput(NewSyncOutput(os.Stderr), fileErrorOutput),
-		useAnsiColors: envFlagIsSet(EnvironmentVariableUseAnsiColors, DefaultWriterUseAnsiColors),
-		showTimestamp: envFlagIsSet(EnvironmentVariableShowTimestamp, DefaultWriterShowTimestamp),
-		showLabel:     envFlagIsSet(EnvironmentVariableShowLabel, DefaultWriterShowLabel),
-		label:         os.Getenv(EnvironmentVariableLogLabel),
-		bufferPool:    NewBufferPool(DefaultBufferPoolSize),
+		Output:               NewMultiOutput(NewSyncOutput(NewTerminalAwareOutput(os.Stdout)), NewAnsiStripOutput(fileOutput)),
+		ErrorOutput:          NewMultiOutput(NewSyncOutput(NewTerminalAwareOutput(os.Stderr)), NewAnsiStripOutput(fileErrorOutput)),
+		useAnsiColors:        useAnsiColorsFromEnvironment(),
+		colorProfile:         DetectColorProfile(),
+		showTimestamp:        envFlagIsSet(EnvironmentVariableShowTimestamp, DefaultWriterShowTimestamp),
+		showLabel:            envFlagIsSet(EnvironmentVariableShowLabel, DefaultWriterShowLabel),
+		label:                os.Getenv(EnvironmentVariableLogLabel),
+		namespace:            os.Getenv(EnvironmentVariableLogNamespace),
+		sanitizeControlChars: DefaultWriterSanitizeControlChars,
+		bufferPool:           NewBufferPool(DefaultBufferPoolSize),
+		outputFormat:         ParseOutputFormat(os.Getenv(EnvironmentVariableLogFormat)),
+	}
+}
+
+// useAnsiColorsFromEnvironment returns if ansi colors are enabled from the environment
+// and the console (on Windows) can process them.
+func useAnsiColorsFromEnvironment() bool {
+	if !envFlagIsSet(EnvironmentVariableUseAnsiColors, DefaultWriterUseAnsiColors) {
+		return false
 	}
+	return EnableVirtualTerminal(os.Stdout) == nil && EnableVirtualTerminal(os.Stderr) == nil
 }
 
 // Writer handles outputting logging events to given writer streams.
@@ -109,13 +181,83 @@ type Writer struct {
 	showTimestamp bool
 	showLabel     bool
 	useAnsiColors bool
-
-	timeFormat string
-	label      string
+	colorProfile  ColorProfile
+
+	showQuery           bool
+	showRoute           bool
+	scrubbedQueryParams []string
+	maskedHeaders       []string
+	secretScanner       *SecretScanner
+	errorStreamEvents   *EventFlagSet
+	flushSeverity       EventFlag
+
+	timeFormat  string
+	label       string
+	namespace   string
+	fields      map[string]interface{}
+	eventLabels map[EventFlag]string
+	eventGlyphs map[EventFlag]string
+
+	continuationPrefix   string
+	sanitizeControlChars bool
+	maxLineLength        int
+	sizeUnits            SizeUnits
+	sizePrecision        int
+	durationFormat       DurationFormat
+	durationPrecision    int
+	outputFormat         OutputFormat
+	sequenceNumbers      bool
+
+	formatCache atomic.Value // *formatCache, see `resetFormatCache`
+
+	alignColumns   bool
+	timestampWidth int32
+	eventWidth     int32
+	bytesWritten   int64
+	sequence       uint64
 
 	bufferPool *BufferPool
 }
 
+// ErrorStreamEvents returns the events written to the error stream, or nil if the default mapping is used.
+func (wr *Writer) ErrorStreamEvents() *EventFlagSet { return wr.errorStreamEvents }
+
+// SetErrorStreamEvents sets the events the agent writes to the error stream, with every other event written to
+// the output stream, e.g. `NewEventFlagSet(EventError, EventFatalError)` to send warnings to stdout.
+// By default (nil) warnings, errors and fatals go to the error stream.
+func (wr *Writer) SetErrorStreamEvents(events *EventFlagSet) { wr.errorStreamEvents = events }
+
+// UsesErrorOutput returns if an event is written to the error stream, given its default mapping.
+func (wr *Writer) UsesErrorOutput(event EventFlag, defaultToErrorOutput bool) bool {
+	if wr.errorStreamEvents == nil {
+		return defaultToErrorOutput
+	}
+	return wr.errorStreamEvents.IsEnabled(event)
+}
+
+// FlushSeverity returns the severity at and above which events flush buffered outputs, if set.
+func (wr *Writer) FlushSeverity() EventFlag { return wr.flushSeverity }
+
+// SetFlushSeverity sets the severity (e.g. `EventError`) at and above which the agent flushes buffered outputs
+// (see `NewBufferedOutput`) as soon as an event is written, so it appears promptly.
+func (wr *Writer) SetFlushSeverity(severity EventFlag) { wr.flushSeverity = severity }
+
+// Flush flushes the output and error output, if they buffer writes.
+func (wr *Writer) Flush() error {
+	if err := FlushOutput(wr.Output); err != nil {
+		return err
+	}
+	return FlushOutput(wr.ErrorOutput)
+}
+
+// flushForSeverity flushes the outputs if an event is at or above the flush severity.
+func (wr *Writer) flushForSeverity(event EventFlag) error {
+	if len(wr.flushSeverity) == 0 || !IsSeverityAtLeast(event, wr.flushSeverity) {
+		return nil
+	}
+	return wr.Flush()
+}
+
 // GetErrorOutput returns an io.Writer for the error stream.
 func (wr *Writer) GetErrorOutput() io.Writer {
 	if wr.ErrorOutput != nil {
@@ -127,14 +269,62 @@ func (wr *Writer) GetErrorOutput() io.Writer {
 // Colorize (optionally) applies a color to a string.
 func (wr *Writer) Colorize(value string, color AnsiColorCode) string {
 	if wr.useAnsiColors {
-		return color.Apply(value)
+		return color.Degrade(wr.colorProfile).Apply(value)
 	}
 	return value
 }
 
-// FormatEvent formats an event label.
+// FormatEvent formats an event label. It's empty for json output, where the event is a field of the line (see `Record`).
 func (wr *Writer) FormatEvent(event EventFlag, color AnsiColorCode) string {
-	return fmt.Sprintf("[%s]", wr.Colorize(string(event), color))
+	if wr.isStructured() {
+		return ""
+	}
+	formatted := wr.formatEventLabel(event, color)
+	if wr.alignColumns {
+		return formatted + wr.alignmentPadding(&wr.eventWidth, wr.eventWidthOf(event))
+	}
+	return formatted
+}
+
+// EventLabel returns the label written for an event, which defaults to the event flag.
+func (wr *Writer) EventLabel(event EventFlag) string {
+	if label, hasLabel := wr.eventLabels[event]; hasLabel {
+		return label
+	}
+	return string(event)
+}
+
+// SetEventLabel sets the label written for an event, e.g. `ERR` for `error`.
+func (wr *Writer) SetEventLabel(event EventFlag, label string) {
+	eventLabels := map[EventFlag]string{event: label}
+	for existing, existingLabel := range wr.eventLabels {
+		if existing != event {
+			eventLabels[existing] = existingLabel
+		}
+	}
+	wr.eventLabels = eventLabels
+	wr.resetFormatCache()
+}
+
+// SetEventGlyphs sets glyphs (or any short strings) written ahead of the labels of events,
+// e.g. `DefaultEventGlyphs`. Events are written without glyphs by default.
+func (wr *Writer) SetEventGlyphs(glyphs map[EventFlag]string) {
+	eventGlyphs := map[EventFlag]string{}
+	for event, glyph := range glyphs {
+		eventGlyphs[event] = glyph
+	}
+	wr.eventGlyphs = eventGlyphs
+	wr.resetFormatCache()
+}
+
+// SetEventLabels sets the labels written for events; events without a label are written as their flag.
+func (wr *Writer) SetEventLabels(labels map[EventFlag]string) {
+	eventLabels := map[EventFlag]string{}
+	for event, label := range labels {
+		eventLabels[event] = label
+	}
+	wr.eventLabels = eventLabels
+	wr.resetFormatCache()
 }
 
 // FormatLabel returns the app name.
@@ -142,18 +332,37 @@ func (wr *Writer) FormatLabel() string {
 	return wr.Colorize(wr.label, ColorBlue)
 }
 
+// FormatNamespace returns the namespace (or tenant) label.
+func (wr *Writer) FormatNamespace() string {
+	return wr.formatNamespace(wr.namespace)
+}
+
+// formatNamespace returns the label of a given namespace.
+func (wr *Writer) formatNamespace(namespace string) string {
+	return fmt.Sprintf("{%s}", wr.Colorize(namespace, ColorCyan))
+}
+
+// FormatRequestURI returns the path (or if enabled, the matched route) of a request,
+// and (if enabled) its query with the values of scrubbed parameters redacted.
+func (wr *Writer) FormatRequestURI(req *http.Request) string {
+	path := req.URL.Path
+	if wr.showRoute {
+		path = GetRoute(req)
+	}
+	if !wr.showQuery || len(req.URL.RawQuery) == 0 {
+		return wr.Sanitize(path)
+	}
+	return wr.Sanitize(path + "?" + ScrubQuery(req.URL.RawQuery, wr.ScrubbedQueryParams()...))
+}
+
 // ColorizeByStatusCode colorizes a string by a status code (green, yellow, red).
 func (wr *Writer) ColorizeByStatusCode(statusCode int, value string) string {
-	if wr.useAnsiColors {
-		if statusCode >= http.StatusOK && statusCode < 300 { //the http 2xx range is ok
-			return ColorGreen.Apply(value)
-		} else if statusCode == http.StatusInternalServerError {
-			return ColorRed.Apply(value)
-		} else {
-			return ColorYellow.Apply(value)
-		}
+	if statusCode >= http.StatusOK && statusCode < 300 { //the http 2xx range is ok
+		return wr.Colorize(value, ColorGreen)
+	} else if statusCode == http.StatusInternalServerError {
+		return wr.Colorize(value, ColorRed)
 	}
-	return value
+	return wr.Colorize(value, ColorYellow)
 }
 
 // GetTimestamp returns a new timestamp string.
@@ -162,10 +371,107 @@ func (wr *Writer) GetTimestamp(optionalTimeSource ...TimeSource) string {
 	if len(wr.timeFormat) > 0 {
 		timeFormat = wr.timeFormat
 	}
+	var timestamp string
 	if len(optionalTimeSource) > 0 {
-		return wr.Colorize(optionalTimeSource[0].UTCNow().Format(timeFormat), ColorGray)
+		timestamp = optionalTimeSource[0].UTCNow().Format(timeFormat)
+	} else {
+		timestamp = time.Now().UTC().Format(timeFormat)
+	}
+	if wr.alignColumns {
+		timestamp = timestamp + wr.alignmentPadding(&wr.timestampWidth, len(timestamp))
+	}
+	return wr.Colorize(timestamp, ColorGray)
+}
+
+// ContinuationPrefix returns the prefix for the continuation lines of multi-line messages.
+func (wr *Writer) ContinuationPrefix() string { return wr.continuationPrefix }
+
+// SetContinuationPrefix sets a prefix (e.g. an indent or a marker) for the continuation lines of multi-line messages
+// and stack traces, so they stay visually attached to their header line. An empty prefix writes them as is.
+func (wr *Writer) SetContinuationPrefix(prefix string) { wr.continuationPrefix = prefix }
+
+// MaxLineLength returns the maximum length in bytes of a written line, excluding the newline.
+func (wr *Writer) MaxLineLength() int { return wr.maxLineLength }
+
+// SetMaxLineLength sets the maximum length in bytes of a written line, excluding the newline.
+// Each line of a multi-line message is limited on its own. Longer lines are truncated on a rune boundary (and outside of
+// ansi escape sequences) after secrets are redacted (see `SetSecretScanner`), and end with `TruncationMarker`.
+// Json output isn't truncated. A length <= 0 disables truncation.
+func (wr *Writer) SetMaxLineLength(maxLineLength int) { wr.maxLineLength = maxLineLength }
+
+// FormatSize formats a size in bytes with the writer's size units and precision.
+func (wr *Writer) FormatSize(sizeBytes int) string {
+	return File.FormatSizeWithUnits(sizeBytes, wr.sizeUnits, wr.sizePrecision)
+}
+
+// FormatDuration formats a duration (e.g. a request's elapsed time) with the writer's duration format and precision.
+func (wr *Writer) FormatDuration(d time.Duration) string {
+	return FormatDuration(d, wr.durationFormat, wr.durationPrecision)
+}
+
+// DurationFormat returns how durations are written.
+func (wr *Writer) DurationFormat() DurationFormat { return wr.durationFormat }
+
+// SetDurationFormat sets how durations are written, e.g. as a fixed number of milliseconds for numeric parsing.
+func (wr *Writer) SetDurationFormat(format DurationFormat) { wr.durationFormat = format }
+
+// DurationPrecision returns the decimal places durations are written with (for the fixed formats).
+func (wr *Writer) DurationPrecision() int { return wr.durationPrecision }
+
+// SetDurationPrecision sets the decimal places durations are written with (for the fixed formats).
+func (wr *Writer) SetDurationPrecision(precision int) { wr.durationPrecision = precision }
+
+// SizeUnits returns the units sizes are written in.
+func (wr *Writer) SizeUnits() SizeUnits { return wr.sizeUnits }
+
+// SetSizeUnits sets the units sizes (e.g. response content lengths) are written in.
+func (wr *Writer) SetSizeUnits(units SizeUnits) { wr.sizeUnits = units }
+
+// SizePrecision returns the decimal places sizes are written with (for binary and decimal units).
+func (wr *Writer) SizePrecision() int { return wr.sizePrecision }
+
+// SetSizePrecision sets the decimal places sizes are written with (for binary and decimal units).
+func (wr *Writer) SetSizePrecision(precision int) { wr.sizePrecision = precision }
+
+// AlignColumns is a formatting option.
+func (wr *Writer) AlignColumns() bool { return wr.alignColumns }
+
+// SetAlignColumns sets if the timestamp and event label columns are padded to a common width,
+// so output with mixed severities lines up. Columns grow to the widest value written so far,
+// starting from the widest of the severity labels.
+func (wr *Writer) SetAlignColumns(alignColumns bool) {
+	if alignColumns {
+		var width int
+		for _, event := range []EventFlag{EventFatalError, EventError, EventWarning, EventInfo, EventDebug} {
+			if labelWidth := wr.eventWidthOf(event); labelWidth > width {
+				width = labelWidth
+			}
+		}
+		atomic.StoreInt32(&wr.eventWidth, int32(width))
+	}
+	wr.alignColumns = alignColumns
+}
+
+// eventWidthOf returns the width in runes of a formatted event label.
+func (wr *Writer) eventWidthOf(event EventFlag) int {
+	width := utf8.RuneCountInString(wr.EventLabel(event)) + 2
+	if glyph, hasGlyph := wr.eventGlyphs[event]; hasGlyph {
+		width += utf8.RuneCountInString(glyph) + 1
+	}
+	return width
+}
+
+// alignmentPadding returns the spaces that pad a value to a column's width, widening the column if needed.
+func (wr *Writer) alignmentPadding(columnWidth *int32, width int) string {
+	for {
+		current := atomic.LoadInt32(columnWidth)
+		if int32(width) <= current {
+			return strings.Repeat(" ", int(current)-width)
+		}
+		if atomic.CompareAndSwapInt32(columnWidth, current, int32(width)) {
+			return ""
+		}
 	}
-	return wr.Colorize(time.Now().UTC().Format(timeFormat), ColorGray)
 }
 
 // Printf writes to the output stream.
@@ -195,22 +501,7 @@ func (wr *Writer) Write(binary []byte) (int64, error) {
 
 // WriteWithTimeSource writes a binary blob to a given writer, and with a given timing source.
 func (wr *Writer) WriteWithTimeSource(ts TimeSource, binary []byte) (int64, error) {
-	buf := wr.bufferPool.Get()
-	defer wr.bufferPool.Put(buf)
-
-	if wr.showTimestamp {
-		buf.WriteString(wr.GetTimestamp(ts))
-		buf.WriteRune(RuneSpace)
-	}
-
-	if wr.showLabel && len(wr.label) > 0 {
-		buf.WriteString(wr.FormatLabel())
-		buf.WriteRune(RuneSpace)
-	}
-
-	buf.Write(binary)
-	buf.WriteRune(RuneNewline)
-	return buf.WriteTo(wr.Output)
+	return wr.fwriteWithTimeSource(ts, wr.Output, binary)
 }
 
 // Fprintf writes a given string and args to a writer.
@@ -226,34 +517,162 @@ func (wr *Writer) FprintfWithTimeSource(ts TimeSource, w io.Writer, format strin
 	if len(format) == 0 {
 		return 0, nil
 	}
-	message := fmt.Sprintf(format, args...)
+	message := formatMessage(format, args...)
 	if len(message) == 0 {
 		return 0, nil
 	}
+	return wr.fwriteWithTimeSource(ts, w, []byte(message))
+}
 
+// fwriteWithTimeSource writes a message body to a given writer, with a given timing source.
+func (wr *Writer) fwriteWithTimeSource(ts TimeSource, w io.Writer, body []byte) (int64, error) {
+	if wr.isStructured() {
+		return wr.fwriteRecord(ts, w, body)
+	}
 	buf := wr.bufferPool.Get()
 	defer wr.bufferPool.Put(buf)
 
+	wr.writePrefix(buf, ts)
+	wr.writeSequence(buf, SequenceFromTimeSource(ts), wr.nextSequence())
+
+	wr.writeBody(buf, body)
+	return wr.writeBuffer(w, buf)
+}
+
+// writeBuffer writes a rendered line to a given writer, scanning it for secrets if a scanner is set,
+// then truncating its lines to the max line length (see `SetMaxLineLength`), so secrets can't be cut past recognition.
+func (wr *Writer) writeBuffer(w io.Writer, buf *bytes.Buffer) (int64, error) {
+	if wr.secretScanner == nil && (wr.maxLineLength <= 0 || wr.isStructured()) {
+		written, err := buf.WriteTo(w)
+		atomic.AddInt64(&wr.bytesWritten, written)
+		return written, err
+	}
+	contents := buf.Bytes()
+	if wr.secretScanner != nil {
+		contents = wr.secretScanner.Scan(contents)
+	}
+	if wr.maxLineLength > 0 && !wr.isStructured() {
+		contents = truncateLines(contents, wr.maxLineLength)
+	}
+	written, err := w.Write(contents)
+	atomic.AddInt64(&wr.bytesWritten, int64(written))
+	return int64(written), err
+}
+
+// BytesWritten returns the number of bytes the writer has written to its outputs.
+func (wr *Writer) BytesWritten() int64 {
+	return atomic.LoadInt64(&wr.bytesWritten)
+}
+
+// writeBody writes a message and the trailing newline to a buffer,
+// prefixing continuation lines (as configured).
+func (wr *Writer) writeBody(buf *bytes.Buffer, body []byte) {
+	if len(wr.continuationPrefix) > 0 {
+		body = bytes.TrimRight(body, "\n")
+		body = bytes.Replace(body, []byte{'\n'}, []byte("\n"+wr.continuationPrefix), -1)
+	}
+	buf.Write(body)
+	buf.WriteRune(RuneNewline)
+}
+
+// truncateLines truncates each line (excluding its newline) longer than maxLength, ending it with `TruncationMarker`.
+// Lines with ansi escape sequences also end with a reset, so a color cut short doesn't run on.
+func truncateLines(contents []byte, maxLength int) []byte {
+	var truncated []byte
+	for start := 0; start < len(contents); {
+		end := bytes.IndexByte(contents[start:], '\n')
+		if end < 0 {
+			end = len(contents)
+		} else {
+			end += start
+		}
+		line := contents[start:end]
+		if len(line) > maxLength {
+			if truncated == nil {
+				truncated = append(make([]byte, 0, len(contents)), contents[:start]...)
+			}
+			marker := TruncationMarker
+			if bytes.IndexByte(line, '\033') >= 0 {
+				marker += ColorReset.escaped()
+			}
+			truncated = append(truncated, line[:truncationPoint(line, maxLength-len(marker))]...)
+			truncated = append(truncated, marker...)
+		} else if truncated != nil {
+			truncated = append(truncated, line...)
+		}
+		if end < len(contents) && truncated != nil {
+			truncated = append(truncated, '\n')
+		}
+		start = end + 1
+	}
+	if truncated == nil {
+		return contents
+	}
+	return truncated
+}
+
+// truncationPoint returns the largest length <= maxLength that doesn't split a utf-8 encoded rune
+// or an ansi escape sequence.
+func truncationPoint(value []byte, maxLength int) int {
+	if maxLength <= 0 {
+		return 0
+	}
+	if maxLength >= len(value) {
+		return len(value)
+	}
+	for maxLength > 0 && !utf8.RuneStart(value[maxLength]) {
+		maxLength--
+	}
+	if escape := bytes.LastIndexByte(value[:maxLength], '\033'); escape >= 0 && !isAnsiSequenceEnded(value[escape:maxLength]) {
+		return escape
+	}
+	return maxLength
+}
+
+// isAnsiSequenceEnded returns if an escape sequence (starting at its escape byte) ends within a value.
+func isAnsiSequenceEnded(sequence []byte) bool {
+	if len(sequence) < 2 || sequence[1] != '[' {
+		return len(sequence) >= 2
+	}
+	for _, b := range sequence[2:] {
+		if b >= 0x40 && b <= 0x7e {
+			return true
+		}
+	}
+	return false
+}
+
+// writePrefix writes the timestamp, label and namespace (as configured) to a buffer.
+func (wr *Writer) writePrefix(buf *bytes.Buffer, ts TimeSource) {
 	if wr.showTimestamp {
 		buf.WriteString(wr.GetTimestamp(ts))
 		buf.WriteRune(RuneSpace)
 	}
 
-	if wr.showLabel && len(wr.label) > 0 {
-		buf.WriteString(wr.FormatLabel())
-		buf.WriteRune(RuneSpace)
+	if namespace := NamespaceFromTimeSource(ts); len(namespace) > 0 && namespace != wr.namespace {
+		buf.WriteString(wr.renderPrefix(namespace))
+		return
 	}
-
-	buf.WriteString(message)
-	buf.WriteRune(RuneNewline)
-	return buf.WriteTo(w)
+	buf.WriteString(wr.formatPrefix())
 }
 
 // UseAnsiColors is a formatting option.
 func (wr *Writer) UseAnsiColors() bool { return wr.useAnsiColors }
 
 // SetUseAnsiColors sets a formatting option.
-func (wr *Writer) SetUseAnsiColors(useAnsiColors bool) { wr.useAnsiColors = useAnsiColors }
+func (wr *Writer) SetUseAnsiColors(useAnsiColors bool) {
+	wr.useAnsiColors = useAnsiColors
+	wr.resetFormatCache()
+}
+
+// ColorProfile returns the range of colors the writer's output supports.
+func (wr *Writer) ColorProfile() ColorProfile { return wr.colorProfile }
+
+// SetColorProfile sets the range of colors the writer's output supports; colors outside it are degraded.
+func (wr *Writer) SetColorProfile(profile ColorProfile) {
+	wr.colorProfile = profile
+	wr.resetFormatCache()
+}
 
 // ShowTimestamp is a formatting option.
 func (wr *Writer) ShowTimestamp() bool { return wr.showTimestamp }
@@ -265,13 +684,82 @@ func (wr *Writer) SetShowTimestamp(showTimestamp bool) { wr.showTimestamp = show
 func (wr *Writer) ShowLabel() bool { return wr.showLabel }
 
 // SetShowLabel sets a formatting option.
-func (wr *Writer) SetShowLabel(showLabel bool) { wr.showLabel = showLabel }
+func (wr *Writer) SetShowLabel(showLabel bool) {
+	wr.showLabel = showLabel
+	wr.resetFormatCache()
+}
+
+// ShowQuery is a formatting option.
+func (wr *Writer) ShowQuery() bool { return wr.showQuery }
+
+// SetShowQuery sets a formatting option.
+func (wr *Writer) SetShowQuery(showQuery bool) { wr.showQuery = showQuery }
+
+// ShowRoute is a formatting option.
+func (wr *Writer) ShowRoute() bool { return wr.showRoute }
+
+// SetShowRoute sets a formatting option; if set, request events show the matched route instead of the path.
+func (wr *Writer) SetShowRoute(showRoute bool) { wr.showRoute = showRoute }
+
+// ScrubbedQueryParams returns the query parameters whose values are redacted.
+// It defaults to `DefaultScrubbedQueryParams`.
+func (wr *Writer) ScrubbedQueryParams() []string {
+	if wr.scrubbedQueryParams == nil {
+		return DefaultScrubbedQueryParams
+	}
+	return wr.scrubbedQueryParams
+}
+
+// SetScrubbedQueryParams sets the query parameters whose values are redacted.
+func (wr *Writer) SetScrubbedQueryParams(params ...string) { wr.scrubbedQueryParams = params }
+
+// SecretScanner returns the scanner applied to output before it is written.
+func (wr *Writer) SecretScanner() *SecretScanner {
+	if wr == nil {
+		return nil
+	}
+	return wr.secretScanner
+}
+
+// SetSecretScanner sets a scanner applied to output before it is written, and by the agent's sinks that don't
+// write through the writer (see `SecretScanner`).
+func (wr *Writer) SetSecretScanner(scanner *SecretScanner) { wr.secretScanner = scanner }
 
 // Label is a formatting option.
 func (wr *Writer) Label() string { return wr.label }
 
 // SetLabel sets a formatting option.
-func (wr *Writer) SetLabel(label string) { wr.label = label }
+func (wr *Writer) SetLabel(label string) {
+	wr.label = label
+	wr.resetFormatCache()
+}
+
+// Namespace is a formatting option.
+func (wr *Writer) Namespace() string { return wr.namespace }
+
+// namespaceFor returns the namespace of a line written at a time source: the one of the agent that fired
+// its event (see `Agent.SetNamespace`), or else the writer's.
+func (wr *Writer) namespaceFor(ts TimeSource) string {
+	if namespace := NamespaceFromTimeSource(ts); len(namespace) > 0 {
+		return namespace
+	}
+	return wr.namespace
+}
+
+// SetNamespace sets a formatting option.
+func (wr *Writer) SetNamespace(namespace string) {
+	wr.namespace = namespace
+	wr.resetFormatCache()
+}
+
+// StaticFields returns the key/value fields stamped on every line, after the namespace.
+func (wr *Writer) StaticFields() map[string]interface{} { return wr.fields }
+
+// SetStaticFields sets key/value fields (e.g. the pod and node, see `KubernetesMetadata`) stamped on every line.
+func (wr *Writer) SetStaticFields(fields map[string]interface{}) {
+	wr.fields = fields
+	wr.resetFormatCache()
+}
 
 // TimeFormat is a formatting option.
 func (wr *Writer) TimeFormat() string { return wr.timeFormat }